@@ -119,7 +119,7 @@ export const restaurantAPI = {
   create: (data: any) => api.post("/restaurants", data),
   update: (id: string, data: any) => api.put(`/restaurants/${id}`, data),
   delete: (id: string) => api.delete(`/restaurants/${id}`),
-  getMenu: (id: string) => api.get(`/restaurants/${id}/menu`),
+  getMenu: (id: string) => api.get(`/restaurants/${id}/menu`, { params: { flat: true } }),
   edit: (id: string, data: any) => api.put(`/restaurants/${id}`, data),
   setVerified: (id: string, isVerified: boolean) =>
     api.patch(`/restaurants/${id}/verify`, { is_verified: isVerified }),
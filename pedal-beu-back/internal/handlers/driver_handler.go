@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/haile-paa/pedal-delivery/internal/models"
 	"github.com/haile-paa/pedal-delivery/internal/repositories"
+	"github.com/haile-paa/pedal-delivery/internal/services"
 	"github.com/haile-paa/pedal-delivery/pkg/auth"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -80,6 +81,7 @@ func (h *DriverHandler) GetAllDrivers(c *gin.Context) {
 			"total_trips": d.TotalTrips,
 			"is_online":   d.IsOnline,
 			"location":    locationPayload,
+			"risk_score":  services.CurrentRiskScore(d, time.Now()),
 			"user": gin.H{
 				"name":     userName,
 				"phone":    userPhone,
@@ -124,14 +126,16 @@ func (h *DriverHandler) GetDriverByID(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"id":          d.ID.Hex(),
-		"user_id":     d.UserID.Hex(),
-		"status":      d.Status,
-		"vehicle":     d.Vehicle,
-		"rating":      d.Rating,
-		"total_trips": d.TotalTrips,
-		"is_online":   d.IsOnline,
-		"location":    locationPayload,
+		"id":               d.ID.Hex(),
+		"user_id":          d.UserID.Hex(),
+		"status":           d.Status,
+		"vehicle":          d.Vehicle,
+		"rating":           d.Rating,
+		"total_trips":      d.TotalTrips,
+		"is_online":        d.IsOnline,
+		"location":         locationPayload,
+		"risk_score":       services.CurrentRiskScore(d, time.Now()),
+		"last_gps_anomaly": d.LastGPSAnomaly,
 		"user": gin.H{
 			"name":     userName,
 			"phone":    userPhone,
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LedgerHandler struct {
+	ledgerService services.LedgerService
+}
+
+func NewLedgerHandler(ledgerService services.LedgerService) *LedgerHandler {
+	return &LedgerHandler{ledgerService: ledgerService}
+}
+
+// queryPage reads the page and limit query parameters; the service
+// normalizes out-of-range values.
+func queryPage(c *gin.Context) (int64, int64) {
+	page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	return page, limit
+}
+
+// GetPayoutAccount godoc
+// @Summary Get my payout account
+// @Description The bank account or telebirr wallet the current driver's settlements are paid to; null if none is set
+// @Tags driver
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.PayoutAccount
+// @Router /api/v1/driver/payout-account [get]
+func (h *LedgerHandler) GetPayoutAccount(c *gin.Context) {
+	userID := c.MustGet("userID").(primitive.ObjectID)
+
+	account, err := h.ledgerService.GetPayoutAccount(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// UpdatePayoutAccount godoc
+// @Summary Set my payout account
+// @Description Set where the current driver's settlements are paid: method bank (bank_name and account_number required) or telebirr (phone required)
+// @Tags driver
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.PayoutAccount true "Payout account"
+// @Success 200 {object} models.PayoutAccount
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/driver/payout-account [put]
+func (h *LedgerHandler) UpdatePayoutAccount(c *gin.Context) {
+	userID := c.MustGet("userID").(primitive.ObjectID)
+
+	var req models.PayoutAccount
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := h.ledgerService.UpdatePayoutAccount(c.Request.Context(), userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// GetMyCashAccount godoc
+// @Summary Get my cash balance
+// @Description The cash-on-delivery cash the current driver holds for the platform and their cash limit; over_limit drivers aren't offered cash orders until they deposit
+// @Tags driver
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} services.CashAccount
+// @Router /api/v1/driver/cash [get]
+func (h *LedgerHandler) GetMyCashAccount(c *gin.Context) {
+	userID := c.MustGet("userID").(primitive.ObjectID)
+
+	account, err := h.ledgerService.GetCashAccount(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// GetDriverCashAccount godoc
+// @Summary Get a driver's cash balance
+// @Description The cash-on-delivery cash a driver holds for the platform and their cash limit (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Driver ID"
+// @Success 200 {object} services.CashAccount
+// @Router /api/v1/admin/drivers/{id}/cash [get]
+func (h *LedgerHandler) GetDriverCashAccount(c *gin.Context) {
+	account, err := h.ledgerService.GetDriverCashAccount(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// PostCashDeposit godoc
+// @Summary Record a cash deposit
+// @Description Record cash a driver handed in; it comes off their cash balance and is credited back on their ledger (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Driver ID"
+// @Param request body models.CashDepositRequest true "Deposit"
+// @Success 201 {object} models.LedgerEntry
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/drivers/{id}/cash-deposits [post]
+func (h *LedgerHandler) PostCashDeposit(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+
+	var req models.CashDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.ledgerService.PostCashDeposit(c.Request.Context(), c.Param("id"), adminID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// SetCashLimit godoc
+// @Summary Set a driver's cash limit
+// @Description Set the cash balance at which a driver stops being offered cash orders; null reverts to the default (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Driver ID"
+// @Param request body models.CashLimitRequest true "Cash limit"
+// @Success 200 {object} services.CashAccount
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/drivers/{id}/cash-limit [put]
+func (h *LedgerHandler) SetCashLimit(c *gin.Context) {
+	var req models.CashLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := h.ledgerService.SetCashLimit(c.Request.Context(), c.Param("id"), req.CashLimit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// GetDriverLedger godoc
+// @Summary Get a driver's ledger
+// @Description A driver's ledger entries, newest first, with their unsettled balance — positive if the platform owes them, negative if they owe the platform (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Driver ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} gin.H{"data": []models.LedgerEntry, "pagination": gin.H{"page":1,"limit":20,"total":100}, "balance": 0}
+// @Router /api/v1/admin/drivers/{id}/ledger [get]
+func (h *LedgerHandler) GetDriverLedger(c *gin.Context) {
+	page, limit := queryPage(c)
+
+	entries, total, balance, err := h.ledgerService.GetDriverLedger(c.Request.Context(), c.Param("id"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       entries,
+		"pagination": listPage(page, limit, total),
+		"balance":    balance,
+	})
+}
+
+// PostLedgerAdjustment godoc
+// @Summary Post a bonus or penalty
+// @Description Credit a bonus to a driver or debit a penalty from them; it's paid out or recovered in the next settlement run (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Driver ID"
+// @Param request body models.LedgerAdjustmentRequest true "Adjustment"
+// @Success 201 {object} models.LedgerEntry
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/drivers/{id}/ledger [post]
+func (h *LedgerHandler) PostLedgerAdjustment(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+
+	var req models.LedgerAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.ledgerService.PostAdjustment(c.Request.Context(), c.Param("id"), adminID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// RunSettlement godoc
+// @Summary Run a driver settlement
+// @Description Settle every unsettled ledger entry posted in the period and create a payout batch for each driver who's owed money. Drivers who owe the platform or have no payout account are skipped and their entries carried over (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.RunSettlementRequest true "Period"
+// @Success 201 {object} models.SettlementRun
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/settlements [post]
+func (h *LedgerHandler) RunSettlement(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+
+	var req models.RunSettlementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	run, err := h.ledgerService.RunSettlement(c.Request.Context(), adminID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, run)
+}
+
+// GetSettlements godoc
+// @Summary List settlement runs
+// @Description Settlement runs, newest first (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} gin.H{"data": []models.SettlementRun, "pagination": gin.H{"page":1,"limit":20,"total":100}}
+// @Router /api/v1/admin/settlements [get]
+func (h *LedgerHandler) GetSettlements(c *gin.Context) {
+	page, limit := queryPage(c)
+
+	runs, total, err := h.ledgerService.GetSettlements(c.Request.Context(), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       runs,
+		"pagination": listPage(page, limit, total),
+	})
+}
+
+// GetSettlement godoc
+// @Summary Get a settlement run
+// @Description A settlement run with its per-driver payout batches (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Settlement ID"
+// @Success 200 {object} gin.H{"settlement": models.SettlementRun, "payouts": []models.PayoutBatch}
+// @Router /api/v1/admin/settlements/{id} [get]
+func (h *LedgerHandler) GetSettlement(c *gin.Context) {
+	run, batches, err := h.ledgerService.GetSettlement(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"settlement": run,
+		"payouts":    batches,
+	})
+}
+
+// ExportSettlement godoc
+// @Summary Export a bulk-payment file
+// @Description Download a settlement run's payouts as a bank bulk-transfer CSV (format=bank) or a telebirr bulk-payment CSV (format=telebirr); each lists only the payouts going to that channel (admin only)
+// @Tags admin
+// @Produce text/csv
+// @Security BearerAuth
+// @Param id path string true "Settlement ID"
+// @Param format query string true "bank or telebirr"
+// @Success 200 {file} file
+// @Router /api/v1/admin/settlements/{id}/export [get]
+func (h *LedgerHandler) ExportSettlement(c *gin.Context) {
+	format := c.Query("format")
+	if format != "bank" && format != "telebirr" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be bank or telebirr"})
+		return
+	}
+
+	run, batches, err := h.ledgerService.GetSettlement(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := services.WritePayoutFile(&buf, format, batches); err != nil {
+		log.Printf("Error writing payout file: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export payouts"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="payouts-%s-%s.csv"`, run.ID.Hex(), format))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -162,8 +163,9 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	// Only drivers who are online and within range will see it —
 	// the AvailableOrdersScreen fetches via REST with geo filtering;
 	// the WebSocket push is just a "hey, refresh" signal so they
-	// know to call fetchAvailableOrders() immediately.
-	if websocket.GlobalHub != nil {
+	// know to call fetchAvailableOrders() immediately. An order paid by
+	// hosted checkout is announced once it's paid (OnOrderReleased).
+	if websocket.GlobalHub != nil && order.Status != models.OrderAwaitingPayment {
 		websocket.GlobalHub.BroadcastToRoom("drivers", websocket.WebSocketEvent{
 			Type: "order:new",
 			Data: order,
@@ -173,6 +175,33 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	c.JSON(http.StatusCreated, order)
 }
 
+// QuoteOrder prices a cart exactly as CreateOrder would (delivery fee,
+// zone surge, service charge, tax) without placing it, for the checkout
+// screen to show before the customer confirms.
+func (h *OrderHandler) QuoteOrder(c *gin.Context) {
+	userID := c.MustGet("userID").(primitive.ObjectID)
+	userRole := c.MustGet("userRole").(string)
+
+	if userRole != "customer" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only customers can request quotes"})
+		return
+	}
+
+	var req models.OrderQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quote, err := h.orderService.QuoteOrder(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
 func (h *OrderHandler) VerifyOrderPayment(c *gin.Context) {
 	userID := c.MustGet("userID").(primitive.ObjectID)
 	userRole := c.MustGet("userRole").(string)
@@ -195,6 +224,15 @@ func (h *OrderHandler) VerifyOrderPayment(c *gin.Context) {
 	}
 
 	order, err := h.orderService.VerifyOrderPayment(c.Request.Context(), orderID, userID, &req)
+	if errors.Is(err, services.ErrPaymentVerificationQueued) {
+		// Still being checked; the result arrives as payment:verification
+		// in the order's room.
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": err.Error(),
+			"order":   order,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -256,7 +294,11 @@ func (h *OrderHandler) ReviewPaymentProof(c *gin.Context) {
 
 	order, err := h.orderService.ReviewPaymentProof(c.Request.Context(), orderID, adminID, &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrPaymentReviewClaimed) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -270,6 +312,24 @@ func (h *OrderHandler) GetPaymentVerificationHealth(c *gin.Context) {
 	c.JSON(http.StatusOK, h.orderService.GetPaymentVerificationHealth(c.Request.Context()))
 }
 
+// RebuildAggregates godoc
+// @Summary Rebuild rating and earnings aggregates
+// @Description Recompute every driver's and restaurant's rating, trip, acceptance and earnings totals, and the daily earnings buckets, from order history. Safe to rerun (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} services.AggregateRebuildReport
+// @Router /api/v1/admin/aggregates/rebuild [post]
+func (h *OrderHandler) RebuildAggregates(c *gin.Context) {
+	report, err := h.orderService.RebuildAggregates(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
 // @Summary Get order by ID
 // @Description Get order details by order ID
 // @Tags orders
@@ -457,6 +517,60 @@ func (h *OrderHandler) RateOrder(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Order rated successfully"})
 }
 
+// GetTipPresets godoc
+// @Summary Get tip presets
+// @Description The fixed and percentage tips to suggest at checkout and after delivery, the largest tip accepted and how many hours after delivery an order can still be tipped
+// @Tags orders
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} services.TipPresets
+// @Router /api/v1/orders/tip-presets [get]
+func (h *OrderHandler) GetTipPresets(c *gin.Context) {
+	c.JSON(http.StatusOK, h.orderService.GetTipPresets())
+}
+
+// TipOrder godoc
+// @Summary Tip the driver
+// @Description Tip the driver of a delivered order that wasn't tipped at checkout, as an amount or a percentage of the food subtotal. It's paid from the customer's wallet and goes to the driver in full
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Param request body models.TipRequest true "Tip"
+// @Success 200 {object} models.Order
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/orders/{id}/tip [post]
+func (h *OrderHandler) TipOrder(c *gin.Context) {
+	userID := c.MustGet("userID").(primitive.ObjectID)
+	userRole := c.MustGet("userRole").(string)
+
+	if userRole != "customer" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only customers can tip"})
+		return
+	}
+
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req models.TipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := h.orderService.AddTip(c.Request.Context(), orderID, userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
 // @Summary Get available orders for drivers
 // @Description Get orders available for pickup by drivers
 // @Tags driver
@@ -534,6 +648,42 @@ func (h *OrderHandler) RejectOrder(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Order rejected"})
 }
 
+// ConfirmCashCollected godoc
+// @Summary Confirm cash collected
+// @Description The assigned driver confirms how much cash they took for a cash-on-delivery order. Required before a cash order can be marked delivered; an amount short of the order total flags the order for review
+// @Tags driver
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Param request body models.ConfirmCashRequest true "Cash received"
+// @Success 200 {object} models.CashCollection
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/driver/orders/{id}/cash-collected [post]
+func (h *OrderHandler) ConfirmCashCollected(c *gin.Context) {
+	driverID := c.MustGet("userID").(primitive.ObjectID)
+
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req models.ConfirmCashRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection, err := h.orderService.ConfirmCashCollected(c.Request.Context(), orderID, driverID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
 // GetDriverOrders returns orders assigned to the authenticated driver.
 // GET /api/v1/orders/driver
 func (h *OrderHandler) GetDriverOrders(c *gin.Context) {
@@ -599,6 +749,10 @@ func (h *OrderHandler) AcceptOrder(c *gin.Context) {
 	}
 
 	err = h.orderService.AssignDriver(c.Request.Context(), orderID, driverID)
+	if errors.Is(err, services.ErrCashLimitReached) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
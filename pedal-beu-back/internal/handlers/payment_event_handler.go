@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haile-paa/pedal-delivery/internal/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PaymentEventHandler struct {
+	paymentEventService services.PaymentEventService
+}
+
+func NewPaymentEventHandler(paymentEventService services.PaymentEventService) *PaymentEventHandler {
+	return &PaymentEventHandler{paymentEventService: paymentEventService}
+}
+
+// PaymentWebhook godoc
+// @Summary Payment provider webhook
+// @Description Receives a payment provider's signed callback about a hosted checkout; the provider is the order's payment method (e.g. chapa). Each event is stored and applied once however often it's delivered; a 500 means it was stored but couldn't be applied, for the provider to retry
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param provider path string true "Payment provider"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhooks/payments/{provider} [post]
+func (h *PaymentEventHandler) PaymentWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "could not read body"})
+		return
+	}
+
+	event, err := h.paymentEventService.HandleWebhook(c.Request.Context(), c.Param("provider"), c.Request.Header, body)
+	switch {
+	case errors.Is(err, services.ErrUnknownPaymentProvider):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrInvalidWebhookSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case err != nil && event == nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "event_id": event.ID})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"received":  true,
+		"event_id":  event.ID,
+		"duplicate": event.Deliveries > 1,
+	})
+}
+
+// GetPaymentEvents godoc
+// @Summary List payment events
+// @Description Payment provider webhooks as received, newest first, with whether each was applied (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param provider query string false "Only this provider's events"
+// @Param status query string false "received, processing, processed or failed"
+// @Param order_id query string false "Only this order's events"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} gin.H{"data": []models.PaymentEvent, "pagination": gin.H{"page":1,"limit":20,"total":100}}
+// @Router /api/v1/admin/payment-events [get]
+func (h *PaymentEventHandler) GetPaymentEvents(c *gin.Context) {
+	page, limit := queryPage(c)
+
+	events, total, err := h.paymentEventService.GetEvents(c.Request.Context(), c.Query("provider"), c.Query("status"), c.Query("order_id"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       events,
+		"pagination": listPage(page, limit, total),
+	})
+}
+
+// GetPaymentEvent godoc
+// @Summary Get a payment event
+// @Description A payment provider webhook with its headers and raw body (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment event ID"
+// @Success 200 {object} models.PaymentEvent
+// @Router /api/v1/admin/payment-events/{id} [get]
+func (h *PaymentEventHandler) GetPaymentEvent(c *gin.Context) {
+	event, err := h.paymentEventService.GetEvent(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// ReplayPaymentEvent godoc
+// @Summary Replay a payment event
+// @Description Apply a failed payment provider webhook again, re-checking its signature (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment event ID"
+// @Success 200 {object} models.PaymentEvent
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/payment-events/{id}/replay [post]
+func (h *PaymentEventHandler) ReplayPaymentEvent(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+
+	event, err := h.paymentEventService.ReplayEvent(c.Request.Context(), c.Param("id"), adminID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrPaymentEventNotReplayable) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "event": event})
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PaymentReviewHandler struct {
+	orderService services.OrderService
+}
+
+func NewPaymentReviewHandler(orderService services.OrderService) *PaymentReviewHandler {
+	return &PaymentReviewHandler{orderService: orderService}
+}
+
+// GetPaymentReviewQueue godoc
+// @Summary Payment review queue
+// @Description Orders whose payment is waiting for an admin, longest waiting first, each with its proof, receipt and provider response, its SLA timer, who has claimed it and the customer's payment history (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} gin.H{"data": []services.PaymentReviewItem, "pagination": gin.H{"page":1,"limit":20,"total":100}}
+// @Router /api/v1/admin/payment-reviews [get]
+func (h *PaymentReviewHandler) GetPaymentReviewQueue(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+	page, limit := queryPage(c)
+
+	items, total, err := h.orderService.GetPaymentReviewQueue(c.Request.Context(), adminID, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	overdue := 0
+	for _, item := range items {
+		if item.Overdue {
+			overdue++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":       items,
+		"pagination": listPage(page, limit, total),
+		"overdue":    overdue, // on this page
+	})
+}
+
+// ClaimPaymentReview godoc
+// @Summary Claim a payment review
+// @Description Take an order's payment review so no other admin reviews it at the same time; the claim lapses after PAYMENT_REVIEW_CLAIM_MIN, and claiming again renews it (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Success 200 {object} models.Order
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/payment-reviews/{id}/claim [post]
+func (h *PaymentReviewHandler) ClaimPaymentReview(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	order, err := h.orderService.ClaimPaymentReview(c.Request.Context(), orderID, adminID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrPaymentReviewClaimed) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// ReleasePaymentReview godoc
+// @Summary Release a payment review
+// @Description Hand back an order's payment review you claimed without deciding it (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/payment-reviews/{id}/claim [delete]
+func (h *PaymentReviewHandler) ReleasePaymentReview(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	if err := h.orderService.ReleasePaymentReview(c.Request.Context(), orderID, adminID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Payment review released"})
+}
+
+// BulkReviewPaymentProof godoc
+// @Summary Approve or reject payments in bulk
+// @Description Approve or reject up to 50 orders' payments with the same notes. Each is decided on its own: orders another admin has claimed, or already decided, come back with an error and the rest still go through (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.BulkReviewPaymentProofRequest true "Orders and decision"
+// @Success 200 {object} gin.H{"data": []services.PaymentReviewResult, "reviewed": 1, "failed": 0}
+// @Router /api/v1/admin/payment-reviews/bulk [post]
+func (h *PaymentReviewHandler) BulkReviewPaymentProof(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+
+	var req models.BulkReviewPaymentProofRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := h.orderService.BulkReviewPaymentProof(c.Request.Context(), adminID, &req)
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     results,
+		"reviewed": len(results) - failed,
+		"failed":   failed,
+	})
+}
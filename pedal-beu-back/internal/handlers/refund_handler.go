@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+	"github.com/haile-paa/pedal-delivery/internal/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RefundHandler struct {
+	refundService services.RefundService
+}
+
+func NewRefundHandler(refundService services.RefundService) *RefundHandler {
+	return &RefundHandler{refundService: refundService}
+}
+
+// CreateRefund godoc
+// @Summary Issue a refund
+// @Description Refund what's left of an order (type=full) or particular item lines (type=partial), to the original payment method or the customer's wallet. Wallet refunds are processed at once; original-method refunds stay pending until the transfer back is recorded. charge_driver charges any delivery fee and tip refunded back to the driver (admin)
+// @Tags refunds
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateRefundRequest true "Refund"
+// @Success 201 {object} models.Refund
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/refunds [post]
+func (h *RefundHandler) CreateRefund(c *gin.Context) {
+	actorID := c.MustGet("userID").(primitive.ObjectID)
+	actorRole := c.MustGet("userRole").(string)
+
+	var req models.CreateRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := h.refundService.CreateRefund(c.Request.Context(), actorID, actorRole, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, refund)
+}
+
+// GetRefunds godoc
+// @Summary List refunds
+// @Description Refunds, newest first (admin)
+// @Tags refunds
+// @Produce json
+// @Security BearerAuth
+// @Param order_id query string false "Only this order's refunds"
+// @Param status query string false "pending or processed"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} gin.H{"data": []models.Refund, "pagination": gin.H{"page":1,"limit":20,"total":100}}
+// @Router /api/v1/refunds [get]
+func (h *RefundHandler) GetRefunds(c *gin.Context) {
+	page, limit := queryPage(c)
+
+	refunds, total, err := h.refundService.GetRefunds(c.Request.Context(), c.Query("order_id"), c.Query("status"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       refunds,
+		"pagination": listPage(page, limit, total),
+	})
+}
+
+// GetRefund godoc
+// @Summary Get a refund
+// @Tags refunds
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Refund ID"
+// @Success 200 {object} models.Refund
+// @Router /api/v1/refunds/{id} [get]
+func (h *RefundHandler) GetRefund(c *gin.Context) {
+	refund, err := h.refundService.GetRefund(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, refund)
+}
+
+// ProcessRefund godoc
+// @Summary Process a refund
+// @Description Record that a pending refund has been paid back, with the reference of the transfer (not needed for cash). For a wallet refund whose credit failed, retries it (admin)
+// @Tags refunds
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Refund ID"
+// @Param request body models.ProcessRefundRequest false "Transfer reference"
+// @Success 200 {object} models.Refund
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/refunds/{id}/process [post]
+func (h *RefundHandler) ProcessRefund(c *gin.Context) {
+	actorID := c.MustGet("userID").(primitive.ObjectID)
+
+	var req models.ProcessRefundRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	refund, err := h.refundService.ProcessRefund(c.Request.Context(), c.Param("id"), actorID, req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, repositories.ErrRefundProcessed) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, refund)
+}
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"math"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/haile-paa/pedal-delivery/internal/models"
@@ -337,23 +342,83 @@ func (h *RestaurantHandler) SearchRestaurants(c *gin.Context) {
 
 // GetRestaurantMenu godoc
 // @Summary Get restaurant menu
-// @Description Get menu items for a specific restaurant
+// @Description Get the menu for a specific restaurant, grouped by category with only what can be ordered right now. With flat=true (or page/limit) returns the plain item list instead.
 // @Tags restaurants
 // @Accept json
 // @Produce json
 // @Param id path string true "Restaurant ID"
+// @Param flat query bool false "Return the flat item list"
+// @Param category query string false "Only this category"
+// @Param available query bool false "Only available (true) or unavailable (false) items"
+// @Param q query string false "Item name contains"
+// @Param diet query string false "Comma-separated dietary tags every item must have (vegan, vegetarian, fasting, halal, gluten_free)"
+// @Param exclude_allergens query string false "Comma-separated allergens to leave out (nuts, dairy, eggs, gluten, soy, fish, shellfish, sesame)"
+// @Param page query int false "Page number (paginates the response)"
+// @Param limit query int false "Items per page (paginates the response)"
+// @Success 200 {object} services.RestaurantMenu
 // @Success 200 {array} models.MenuItem
+// @Success 200 {object} gin.H{"data": []models.MenuItem, "pagination": gin.H{"page":1,"limit":20,"total":100}}
 // @Router /restaurants/{id}/menu [get]
 func (h *RestaurantHandler) GetRestaurantMenu(c *gin.Context) {
 	restaurantID := c.Param("id")
 
-	menu, err := h.service.GetMenuItems(c.Request.Context(), restaurantID)
+	var query models.MenuItemQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	if !query.Flat && query.Page == 0 && query.Limit == 0 {
+		grouped, err := h.service.GetMenu(c.Request.Context(), restaurantID, time.Now(), query)
+		if errors.Is(err, services.ErrInvalidDietaryFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Restaurant or menu not found"})
+			return
+		}
+		c.JSON(http.StatusOK, grouped)
+		return
+	}
+
+	menu, total, err := h.service.GetMenuItems(c.Request.Context(), restaurantID, query)
+	if errors.Is(err, services.ErrInvalidDietaryFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Restaurant or menu not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, menu)
+	// Management screens fetch the whole menu as a bare array.
+	if query.Page == 0 && query.Limit == 0 {
+		c.JSON(http.StatusOK, menu)
+		return
+	}
+
+	page, limit := query.Page, query.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": menu,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
 }
 
 // CreateRestaurant godoc
@@ -421,7 +486,9 @@ func (h *RestaurantHandler) UpdateRestaurant(c *gin.Context) {
 
 	restaurantID := c.Param("id")
 
-	restaurant, err := h.service.UpdateRestaurant(c.Request.Context(), restaurantID, req)
+	actorID := c.MustGet("userID").(primitive.ObjectID)
+
+	restaurant, err := h.service.UpdateRestaurant(c.Request.Context(), restaurantID, req, actorID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -472,7 +539,9 @@ func (h *RestaurantHandler) AddMenuItem(c *gin.Context) {
 		return
 	}
 
-	menuItem, err := h.service.AddMenuItem(c.Request.Context(), restaurantID, req)
+	actorID := c.MustGet("userID").(primitive.ObjectID)
+
+	menuItem, err := h.service.AddMenuItem(c.Request.Context(), restaurantID, req, actorID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -503,7 +572,9 @@ func (h *RestaurantHandler) UpdateMenuItem(c *gin.Context) {
 		return
 	}
 
-	menuItem, err := h.service.UpdateMenuItem(c.Request.Context(), restaurantID, itemID, req)
+	actorID := c.MustGet("userID").(primitive.ObjectID)
+
+	menuItem, err := h.service.UpdateMenuItem(c.Request.Context(), restaurantID, itemID, req, actorID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -511,3 +582,319 @@ func (h *RestaurantHandler) UpdateMenuItem(c *gin.Context) {
 
 	c.JSON(http.StatusOK, menuItem)
 }
+
+// DeleteMenuItem godoc
+// @Summary Archive menu item
+// @Description Take a menu item off the menu. It's archived rather than deleted, so past orders still resolve it, and can be brought back (admin only)
+// @Tags restaurants
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Restaurant ID"
+// @Param itemId path string true "Menu Item ID"
+// @Success 200 {object} gin.H{"message": "Menu item archived"}
+// @Router /restaurants/{id}/menu/{itemId} [delete]
+func (h *RestaurantHandler) DeleteMenuItem(c *gin.Context) {
+	actorID := c.MustGet("userID").(primitive.ObjectID)
+
+	if err := h.service.DeleteMenuItem(c.Request.Context(), c.Param("id"), c.Param("itemId"), actorID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Menu item archived"})
+}
+
+// UnarchiveMenuItem godoc
+// @Summary Unarchive menu item
+// @Description Put an archived menu item back on the menu (admin only)
+// @Tags restaurants
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Restaurant ID"
+// @Param itemId path string true "Menu Item ID"
+// @Success 200 {object} gin.H{"message": "Menu item restored"}
+// @Router /restaurants/{id}/menu/{itemId}/unarchive [post]
+func (h *RestaurantHandler) UnarchiveMenuItem(c *gin.Context) {
+	actorID := c.MustGet("userID").(primitive.ObjectID)
+
+	if err := h.service.UnarchiveMenuItem(c.Request.Context(), c.Param("id"), c.Param("itemId"), actorID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Menu item restored"})
+}
+
+// GetMenuHistory godoc
+// @Summary Get menu change history
+// @Description List who changed what on a restaurant's menu and when, newest first. field=price gives the price-change log (admin only)
+// @Tags restaurants
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Restaurant ID"
+// @Param item_id query string false "Only this menu item"
+// @Param field query string false "Only changes to this field (e.g. price, is_available)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} gin.H{"data": []models.MenuItemChange, "pagination": gin.H{"page":1,"limit":20,"total":100}}
+// @Router /restaurants/{id}/menu/history [get]
+func (h *RestaurantHandler) GetMenuHistory(c *gin.Context) {
+	var query models.MenuHistoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	changes, total, err := h.service.GetMenuHistory(c.Request.Context(), c.Param("id"), query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, limit := query.Page, query.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": changes,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// RestoreMenuItemVersion godoc
+// @Summary Restore a previous menu item version
+// @Description Put a menu item back to how it was before the given history entry. The restore is recorded in the history too (admin only)
+// @Tags restaurants
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Restaurant ID"
+// @Param changeId path string true "Menu history entry ID"
+// @Success 200 {object} models.MenuItem
+// @Router /restaurants/{id}/menu/history/{changeId}/restore [post]
+func (h *RestaurantHandler) RestoreMenuItemVersion(c *gin.Context) {
+	actorID := c.MustGet("userID").(primitive.ObjectID)
+
+	menuItem, err := h.service.RestoreMenuItemVersion(c.Request.Context(), c.Param("id"), c.Param("changeId"), actorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, menuItem)
+}
+
+// GetMenuCategories godoc
+// @Summary Get menu categories
+// @Description Get a restaurant's menu categories in display order, including inactive and out-of-schedule ones
+// @Tags restaurants
+// @Produce json
+// @Param id path string true "Restaurant ID"
+// @Success 200 {array} models.MenuCategory
+// @Router /restaurants/{id}/categories [get]
+func (h *RestaurantHandler) GetMenuCategories(c *gin.Context) {
+	categories, err := h.service.ListMenuCategories(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// CreateMenuCategory godoc
+// @Summary Create menu category
+// @Description Add a menu category, optionally with a serving schedule (admin only)
+// @Tags restaurants
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Restaurant ID"
+// @Param category body models.MenuCategoryRequest true "Category data"
+// @Success 201 {object} models.MenuCategory
+// @Router /restaurants/{id}/categories [post]
+func (h *RestaurantHandler) CreateMenuCategory(c *gin.Context) {
+	var req models.MenuCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.service.CreateMenuCategory(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateMenuCategory godoc
+// @Summary Update menu category
+// @Description Replace a menu category's details and schedule (admin only)
+// @Tags restaurants
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Restaurant ID"
+// @Param categoryId path string true "Category ID"
+// @Param category body models.MenuCategoryRequest true "Category data"
+// @Success 200 {object} models.MenuCategory
+// @Router /restaurants/{id}/categories/{categoryId} [put]
+func (h *RestaurantHandler) UpdateMenuCategory(c *gin.Context) {
+	var req models.MenuCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.service.UpdateMenuCategory(c.Request.Context(), c.Param("id"), c.Param("categoryId"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteMenuCategory godoc
+// @Summary Delete menu category
+// @Description Delete a menu category; its items stay on the menu under the same name (admin only)
+// @Tags restaurants
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Restaurant ID"
+// @Param categoryId path string true "Category ID"
+// @Success 200 {object} gin.H{"message": "Menu category deleted successfully"}
+// @Router /restaurants/{id}/categories/{categoryId} [delete]
+func (h *RestaurantHandler) DeleteMenuCategory(c *gin.Context) {
+	if err := h.service.DeleteMenuCategory(c.Request.Context(), c.Param("id"), c.Param("categoryId")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Menu category deleted successfully"})
+}
+
+// ExportMenu godoc
+// @Summary Export restaurant menu
+// @Description Download a restaurant's categories and items as JSON (default) or CSV, in the format the import endpoint accepts (admin only)
+// @Tags restaurants
+// @Produce json
+// @Produce text/csv
+// @Security ApiKeyAuth
+// @Param id path string true "Restaurant ID"
+// @Param format query string false "json or csv" default(json)
+// @Success 200 {object} models.MenuFile
+// @Router /restaurants/{id}/menu/export [get]
+func (h *RestaurantHandler) ExportMenu(c *gin.Context) {
+	restaurantID := c.Param("id")
+
+	file, err := h.service.ExportMenu(c.Request.Context(), restaurantID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="menu-%s.json"`, restaurantID))
+		c.JSON(http.StatusOK, file)
+	case "csv":
+		var buf bytes.Buffer
+		if err := services.WriteMenuCSV(&buf, file); err != nil {
+			log.Printf("Error writing menu CSV: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export menu"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="menu-%s.csv"`, restaurantID))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+	}
+}
+
+// ImportMenu godoc
+// @Summary Import restaurant menu
+// @Description Create or update a restaurant's menu from a JSON or CSV file. Items are matched by SKU and categories by name, so re-importing updates rather than duplicates. Every row is validated first; if any fails nothing is written and the errors are returned (422). dry_run=true validates and reports without writing (admin only)
+// @Tags restaurants
+// @Accept json
+// @Accept text/csv
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Restaurant ID"
+// @Param format query string false "json or csv; defaults to csv for CSV and multipart uploads, json otherwise"
+// @Param dry_run query bool false "Validate only"
+// @Param file formData file false "CSV file (multipart uploads)"
+// @Param menu body models.MenuFile false "JSON menu"
+// @Success 200 {object} services.MenuImportReport
+// @Failure 422 {object} services.MenuImportReport
+// @Router /restaurants/{id}/menu/import [post]
+func (h *RestaurantHandler) ImportMenu(c *gin.Context) {
+	restaurantID := c.Param("id")
+	actorID := c.MustGet("userID").(primitive.ObjectID)
+	dryRun := c.Query("dry_run") == "true"
+
+	format := c.Query("format")
+	if format == "" {
+		format = "json"
+		if contentType := c.ContentType(); contentType == "text/csv" || contentType == "multipart/form-data" {
+			format = "csv"
+		}
+	}
+
+	var report *services.MenuImportReport
+	var err error
+	switch format {
+	case "json":
+		var file models.MenuFile
+		if bindErr := c.ShouldBindJSON(&file); bindErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": bindErr.Error()})
+			return
+		}
+		report, err = h.service.ImportMenu(c.Request.Context(), restaurantID, &file, dryRun, actorID)
+	case "csv":
+		body := io.Reader(c.Request.Body)
+		if c.ContentType() == "multipart/form-data" {
+			upload, formErr := c.FormFile("file")
+			if formErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is required"})
+				return
+			}
+			opened, openErr := upload.Open()
+			if openErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read CSV file"})
+				return
+			}
+			defer opened.Close()
+			body = opened
+		}
+		report, err = h.service.ImportMenuCSV(c.Request.Context(), restaurantID, body, dryRun, actorID)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(report.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
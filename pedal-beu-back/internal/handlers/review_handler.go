@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+	"github.com/haile-paa/pedal-delivery/internal/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReviewHandler struct {
+	reviewService services.ReviewService
+}
+
+func NewReviewHandler(reviewService services.ReviewService) *ReviewHandler {
+	return &ReviewHandler{reviewService: reviewService}
+}
+
+// reviewError answers a review service error: 403 for ErrReviewNotAllowed,
+// 409 for a second review of the same order, 400 otherwise.
+func reviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrReviewNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, repositories.ErrDuplicateReview):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// listPage is the pagination block of the review and ledger list responses.
+func listPage(page, limit, total int64) gin.H {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	if totalPages < 1 {
+		totalPages = 1
+	}
+	return gin.H{
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": totalPages,
+	}
+}
+
+// CreateReview godoc
+// @Summary Review a delivered order's restaurant
+// @Description Publish a review of the restaurant an order came from. Only the customer who placed the order can, once it's delivered, and only once per order. Rating and comment default to the order's rating. Photos are URLs from POST /upload with type=reviews (at most 5)
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Param request body models.CreateReviewRequest true "Review"
+// @Success 201 {object} models.Review
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/orders/{id}/review [post]
+func (h *ReviewHandler) CreateReview(c *gin.Context) {
+	userID := c.MustGet("userID").(primitive.ObjectID)
+	if c.MustGet("userRole").(string) != "customer" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only customers can review orders"})
+		return
+	}
+
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req models.CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := h.reviewService.CreateReview(c.Request.Context(), orderID, userID, req)
+	if err != nil {
+		reviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// GetRestaurantReviews godoc
+// @Summary Get restaurant reviews
+// @Description Published reviews of a restaurant with the owner's replies, plus the average rating and a 1-5 star histogram
+// @Tags reviews
+// @Produce json
+// @Param id path string true "Restaurant ID"
+// @Param rating query int false "Only reviews with this many stars"
+// @Param with_photos query bool false "Only reviews with photos"
+// @Param sort query string false "newest (default), oldest, highest or lowest"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} gin.H{"data": []models.Review, "pagination": gin.H{"page":1,"limit":20,"total":100}, "summary": repositories.ReviewSummary}
+// @Router /restaurants/{id}/reviews [get]
+func (h *ReviewHandler) GetRestaurantReviews(c *gin.Context) {
+	var query models.ReviewQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	reviews, total, summary, err := h.reviewService.GetRestaurantReviews(c.Request.Context(), c.Param("id"), query)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       reviews,
+		"pagination": listPage(query.Page, query.Limit, total),
+		"summary":    summary,
+	})
+}
+
+// ReplyToReview godoc
+// @Summary Reply to a review
+// @Description Set the restaurant's public reply to a review, replacing any earlier one. Only the restaurant's owner or an admin
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Restaurant ID"
+// @Param reviewId path string true "Review ID"
+// @Param request body models.ReviewReplyRequest true "Reply"
+// @Success 200 {object} models.Review
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/restaurants/{id}/reviews/{reviewId}/reply [post]
+func (h *ReviewHandler) ReplyToReview(c *gin.Context) {
+	userID := c.MustGet("userID").(primitive.ObjectID)
+	userRole := c.MustGet("userRole").(string)
+
+	var req models.ReviewReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := h.reviewService.ReplyToReview(c.Request.Context(), c.Param("id"), c.Param("reviewId"), userID, userRole, req)
+	if err != nil {
+		reviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// FlagReview godoc
+// @Summary Flag a review
+// @Description Report a review for moderation. Each user can flag a review once
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Restaurant ID"
+// @Param reviewId path string true "Review ID"
+// @Param request body models.FlagReviewRequest true "Reason"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/restaurants/{id}/reviews/{reviewId}/flag [post]
+func (h *ReviewHandler) FlagReview(c *gin.Context) {
+	userID := c.MustGet("userID").(primitive.ObjectID)
+
+	var req models.FlagReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.reviewService.FlagReview(c.Request.Context(), c.Param("id"), c.Param("reviewId"), userID, req); err != nil {
+		reviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review flagged for moderation"})
+}
+
+// GetReviewsForModeration godoc
+// @Summary List reviews for moderation
+// @Description Flagged reviews, oldest first, with who flagged them and why. status=hidden, published or all lists those instead, newest first (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "flagged (default), hidden, published or all"
+// @Param restaurant_id query string false "Only this restaurant"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} gin.H{"data": []models.Review, "pagination": gin.H{"page":1,"limit":20,"total":100}}
+// @Router /api/v1/admin/reviews [get]
+func (h *ReviewHandler) GetReviewsForModeration(c *gin.Context) {
+	var query models.AdminReviewQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	reviews, total, err := h.reviewService.GetReviewsForModeration(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       reviews,
+		"pagination": listPage(query.Page, query.Limit, total),
+	})
+}
+
+// ModerateReview godoc
+// @Summary Moderate a review
+// @Description hide takes a review off the restaurant's page, unhide puts it back, dismiss_flags keeps it up. Every action clears the review's flags (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Param request body models.ModerateReviewRequest true "Decision"
+// @Success 200 {object} models.Review
+// @Router /api/v1/admin/reviews/{id}/moderation [patch]
+func (h *ReviewHandler) ModerateReview(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+
+	var req models.ModerateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := h.reviewService.ModerateReview(c.Request.Context(), c.Param("id"), adminID, req)
+	if err != nil {
+		reviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/services"
+)
+
+type SearchHandler struct {
+	searchService services.SearchService
+}
+
+func NewSearchHandler(searchService services.SearchService) *SearchHandler {
+	return &SearchHandler{searchService: searchService}
+}
+
+// Search godoc
+// @Summary Search restaurants and dishes
+// @Description Typo-tolerant search over restaurant names, cuisines and menu items (Amharic or English), ranked by relevance, distance, rating and open status, with cuisine and price-band facets
+// @Tags search
+// @Produce json
+// @Param q query string true "Search text"
+// @Param latitude query number false "Customer latitude"
+// @Param longitude query number false "Customer longitude"
+// @Param cuisine query string false "Only this cuisine"
+// @Param price_band query string false "Only this price band ($, $$ or $$$)"
+// @Param diet query string false "Comma-separated dietary tags a dish must have (vegan, vegetarian, fasting, halal, gluten_free)"
+// @Param exclude_allergens query string false "Comma-separated allergens a dish mustn't contain"
+// @Param limit query int false "Max results" default(20)
+// @Success 200 {object} services.SearchResponse
+// @Router /search [get]
+func (h *SearchHandler) Search(c *gin.Context) {
+	var query models.SearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search parameters"})
+		return
+	}
+
+	response, err := h.searchService.Search(c.Request.Context(), query)
+	if errors.Is(err, services.ErrInvalidDietaryFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error searching: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
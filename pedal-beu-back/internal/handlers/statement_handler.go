@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+	"github.com/haile-paa/pedal-delivery/internal/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type StatementHandler struct {
+	statementService services.StatementService
+}
+
+func NewStatementHandler(statementService services.StatementService) *StatementHandler {
+	return &StatementHandler{statementService: statementService}
+}
+
+// GetCommission godoc
+// @Summary Get a restaurant's commission
+// @Description The commission the platform takes from each of the restaurant's delivered orders; is_default is true when it's the platform-wide rate rather than one set for this restaurant (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Restaurant ID"
+// @Success 200 {object} gin.H{"commission": models.Commission, "is_default": true}
+// @Router /api/v1/restaurants/{id}/commission [get]
+func (h *StatementHandler) GetCommission(c *gin.Context) {
+	commission, isDefault, err := h.statementService.GetCommission(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"commission": commission,
+		"is_default": isDefault,
+	})
+}
+
+// SetCommission godoc
+// @Summary Set a restaurant's commission
+// @Description Set the percentage of food sales and/or fixed amount per order the platform takes from the restaurant. Applies to statements generated from now on (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Restaurant ID"
+// @Param request body models.Commission true "Commission"
+// @Success 200 {object} models.Commission
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/restaurants/{id}/commission [put]
+func (h *StatementHandler) SetCommission(c *gin.Context) {
+	var req models.Commission
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	commission, err := h.statementService.SetCommission(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, commission)
+}
+
+// GenerateStatements godoc
+// @Summary Generate restaurant statements
+// @Description Bill every order delivered in the period that isn't on a statement yet, one statement per restaurant (or just restaurant_id's). Restaurants with no such orders get none (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.GenerateStatementsRequest true "Period"
+// @Success 201 {object} gin.H{"data": []models.RestaurantStatement}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/statements [post]
+func (h *StatementHandler) GenerateStatements(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+
+	var req models.GenerateStatementsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	statements, err := h.statementService.GenerateStatements(c.Request.Context(), adminID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": statements})
+}
+
+// GetStatements godoc
+// @Summary List restaurant statements
+// @Description Restaurant statements, newest first, without their order lines (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param restaurant_id query string false "Only this restaurant's statements"
+// @Param status query string false "unpaid or paid"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} gin.H{"data": []models.RestaurantStatement, "pagination": gin.H{"page":1,"limit":20,"total":100}}
+// @Router /api/v1/admin/statements [get]
+func (h *StatementHandler) GetStatements(c *gin.Context) {
+	page, limit := queryPage(c)
+
+	statements, total, err := h.statementService.GetStatements(c.Request.Context(), c.Query("restaurant_id"), c.Query("status"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       statements,
+		"pagination": listPage(page, limit, total),
+	})
+}
+
+// GetStatement godoc
+// @Summary Get a restaurant statement
+// @Description A statement with its order lines and adjustments (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Statement ID"
+// @Success 200 {object} models.RestaurantStatement
+// @Router /api/v1/admin/statements/{id} [get]
+func (h *StatementHandler) GetStatement(c *gin.Context) {
+	statement, err := h.statementService.GetStatement(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// ExportStatement godoc
+// @Summary Export a restaurant statement
+// @Description Download a statement as CSV (format=csv) or PDF (format=pdf) (admin only)
+// @Tags admin
+// @Produce text/csv
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path string true "Statement ID"
+// @Param format query string false "csv or pdf" default(csv)
+// @Success 200 {file} file
+// @Router /api/v1/admin/statements/{id}/export [get]
+func (h *StatementHandler) ExportStatement(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "pdf" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or pdf"})
+		return
+	}
+
+	statement, err := h.statementService.GetStatement(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	if format == "pdf" {
+		contentType = "application/pdf"
+		err = services.WriteStatementPDF(&buf, statement)
+	} else {
+		err = services.WriteStatementCSV(&buf, statement)
+	}
+	if err != nil {
+		log.Printf("Error writing statement %s: %v", statement.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export statement"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%s.%s"`, statement.ID.Hex(), format))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// AddStatementAdjustment godoc
+// @Summary Adjust a restaurant statement
+// @Description Add a correction to an unpaid statement: a positive amount is added to what the restaurant is paid, a negative one deducted (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Statement ID"
+// @Param request body models.StatementAdjustmentRequest true "Adjustment"
+// @Success 200 {object} models.RestaurantStatement
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/statements/{id}/adjustments [post]
+func (h *StatementHandler) AddStatementAdjustment(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+
+	var req models.StatementAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	statement, err := h.statementService.AddAdjustment(c.Request.Context(), c.Param("id"), adminID, req)
+	if err != nil {
+		c.JSON(statementErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// MarkStatementPaid godoc
+// @Summary Mark a restaurant statement paid
+// @Description Record that the statement's net payable has been paid to the restaurant, with an optional transfer reference. A paid statement can't be adjusted (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Statement ID"
+// @Param request body models.MarkStatementPaidRequest false "Payment reference"
+// @Success 200 {object} models.RestaurantStatement
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/statements/{id}/mark-paid [post]
+func (h *StatementHandler) MarkStatementPaid(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+
+	var req models.MarkStatementPaidRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	statement, err := h.statementService.MarkPaid(c.Request.Context(), c.Param("id"), adminID, req)
+	if err != nil {
+		c.JSON(statementErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// statementErrorStatus is 409 for changes to a paid statement, 400 for
+// anything else.
+func statementErrorStatus(err error) int {
+	if errors.Is(err, repositories.ErrStatementPaid) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
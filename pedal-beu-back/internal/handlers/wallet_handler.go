@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WalletHandler struct {
+	walletService services.WalletService
+}
+
+func NewWalletHandler(walletService services.WalletService) *WalletHandler {
+	return &WalletHandler{walletService: walletService}
+}
+
+// GetMyWallet godoc
+// @Summary Get my wallet
+// @Description The current user's store credit balance and the lots it's made of, with when each expires
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Wallet
+// @Router /api/v1/users/wallet [get]
+func (h *WalletHandler) GetMyWallet(c *gin.Context) {
+	userID := c.MustGet("userID").(primitive.ObjectID)
+
+	wallet, err := h.walletService.GetWallet(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, wallet)
+}
+
+// GetMyWalletTransactions godoc
+// @Summary List my wallet transactions
+// @Description Credits, spending and expiries on the current user's wallet, newest first
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} gin.H{"data": []models.WalletTransaction, "pagination": gin.H{"page":1,"limit":20,"total":100}}
+// @Router /api/v1/users/wallet/transactions [get]
+func (h *WalletHandler) GetMyWalletTransactions(c *gin.Context) {
+	userID := c.MustGet("userID").(primitive.ObjectID)
+	h.transactions(c, userID)
+}
+
+// GetUserWallet godoc
+// @Summary Get a user's wallet
+// @Description A customer's store credit balance and lots (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} models.Wallet
+// @Router /api/v1/admin/users/{id}/wallet [get]
+func (h *WalletHandler) GetUserWallet(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	wallet, err := h.walletService.GetWallet(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, wallet)
+}
+
+// GetUserWalletTransactions godoc
+// @Summary List a user's wallet transactions
+// @Description Credits, spending and expiries on a customer's wallet, newest first (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} gin.H{"data": []models.WalletTransaction, "pagination": gin.H{"page":1,"limit":20,"total":100}}
+// @Router /api/v1/admin/users/{id}/wallet/transactions [get]
+func (h *WalletHandler) GetUserWalletTransactions(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	h.transactions(c, userID)
+}
+
+func (h *WalletHandler) transactions(c *gin.Context, userID primitive.ObjectID) {
+	page, limit := queryPage(c)
+
+	txns, total, err := h.walletService.GetTransactions(c.Request.Context(), userID, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       txns,
+		"pagination": listPage(page, limit, total),
+	})
+}
+
+// GrantWalletCredit godoc
+// @Summary Grant store credit
+// @Description Add credit to a customer's wallet with a reason, optionally expiring at expires_at (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body models.GrantWalletCreditRequest true "Credit"
+// @Success 201 {object} models.WalletTransaction
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/users/{id}/wallet/credits [post]
+func (h *WalletHandler) GrantWalletCredit(c *gin.Context) {
+	adminID := c.MustGet("userID").(primitive.ObjectID)
+
+	var req models.GrantWalletCreditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	txn, err := h.walletService.GrantCredit(c.Request.Context(), c.Param("id"), adminID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, txn)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/services"
+)
+
+type ZoneHandler struct {
+	zoneService services.ZoneService
+}
+
+func NewZoneHandler(zoneService services.ZoneService) *ZoneHandler {
+	return &ZoneHandler{zoneService: zoneService}
+}
+
+// GetZones returns every service zone, active or not.
+// GET /api/v1/admin/zones
+func (h *ZoneHandler) GetZones(c *gin.Context) {
+	zones, err := h.zoneService.ListZones(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch zones"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": zones})
+}
+
+// GetZone returns a single service zone.
+// GET /api/v1/admin/zones/:id
+func (h *ZoneHandler) GetZone(c *gin.Context) {
+	zone, err := h.zoneService.GetZone(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, zone)
+}
+
+// CreateZone adds a service zone. The area is a GeoJSON Polygon.
+// POST /api/v1/admin/zones
+func (h *ZoneHandler) CreateZone(c *gin.Context) {
+	var req models.ServiceZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, err := h.zoneService.CreateZone(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, zone)
+}
+
+// UpdateZone replaces a service zone's settings.
+// PUT /api/v1/admin/zones/:id
+func (h *ZoneHandler) UpdateZone(c *gin.Context) {
+	var req models.ServiceZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, err := h.zoneService.UpdateZone(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, zone)
+}
+
+// DeleteZone removes a service zone. Deleting the last active zone turns
+// zone enforcement off entirely.
+// DELETE /api/v1/admin/zones/:id
+func (h *ZoneHandler) DeleteZone(c *gin.Context) {
+	if err := h.zoneService.DeleteZone(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Zone deleted successfully"})
+}
@@ -143,9 +143,132 @@ type DriverEarnings struct {
 	ThisMonth    float64    `bson:"this_month" json:"this_month"`
 	Total        float64    `bson:"total" json:"total"`
 	Pending      float64    `bson:"pending" json:"pending"`
+	SurgeBonus   float64    `bson:"surge_bonus" json:"surge_bonus"` // part of Total
+	Tips         float64    `bson:"tips" json:"tips"`               // part of Total
 	LastPayoutAt *time.Time `bson:"last_payout_at,omitempty" json:"last_payout_at,omitempty"`
 }
 
+// DriverEarningsDay is one driver's delivered-order totals for one
+// service-area day, kept up to date as orders are delivered so the driver
+// stats and earnings chart never have to scan order history.
+type DriverEarningsDay struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	DriverID   primitive.ObjectID `bson:"driver_id" json:"driver_id"` // User._id, same as order.driver_id
+	Day        string             `bson:"day" json:"day"`             // "2006-01-02" in Africa/Addis_Ababa
+	Deliveries int                `bson:"deliveries" json:"deliveries"`
+	Earnings   float64            `bson:"earnings" json:"earnings"` // delivery fees plus surge bonus and tips
+	BaseFees   float64            `bson:"base_fees" json:"base_fees"`
+	SurgeBonus float64            `bson:"surge_bonus" json:"surge_bonus"`
+	Tips       float64            `bson:"tips" json:"tips"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// PayoutAccount is where a driver's settlements are paid: a bank account
+// or a telebirr wallet.
+type PayoutAccount struct {
+	Method        string `bson:"method" json:"method" binding:"required,oneof=bank telebirr"`
+	AccountName   string `bson:"account_name" json:"account_name" binding:"required"`
+	BankName      string `bson:"bank_name,omitempty" json:"bank_name,omitempty"`           // bank only
+	AccountNumber string `bson:"account_number,omitempty" json:"account_number,omitempty"` // bank only
+	Phone         string `bson:"phone,omitempty" json:"phone,omitempty"`                   // telebirr only
+}
+
+// Ledger entry types (LedgerEntry.Type).
+const (
+	LedgerDelivery      = "delivery"       // delivery fee and surge bonus earned on an order
+	LedgerTip           = "tip"            // a customer's tip
+	LedgerBonus         = "bonus"          // admin-granted bonus
+	LedgerCashCollected = "cash_collected" // cash the driver took from a customer on the platform's behalf
+	LedgerPenalty       = "penalty"        // admin-imposed penalty
+	LedgerPayout        = "payout"         // a settlement run paying the driver out
+	LedgerCashDeposit   = "cash_deposit"   // cash the driver handed in to the platform
+	LedgerRefund        = "refund"         // delivery fee or tip refunded to a customer and charged back to the driver
+)
+
+// Platform-side ledger accounts. Each driver has their own account,
+// "driver:<user id>", which every entry credits or debits.
+const (
+	LedgerAccountDeliveryFees = "platform:delivery_fees"
+	LedgerAccountTips         = "platform:tips"
+	LedgerAccountBonuses      = "platform:bonuses"
+	LedgerAccountCash         = "platform:cash"
+	LedgerAccountPenalties    = "platform:penalties"
+	LedgerAccountPayouts      = "platform:payouts"
+	LedgerAccountRefunds      = "platform:refunds"
+)
+
+// LedgerEntry is one double-entry posting: Amount moves from DebitAccount
+// to CreditAccount, and one side is always the driver's own account. Net
+// is the entry's effect on what the platform owes the driver — positive
+// for credits, negative for debits — so balances are a plain sum.
+type LedgerEntry struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	DriverID      primitive.ObjectID  `bson:"driver_id" json:"driver_id"` // User._id, same as order.driver_id
+	Type          string              `bson:"type" json:"type"`
+	DebitAccount  string              `bson:"debit_account" json:"debit_account"`
+	CreditAccount string              `bson:"credit_account" json:"credit_account"`
+	Amount        float64             `bson:"amount" json:"amount"`
+	Net           float64             `bson:"net" json:"net"`
+	SurgeBonus    float64             `bson:"surge_bonus,omitempty" json:"surge_bonus,omitempty"` // part of Amount, delivery entries only
+	OrderID       *primitive.ObjectID `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	OrderNumber   string              `bson:"order_number,omitempty" json:"order_number,omitempty"`
+	Description   string              `bson:"description,omitempty" json:"description,omitempty"`
+	// IdempotencyKey makes posting the same event twice (e.g. a delivery
+	// retried) a no-op; manual entries have none.
+	IdempotencyKey string              `bson:"idempotency_key,omitempty" json:"-"`
+	SettlementID   *primitive.ObjectID `bson:"settlement_id,omitempty" json:"settlement_id,omitempty"`
+	SettledAt      *time.Time          `bson:"settled_at,omitempty" json:"settled_at,omitempty"`
+	CreatedBy      *primitive.ObjectID `bson:"created_by,omitempty" json:"created_by,omitempty"` // admin, for bonuses, penalties and payouts
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// Settlement run statuses (SettlementRun.Status).
+const (
+	SettlementProcessing = "processing"
+	SettlementCompleted  = "completed"
+	SettlementFailed     = "failed"
+)
+
+// SettlementRun pays drivers out for a period: every unsettled ledger
+// entry posted in it is settled, and each driver who's owed money gets a
+// PayoutBatch.
+type SettlementRun struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PeriodStart time.Time          `bson:"period_start" json:"period_start"`
+	PeriodEnd   time.Time          `bson:"period_end" json:"period_end"` // exclusive
+	Status      string             `bson:"status" json:"status"`
+	PayoutCount int                `bson:"payout_count" json:"payout_count"`
+	EntryCount  int                `bson:"entry_count" json:"entry_count"`
+	TotalAmount float64            `bson:"total_amount" json:"total_amount"`
+	// Skipped are drivers left for a later run — they owe more than
+	// they've earned, or have no payout account yet.
+	Skipped     []SettlementSkip   `bson:"skipped,omitempty" json:"skipped,omitempty"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedBy   primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	CompletedAt *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+type SettlementSkip struct {
+	DriverID primitive.ObjectID `bson:"driver_id" json:"driver_id"`
+	Balance  float64            `bson:"balance" json:"balance"`
+	Reason   string             `bson:"reason" json:"reason"`
+}
+
+// PayoutBatch is one driver's payout in a settlement run, with the payout
+// account it goes to as it was when the run was made.
+type PayoutBatch struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SettlementID  primitive.ObjectID `bson:"settlement_id" json:"settlement_id"`
+	DriverID      primitive.ObjectID `bson:"driver_id" json:"driver_id"`
+	DriverName    string             `bson:"driver_name" json:"driver_name"`
+	Amount        float64            `bson:"amount" json:"amount"`
+	EntryCount    int                `bson:"entry_count" json:"entry_count"`
+	Account       PayoutAccount      `bson:"account" json:"account"`
+	LedgerEntryID primitive.ObjectID `bson:"ledger_entry_id" json:"ledger_entry_id"` // the payout entry
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+}
+
 type Driver struct {
 	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID          primitive.ObjectID `bson:"user_id" json:"user_id"`
@@ -155,28 +278,219 @@ type Driver struct {
 	IsOnline        bool               `bson:"is_online" json:"is_online"`
 	Location        GeoLocation        `bson:"location" json:"location"`
 	Rating          float64            `bson:"rating" json:"rating"`
+	RatingSum       int                `bson:"rating_sum" json:"-"` // Rating is RatingSum / RatingCount
+	RatingCount     int                `bson:"rating_count" json:"rating_count"`
 	TotalTrips      int                `bson:"total_trips" json:"total_trips"`
+	AcceptedOrders  int                `bson:"accepted_orders" json:"accepted_orders"` // orders this driver took
+	RejectedOrders  int                `bson:"rejected_orders" json:"rejected_orders"` // orders this driver turned down
 	Earnings        DriverEarnings     `bson:"earnings" json:"earnings"`
 	RejectionReason string             `bson:"rejection_reason,omitempty" json:"rejection_reason,omitempty"`
+	PayoutAccount   *PayoutAccount     `bson:"payout_account,omitempty" json:"payout_account,omitempty"`
+	CashBalance     float64            `bson:"cash_balance" json:"cash_balance"`                 // COD cash held for the platform, not yet deposited or settled
+	CashLimit       *float64           `bson:"cash_limit,omitempty" json:"cash_limit,omitempty"` // nil uses DRIVER_CASH_LIMIT
 	IsActive        bool               `bson:"is_active" json:"is_active"`
-	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+	// RiskScore (0-100) is raised by the GPS anomaly detector
+	// (services/location_anomaly_service.go) each time one of this
+	// driver's pings looks implausible, and decays back down over time.
+	RiskScore      float64     `bson:"risk_score,omitempty" json:"risk_score"`
+	LastGPSAnomaly *GPSAnomaly `bson:"last_gps_anomaly,omitempty" json:"last_gps_anomaly,omitempty"`
+	CreatedAt      time.Time   `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time   `bson:"updated_at" json:"updated_at"`
+}
+
+type GPSAnomaly struct {
+	Type       string    `bson:"type" json:"type"` // "impossible_speed", "teleport", "repeated_coordinates", "out_of_service_area"
+	Details    string    `bson:"details" json:"details"`
+	Lat        float64   `bson:"lat" json:"lat"`
+	Lng        float64   `bson:"lng" json:"lng"`
+	DetectedAt time.Time `bson:"detected_at" json:"detected_at"`
+}
+
+// ServiceZone is an admin-drawn delivery area. When at least one active
+// zone exists, orders can only be placed to addresses inside one, and
+// restaurant listings only show places within the zone's max delivery
+// distance of the customer. With no zones configured nothing is enforced.
+type ServiceZone struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name     string             `bson:"name" json:"name"`
+	Area     GeoPolygon         `bson:"area" json:"area"`
+	IsActive bool               `bson:"is_active" json:"is_active"`
+	// ActiveHours limits when orders are accepted in the zone, evaluated in
+	// Addis Ababa time. An empty schedule (no slots on any day) means the
+	// zone is always open.
+	ActiveHours     OpeningHours `bson:"active_hours" json:"active_hours"`
+	SurgeMultiplier float64      `bson:"surge_multiplier" json:"surge_multiplier"` // applied to the delivery fee; <= 1 means no surge
+	// FeeBands price delivery by restaurant→drop-off distance, for
+	// restaurants without their own delivery_fee. Checked in order of
+	// UpToKm; distances beyond the last band use the last band's fee.
+	FeeBands              []DeliveryFeeBand `bson:"fee_bands,omitempty" json:"fee_bands,omitempty"`
+	MaxDeliveryDistanceKm float64           `bson:"max_delivery_distance_km" json:"max_delivery_distance_km"` // 0 = no limit
+	// LiveSurge is written every minute by the surge engine
+	// (services/surge_service.go). The higher of it and SurgeMultiplier
+	// applies, so admins can still force a floor by hand.
+	LiveSurge *ZoneSurge `bson:"live_surge,omitempty" json:"live_surge,omitempty"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `bson:"updated_at" json:"updated_at"`
+}
+
+type ZoneSurge struct {
+	Multiplier float64   `bson:"multiplier" json:"multiplier"`
+	Demand     int64     `bson:"demand" json:"demand"` // unassigned orders picking up in the zone
+	Supply     int64     `bson:"supply" json:"supply"` // online drivers in the zone
+	ComputedAt time.Time `bson:"computed_at" json:"computed_at"`
+}
+
+// GeoPolygon is a GeoJSON Polygon: an outer ring followed by optional
+// holes, each a closed ring of [longitude, latitude] pairs.
+type GeoPolygon struct {
+	Type        string        `bson:"type" json:"type"` // "Polygon"
+	Coordinates [][][]float64 `bson:"coordinates" json:"coordinates"`
+}
+
+type DeliveryFeeBand struct {
+	UpToKm float64 `bson:"up_to_km" json:"up_to_km"`
+	Fee    float64 `bson:"fee" json:"fee"`
 }
 
 // Restaurant models
 type MenuItem struct {
-	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name            string             `bson:"name" json:"name"`
-	Description     string             `bson:"description" json:"description"`
-	Price           float64            `bson:"price" json:"price"`
-	Image           string             `bson:"image,omitempty" json:"image,omitempty"`
-	Category        string             `bson:"category" json:"category"`
-	IsAvailable     bool               `bson:"is_available" json:"is_available"`
-	Ingredients     []string           `bson:"ingredients,omitempty" json:"ingredients,omitempty"`
-	Addons          []Addon            `bson:"addons,omitempty" json:"addons,omitempty"`
-	PreparationTime int                `bson:"preparation_time" json:"preparation_time"` // in minutes
-	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	RestaurantID    primitive.ObjectID  `bson:"restaurant_id,omitempty" json:"restaurant_id,omitempty"`
+	SKU             string              `bson:"sku,omitempty" json:"sku,omitempty"` // the restaurant's own code, unique per restaurant
+	Name            string              `bson:"name" json:"name"`
+	Description     string              `bson:"description" json:"description"`
+	Price           float64             `bson:"price" json:"price"`
+	Image           string              `bson:"image,omitempty" json:"image,omitempty"`
+	Category        string              `bson:"category" json:"category"`
+	CategoryID      *primitive.ObjectID `bson:"category_id,omitempty" json:"category_id,omitempty"`
+	IsAvailable     bool                `bson:"is_available" json:"is_available"`
+	Ingredients     []string            `bson:"ingredients,omitempty" json:"ingredients,omitempty"`
+	DietaryTags     []string            `bson:"dietary_tags,omitempty" json:"dietary_tags,omitempty"` // Diet* constants
+	Allergens       []string            `bson:"allergens,omitempty" json:"allergens,omitempty"`       // Allergen* constants
+	Addons          []Addon             `bson:"addons,omitempty" json:"addons,omitempty"`
+	Variants        []MenuItemVariant   `bson:"variants,omitempty" json:"variants,omitempty"`
+	ModifierGroups  []ModifierGroup     `bson:"modifier_groups,omitempty" json:"modifier_groups,omitempty"`
+	Stock           *MenuItemStock      `bson:"stock,omitempty" json:"stock,omitempty"`             // nil = not stock-tracked
+	PreparationTime int                 `bson:"preparation_time" json:"preparation_time"`           // in minutes
+	ArchivedAt      *time.Time          `bson:"archived_at,omitempty" json:"archived_at,omitempty"` // set = removed from the menu
+	CreatedAt       time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// MenuItemChange is one entry in a restaurant's menu history: who did what
+// to which item, and when. Previous is the whole item as it was before the
+// change — restoring an entry puts the item back to exactly that.
+type MenuItemChange struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RestaurantID primitive.ObjectID `bson:"restaurant_id" json:"restaurant_id"`
+	MenuItemID   primitive.ObjectID `bson:"menu_item_id" json:"menu_item_id"`
+	ItemName     string             `bson:"item_name" json:"item_name"`
+	Action       string             `bson:"action" json:"action"` // created, updated, archived, unarchived or restored
+	Changes      []MenuFieldChange  `bson:"changes,omitempty" json:"changes,omitempty"`
+	Previous     *MenuItem          `bson:"previous,omitempty" json:"previous,omitempty"`
+	ChangedBy    primitive.ObjectID `bson:"changed_by" json:"changed_by"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// MenuFieldChange is one field's before and after in a MenuItemChange.
+type MenuFieldChange struct {
+	Field string      `bson:"field" json:"field"`
+	From  interface{} `bson:"from" json:"from"`
+	To    interface{} `bson:"to" json:"to"`
+}
+
+// Dietary tags a menu item can carry (MenuItem.DietaryTags). Fasting is
+// the Ethiopian Orthodox "tsom" — no meat, dairy or eggs; vegan food
+// always qualifies.
+const (
+	DietVegan      = "vegan"
+	DietVegetarian = "vegetarian"
+	DietFasting    = "fasting"
+	DietHalal      = "halal"
+	DietGlutenFree = "gluten_free"
+)
+
+// Allergens a menu item can declare (MenuItem.Allergens).
+const (
+	AllergenNuts      = "nuts"
+	AllergenDairy     = "dairy"
+	AllergenEggs      = "eggs"
+	AllergenGluten    = "gluten"
+	AllergenSoy       = "soy"
+	AllergenFish      = "fish"
+	AllergenShellfish = "shellfish"
+	AllergenSesame    = "sesame"
+)
+
+// DietaryFlags say which diets at least one of a restaurant's available
+// items caters for. Computed for listings, never stored.
+type DietaryFlags struct {
+	HasVeganOptions      bool `json:"has_vegan_options"`
+	HasVegetarianOptions bool `json:"has_vegetarian_options"`
+	HasFastingOptions    bool `json:"has_fasting_options"`
+	HasHalalOptions      bool `json:"has_halal_options"`
+	HasGlutenFreeOptions bool `json:"has_gluten_free_options"`
+}
+
+// MenuItemStock is an optional daily count for a menu item. Orders take
+// from Remaining and cancellations put back; at zero the item is switched
+// unavailable (AutoDisabled records that it was the stock, not a person,
+// that did it) and the daily reset refills it and switches it back on.
+type MenuItemStock struct {
+	DailyQuantity     int       `bson:"daily_quantity" json:"daily_quantity"`
+	Remaining         int       `bson:"remaining" json:"remaining"`
+	LowStockThreshold int       `bson:"low_stock_threshold" json:"low_stock_threshold"`
+	AutoDisabled      bool      `bson:"auto_disabled" json:"auto_disabled"`
+	ResetAt           time.Time `bson:"reset_at" json:"reset_at"`
+}
+
+// MenuCategory is a section of a restaurant's menu. Items point at it by
+// CategoryID (MenuItem.Category keeps the name for older clients). An empty
+// Schedule means the category is always on; otherwise its items can only be
+// ordered inside the schedule's slots, in the restaurant's timezone.
+type MenuCategory struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RestaurantID primitive.ObjectID `bson:"restaurant_id" json:"restaurant_id"`
+	Name         string             `bson:"name" json:"name"`
+	Description  string             `bson:"description,omitempty" json:"description,omitempty"`
+	Image        string             `bson:"image,omitempty" json:"image,omitempty"`
+	SortOrder    int                `bson:"sort_order" json:"sort_order"`
+	Schedule     OpeningHours       `bson:"schedule" json:"schedule"`
+	IsActive     bool               `bson:"is_active" json:"is_active"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// MenuItemVariant is one mutually exclusive version of an item (usually a
+// size) with its own base price. When an item has variants the customer
+// must pick exactly one, and MenuItem.Price is only the "from" price shown
+// before they do.
+type MenuItemVariant struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name    string             `bson:"name" json:"name"`
+	Price   float64            `bson:"price" json:"price"`
+	SoldOut bool               `bson:"sold_out" json:"sold_out"`
+}
+
+// ModifierGroup is a set of options the customer picks between ("pick 1
+// sauce", "up to 3 toppings"). MinSelect > 0 makes the group required;
+// MaxSelect 0 means no upper limit. Options can carry their own groups
+// ("Extra cheese" -> "which cheese?"), which only apply when that option is
+// chosen.
+type ModifierGroup struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	MinSelect int                `bson:"min_select" json:"min_select"`
+	MaxSelect int                `bson:"max_select" json:"max_select"`
+	Options   []ModifierOption   `bson:"options" json:"options"`
+}
+
+type ModifierOption struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name    string             `bson:"name" json:"name"`
+	Price   float64            `bson:"price" json:"price"` // added to the item's price
+	SoldOut bool               `bson:"sold_out" json:"sold_out"`
+	Groups  []ModifierGroup    `bson:"groups,omitempty" json:"groups,omitempty"`
 }
 
 type Addon struct {
@@ -197,32 +511,105 @@ type Restaurant struct {
 	Phone        string             `bson:"phone" json:"phone"`
 	Email        string             `bson:"email,omitempty" json:"email,omitempty"`
 	Images       []string           `bson:"images" json:"images"`
-	Menu         []MenuItem         `bson:"menu" json:"menu"`
+	// Timezone is the IANA zone opening hours and menu schedules are read
+	// in; empty means Africa/Addis_Ababa.
+	Timezone     string             `bson:"timezone,omitempty" json:"timezone,omitempty"`
+	// Menu items live in the menu_items collection; this is only filled in
+	// by the restaurant detail endpoint (and read once by the startup
+	// migration from restaurants that still embed it).
+	Menu         []MenuItem         `bson:"menu,omitempty" json:"menu"`
+	DietaryFlags *DietaryFlags      `bson:"-" json:"dietary_flags,omitempty"` // listings only
 	IsActive     bool               `bson:"is_active" json:"is_active"`
 	IsVerified   bool               `bson:"is_verified" json:"is_verified"`
 	Rating       float64            `bson:"rating" json:"rating"`
 	TotalReviews int                `bson:"total_reviews" json:"total_reviews"`
+	RatingSum    int                `bson:"rating_sum" json:"-"` // Rating is RatingSum / TotalReviews
 	OpeningHours OpeningHours       `bson:"opening_hours" json:"opening_hours"`
 	DeliveryFee  float64            `bson:"delivery_fee" json:"delivery_fee"`
 	MinOrder     float64            `bson:"min_order" json:"min_order"`
 	DeliveryTime int                `bson:"delivery_time" json:"delivery_time"` // in minutes
+	Commission   *Commission        `bson:"commission,omitempty" json:"commission,omitempty"` // nil uses the platform default
 	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+// Commission is what the platform keeps from each of a restaurant's
+// delivered orders: Percent of the food subtotal plus FixedPerOrder.
+type Commission struct {
+	Percent       float64 `bson:"percent" json:"percent" binding:"gte=0,lte=100"`
+	FixedPerOrder float64 `bson:"fixed_per_order" json:"fixed_per_order" binding:"gte=0"`
+}
+
+// Restaurant statement statuses (RestaurantStatement.Status).
+const (
+	StatementUnpaid = "unpaid"
+	StatementPaid   = "paid"
+)
+
+// RestaurantStatement is what the platform owes a restaurant for the
+// orders it delivered in a period: their food sales, less commission and
+// refunds, plus any adjustments an admin added. Each delivered order is
+// billed on exactly one statement.
+type RestaurantStatement struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RestaurantID   primitive.ObjectID `bson:"restaurant_id" json:"restaurant_id"`
+	RestaurantName string             `bson:"restaurant_name" json:"restaurant_name"`
+	PeriodStart    time.Time          `bson:"period_start" json:"period_start"`
+	PeriodEnd      time.Time          `bson:"period_end" json:"period_end"` // exclusive
+	// Commission is the rate the statement was generated with; later
+	// changes to the restaurant's rate don't touch existing statements.
+	Commission       Commission            `bson:"commission" json:"commission"`
+	Orders           []StatementOrder      `bson:"orders" json:"orders"`
+	OrderCount       int                   `bson:"order_count" json:"order_count"`
+	GrossSales       float64               `bson:"gross_sales" json:"gross_sales"` // food subtotals
+	CommissionTotal  float64               `bson:"commission_total" json:"commission_total"`
+	Refunds          float64               `bson:"refunds" json:"refunds"`
+	Adjustments      []StatementAdjustment `bson:"adjustments,omitempty" json:"adjustments,omitempty"`
+	AdjustmentTotal  float64               `bson:"adjustment_total" json:"adjustment_total"`
+	NetPayable       float64               `bson:"net_payable" json:"net_payable"`
+	Status           string                `bson:"status" json:"status"`
+	PaidAt           *time.Time            `bson:"paid_at,omitempty" json:"paid_at,omitempty"`
+	PaidBy           *primitive.ObjectID   `bson:"paid_by,omitempty" json:"paid_by,omitempty"`
+	PaymentReference string                `bson:"payment_reference,omitempty" json:"payment_reference,omitempty"`
+	CreatedBy        primitive.ObjectID    `bson:"created_by" json:"created_by"`
+	CreatedAt        time.Time             `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time             `bson:"updated_at" json:"updated_at"`
+}
+
+// StatementOrder is one delivered order's line on a statement.
+type StatementOrder struct {
+	OrderID     primitive.ObjectID `bson:"order_id" json:"order_id"`
+	OrderNumber string             `bson:"order_number" json:"order_number"`
+	DeliveredAt time.Time          `bson:"delivered_at" json:"delivered_at"`
+	FoodSales   float64            `bson:"food_sales" json:"food_sales"`
+	Commission  float64            `bson:"commission" json:"commission"`
+	Refunded    float64            `bson:"refunded" json:"refunded"`
+	Net         float64            `bson:"net" json:"net"`
+}
+
+// StatementAdjustment is an admin's correction to a statement: positive
+// adds to what the restaurant is paid, negative takes from it.
+type StatementAdjustment struct {
+	Amount    float64            `bson:"amount" json:"amount"`
+	Reason    string             `bson:"reason" json:"reason"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
 // Order models
 type OrderStatus string
 
 const (
-	OrderPending   OrderStatus = "pending"
-	OrderAccepted  OrderStatus = "accepted"
-	OrderPreparing OrderStatus = "preparing"
-	OrderReady     OrderStatus = "ready"
-	OrderPickedUp  OrderStatus = "picked_up"
-	OrderOnTheWay  OrderStatus = "on_the_way"
-	OrderDelivered OrderStatus = "delivered"
-	OrderCancelled OrderStatus = "cancelled"
-	OrderRejected  OrderStatus = "rejected"
+	OrderPending         OrderStatus = "pending"
+	OrderAccepted        OrderStatus = "accepted"
+	OrderPreparing       OrderStatus = "preparing"
+	OrderReady           OrderStatus = "ready"
+	OrderPickedUp        OrderStatus = "picked_up"
+	OrderOnTheWay        OrderStatus = "on_the_way"
+	OrderDelivered       OrderStatus = "delivered"
+	OrderCancelled       OrderStatus = "cancelled"
+	OrderRejected        OrderStatus = "rejected"
+	OrderAwaitingPayment OrderStatus = "awaiting_payment" // placed, but hidden from drivers until its hosted checkout is paid
 )
 
 type OrderItem struct {
@@ -231,8 +618,31 @@ type OrderItem struct {
 	Quantity   int                `bson:"quantity" json:"quantity"`
 	Price      float64            `bson:"price" json:"price"`
 	Addons     []OrderItemAddon   `bson:"addons,omitempty" json:"addons,omitempty"`
+	Variant    *OrderItemVariant  `bson:"variant,omitempty" json:"variant,omitempty"`
+	Modifiers  []OrderModifier    `bson:"modifiers,omitempty" json:"modifiers,omitempty"`
 	Total      float64            `bson:"total" json:"total"`
 	Notes      string             `bson:"notes,omitempty" json:"notes,omitempty"`
+	// RefundedQuantity is how many of Quantity have been refunded,
+	// counting refunds still pending.
+	RefundedQuantity int `bson:"refunded_quantity,omitempty" json:"refunded_quantity,omitempty"`
+}
+
+// OrderItemVariant and OrderModifier snapshot what the customer chose and
+// what it cost when they ordered, so later menu edits don't rewrite past
+// orders.
+type OrderItemVariant struct {
+	VariantID primitive.ObjectID `bson:"variant_id" json:"variant_id"`
+	Name      string             `bson:"name" json:"name"`
+	Price     float64            `bson:"price" json:"price"`
+}
+
+type OrderModifier struct {
+	GroupID   primitive.ObjectID `bson:"group_id" json:"group_id"`
+	GroupName string             `bson:"group_name" json:"group_name"`
+	OptionID  primitive.ObjectID `bson:"option_id" json:"option_id"`
+	Name      string             `bson:"name" json:"name"`
+	Price     float64            `bson:"price" json:"price"`
+	Modifiers []OrderModifier    `bson:"modifiers,omitempty" json:"modifiers,omitempty"`
 }
 
 type OrderItemAddon struct {
@@ -242,12 +652,18 @@ type OrderItemAddon struct {
 }
 
 type OrderAmount struct {
-	Subtotal      float64 `bson:"subtotal" json:"subtotal"`
-	DeliveryFee   float64 `bson:"delivery_fee" json:"delivery_fee"`
-	ServiceCharge float64 `bson:"service_charge" json:"service_charge"`
-	Discount      float64 `bson:"discount" json:"discount"`
-	Tax           float64 `bson:"tax" json:"tax"`
-	Total         float64 `bson:"total" json:"total"`
+	Subtotal    float64 `bson:"subtotal" json:"subtotal"`
+	DeliveryFee float64 `bson:"delivery_fee" json:"delivery_fee"`
+	// SurgeMultiplier is the zone multiplier in effect when the order was
+	// priced (1 = no surge) and SurgeFee the extra it added on top of
+	// DeliveryFee. Both go to the driver as a bonus.
+	SurgeMultiplier float64 `bson:"surge_multiplier,omitempty" json:"surge_multiplier,omitempty"`
+	SurgeFee        float64 `bson:"surge_fee,omitempty" json:"surge_fee,omitempty"`
+	ServiceCharge   float64 `bson:"service_charge" json:"service_charge"`
+	Discount        float64 `bson:"discount" json:"discount"`
+	Tax             float64 `bson:"tax" json:"tax"`
+	Tip             float64 `bson:"tip,omitempty" json:"tip,omitempty"` // the customer's tip; goes to the driver in full, never commissioned
+	Total           float64 `bson:"total" json:"total"`
 }
 
 type DeliveryInfo struct {
@@ -284,7 +700,7 @@ type Order struct {
 	DeliveryInfo        DeliveryInfo         `bson:"delivery_info" json:"delivery_info"`
 	Timeline            []OrderEvent         `bson:"timeline" json:"timeline"`
 	PaymentMethod       string               `bson:"payment_method" json:"payment_method"`
-	PaymentStatus       string               `bson:"payment_status" json:"payment_status"` // "pending", "paid", "failed", "refunded"
+	PaymentStatus       string               `bson:"payment_status" json:"payment_status"` // "pending", "paid", "failed", "partially_refunded", "refunded"
 	PaymentVerification *PaymentVerification `bson:"payment_verification,omitempty" json:"payment_verification,omitempty"`
 	Rating              *OrderRating         `bson:"rating,omitempty" json:"rating,omitempty"`
 	IsScheduled         bool                 `bson:"is_scheduled" json:"is_scheduled"`
@@ -293,13 +709,67 @@ type Order struct {
 	// Drivers who rejected this order — excluded from their available-orders
 	// list so they don't keep seeing an order they've already declined.
 	RejectedByDrivers []primitive.ObjectID `bson:"rejected_by_drivers,omitempty" json:"rejected_by_drivers,omitempty"`
-	CreatedAt         time.Time            `bson:"created_at" json:"created_at"`
-	UpdatedAt         time.Time            `bson:"updated_at" json:"updated_at"`
+	// Arrivals is filled in by the geofence engine (services/geofence_service.go)
+	// the first time the assigned driver dwells inside the pickup or drop-off
+	// radius. Absent until the first arrival is detected.
+	Arrivals *OrderArrivals `bson:"arrivals,omitempty" json:"arrivals,omitempty"`
+	// ZoneID is the service zone the delivery address fell in when the
+	// order was placed. Absent when no zones were configured at the time.
+	ZoneID *primitive.ObjectID `bson:"zone_id,omitempty" json:"zone_id,omitempty"`
+	// StatementID is the restaurant statement this order was billed on;
+	// absent until a statement covering its delivery is generated.
+	StatementID *primitive.ObjectID `bson:"statement_id,omitempty" json:"statement_id,omitempty"`
+	// CashCollection is the cash the driver took for a cash-on-delivery
+	// order; absent until they confirm it.
+	CashCollection *CashCollection `bson:"cash_collection,omitempty" json:"cash_collection,omitempty"`
+	// Refunds totals up the refunds issued on this order; absent until the
+	// first one. The refunds themselves are in the refunds collection.
+	Refunds *OrderRefunds `bson:"refunds,omitempty" json:"refunds,omitempty"`
+	// WalletPayment is the part of the total paid from the customer's
+	// wallet at checkout; absent if none was.
+	WalletPayment *OrderWalletPayment `bson:"wallet_payment,omitempty" json:"wallet_payment,omitempty"`
+	// Checkout is the payment provider checkout the customer pays at, for
+	// orders paid through one; absent for the rest.
+	Checkout *OrderCheckout `bson:"checkout,omitempty" json:"checkout,omitempty"`
+	// ReleasedAt is when drivers could first see the order: when it was
+	// placed, or once its checkout was paid. Absent on older orders, which
+	// go by CreatedAt.
+	ReleasedAt *time.Time `bson:"released_at,omitempty" json:"released_at,omitempty"`
+	// Flags are things about this order worth a human look (e.g. a
+	// "delivered" tap made far from the drop-off). They never block the
+	// order lifecycle — they're surfaced to admins for review.
+	Flags     []OrderFlag `bson:"flags,omitempty" json:"flags,omitempty"`
+	CreatedAt time.Time   `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time   `bson:"updated_at" json:"updated_at"`
+}
+
+type OrderArrivals struct {
+	Restaurant *time.Time `bson:"restaurant,omitempty" json:"restaurant,omitempty"`
+	Dropoff    *time.Time `bson:"dropoff,omitempty" json:"dropoff,omitempty"`
+}
+
+// CashCollection records the cash handed over for a cash-on-delivery
+// order. Confirmed is false when an admin delivered the order and the
+// full total was assumed collected on the driver's behalf.
+type CashCollection struct {
+	Amount      float64            `bson:"amount" json:"amount"`
+	Expected    float64            `bson:"expected" json:"expected"` // the order total
+	Confirmed   bool               `bson:"confirmed" json:"confirmed"`
+	DriverID    primitive.ObjectID `bson:"driver_id" json:"driver_id"`
+	CollectedAt time.Time          `bson:"collected_at" json:"collected_at"`
+}
+
+type OrderFlag struct {
+	Type      string             `bson:"type" json:"type"` // "delivered_far_from_dropoff", "cash_short", "payment_amount_mismatch", "paid_after_cancellation"
+	Reason    string             `bson:"reason" json:"reason"`
+	DistanceM float64            `bson:"distance_m,omitempty" json:"distance_m,omitempty"`
+	ActorID   primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 }
 
 type PaymentVerification struct {
 	Method               string                 `bson:"method" json:"method"`
-	Status               string                 `bson:"status" json:"status"` // "pending", "pending_review", "verified", "failed", "rejected"
+	Status               string                 `bson:"status" json:"status"` // "pending", "retrying", "pending_review", "verified", "failed", "rejected"
 	TransactionReference string                 `bson:"transaction_reference,omitempty" json:"transaction_reference,omitempty"`
 	VerificationURL      string                 `bson:"verification_url,omitempty" json:"verification_url,omitempty"`
 	ProviderStatus       string                 `bson:"provider_status,omitempty" json:"provider_status,omitempty"`
@@ -309,12 +779,31 @@ type PaymentVerification struct {
 	ProofURL             string                 `bson:"proof_url,omitempty" json:"proof_url,omitempty"`
 	ReviewedBy           *primitive.ObjectID    `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
 	ReviewedAt           *time.Time             `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	ReviewNotes          string                 `bson:"review_notes,omitempty" json:"review_notes,omitempty"`
+	ReviewRequestedAt    *time.Time             `bson:"review_requested_at,omitempty" json:"review_requested_at,omitempty"` // when it went to pending_review; the review SLA counts from here
+	ClaimedBy            *primitive.ObjectID    `bson:"claimed_by,omitempty" json:"claimed_by,omitempty"`                   // the admin reviewing it, until ClaimedUntil
+	ClaimedUntil         *time.Time             `bson:"claimed_until,omitempty" json:"claimed_until,omitempty"`
 	FailureReason        string                 `bson:"failure_reason,omitempty" json:"failure_reason,omitempty"`
 	VerifiedAt           *time.Time             `bson:"verified_at,omitempty" json:"verified_at,omitempty"`
 	CheckedAt            *time.Time             `bson:"checked_at,omitempty" json:"checked_at,omitempty"`
+	Receipt              *PaymentReceipt        `bson:"receipt,omitempty" json:"receipt,omitempty"` // what the provider's receipt page said
 	RawResponse          map[string]interface{} `bson:"raw_response,omitempty" json:"raw_response,omitempty"`
 }
 
+// PaymentReceipt is the named fields read off a CBE or telebirr receipt.
+// Accounts are as the receipt shows them, which is usually masked
+// (1****7072).
+type PaymentReceipt struct {
+	Payer           string    `bson:"payer" json:"payer"`
+	PayerAccount    string    `bson:"payer_account,omitempty" json:"payer_account,omitempty"`
+	ReceiverName    string    `bson:"receiver_name" json:"receiver_name"`
+	ReceiverAccount string    `bson:"receiver_account" json:"receiver_account"`
+	Amount          float64   `bson:"amount" json:"amount"`
+	Date            time.Time `bson:"date" json:"date"`
+	Reference       string    `bson:"reference" json:"reference"`
+	Status          string    `bson:"status,omitempty" json:"status,omitempty"` // telebirr's transaction status
+}
+
 type OrderRating struct {
 	FoodRating       int       `bson:"food_rating" json:"food_rating"`
 	DeliveryRating   int       `bson:"delivery_rating" json:"delivery_rating"`
@@ -323,12 +812,288 @@ type OrderRating struct {
 	RatedAt          time.Time `bson:"rated_at" json:"rated_at"`
 }
 
+// Review statuses (Review.Status).
+const (
+	ReviewPublished = "published"
+	ReviewHidden    = "hidden"
+)
+
+// Review is a customer's public review of a restaurant, tied to one of
+// their delivered orders so only real customers can leave one (at most one
+// per order). Photos are URLs from POST /upload with type=reviews.
+type Review struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RestaurantID primitive.ObjectID `bson:"restaurant_id" json:"restaurant_id"`
+	OrderID      primitive.ObjectID `bson:"order_id" json:"order_id"`
+	CustomerID   primitive.ObjectID `bson:"customer_id" json:"-"`
+	// CustomerName is what the review is shown under, e.g. "Abebe K.".
+	CustomerName string            `bson:"customer_name" json:"customer_name"`
+	Rating       int               `bson:"rating" json:"rating"` // 1-5
+	Comment      string            `bson:"comment,omitempty" json:"comment,omitempty"`
+	Photos       []string          `bson:"photos,omitempty" json:"photos,omitempty"`
+	Reply        *ReviewReply      `bson:"reply,omitempty" json:"reply,omitempty"`
+	Status       string            `bson:"status" json:"status"`
+	Flags        []ReviewFlag      `bson:"flags,omitempty" json:"flags,omitempty"` // admin views only
+	FlagCount    int               `bson:"flag_count" json:"flag_count"`
+	Moderation   *ReviewModeration `bson:"moderation,omitempty" json:"moderation,omitempty"`
+	CreatedAt    time.Time         `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time         `bson:"updated_at" json:"updated_at"`
+}
+
+// ReviewReply is the restaurant's public answer to a review, written by its
+// owner or an admin. A new reply replaces the old one.
+type ReviewReply struct {
+	Text      string             `bson:"text" json:"text"`
+	RepliedBy primitive.ObjectID `bson:"replied_by" json:"-"`
+	Role      string             `bson:"role" json:"role"` // "owner" or "admin"
+	RepliedAt time.Time          `bson:"replied_at" json:"replied_at"`
+}
+
+// ReviewFlag is one user reporting a review for moderation.
+type ReviewFlag struct {
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Reason    string             `bson:"reason" json:"reason"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ReviewModeration is the last moderation decision taken on a review.
+type ReviewModeration struct {
+	Action      string             `bson:"action" json:"action"` // "hide", "unhide" or "dismiss_flags"
+	Reason      string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	ModeratedBy primitive.ObjectID `bson:"moderated_by" json:"moderated_by"`
+	ModeratedAt time.Time          `bson:"moderated_at" json:"moderated_at"`
+}
+
 type CancellationInfo struct {
 	Reason       string             `bson:"reason" json:"reason"`
 	CancelledBy  primitive.ObjectID `bson:"cancelled_by" json:"cancelled_by"`
 	Role         string             `bson:"role" json:"role"`
 	Timestamp    time.Time          `bson:"timestamp" json:"timestamp"`
-	RefundAmount float64            `bson:"refund_amount,omitempty" json:"refund_amount,omitempty"`
+	RefundAmount float64            `bson:"refund_amount,omitempty" json:"refund_amount,omitempty"` // refunds processed so far
+}
+
+// OrderRefunds is the running total of an order's refunds. Requested
+// counts pending refunds too, so no more than the order total can ever be
+// asked for; Refunded and Food only count processed ones.
+type OrderRefunds struct {
+	Requested float64 `bson:"requested" json:"requested"`
+	Refunded  float64 `bson:"refunded" json:"refunded"`
+	Food      float64 `bson:"food" json:"food"` // the food-sales part of Refunded
+}
+
+// Refund statuses (Refund.Status).
+const (
+	RefundPending   = "pending"
+	RefundProcessed = "processed"
+)
+
+// Where a refund is paid (Refund.Destination).
+const (
+	RefundToOriginalMethod = "original_method" // back through the order's payment method
+	RefundToWallet         = "wallet"          // as credit on the customer's wallet
+)
+
+// Refund reason codes (Refund.ReasonCode).
+const (
+	RefundReasonMissingItem      = "missing_item"
+	RefundReasonWrongItem        = "wrong_item"
+	RefundReasonQuality          = "quality"
+	RefundReasonLateDelivery     = "late_delivery"
+	RefundReasonNotDelivered     = "not_delivered"
+	RefundReasonOrderCancelled   = "order_cancelled"
+	RefundReasonDuplicatePayment = "duplicate_payment"
+	RefundReasonOther            = "other"
+)
+
+// Refund gives a customer back money on an order: all that's left of it
+// (full) or particular items (partial). It's pending until the money has
+// actually gone back — at once for wallet credit, or when an admin
+// records the transfer for the original payment method.
+//
+// Amount is split by who bears it: Food comes off the restaurant's next
+// statement, Delivery and Tip are charged back to the driver if
+// ChargeDriver is set, and the rest (service charge and tax) is the
+// platform's.
+type Refund struct {
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OrderID         primitive.ObjectID  `bson:"order_id" json:"order_id"`
+	OrderNumber     string              `bson:"order_number" json:"order_number"`
+	CustomerID      primitive.ObjectID  `bson:"customer_id" json:"customer_id"`
+	RestaurantID    primitive.ObjectID  `bson:"restaurant_id" json:"restaurant_id"`
+	DriverID        *primitive.ObjectID `bson:"driver_id,omitempty" json:"driver_id,omitempty"`
+	Type            string              `bson:"type" json:"type"` // "full" or "partial"
+	Items           []RefundItem        `bson:"items,omitempty" json:"items,omitempty"`
+	Amount          float64             `bson:"amount" json:"amount"`
+	Food            float64             `bson:"food" json:"food"`
+	Delivery        float64             `bson:"delivery" json:"delivery"` // delivery fee and surge
+	Tip             float64             `bson:"tip" json:"tip"`
+	ReasonCode      string              `bson:"reason_code" json:"reason_code"`
+	Note            string              `bson:"note,omitempty" json:"note,omitempty"`
+	Destination     string              `bson:"destination" json:"destination"`
+	PaymentMethod   string              `bson:"payment_method" json:"payment_method"` // the order's
+	ChargeDriver    bool                `bson:"charge_driver" json:"charge_driver"`
+	Status          string              `bson:"status" json:"status"`
+	Reference       string              `bson:"reference,omitempty" json:"reference,omitempty"`       // the transfer back, for original_method refunds
+	StatementID     *primitive.ObjectID `bson:"statement_id,omitempty" json:"statement_id,omitempty"` // the restaurant statement Food was deducted on
+	RequestedBy     primitive.ObjectID  `bson:"requested_by" json:"requested_by"`
+	RequestedByRole string              `bson:"requested_by_role" json:"requested_by_role"`
+	ProcessedBy     *primitive.ObjectID `bson:"processed_by,omitempty" json:"processed_by,omitempty"`
+	ProcessedAt     *time.Time          `bson:"processed_at,omitempty" json:"processed_at,omitempty"`
+	CreatedAt       time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// RefundItem is one order line refunded, in part or whole.
+type RefundItem struct {
+	Line       int                `bson:"line" json:"line"` // index into the order's items
+	MenuItemID primitive.ObjectID `bson:"menu_item_id" json:"menu_item_id"`
+	Name       string             `bson:"name" json:"name"`
+	Quantity   int                `bson:"quantity" json:"quantity"`
+	Amount     float64            `bson:"amount" json:"amount"` // the food value refunded
+}
+
+// OrderCheckout is a hosted checkout with a payment provider. Reference
+// is our reference for it (the order ID); ProviderReference is the
+// provider's, once the payment goes through.
+type OrderCheckout struct {
+	Provider          string    `bson:"provider" json:"provider"`
+	Reference         string    `bson:"reference" json:"reference"`
+	CheckoutURL       string    `bson:"checkout_url" json:"checkout_url"`
+	Amount            float64   `bson:"amount" json:"amount"`
+	ProviderReference string    `bson:"provider_reference,omitempty" json:"provider_reference,omitempty"`
+	InitiatedAt       time.Time `bson:"initiated_at" json:"initiated_at"`
+	ExpiresAt         time.Time `bson:"expires_at" json:"expires_at"` // cancelled if still unpaid by then
+}
+
+// OrderWalletPayment is wallet credit put towards an order. If the order
+// is cancelled before it's delivered the credit goes straight back to the
+// wallet, and nothing more of it is left to refund.
+type OrderWalletPayment struct {
+	Amount     float64    `bson:"amount" json:"amount"`
+	Returned   float64    `bson:"returned,omitempty" json:"returned,omitempty"` // less any refunds already issued
+	ReturnedAt *time.Time `bson:"returned_at,omitempty" json:"returned_at,omitempty"`
+}
+
+// Wallet transaction types (WalletTransaction.Type).
+const (
+	WalletGrant        = "grant"         // store credit granted by an admin
+	WalletRefund       = "refund"        // a refund paid to the wallet
+	WalletOrderPayment = "order_payment" // credit put towards an order
+	WalletOrderReturn  = "order_return"  // an order payment handed back when the order was cancelled
+	WalletTip          = "tip"           // a tip for the driver given after delivery
+	WalletExpiry       = "expiry"        // credit that expired unspent
+)
+
+// WalletTransaction is one entry in a customer's wallet log. The log is
+// append-only and numbered per user by Seq; each entry records the balance
+// and credit lots it leaves, so the latest entry is always the wallet's
+// true state.
+type WalletTransaction struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Seq          int64              `bson:"seq" json:"seq"`
+	Type         string             `bson:"type" json:"type"`
+	Amount       float64            `bson:"amount" json:"amount"` // negative for spending and expiry
+	BalanceAfter float64            `bson:"balance_after" json:"balance_after"`
+	Lots         []WalletLot        `bson:"lots" json:"-"` // the lots left after this entry
+	// ExpiresAt is when credit added by this entry expires; absent if it
+	// never does.
+	ExpiresAt *time.Time          `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	Reason    string              `bson:"reason,omitempty" json:"reason,omitempty"`
+	OrderID   *primitive.ObjectID `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	RefundID  *primitive.ObjectID `bson:"refund_id,omitempty" json:"refund_id,omitempty"`
+	// IdempotencyKey makes recording the same event twice (e.g. a refund
+	// credit retried) a no-op; admin grants have none.
+	IdempotencyKey string              `bson:"idempotency_key,omitempty" json:"-"`
+	CreatedBy      *primitive.ObjectID `bson:"created_by,omitempty" json:"created_by,omitempty"` // admin, for grants
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// WalletLot is unspent credit added by one transaction. Spending draws on
+// the lots that expire soonest first, and on lots that never expire last.
+type WalletLot struct {
+	TransactionID primitive.ObjectID `bson:"transaction_id" json:"transaction_id"`
+	Amount        float64            `bson:"amount" json:"amount"` // what's left of it
+	ExpiresAt     *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// Wallet is the balance projection of a customer's wallet log, kept for
+// reads and the expiry sweep. Version is the Seq of the last transaction
+// applied to it.
+type Wallet struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Balance   float64            `bson:"balance" json:"balance"`
+	Lots      []WalletLot        `bson:"lots" json:"lots"`
+	Version   int64              `bson:"version" json:"version"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Payment webhook event statuses (PaymentEvent.Status).
+const (
+	PaymentEventReceived   = "received"   // stored, not applied yet
+	PaymentEventProcessing = "processing" // being applied, until LockedUntil
+	PaymentEventProcessed  = "processed"
+	PaymentEventFailed     = "failed" // applying it failed; see Error
+)
+
+// PaymentEvent is a payment provider's webhook as it arrived, stored
+// before it's applied. A provider event is stored once however often it's
+// delivered (Deliveries counts them), which is what makes redelivery
+// harmless, and a failed one can be replayed from Headers and Body.
+type PaymentEvent struct {
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Provider        string              `bson:"provider" json:"provider"`
+	EventID         string              `bson:"event_id" json:"event_id"`   // the provider's, or derived from the body
+	Reference       string              `bson:"reference" json:"reference"` // our checkout reference, the order ID
+	OrderID         *primitive.ObjectID `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	ProviderStatus  string              `bson:"provider_status" json:"provider_status"`
+	Status          string              `bson:"status" json:"status"`
+	Error           string              `bson:"error,omitempty" json:"error,omitempty"`
+	Headers         map[string]string   `bson:"headers" json:"headers"`
+	Body            string              `bson:"body" json:"body"`
+	Deliveries      int                 `bson:"deliveries" json:"deliveries"`
+	Attempts        int                 `bson:"attempts" json:"attempts"`
+	LockedUntil     *time.Time          `bson:"locked_until,omitempty" json:"-"`
+	ReplayedBy      *primitive.ObjectID `bson:"replayed_by,omitempty" json:"replayed_by,omitempty"` // the admin who last replayed it
+	ReplayedAt      *time.Time          `bson:"replayed_at,omitempty" json:"replayed_at,omitempty"`
+	ProcessedAt     *time.Time          `bson:"processed_at,omitempty" json:"processed_at,omitempty"`
+	ReceivedAt      time.Time           `bson:"received_at" json:"received_at"`
+	LastDeliveredAt time.Time           `bson:"last_delivered_at" json:"last_delivered_at"`
+	UpdatedAt       time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// Payment verification job statuses (PaymentVerificationJob.Status).
+const (
+	VerificationJobQueued    = "queued"
+	VerificationJobRunning   = "running" // being tried, until LockedUntil
+	VerificationJobSucceeded = "succeeded"
+	VerificationJobFailed    = "failed"    // the provider ruled the payment out
+	VerificationJobEscalated = "escalated" // still unresolved at DeadlineAt; left for admin review
+	VerificationJobCancelled = "cancelled" // the order stopped waiting on it
+)
+
+// PaymentVerificationJob retries a transfer verification the provider
+// couldn't answer (it timed out, was down, or had no receipt yet) until it
+// does or DeadlineAt passes. An order has at most one; verifying again
+// replaces it.
+type PaymentVerificationJob struct {
+	ID                   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrderID              primitive.ObjectID `bson:"order_id" json:"order_id"`
+	CustomerID           primitive.ObjectID `bson:"customer_id" json:"customer_id"`
+	Method               string             `bson:"method" json:"method"`
+	TransactionReference string             `bson:"transaction_reference" json:"transaction_reference"`
+	Amount               float64            `bson:"amount" json:"amount"`
+	PayerPhone           string             `bson:"payer_phone,omitempty" json:"payer_phone,omitempty"`
+	Status               string             `bson:"status" json:"status"`
+	Attempts             int                `bson:"attempts" json:"attempts"` // retries, not counting the customer's own check
+	LastError            string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	NextAttemptAt        time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`
+	DeadlineAt           time.Time          `bson:"deadline_at" json:"deadline_at"`
+	LockedUntil          *time.Time         `bson:"locked_until,omitempty" json:"-"`
+	ResolvedAt           *time.Time         `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	CreatedAt            time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt            time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
 // Request/Response DTOs
@@ -397,7 +1162,31 @@ type CreateOrderRequest struct {
 	Items         []OrderItemRequest `json:"items" binding:"required,min=1"`
 	AddressID     string             `json:"address_id" binding:"required"`
 	Notes         string             `json:"notes"`
-	PaymentMethod string             `json:"payment_method" binding:"required"`
+	PaymentMethod string             `json:"payment_method" binding:"required"` // "wallet" pays the whole order from the wallet
+	Tip           *TipRequest        `json:"tip,omitempty"`
+	// WalletAmount is wallet credit to put towards the order, the rest
+	// being paid by PaymentMethod.
+	WalletAmount float64 `json:"wallet_amount" binding:"omitempty,gte=0"`
+}
+
+// OrderQuoteRequest is CreateOrderRequest minus the checkout-only fields —
+// enough to price the cart before the customer commits.
+type OrderQuoteRequest struct {
+	RestaurantID string             `json:"restaurant_id" binding:"required"`
+	Items        []OrderItemRequest `json:"items" binding:"required,min=1"`
+	AddressID    string             `json:"address_id" binding:"required"`
+	Tip          *TipRequest        `json:"tip,omitempty"`
+}
+
+// OrderQuote is what POST /orders/quote returns: the exact items and
+// amounts CreateOrder would store if the order were placed right now.
+type OrderQuote struct {
+	RestaurantID      primitive.ObjectID  `json:"restaurant_id"`
+	Items             []OrderItem         `json:"items"`
+	Amount            OrderAmount         `json:"amount"`
+	ZoneID            *primitive.ObjectID `json:"zone_id,omitempty"`
+	DistanceKm        float64             `json:"distance_km,omitempty"`
+	EstimatedDelivery time.Time           `json:"estimated_delivery"`
 }
 
 type VerifyOrderPaymentRequest struct {
@@ -420,10 +1209,20 @@ type ReviewPaymentProofRequest struct {
 	Notes    string `json:"notes"`
 }
 
+// BulkReviewPaymentProofRequest approves or rejects several orders'
+// payments at once with the same notes.
+type BulkReviewPaymentProofRequest struct {
+	OrderIDs []string `json:"order_ids" binding:"required,min=1,max=50"`
+	Approved bool     `json:"approved"`
+	Notes    string   `json:"notes"`
+}
+
 type OrderItemRequest struct {
 	MenuItemID string                  `json:"menu_item_id" binding:"required"`
 	Quantity   int                     `json:"quantity" binding:"required,min=1"`
 	Addons     []OrderItemAddonRequest `json:"addons"`
+	VariantID  string                  `json:"variant_id"`
+	Modifiers  []OrderModifierRequest  `json:"modifiers"`
 	Notes      string                  `json:"notes"`
 }
 
@@ -431,6 +1230,14 @@ type OrderItemAddonRequest struct {
 	AddonID string `json:"addon_id" binding:"required"`
 }
 
+// OrderModifierRequest picks one option from one of the item's modifier
+// groups; Modifiers holds picks from that option's own nested groups.
+type OrderModifierRequest struct {
+	GroupID   string                 `json:"group_id" binding:"required"`
+	OptionID  string                 `json:"option_id" binding:"required"`
+	Modifiers []OrderModifierRequest `json:"modifiers"`
+}
+
 type CreateRestaurantRequest struct {
 	Name         string                  `json:"name" binding:"required"`
 	Description  string                  `json:"description"`
@@ -445,6 +1252,7 @@ type CreateRestaurantRequest struct {
 	DeliveryTime int                     `json:"delivery_time"`
 	Images       []string                `json:"images"` // Add this field too
 	Menu         []CreateMenuItemRequest `json:"menu"`   // Add this field
+	Timezone     string                  `json:"timezone"`
 }
 
 type DriverApplicationRequest struct {
@@ -481,6 +1289,73 @@ type RestaurantQuery struct {
 	Radius    float64 `form:"radius" default:"10000"`
 }
 
+// MenuItemQuery is GET /restaurants/:id/menu's query string. By default
+// the menu comes back grouped by category with only what can be ordered
+// right now. flat=true gives the whole (filtered) menu as a plain array, as
+// it used to be; page or limit paginate that flat list.
+type MenuItemQuery struct {
+	Flat      bool   `form:"flat"`
+	Page      int    `form:"page"`
+	Limit     int    `form:"limit"`
+	Category  string `form:"category"`
+	Available *bool  `form:"available"`
+	Q         string `form:"q"`
+	// Comma-separated: every listed diet must be tagged, none of the
+	// listed allergens may be.
+	Diet             string `form:"diet"`
+	ExcludeAllergens string `form:"exclude_allergens"`
+}
+
+// MenuHistoryQuery is GET /restaurants/:id/menu/history's query string.
+// field=price gives just the price-change log.
+type MenuHistoryQuery struct {
+	ItemID string `form:"item_id"`
+	Field  string `form:"field"`
+	Page   int64  `form:"page"`
+	Limit  int64  `form:"limit"`
+}
+
+// ReviewQuery is GET /restaurants/:id/reviews's query string.
+type ReviewQuery struct {
+	Rating     int    `form:"rating"`      // only this many stars
+	WithPhotos bool   `form:"with_photos"` // only reviews with photos
+	Sort       string `form:"sort"`        // "newest" (default), "oldest", "highest" or "lowest"
+	Page       int64  `form:"page"`
+	Limit      int64  `form:"limit"`
+}
+
+// AdminReviewQuery is GET /admin/reviews's query string.
+type AdminReviewQuery struct {
+	Status       string `form:"status"` // "flagged" (default), "hidden", "published" or "all"
+	RestaurantID string `form:"restaurant_id"`
+	Page         int64  `form:"page"`
+	Limit        int64  `form:"limit"`
+}
+
+// SearchQuery is GET /api/v1/search's query string.
+type SearchQuery struct {
+	Q         string  `form:"q" binding:"required"`
+	Latitude  float64 `form:"latitude"`
+	Longitude float64 `form:"longitude"`
+	Cuisine   string  `form:"cuisine"`
+	PriceBand string  `form:"price_band"` // "$", "$$" or "$$$"
+	Limit     int     `form:"limit"`
+	// Same as MenuItemQuery's: only dishes (and restaurants with dishes)
+	// that fit.
+	Diet             string `form:"diet"`
+	ExcludeAllergens string `form:"exclude_allergens"`
+}
+
+type ServiceZoneRequest struct {
+	Name                  string            `json:"name" binding:"required"`
+	Area                  GeoPolygon        `json:"area" binding:"required"`
+	IsActive              *bool             `json:"is_active"`
+	ActiveHours           OpeningHours      `json:"active_hours"`
+	SurgeMultiplier       float64           `json:"surge_multiplier"`
+	FeeBands              []DeliveryFeeBand `json:"fee_bands"`
+	MaxDeliveryDistanceKm float64           `json:"max_delivery_distance_km"`
+}
+
 type UpdateRestaurantRequest struct {
 	Name         string                  `json:"name"`
 	Description  string                  `json:"description"`
@@ -495,19 +1370,68 @@ type UpdateRestaurantRequest struct {
 	Longitude    float64                 `json:"longitude"`
 	Images       []string                `json:"images"` // Add this
 	Menu         []CreateMenuItemRequest `json:"menu"`   // Add this
+	Timezone     string                  `json:"timezone"`
+}
+
+type MenuCategoryRequest struct {
+	Name        string       `json:"name" binding:"required"`
+	Description string       `json:"description"`
+	Image       string       `json:"image"`
+	SortOrder   int          `json:"sort_order"`
+	Schedule    OpeningHours `json:"schedule"`
+	IsActive    *bool        `json:"is_active"`
+}
+
+// MenuFile is a restaurant's whole menu in the JSON export format, which
+// the JSON import accepts back (restaurant_id and exported_at are ignored
+// on import). Categories are matched to existing ones by name, items by SKU.
+type MenuFile struct {
+	RestaurantID primitive.ObjectID    `json:"restaurant_id,omitempty"`
+	ExportedAt   *time.Time            `json:"exported_at,omitempty"`
+	Categories   []MenuCategoryRequest `json:"categories"`
+	Items        []MenuFileItem        `json:"items"`
+}
+
+// MenuFileItem is one menu item in an export/import file. On import a nil
+// IsAvailable means available for a new item and unchanged for an existing
+// one; likewise omitted variants/modifier groups leave an existing item's
+// as they are (the CSV format never carries them).
+type MenuFileItem struct {
+	SKU             string            `json:"sku"`
+	Name            string            `json:"name"`
+	Description     string            `json:"description"`
+	Category        string            `json:"category"`
+	Price           float64           `json:"price"`
+	IsAvailable     *bool             `json:"is_available"`
+	PreparationTime int               `json:"preparation_time"`
+	Image           string            `json:"image"`
+	Ingredients     []string          `json:"ingredients"`
+	DietaryTags     []string          `json:"dietary_tags"`
+	Allergens       []string          `json:"allergens"`
+	Addons          []Addon           `json:"addons"`
+	Variants        []MenuItemVariant `json:"variants,omitempty"`
+	ModifierGroups  []ModifierGroup   `json:"modifier_groups,omitempty"`
 }
 
 // Add these to models/requests.go
 type CreateMenuItemRequest struct {
-	Name            string   `json:"name" binding:"required"`
-	Description     string   `json:"description"`
-	Price           float64  `json:"price" binding:"required"`
-	Category        string   `json:"category" binding:"required"`
-	Ingredients     []string `json:"ingredients"`
-	Addons          []Addon  `json:"addons"`
-	PreparationTime int      `json:"preparation_time"`
-	IsAvailable     *bool    `json:"is_available" default:"true"`
-	Image           string   `json:"image"`
+	Name              string            `json:"name" binding:"required"`
+	SKU               string            `json:"sku"`
+	Description       string            `json:"description"`
+	Price             float64           `json:"price" binding:"required"`
+	Category          string            `json:"category"` // required unless category_id is given
+	CategoryID        string            `json:"category_id"`
+	Ingredients       []string          `json:"ingredients"`
+	DietaryTags       []string          `json:"dietary_tags"`
+	Allergens         []string          `json:"allergens"`
+	Addons            []Addon           `json:"addons"`
+	Variants          []MenuItemVariant `json:"variants"`
+	ModifierGroups    []ModifierGroup   `json:"modifier_groups"`
+	DailyStock        *int              `json:"daily_stock"` // 0 turns stock tracking off
+	LowStockThreshold *int              `json:"low_stock_threshold"`
+	PreparationTime   int               `json:"preparation_time"`
+	IsAvailable       *bool             `json:"is_available" default:"true"`
+	Image             string            `json:"image"`
 }
 
 type CreateAddonRequest struct {
@@ -517,13 +1441,122 @@ type CreateAddonRequest struct {
 }
 
 type UpdateMenuItemRequest struct {
-	Name            string   `json:"name"`
-	Description     string   `json:"description"`
-	Price           float64  `json:"price"`
-	Category        string   `json:"category"`
-	Ingredients     []string `json:"ingredients"`
-	Addons          []Addon  `json:"addons"`
-	IsAvailable     *bool    `json:"is_available"` // Use pointer to distinguish between false and not provided
-	PreparationTime int      `json:"preparation_time"`
-	Image           string   `json:"image"`
+	Name              string            `json:"name"`
+	SKU               string            `json:"sku"`
+	Description       string            `json:"description"`
+	Price             float64           `json:"price"`
+	Category          string            `json:"category"`
+	CategoryID        string            `json:"category_id"`
+	Ingredients       []string          `json:"ingredients"`
+	DietaryTags       []string          `json:"dietary_tags"`
+	Allergens         []string          `json:"allergens"`
+	Addons            []Addon           `json:"addons"`
+	Variants          []MenuItemVariant `json:"variants"`
+	ModifierGroups    []ModifierGroup   `json:"modifier_groups"`
+	DailyStock        *int              `json:"daily_stock"` // 0 turns stock tracking off
+	LowStockThreshold *int              `json:"low_stock_threshold"`
+	IsAvailable       *bool             `json:"is_available"` // Use pointer to distinguish between false and not provided
+	PreparationTime   int               `json:"preparation_time"`
+	Image             string            `json:"image"`
+}
+
+type CreateReviewRequest struct {
+	// Rating and Comment default to the order's rating (POST
+	// /orders/:id/rate) when left out.
+	Rating  int      `json:"rating"`
+	Comment string   `json:"comment"`
+	Photos  []string `json:"photos"`
+}
+
+type ReviewReplyRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+type FlagReviewRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type ModerateReviewRequest struct {
+	Action string `json:"action" binding:"required,oneof=hide unhide dismiss_flags"`
+	Reason string `json:"reason"`
+}
+
+type LedgerAdjustmentRequest struct {
+	Type        string  `json:"type" binding:"required,oneof=bonus penalty"`
+	Amount      float64 `json:"amount" binding:"required,gt=0"`
+	Description string  `json:"description" binding:"required"`
+}
+
+type RunSettlementRequest struct {
+	// Days as "2006-01-02" in Africa/Addis_Ababa time, both inclusive.
+	PeriodStart string `json:"period_start" binding:"required"`
+	PeriodEnd   string `json:"period_end" binding:"required"`
+}
+
+type GenerateStatementsRequest struct {
+	// RestaurantID limits generation to one restaurant; empty generates a
+	// statement for every restaurant with unbilled deliveries.
+	RestaurantID string `json:"restaurant_id"`
+	// Days as "2006-01-02" in Africa/Addis_Ababa time, both inclusive.
+	PeriodStart string `json:"period_start" binding:"required"`
+	PeriodEnd   string `json:"period_end" binding:"required"`
+}
+
+type StatementAdjustmentRequest struct {
+	Amount float64 `json:"amount" binding:"required"` // negative deducts
+	Reason string  `json:"reason" binding:"required"`
+}
+
+type MarkStatementPaidRequest struct {
+	Reference string `json:"reference"` // bank transfer or telebirr transaction reference
+}
+
+type ConfirmCashRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"` // cash actually received from the customer
+}
+
+type CashDepositRequest struct {
+	Amount    float64 `json:"amount" binding:"required,gt=0"`
+	Reference string  `json:"reference"` // bank slip or receipt number
+	Note      string  `json:"note"`
+}
+
+type CashLimitRequest struct {
+	// CashLimit is the cash balance at which the driver stops being
+	// offered cash orders; null reverts to the default.
+	CashLimit *float64 `json:"cash_limit" binding:"omitempty,gte=0"`
+}
+
+// TipRequest is a tip for the driver, either a fixed amount or a
+// percentage of the food subtotal; set one or the other. See GET
+// /orders/tip-presets for the suggested values.
+type TipRequest struct {
+	Amount  float64 `json:"amount" binding:"omitempty,gte=0"`
+	Percent float64 `json:"percent" binding:"omitempty,gte=0,lte=100"`
+}
+
+type CreateRefundRequest struct {
+	OrderID string `json:"order_id" binding:"required"`
+	Type    string `json:"type" binding:"required,oneof=full partial"`
+	// Items are the lines to refund for a partial refund.
+	Items        []RefundItemRequest `json:"items" binding:"omitempty,dive"`
+	ReasonCode   string              `json:"reason_code" binding:"required,oneof=missing_item wrong_item quality late_delivery not_delivered order_cancelled duplicate_payment other"`
+	Note         string              `json:"note"`
+	Destination  string              `json:"destination" binding:"required,oneof=original_method wallet"`
+	ChargeDriver bool                `json:"charge_driver"` // charge the delivery and tip refunded back to the driver
+}
+
+type RefundItemRequest struct {
+	Line     int `json:"line" binding:"gte=0"`
+	Quantity int `json:"quantity" binding:"required,gt=0"`
+}
+
+type ProcessRefundRequest struct {
+	Reference string `json:"reference"` // the transfer back to the customer
+}
+
+type GrantWalletCreditRequest struct {
+	Amount    float64    `json:"amount" binding:"required,gt=0"`
+	Reason    string     `json:"reason" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"` // null never expires
 }
\ No newline at end of file
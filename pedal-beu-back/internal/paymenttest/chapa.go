@@ -0,0 +1,292 @@
+// Package paymenttest has stand-ins for payment providers' APIs, for
+// tests of the payment code.
+package paymenttest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeChapaServer stands in for Chapa's API: serve it with
+// httptest.NewServer, point ChapaConfig.BaseURL at it, and checkouts
+// open on its own page, where Pay and Fail send the checkout's callback
+// URL a webhook signed with WebhookSecret, just as Chapa would. Tests can
+// skip the page and call Complete.
+type FakeChapaServer struct {
+	WebhookSecret string
+
+	mu     sync.Mutex
+	txns   map[string]*fakeChapaTxn
+	mux    *http.ServeMux
+	client *http.Client
+}
+
+type fakeChapaTxn struct {
+	TxRef       string `json:"tx_ref"`
+	Reference   string `json:"reference"`
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency"`
+	Email       string `json:"email"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Status      string `json:"status"` // "pending", "success" or "failed"
+	CallbackURL string `json:"-"`
+	ReturnURL   string `json:"-"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+func NewFakeChapaServer(webhookSecret string) *FakeChapaServer {
+	f := &FakeChapaServer{
+		WebhookSecret: webhookSecret,
+		txns:          map[string]*fakeChapaTxn{},
+		mux:           http.NewServeMux(),
+		client:        &http.Client{Timeout: 10 * time.Second},
+	}
+	f.mux.HandleFunc("POST /v1/transaction/initialize", f.initialize)
+	f.mux.HandleFunc("GET /v1/transaction/verify/{txRef}", f.verify)
+	f.mux.HandleFunc("GET /checkout/{txRef}", f.checkoutPage)
+	f.mux.HandleFunc("POST /checkout/{txRef}/{outcome}", f.checkoutAction)
+	return f
+}
+
+func (f *FakeChapaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mux.ServeHTTP(w, r)
+}
+
+func fakeChapaJSON(w http.ResponseWriter, code int, status string, message interface{}, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": status, "message": message, "data": data})
+}
+
+func (f *FakeChapaServer) initialize(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		fakeChapaJSON(w, http.StatusUnauthorized, "failed", "Invalid API Key", nil)
+		return
+	}
+
+	var req struct {
+		Amount      string `json:"amount"`
+		Currency    string `json:"currency"`
+		Email       string `json:"email"`
+		FirstName   string `json:"first_name"`
+		LastName    string `json:"last_name"`
+		TxRef       string `json:"tx_ref"`
+		CallbackURL string `json:"callback_url"`
+		ReturnURL   string `json:"return_url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TxRef == "" || req.Amount == "" {
+		fakeChapaJSON(w, http.StatusBadRequest, "failed", "amount and tx_ref are required", nil)
+		return
+	}
+
+	if req.Currency == "" {
+		req.Currency = "ETB"
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, used := f.txns[req.TxRef]; used {
+		fakeChapaJSON(w, http.StatusBadRequest, "failed", "Transaction reference has been used before", nil)
+		return
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	f.txns[req.TxRef] = &fakeChapaTxn{
+		TxRef:       req.TxRef,
+		Reference:   "FAKE" + fakeChapaRandomHex(6),
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		Email:       req.Email,
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		Status:      "pending",
+		CallbackURL: req.CallbackURL,
+		ReturnURL:   req.ReturnURL,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	checkoutURL := "http://" + r.Host + "/checkout/" + req.TxRef
+	fakeChapaJSON(w, http.StatusOK, "success", "Hosted Link", map[string]string{"checkout_url": checkoutURL})
+}
+
+func (f *FakeChapaServer) verify(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	txn, ok := f.txns[r.PathValue("txRef")]
+	var snapshot fakeChapaTxn
+	if ok {
+		snapshot = *txn
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		fakeChapaJSON(w, http.StatusNotFound, "failed", "Invalid transaction or Transaction not found", nil)
+		return
+	}
+	fakeChapaJSON(w, http.StatusOK, "success", "Payment details", snapshot)
+}
+
+var fakeChapaCheckoutPage = template.Must(template.New("checkout").Parse(`<!doctype html>
+<html><head><title>Fake Chapa checkout</title></head>
+<body style="font-family: sans-serif; max-width: 28rem; margin: 3rem auto">
+<h1>Fake Chapa checkout</h1>
+<p>{{.Amount}} {{.Currency}} — {{.TxRef}}</p>
+<p>Status: <strong>{{.Status}}</strong></p>
+{{if eq .Status "pending"}}
+<form method="post" action="/checkout/{{.TxRef}}/pay" style="display: inline"><button>Pay</button></form>
+<form method="post" action="/checkout/{{.TxRef}}/fail" style="display: inline"><button>Fail</button></form>
+{{end}}
+</body></html>`))
+
+func (f *FakeChapaServer) checkoutPage(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	txn, ok := f.txns[r.PathValue("txRef")]
+	var snapshot fakeChapaTxn
+	if ok {
+		snapshot = *txn
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fakeChapaCheckoutPage.Execute(w, snapshot)
+}
+
+func (f *FakeChapaServer) checkoutAction(w http.ResponseWriter, r *http.Request) {
+	outcome := r.PathValue("outcome")
+	if outcome != "pay" && outcome != "fail" {
+		http.NotFound(w, r)
+		return
+	}
+	txn, err := f.settle(r.PathValue("txRef"), outcome == "pay")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := f.notify(txn); err != nil {
+		fmt.Printf("⚠️  Fake Chapa: %v\n", err)
+	}
+
+	returnURL := txn.ReturnURL
+	if returnURL == "" {
+		returnURL = "/checkout/" + txn.TxRef
+	}
+	http.Redirect(w, r, returnURL, http.StatusSeeOther)
+}
+
+// Complete settles a pending checkout as paid or failed and sends its
+// webhook. A webhook that can't be delivered is reported but the
+// checkout stays settled, as with Chapa, for polling to pick up.
+func (f *FakeChapaServer) Complete(txRef string, paid bool) error {
+	txn, err := f.settle(txRef, paid)
+	if err != nil {
+		return err
+	}
+	return f.notify(txn)
+}
+
+// PayAmount settles a pending checkout as paid for amount rather than
+// what it was opened for, and sends its webhook.
+func (f *FakeChapaServer) PayAmount(txRef, amount string) error {
+	f.mu.Lock()
+	if txn, ok := f.txns[txRef]; ok && txn.Status == "pending" {
+		txn.Amount = amount
+	}
+	f.mu.Unlock()
+	return f.Complete(txRef, true)
+}
+
+// settle moves a pending transaction to success or failed and returns a
+// copy of it.
+func (f *FakeChapaServer) settle(txRef string, paid bool) (fakeChapaTxn, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	txn, ok := f.txns[txRef]
+	if !ok {
+		return fakeChapaTxn{}, fmt.Errorf("no transaction %s", txRef)
+	}
+	if txn.Status != "pending" {
+		return fakeChapaTxn{}, fmt.Errorf("transaction %s is already %s", txRef, txn.Status)
+	}
+	txn.Status = "success"
+	if !paid {
+		txn.Status = "failed"
+	}
+	txn.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	return *txn, nil
+}
+
+// notify sends a settled transaction's webhook.
+func (f *FakeChapaServer) notify(snapshot fakeChapaTxn) error {
+	if snapshot.CallbackURL == "" {
+		return nil
+	}
+	event := "charge.success"
+	if snapshot.Status != "success" {
+		event = "charge.failed"
+	}
+	payload := map[string]interface{}{
+		"event":      event,
+		"tx_ref":     snapshot.TxRef,
+		"reference":  snapshot.Reference,
+		"status":     snapshot.Status,
+		"amount":     snapshot.Amount,
+		"currency":   snapshot.Currency,
+		"email":      snapshot.Email,
+		"first_name": snapshot.FirstName,
+		"last_name":  snapshot.LastName,
+		"created_at": snapshot.CreatedAt,
+		"updated_at": snapshot.UpdatedAt,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return f.sendWebhook(snapshot.CallbackURL, body)
+}
+
+// sendWebhook posts a webhook signed the way Chapa signs them.
+func (f *FakeChapaServer) sendWebhook(callbackURL string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	bodyMAC := hmac.New(sha256.New, []byte(f.WebhookSecret))
+	bodyMAC.Write(body)
+	req.Header.Set("x-chapa-signature", hex.EncodeToString(bodyMAC.Sum(nil)))
+	secretMAC := hmac.New(sha256.New, []byte(f.WebhookSecret))
+	secretMAC.Write([]byte(f.WebhookSecret))
+	req.Header.Set("chapa-signature", hex.EncodeToString(secretMAC.Sum(nil)))
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook not delivered: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook rejected: %s", resp.Status)
+	}
+	return nil
+}
+
+func fakeChapaRandomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return strings.ToUpper(hex.EncodeToString(b))
+}
@@ -15,6 +15,9 @@ import (
 
 type DriverRepository interface {
 	CountActive(ctx context.Context) (int64, error)
+	// CountOnlineWithin counts approved, online drivers whose last known
+	// location is inside area — the surge engine's supply figure.
+	CountOnlineWithin(ctx context.Context, area models.GeoPolygon) (int64, error)
 	FindAll(ctx context.Context) ([]*models.Driver, error)
 	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Driver, error)
 	FindByUserID(ctx context.Context, userID primitive.ObjectID) (*models.Driver, error)
@@ -23,7 +26,48 @@ type DriverRepository interface {
 	UpdateVehicle(ctx context.Context, id primitive.ObjectID, vehicle models.Vehicle) error
 	UpdateOnlineStatus(ctx context.Context, userID primitive.ObjectID, isOnline bool) error
 	UpdateLocation(ctx context.Context, userID primitive.ObjectID, lng, lat float64) error
-	UpdateRating(ctx context.Context, id primitive.ObjectID, rating float64) error
+	// AddRating folds one delivery rating into the driver's rating sum and
+	// count and recomputes Rating from them, in a single update.
+	AddRating(ctx context.Context, userID primitive.ObjectID, rating int) error
+	// RecordDelivery counts a delivered order towards the driver's trips
+	// and lifetime earnings.
+	RecordDelivery(ctx context.Context, userID primitive.ObjectID, baseFee, surgeBonus float64) error
+	// AddTip adds a customer's tip to the driver's lifetime earnings.
+	AddTip(ctx context.Context, userID primitive.ObjectID, tip float64) error
+	// RecordOrderDecision counts an order the driver took (accepted) or
+	// turned down.
+	RecordOrderDecision(ctx context.Context, userID primitive.ObjectID, accepted bool) error
+	// AddPendingEarnings moves the driver's unsettled balance
+	// (earnings.pending) by delta as ledger entries are posted.
+	AddPendingEarnings(ctx context.Context, userID primitive.ObjectID, delta float64) error
+	// RecordPayout stamps earnings.last_payout_at.
+	RecordPayout(ctx context.Context, userID primitive.ObjectID, at time.Time) error
+	SetPayoutAccount(ctx context.Context, userID primitive.ObjectID, account models.PayoutAccount) error
+	// AddCashBalance moves the cash the driver holds for the platform by
+	// delta as cash is collected, deposited and settled.
+	AddCashBalance(ctx context.Context, userID primitive.ObjectID, delta float64) error
+	// SetCashLimit sets the driver's cash limit; nil clears it so the
+	// default applies.
+	SetCashLimit(ctx context.Context, userID primitive.ObjectID, limit *float64) error
+	// ResetAggregates zeroes every driver's ratings, trips, order counts
+	// and earnings totals; SetAggregates then writes one driver's rebuilt
+	// figures. Both are only used by the aggregate rebuild.
+	ResetAggregates(ctx context.Context) error
+	SetAggregates(ctx context.Context, userID primitive.ObjectID, aggregates DriverAggregates) error
+	UpdateRiskScore(ctx context.Context, userID primitive.ObjectID, score float64, anomaly models.GPSAnomaly) error
+}
+
+// DriverAggregates are the running totals kept on a driver document,
+// recomputed from order history by the aggregate rebuild.
+type DriverAggregates struct {
+	RatingSum      int
+	RatingCount    int
+	TotalTrips     int
+	AcceptedOrders int
+	RejectedOrders int
+	Earnings       float64
+	SurgeBonus     float64
+	Tips           float64
 }
 
 type driverRepository struct {
@@ -45,6 +89,17 @@ func (r *driverRepository) CountActive(ctx context.Context) (int64, error) {
 	return r.collection.CountDocuments(ctx, filter)
 }
 
+func (r *driverRepository) CountOnlineWithin(ctx context.Context, area models.GeoPolygon) (int64, error) {
+	filter := bson.M{
+		"is_online": true,
+		"status":    models.DriverApproved,
+		"location": bson.M{
+			"$geoWithin": bson.M{"$geometry": area},
+		},
+	}
+	return r.collection.CountDocuments(ctx, filter)
+}
+
 func (r *driverRepository) FindAll(ctx context.Context) ([]*models.Driver, error) {
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
 	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
@@ -178,20 +233,203 @@ func (r *driverRepository) UpdateLocation(ctx context.Context, userID primitive.
 	return err
 }
 
-// UpdateRating persists a driver's recomputed average delivery rating —
-// called after a customer rates an order, so the static `rating` field
-// (surfaced on the customer's live tracking card via DriverContact) stays
-// in sync with the same live average GetDriverStats reports elsewhere.
-// userID is the driver's User._id (same key AssignDriver/order.driver_id
-// and UpdateOnlineStatus/UpdateLocation use), not the Driver document's own _id.
-func (r *driverRepository) UpdateRating(ctx context.Context, userID primitive.ObjectID, rating float64) error {
+// AddRating adds one delivery rating to the driver's running sum and count
+// and sets rating to their average, as one pipeline update so concurrent
+// ratings can't overwrite each other's average. userID is the driver's
+// User._id (same key AssignDriver/order.driver_id and
+// UpdateOnlineStatus/UpdateLocation use), not the Driver document's own _id.
+func (r *driverRepository) AddRating(ctx context.Context, userID primitive.ObjectID, rating int) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"user_id": userID}, addRatingPipeline("rating_count", rating))
+	return err
+}
+
+// addRatingPipeline builds the update that folds rating into a document's
+// rating_sum and countField and recomputes rating. A document rated before
+// rating_sum existed starts from rating × count, which the aggregate
+// rebuild later replaces with the exact sum.
+func addRatingPipeline(countField string, rating int) mongo.Pipeline {
+	count := bson.M{"$ifNull": bson.A{"$" + countField, 0}}
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"rating_sum": bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{
+					"$rating_sum",
+					bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{bson.M{"$ifNull": bson.A{"$rating", 0}}, count}}, 0}},
+				}},
+				rating,
+			}},
+			countField:   bson.M{"$add": bson.A{count, 1}},
+			"updated_at": time.Now(),
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"rating": bson.M{"$divide": bson.A{"$rating_sum", "$" + countField}},
+		}}},
+	}
+}
+
+func (r *driverRepository) RecordDelivery(ctx context.Context, userID primitive.ObjectID, baseFee, surgeBonus float64) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"user_id": userID},
+		bson.M{
+			"$inc": bson.M{
+				"total_trips":          1,
+				"earnings.total":       baseFee + surgeBonus,
+				"earnings.surge_bonus": surgeBonus,
+			},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+func (r *driverRepository) AddTip(ctx context.Context, userID primitive.ObjectID, tip float64) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"user_id": userID},
+		bson.M{
+			"$inc": bson.M{
+				"earnings.total": tip,
+				"earnings.tips":  tip,
+			},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+func (r *driverRepository) RecordOrderDecision(ctx context.Context, userID primitive.ObjectID, accepted bool) error {
+	field := "rejected_orders"
+	if accepted {
+		field = "accepted_orders"
+	}
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"user_id": userID},
+		bson.M{
+			"$inc": bson.M{field: 1},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+func (r *driverRepository) AddPendingEarnings(ctx context.Context, userID primitive.ObjectID, delta float64) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"user_id": userID},
+		bson.M{
+			"$inc": bson.M{"earnings.pending": delta},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+func (r *driverRepository) RecordPayout(ctx context.Context, userID primitive.ObjectID, at time.Time) error {
 	_, err := r.collection.UpdateOne(
 		ctx,
 		bson.M{"user_id": userID},
 		bson.M{"$set": bson.M{
-			"rating":     rating,
-			"updated_at": time.Now(),
+			"earnings.last_payout_at": at,
+			"updated_at":              time.Now(),
 		}},
 	)
 	return err
-}
\ No newline at end of file
+}
+
+func (r *driverRepository) SetPayoutAccount(ctx context.Context, userID primitive.ObjectID, account models.PayoutAccount) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$set": bson.M{
+			"payout_account": account,
+			"updated_at":     time.Now(),
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("driver not found")
+	}
+	return nil
+}
+
+func (r *driverRepository) ResetAggregates(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{}, bson.M{"$set": driverAggregateFields(DriverAggregates{})})
+	return err
+}
+
+func (r *driverRepository) SetAggregates(ctx context.Context, userID primitive.ObjectID, aggregates DriverAggregates) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"user_id": userID}, bson.M{"$set": driverAggregateFields(aggregates)})
+	return err
+}
+
+func driverAggregateFields(a DriverAggregates) bson.M {
+	rating := 0.0
+	if a.RatingCount > 0 {
+		rating = float64(a.RatingSum) / float64(a.RatingCount)
+	}
+	return bson.M{
+		"rating":               rating,
+		"rating_sum":           a.RatingSum,
+		"rating_count":         a.RatingCount,
+		"total_trips":          a.TotalTrips,
+		"accepted_orders":      a.AcceptedOrders,
+		"rejected_orders":      a.RejectedOrders,
+		"earnings.total":       a.Earnings,
+		"earnings.surge_bonus": a.SurgeBonus,
+		"earnings.tips":        a.Tips,
+		"updated_at":           time.Now(),
+	}
+}
+
+// UpdateRiskScore stores the GPS anomaly detector's latest risk score for a
+// driver along with the anomaly that caused it. Keyed on user_id like
+// UpdateLocation, since that's the ID the WebSocket client knows.
+func (r *driverRepository) UpdateRiskScore(ctx context.Context, userID primitive.ObjectID, score float64, anomaly models.GPSAnomaly) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$set": bson.M{
+			"risk_score":       score,
+			"last_gps_anomaly": anomaly,
+			"updated_at":       time.Now(),
+		}},
+	)
+	return err
+}
+
+func (r *driverRepository) AddCashBalance(ctx context.Context, userID primitive.ObjectID, delta float64) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"user_id": userID},
+		bson.M{
+			"$inc": bson.M{"cash_balance": delta},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+func (r *driverRepository) SetCashLimit(ctx context.Context, userID primitive.ObjectID, limit *float64) error {
+	update := bson.M{
+		"$set": bson.M{"cash_limit": limit, "updated_at": time.Now()},
+	}
+	if limit == nil {
+		update = bson.M{
+			"$unset": bson.M{"cash_limit": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"user_id": userID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("driver not found")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DriverEarningsRepository keeps the per-driver, per-day earnings buckets
+// the driver stats and earnings chart are read from. driverID is always
+// the driver's User._id, the same ID order.driver_id holds.
+type DriverEarningsRepository interface {
+	// AddDelivery adds one delivered order to the driver's bucket for day
+	// ("2006-01-02"), creating the bucket if it's the first that day.
+	AddDelivery(ctx context.Context, driverID primitive.ObjectID, day string, baseFee, surgeBonus float64) error
+	// AddTip adds a tip to the bucket for day, the day the tipped order
+	// was delivered.
+	AddTip(ctx context.Context, driverID primitive.ObjectID, day string, tip float64) error
+	// FindRange returns the driver's buckets from fromDay through toDay
+	// inclusive, oldest first. Days without deliveries have no bucket.
+	FindRange(ctx context.Context, driverID primitive.ObjectID, fromDay, toDay string) ([]models.DriverEarningsDay, error)
+	// ReplaceAll drops every bucket and inserts days in their place — the
+	// aggregate rebuild's last step.
+	ReplaceAll(ctx context.Context, days []models.DriverEarningsDay) error
+}
+
+type driverEarningsRepository struct {
+	collection *mongo.Collection
+}
+
+func NewDriverEarningsRepository() DriverEarningsRepository {
+	collections := database.GetCollections()
+	return &driverEarningsRepository{
+		collection: collections.DriverEarnings,
+	}
+}
+
+func (r *driverEarningsRepository) AddDelivery(ctx context.Context, driverID primitive.ObjectID, day string, baseFee, surgeBonus float64) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"driver_id": driverID, "day": day},
+		bson.M{
+			"$inc": bson.M{
+				"deliveries":  1,
+				"earnings":    baseFee + surgeBonus,
+				"base_fees":   baseFee,
+				"surge_bonus": surgeBonus,
+			},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *driverEarningsRepository) AddTip(ctx context.Context, driverID primitive.ObjectID, day string, tip float64) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"driver_id": driverID, "day": day},
+		bson.M{
+			"$inc": bson.M{
+				"earnings": tip,
+				"tips":     tip,
+			},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *driverEarningsRepository) FindRange(ctx context.Context, driverID primitive.ObjectID, fromDay, toDay string) ([]models.DriverEarningsDay, error) {
+	filter := bson.M{
+		"driver_id": driverID,
+		"day":       bson.M{"$gte": fromDay, "$lte": toDay},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "day", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var days []models.DriverEarningsDay
+	if err := cursor.All(ctx, &days); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+func (r *driverEarningsRepository) ReplaceAll(ctx context.Context, days []models.DriverEarningsDay) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{}); err != nil {
+		return err
+	}
+
+	const batchSize = 1000
+	now := time.Now()
+	for start := 0; start < len(days); start += batchSize {
+		end := start + batchSize
+		if end > len(days) {
+			end = len(days)
+		}
+		docs := make([]interface{}, 0, end-start)
+		for _, day := range days[start:end] {
+			day.UpdatedAt = now
+			docs = append(docs, day)
+		}
+		if _, err := r.collection.InsertMany(ctx, docs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
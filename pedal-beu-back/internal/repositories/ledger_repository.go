@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SettlementTotal is one driver's share of a settlement run's claimed
+// entries. Cash is the COD cash among them still in the driver's hands —
+// collected less deposited — which the payout nets off.
+type SettlementTotal struct {
+	DriverID primitive.ObjectID `bson:"_id"`
+	Net      float64            `bson:"net"`
+	Cash     float64            `bson:"cash"`
+	Entries  int                `bson:"entries"`
+}
+
+type LedgerRepository interface {
+	// Create posts an entry. It fails with ErrDuplicateLedgerEntry if an
+	// entry with the same idempotency key was already posted.
+	Create(ctx context.Context, entry *models.LedgerEntry) error
+	// FindByDriver returns a driver's entries, newest first.
+	FindByDriver(ctx context.Context, driverID primitive.ObjectID, pagination Pagination) ([]models.LedgerEntry, int64, error)
+	// Balance is the net of the driver's unsettled entries: what the
+	// platform owes them (negative if they owe the platform).
+	Balance(ctx context.Context, driverID primitive.ObjectID) (float64, error)
+	// ClaimUnsettled marks every unsettled entry created in [from, to) as
+	// settled by settlementID and returns how many it claimed. An entry can
+	// only ever be claimed by one run.
+	ClaimUnsettled(ctx context.Context, settlementID primitive.ObjectID, from, to, settledAt time.Time) (int64, error)
+	// SettlementTotals sums a run's claimed entries per driver.
+	SettlementTotals(ctx context.Context, settlementID primitive.ObjectID) ([]SettlementTotal, error)
+	// Release hands a driver's entries claimed by settlementID back, so a
+	// later run picks them up.
+	Release(ctx context.Context, settlementID, driverID primitive.ObjectID) error
+}
+
+// ErrDuplicateLedgerEntry is returned by Create when the event the entry
+// records has already been posted.
+var ErrDuplicateLedgerEntry = errors.New("ledger entry already posted")
+
+type ledgerRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLedgerRepository() LedgerRepository {
+	collections := database.GetCollections()
+	return &ledgerRepository{
+		collection: collections.LedgerEntries,
+	}
+}
+
+func (r *ledgerRepository) Create(ctx context.Context, entry *models.LedgerEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	result, err := r.collection.InsertOne(ctx, entry)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrDuplicateLedgerEntry
+		}
+		return err
+	}
+
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *ledgerRepository) FindByDriver(ctx context.Context, driverID primitive.ObjectID, pagination Pagination) ([]models.LedgerEntry, int64, error) {
+	filter := bson.M{"driver_id": driverID}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skip := (pagination.Page - 1) * pagination.Limit
+	opts := options.Find().
+		SetSkip(skip).
+		SetLimit(pagination.Limit).
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.LedgerEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+func (r *ledgerRepository) Balance(ctx context.Context, driverID primitive.ObjectID) (float64, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"driver_id": driverID, "settlement_id": nil}},
+		{"$group": bson.M{"_id": nil, "balance": bson.M{"$sum": "$net"}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Balance float64 `bson:"balance"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	return results[0].Balance, nil
+}
+
+func (r *ledgerRepository) ClaimUnsettled(ctx context.Context, settlementID primitive.ObjectID, from, to, settledAt time.Time) (int64, error) {
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{
+			"settlement_id": nil,
+			"created_at":    bson.M{"$gte": from, "$lt": to},
+		},
+		bson.M{"$set": bson.M{"settlement_id": settlementID, "settled_at": settledAt}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *ledgerRepository) SettlementTotals(ctx context.Context, settlementID primitive.ObjectID) ([]SettlementTotal, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"settlement_id": settlementID, "type": bson.M{"$ne": models.LedgerPayout}}},
+		{"$group": bson.M{
+			"_id":     "$driver_id",
+			"net":     bson.M{"$sum": "$net"},
+			"entries": bson.M{"$sum": 1},
+			"cash": bson.M{"$sum": bson.M{"$switch": bson.M{
+				"branches": bson.A{
+					bson.M{"case": bson.M{"$eq": bson.A{"$type", models.LedgerCashCollected}}, "then": "$amount"},
+					bson.M{"case": bson.M{"$eq": bson.A{"$type", models.LedgerCashDeposit}}, "then": bson.M{"$multiply": bson.A{"$amount", -1}}},
+				},
+				"default": 0,
+			}}},
+		}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []SettlementTotal
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+func (r *ledgerRepository) Release(ctx context.Context, settlementID, driverID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{
+			"settlement_id": settlementID,
+			"driver_id":     driverID,
+			"type":          bson.M{"$ne": models.LedgerPayout},
+		},
+		bson.M{"$unset": bson.M{"settlement_id": "", "settled_at": ""}},
+	)
+	return err
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MenuCategoryRepository interface {
+	Create(ctx context.Context, category *models.MenuCategory) error
+	FindByID(ctx context.Context, restaurantID, categoryID primitive.ObjectID) (*models.MenuCategory, error)
+	// FindByRestaurant returns the restaurant's categories in display order.
+	FindByRestaurant(ctx context.Context, restaurantID primitive.ObjectID) ([]models.MenuCategory, error)
+	Replace(ctx context.Context, category *models.MenuCategory) error
+	Delete(ctx context.Context, restaurantID, categoryID primitive.ObjectID) error
+}
+
+type menuCategoryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMenuCategoryRepository() MenuCategoryRepository {
+	collections := database.GetCollections()
+	return &menuCategoryRepository{
+		collection: collections.MenuCategories,
+	}
+}
+
+func (r *menuCategoryRepository) Create(ctx context.Context, category *models.MenuCategory) error {
+	category.CreatedAt = time.Now()
+	category.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, category)
+	if err != nil {
+		return err
+	}
+
+	category.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *menuCategoryRepository) FindByID(ctx context.Context, restaurantID, categoryID primitive.ObjectID) (*models.MenuCategory, error) {
+	var category models.MenuCategory
+	err := r.collection.FindOne(ctx, bson.M{"_id": categoryID, "restaurant_id": restaurantID}).Decode(&category)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("menu category not found")
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *menuCategoryRepository) FindByRestaurant(ctx context.Context, restaurantID primitive.ObjectID) ([]models.MenuCategory, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "sort_order", Value: 1}, {Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"restaurant_id": restaurantID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	categories := []models.MenuCategory{}
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *menuCategoryRepository) Replace(ctx context.Context, category *models.MenuCategory) error {
+	category.UpdatedAt = time.Now()
+
+	filter := bson.M{"_id": category.ID, "restaurant_id": category.RestaurantID}
+	result, err := r.collection.ReplaceOne(ctx, filter, category)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("menu category not found")
+	}
+	return nil
+}
+
+func (r *menuCategoryRepository) Delete(ctx context.Context, restaurantID, categoryID primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": categoryID, "restaurant_id": restaurantID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("menu category not found")
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MenuHistoryFilter narrows FindByRestaurant. Zero values don't filter.
+type MenuHistoryFilter struct {
+	MenuItemID *primitive.ObjectID
+	Field      string // only entries that changed this field, e.g. "price"
+}
+
+type MenuHistoryRepository interface {
+	Create(ctx context.Context, change *models.MenuItemChange) error
+	FindByID(ctx context.Context, restaurantID, changeID primitive.ObjectID) (*models.MenuItemChange, error)
+	// FindByRestaurant returns the restaurant's menu history, newest first.
+	FindByRestaurant(ctx context.Context, restaurantID primitive.ObjectID, filter MenuHistoryFilter, pagination Pagination) ([]models.MenuItemChange, int64, error)
+}
+
+type menuHistoryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMenuHistoryRepository() MenuHistoryRepository {
+	collections := database.GetCollections()
+	return &menuHistoryRepository{
+		collection: collections.MenuHistory,
+	}
+}
+
+func (r *menuHistoryRepository) Create(ctx context.Context, change *models.MenuItemChange) error {
+	change.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, change)
+	if err != nil {
+		return err
+	}
+
+	change.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *menuHistoryRepository) FindByID(ctx context.Context, restaurantID, changeID primitive.ObjectID) (*models.MenuItemChange, error) {
+	var change models.MenuItemChange
+	err := r.collection.FindOne(ctx, bson.M{"_id": changeID, "restaurant_id": restaurantID}).Decode(&change)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("menu history entry not found")
+		}
+		return nil, err
+	}
+	return &change, nil
+}
+
+func (r *menuHistoryRepository) FindByRestaurant(ctx context.Context, restaurantID primitive.ObjectID, filter MenuHistoryFilter, pagination Pagination) ([]models.MenuItemChange, int64, error) {
+	query := bson.M{"restaurant_id": restaurantID}
+	if filter.MenuItemID != nil {
+		query["menu_item_id"] = *filter.MenuItemID
+	}
+	if filter.Field != "" {
+		query["changes.field"] = filter.Field
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return []models.MenuItemChange{}, 0, err
+	}
+
+	skip := (pagination.Page - 1) * pagination.Limit
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(pagination.Limit)
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return []models.MenuItemChange{}, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	changes := []models.MenuItemChange{}
+	if err := cursor.All(ctx, &changes); err != nil {
+		return []models.MenuItemChange{}, 0, err
+	}
+	return changes, total, nil
+}
@@ -0,0 +1,580 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MenuItemFilter narrows FindByRestaurant. Zero values don't filter.
+type MenuItemFilter struct {
+	Category  string
+	Available *bool
+	Search    string // case-insensitive substring of the name
+	// Archived items are left out of every list unless this is set.
+	IncludeArchived bool
+	// Items must carry every one of DietaryTags and none of
+	// ExcludeAllergens.
+	DietaryTags      []string
+	ExcludeAllergens []string
+}
+
+type MenuItemRepository interface {
+	Create(ctx context.Context, item *models.MenuItem) error
+	CreateMany(ctx context.Context, items []models.MenuItem) error
+	// FindByID also finds archived items, so old orders still resolve.
+	FindByID(ctx context.Context, restaurantID, itemID primitive.ObjectID) (*models.MenuItem, error)
+	// FindByIDs returns the restaurant's items among ids (in no particular
+	// order); ids belonging to another restaurant, and archived items, are
+	// simply not returned.
+	FindByIDs(ctx context.Context, restaurantID primitive.ObjectID, ids []primitive.ObjectID) ([]models.MenuItem, error)
+	FindByRestaurant(ctx context.Context, restaurantID primitive.ObjectID, filter MenuItemFilter, pagination Pagination) ([]models.MenuItem, int64, error)
+	FindAllByRestaurant(ctx context.Context, restaurantID primitive.ObjectID, filter MenuItemFilter) ([]models.MenuItem, error)
+	// FindByRestaurants returns every item of the given restaurants grouped
+	// by restaurant, for building the search index in one query.
+	FindByRestaurants(ctx context.Context, restaurantIDs []primitive.ObjectID) (map[primitive.ObjectID][]models.MenuItem, error)
+	// DietaryTagsByRestaurant returns, per restaurant, the dietary tags
+	// found on its available items. Restaurants with none are left out.
+	DietaryTagsByRestaurant(ctx context.Context, restaurantIDs []primitive.ObjectID) (map[primitive.ObjectID][]string, error)
+	Update(ctx context.Context, restaurantID, itemID primitive.ObjectID, update interface{}) error
+	// Archive takes an item off the menu without deleting it; Unarchive
+	// puts it back.
+	Archive(ctx context.Context, restaurantID, itemID primitive.ObjectID) error
+	Unarchive(ctx context.Context, restaurantID, itemID primitive.ObjectID) error
+	// ArchiveByRestaurant archives the restaurant's whole current menu and
+	// returns the items it archived, as they were before.
+	ArchiveByRestaurant(ctx context.Context, restaurantID primitive.ObjectID) ([]models.MenuItem, error)
+	// Replace overwrites an item with a previous version of it.
+	Replace(ctx context.Context, item *models.MenuItem) error
+	// RenameCategory keeps the denormalized category name on a category's
+	// items in step with the category.
+	RenameCategory(ctx context.Context, restaurantID, categoryID primitive.ObjectID, name string) error
+	// ClearCategory detaches items from a deleted category. They keep the
+	// category name, so they still group under it.
+	ClearCategory(ctx context.Context, restaurantID, categoryID primitive.ObjectID) error
+	// ReserveStock atomically takes qty from a stock-tracked item, failing
+	// with ErrInsufficientStock rather than going below zero, and switches
+	// an available item off when it hits zero. Returns the item as it is
+	// afterwards and whether this call switched it off.
+	ReserveStock(ctx context.Context, restaurantID, itemID primitive.ObjectID, qty int) (*models.MenuItem, bool, error)
+	// ReleaseStock puts qty back (capped at the daily quantity) and switches
+	// an auto-disabled item back on. Stock taken before the item's last
+	// reset (placedAt earlier than reset_at) isn't returned — the reset
+	// already refilled it. When it switched the item back on it returns the
+	// item as it was before, otherwise nil.
+	ReleaseStock(ctx context.Context, itemID primitive.ObjectID, qty int, placedAt time.Time) (*models.MenuItem, error)
+	// ResetDailyStock refills every stock-tracked item last reset before
+	// `before`, re-enabling the ones that sold out. It returns how many it
+	// reset and the items it re-enabled, as they were before.
+	ResetDailyStock(ctx context.Context, before time.Time) (int64, []models.MenuItem, error)
+	// MigrateEmbeddedMenus moves menus still embedded in restaurant
+	// documents into menu_items, keeping item IDs (orders reference them),
+	// and returns how many restaurants it migrated. Safe to run on every
+	// startup: it's a no-op once no restaurant has a "menu" field.
+	MigrateEmbeddedMenus(ctx context.Context) (int, error)
+}
+
+// ErrInsufficientStock is returned by ReserveStock when fewer than the
+// requested quantity are left.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+type menuItemRepository struct {
+	collection  *mongo.Collection
+	restaurants *mongo.Collection
+}
+
+func NewMenuItemRepository() MenuItemRepository {
+	collections := database.GetCollections()
+	return &menuItemRepository{
+		collection:  collections.MenuItems,
+		restaurants: collections.Restaurants,
+	}
+}
+
+func (r *menuItemRepository) Create(ctx context.Context, item *models.MenuItem) error {
+	if item.ID.IsZero() {
+		item.ID = primitive.NewObjectID()
+	}
+	item.CreatedAt = time.Now()
+	item.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, item)
+	return err
+}
+
+func (r *menuItemRepository) CreateMany(ctx context.Context, items []models.MenuItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(items))
+	for i := range items {
+		if items[i].ID.IsZero() {
+			items[i].ID = primitive.NewObjectID()
+		}
+		items[i].CreatedAt = time.Now()
+		items[i].UpdatedAt = time.Now()
+		docs[i] = items[i]
+	}
+
+	_, err := r.collection.InsertMany(ctx, docs)
+	return err
+}
+
+func (r *menuItemRepository) FindByID(ctx context.Context, restaurantID, itemID primitive.ObjectID) (*models.MenuItem, error) {
+	var item models.MenuItem
+	err := r.collection.FindOne(ctx, bson.M{"_id": itemID, "restaurant_id": restaurantID}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("menu item not found")
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *menuItemRepository) FindByIDs(ctx context.Context, restaurantID primitive.ObjectID, ids []primitive.ObjectID) ([]models.MenuItem, error) {
+	filter := bson.M{
+		"restaurant_id": restaurantID,
+		"_id":           bson.M{"$in": ids},
+		"archived_at":   nil,
+	}
+	return r.find(ctx, filter)
+}
+
+func (r *menuItemRepository) FindByRestaurant(ctx context.Context, restaurantID primitive.ObjectID, filter MenuItemFilter, pagination Pagination) ([]models.MenuItem, int64, error) {
+	query := menuItemQuery(restaurantID, filter)
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return []models.MenuItem{}, 0, err
+	}
+	if total == 0 {
+		return []models.MenuItem{}, 0, nil
+	}
+
+	skip := (pagination.Page - 1) * pagination.Limit
+	opts := options.Find().
+		SetSort(menuItemSort()).
+		SetSkip(skip).
+		SetLimit(pagination.Limit)
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return []models.MenuItem{}, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []models.MenuItem{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return []models.MenuItem{}, 0, err
+	}
+	return items, total, nil
+}
+
+func (r *menuItemRepository) FindAllByRestaurant(ctx context.Context, restaurantID primitive.ObjectID, filter MenuItemFilter) ([]models.MenuItem, error) {
+	return r.find(ctx, menuItemQuery(restaurantID, filter))
+}
+
+func (r *menuItemRepository) FindByRestaurants(ctx context.Context, restaurantIDs []primitive.ObjectID) (map[primitive.ObjectID][]models.MenuItem, error) {
+	items, err := r.find(ctx, bson.M{"restaurant_id": bson.M{"$in": restaurantIDs}, "archived_at": nil})
+	if err != nil {
+		return nil, err
+	}
+
+	byRestaurant := make(map[primitive.ObjectID][]models.MenuItem)
+	for _, item := range items {
+		byRestaurant[item.RestaurantID] = append(byRestaurant[item.RestaurantID], item)
+	}
+	return byRestaurant, nil
+}
+
+func (r *menuItemRepository) DietaryTagsByRestaurant(ctx context.Context, restaurantIDs []primitive.ObjectID) (map[primitive.ObjectID][]string, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"restaurant_id": bson.M{"$in": restaurantIDs},
+			"archived_at":   nil,
+			"is_available":  true,
+		}},
+		{"$unwind": "$dietary_tags"},
+		{"$group": bson.M{"_id": "$restaurant_id", "tags": bson.M{"$addToSet": "$dietary_tags"}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		RestaurantID primitive.ObjectID `bson:"_id"`
+		Tags         []string           `bson:"tags"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	tags := make(map[primitive.ObjectID][]string, len(results))
+	for _, result := range results {
+		tags[result.RestaurantID] = result.Tags
+	}
+	return tags, nil
+}
+
+func (r *menuItemRepository) Update(ctx context.Context, restaurantID, itemID primitive.ObjectID, update interface{}) error {
+	updateFields := bson.M{"updated_at": time.Now()}
+	if updateMap, ok := update.(bson.M); ok {
+		for k, v := range updateMap {
+			updateFields[k] = v
+		}
+	}
+
+	filter := bson.M{
+		"_id":           itemID,
+		"restaurant_id": restaurantID,
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": updateFields})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return errors.New("menu item not found")
+	}
+
+	return nil
+}
+
+func (r *menuItemRepository) Archive(ctx context.Context, restaurantID, itemID primitive.ObjectID) error {
+	now := time.Now()
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": itemID, "restaurant_id": restaurantID, "archived_at": nil},
+		bson.M{"$set": bson.M{"archived_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("menu item not found")
+	}
+	return nil
+}
+
+func (r *menuItemRepository) Unarchive(ctx context.Context, restaurantID, itemID primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": itemID, "restaurant_id": restaurantID, "archived_at": bson.M{"$ne": nil}},
+		bson.M{
+			"$unset": bson.M{"archived_at": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("archived menu item not found")
+	}
+	return nil
+}
+
+func (r *menuItemRepository) ArchiveByRestaurant(ctx context.Context, restaurantID primitive.ObjectID) ([]models.MenuItem, error) {
+	filter := bson.M{"restaurant_id": restaurantID, "archived_at": nil}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var items []models.MenuItem
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	// Only the items just read, so the returned list is exactly what was
+	// archived — an item added meanwhile stays on the menu.
+	now := time.Now()
+	_, err = r.collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "archived_at": nil},
+		bson.M{"$set": bson.M{"archived_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *menuItemRepository) Replace(ctx context.Context, item *models.MenuItem) error {
+	item.UpdatedAt = time.Now()
+
+	filter := bson.M{"_id": item.ID, "restaurant_id": item.RestaurantID}
+	result, err := r.collection.ReplaceOne(ctx, filter, item)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("menu item not found")
+	}
+	return nil
+}
+
+func (r *menuItemRepository) RenameCategory(ctx context.Context, restaurantID, categoryID primitive.ObjectID, name string) error {
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"restaurant_id": restaurantID, "category_id": categoryID},
+		bson.M{"$set": bson.M{"category": name, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+func (r *menuItemRepository) ClearCategory(ctx context.Context, restaurantID, categoryID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"restaurant_id": restaurantID, "category_id": categoryID},
+		bson.M{
+			"$unset": bson.M{"category_id": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+func (r *menuItemRepository) ReserveStock(ctx context.Context, restaurantID, itemID primitive.ObjectID, qty int) (*models.MenuItem, bool, error) {
+	filter := bson.M{
+		"_id":             itemID,
+		"restaurant_id":   restaurantID,
+		"stock.remaining": bson.M{"$gte": qty},
+	}
+	update := bson.M{
+		"$inc": bson.M{"stock.remaining": -qty},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var item models.MenuItem
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&item); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, false, ErrInsufficientStock
+		}
+		return nil, false, err
+	}
+
+	switchedOff := false
+	if item.Stock != nil && item.Stock.Remaining == 0 && item.IsAvailable {
+		// Conditional on still being at zero, so a cancellation that
+		// raced in between doesn't get its restock switched off, and on
+		// being available, so an item the restaurant took off itself
+		// isn't switched back on by the next restock.
+		result, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": itemID, "stock.remaining": 0, "is_available": true},
+			bson.M{"$set": bson.M{"is_available": false, "stock.auto_disabled": true}},
+		)
+		if err != nil {
+			return nil, false, err
+		}
+		if result.ModifiedCount > 0 {
+			switchedOff = true
+			item.IsAvailable = false
+			item.Stock.AutoDisabled = true
+		}
+	}
+
+	return &item, switchedOff, nil
+}
+
+func (r *menuItemRepository) ReleaseStock(ctx context.Context, itemID primitive.ObjectID, qty int, placedAt time.Time) (*models.MenuItem, error) {
+	filter := bson.M{
+		"_id":            itemID,
+		"stock.reset_at": bson.M{"$lte": placedAt},
+	}
+	// Pipeline stages run in order, so the second sees the new remaining.
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"stock.remaining": bson.M{"$min": bson.A{
+				bson.M{"$add": bson.A{"$stock.remaining", qty}},
+				"$stock.daily_quantity",
+			}},
+			"updated_at": time.Now(),
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"is_available": bson.M{"$cond": bson.A{
+				bson.M{"$and": bson.A{"$stock.auto_disabled", bson.M{"$gt": bson.A{"$stock.remaining", 0}}}},
+				true,
+				"$is_available",
+			}},
+			"stock.auto_disabled": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$stock.remaining", 0}},
+				false,
+				"$stock.auto_disabled",
+			}},
+		}}},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	var before models.MenuItem
+	if err := r.collection.FindOneAndUpdate(ctx, filter, pipeline, opts).Decode(&before); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if before.Stock == nil || !before.Stock.AutoDisabled ||
+		min(before.Stock.Remaining+qty, before.Stock.DailyQuantity) <= 0 {
+		return nil, nil
+	}
+	return &before, nil
+}
+
+func (r *menuItemRepository) ResetDailyStock(ctx context.Context, before time.Time) (int64, []models.MenuItem, error) {
+	now := time.Now()
+	filter := bson.M{"stock.reset_at": bson.M{"$lt": before}}
+	// One $set stage: every expression sees the document as it was, so
+	// is_available is decided by the old auto_disabled.
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"stock.remaining":     "$stock.daily_quantity",
+			"stock.reset_at":      now,
+			"stock.auto_disabled": false,
+			"is_available":        bson.M{"$cond": bson.A{"$stock.auto_disabled", true, "$is_available"}},
+			"updated_at":          now,
+		}}},
+	}
+
+	// The sold-out items go one at a time so the caller learns which
+	// were switched back on; the rest are refilled in one go after.
+	soldOut := bson.M{"stock.reset_at": bson.M{"$lt": before}, "stock.auto_disabled": true}
+	cursor, err := r.collection.Find(ctx, soldOut)
+	if err != nil {
+		return 0, nil, err
+	}
+	var candidates []models.MenuItem
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return 0, nil, err
+	}
+
+	var reenabled []models.MenuItem
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	for _, candidate := range candidates {
+		var item models.MenuItem
+		err := r.collection.FindOneAndUpdate(ctx,
+			bson.M{"_id": candidate.ID, "stock.reset_at": bson.M{"$lt": before}, "stock.auto_disabled": true},
+			pipeline, opts,
+		).Decode(&item)
+		if err == mongo.ErrNoDocuments {
+			// Restocked by a cancellation since the Find.
+			continue
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		reenabled = append(reenabled, item)
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, pipeline)
+	if err != nil {
+		return 0, nil, err
+	}
+	return int64(len(reenabled)) + result.ModifiedCount, reenabled, nil
+}
+
+func (r *menuItemRepository) MigrateEmbeddedMenus(ctx context.Context) (int, error) {
+	filter := bson.M{"menu": bson.M{"$exists": true}}
+	opts := options.Find().SetProjection(bson.M{"menu": 1})
+
+	cursor, err := r.restaurants.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var restaurant models.Restaurant
+		if err := cursor.Decode(&restaurant); err != nil {
+			return migrated, err
+		}
+
+		// Upsert by _id rather than insert, so a migration interrupted
+		// between copying and unsetting just redoes the copy next start.
+		for _, item := range restaurant.Menu {
+			if item.ID.IsZero() {
+				item.ID = primitive.NewObjectID()
+			}
+			item.RestaurantID = restaurant.ID
+			_, err := r.collection.ReplaceOne(ctx,
+				bson.M{"_id": item.ID},
+				item,
+				options.Replace().SetUpsert(true),
+			)
+			if err != nil {
+				return migrated, err
+			}
+		}
+
+		_, err := r.restaurants.UpdateOne(ctx,
+			bson.M{"_id": restaurant.ID},
+			bson.M{"$unset": bson.M{"menu": ""}},
+		)
+		if err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	return migrated, cursor.Err()
+}
+
+func (r *menuItemRepository) find(ctx context.Context, filter bson.M) ([]models.MenuItem, error) {
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(menuItemSort()))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []models.MenuItem{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func menuItemQuery(restaurantID primitive.ObjectID, filter MenuItemFilter) bson.M {
+	query := bson.M{"restaurant_id": restaurantID}
+	if !filter.IncludeArchived {
+		query["archived_at"] = nil
+	}
+	if filter.Category != "" {
+		query["category"] = filter.Category
+	}
+	if filter.Available != nil {
+		query["is_available"] = *filter.Available
+	}
+	if filter.Search != "" {
+		query["name"] = bson.M{"$regex": regexp.QuoteMeta(filter.Search), "$options": "i"}
+	}
+	if len(filter.DietaryTags) > 0 {
+		query["dietary_tags"] = bson.M{"$all": filter.DietaryTags}
+	}
+	if len(filter.ExcludeAllergens) > 0 {
+		query["allergens"] = bson.M{"$nin": filter.ExcludeAllergens}
+	}
+	return query
+}
+
+// menuItemSort keeps a category's items together and in the order they
+// were added, which is how the embedded array used to read.
+func menuItemSort() bson.D {
+	return bson.D{{Key: "category", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}
+}
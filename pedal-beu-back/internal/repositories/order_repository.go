@@ -23,15 +23,27 @@ type OrderRepository interface {
 	FindByCustomerID(ctx context.Context, customerID primitive.ObjectID, pagination Pagination) ([]models.Order, int64, error)
 	FindByDriverID(ctx context.Context, driverID primitive.ObjectID, pagination Pagination) ([]models.Order, int64, error)
 	FindByRestaurantID(ctx context.Context, restaurantID primitive.ObjectID, pagination Pagination) ([]models.Order, int64, error)
-	FindAvailableOrders(ctx context.Context, driverID primitive.ObjectID, location models.GeoLocation, radius float64) ([]models.Order, error)
+	// FindAvailableOrders lists unassigned orders near location that the
+	// driver hasn't turned down; excludeCash leaves out cash-on-delivery
+	// orders, for drivers over their cash limit.
+	FindAvailableOrders(ctx context.Context, driverID primitive.ObjectID, location models.GeoLocation, radius float64, excludeCash bool) ([]models.Order, error)
 	UpdateStatus(ctx context.Context, orderID primitive.ObjectID, status models.OrderStatus, actorID primitive.ObjectID, actorType string) error
 	AssignDriver(ctx context.Context, orderID, driverID primitive.ObjectID) error
-	RejectOrder(ctx context.Context, orderID, driverID primitive.ObjectID) error
+	// RejectOrder reports whether this call added the driver to the
+	// order's rejections (false if they'd already rejected it).
+	RejectOrder(ctx context.Context, orderID, driverID primitive.ObjectID) (bool, error)
 	UpdateTimeline(ctx context.Context, orderID primitive.ObjectID, event models.OrderEvent) error
 	AddRating(ctx context.Context, orderID primitive.ObjectID, rating models.OrderRating) error
 	UpdatePaymentStatus(ctx context.Context, orderID primitive.ObjectID, status string) error
+	// UpdatePaymentVerification records a payment check and the payment
+	// status it leaves. It never undoes a settled payment: a paid order
+	// ignores anything but another "paid", and a refunded one ignores
+	// everything, so repeated and out-of-order results are harmless.
 	UpdatePaymentVerification(ctx context.Context, orderID primitive.ObjectID, paymentStatus string, verification *models.PaymentVerification) error
 	CancelOrder(ctx context.Context, orderID primitive.ObjectID, cancellation models.CancellationInfo) error
+	// CancelAwaitingPayment cancels the order only if it's still awaiting
+	// payment, and reports whether it did.
+	CancelAwaitingPayment(ctx context.Context, orderID primitive.ObjectID, cancellation models.CancellationInfo) (bool, error)
 	FindActiveOrders(ctx context.Context) ([]models.Order, error)
 	// FindStaleUnassignedOrders finds orders still sitting unassigned
 	// (status "accepted", no driver_id) whose created_at is older than
@@ -41,6 +53,22 @@ type OrderRepository interface {
 	// sorted oldest-first, it would sit at the very TOP of every driver's
 	// list indefinitely).
 	FindStaleUnassignedOrders(ctx context.Context, cutoff time.Time) ([]models.Order, error)
+	// FindActiveByDriverID returns the orders currently assigned to a
+	// driver that haven't been delivered or cancelled yet — what the
+	// geofence engine checks each GPS ping against.
+	FindActiveByDriverID(ctx context.Context, driverID primitive.ObjectID) ([]models.Order, error)
+	// AdvanceStatus is UpdateStatus guarded on the order still being in
+	// `from`, so an automatic (system) transition can never clobber a
+	// status the driver or an admin set a moment earlier.
+	AdvanceStatus(ctx context.Context, orderID primitive.ObjectID, from models.OrderStatus, event models.OrderEvent) error
+	// RecordArrival stamps arrivals.<fence> ("restaurant" or "dropoff")
+	// only if it isn't already set, and reports whether this call set it.
+	RecordArrival(ctx context.Context, orderID primitive.ObjectID, fence string, at time.Time) (bool, error)
+	AddFlag(ctx context.Context, orderID primitive.ObjectID, flag models.OrderFlag) error
+	// CountUnassignedWithin counts orders waiting for a driver (same
+	// criteria as FindAvailableOrders, minus the per-driver bits) whose
+	// pickup is inside area — the surge engine's demand figure.
+	CountUnassignedWithin(ctx context.Context, area models.GeoPolygon, since time.Time) (int64, error)
 
 	// New methods for admin dashboard
 	CountOrders(ctx context.Context, filter interface{}) (int64, error)
@@ -50,6 +78,73 @@ type OrderRepository interface {
 	CountByStatus(ctx context.Context, filter interface{}) (map[string]int, error)
 	RevenueByDay(ctx context.Context, days int) ([]map[string]interface{}, error)
 	GetAllOrders(ctx context.Context, pagination Pagination) ([]models.Order, int64, error)
+	// ForEach streams every order to fn, oldest first, stopping at the
+	// first error — for the aggregate rebuild, which has to see all of
+	// history without holding it in memory.
+	ForEach(ctx context.Context, fn func(order *models.Order) error) error
+	// RestaurantsWithUnbilledDeliveries lists the restaurants that have
+	// delivered orders in [from, to) not yet on any statement.
+	RestaurantsWithUnbilledDeliveries(ctx context.Context, from, to time.Time) ([]primitive.ObjectID, error)
+	// ClaimForStatement stamps statementID on the restaurant's unbilled
+	// orders delivered in [from, to) and returns how many it claimed. An
+	// order can only ever be claimed by one statement.
+	ClaimForStatement(ctx context.Context, restaurantID, statementID primitive.ObjectID, from, to time.Time) (int64, error)
+	// FindByStatement returns the orders billed on a statement, in order
+	// of delivery.
+	FindByStatement(ctx context.Context, statementID primitive.ObjectID) ([]models.Order, error)
+	// ReleaseStatement hands a statement's orders back to be billed again.
+	ReleaseStatement(ctx context.Context, statementID primitive.ObjectID) error
+	// RecordCashCollection sets the order's cash_collection if it isn't
+	// set yet and reports whether this call set it.
+	RecordCashCollection(ctx context.Context, orderID primitive.ObjectID, collection models.CashCollection) (bool, error)
+	// AddTip adds a tip to a delivered order that hasn't been tipped yet,
+	// raising its total by the same amount, and reports whether this call
+	// set it.
+	AddTip(ctx context.Context, orderID primitive.ObjectID, tip float64) (bool, error)
+	// RequestRefund adds amount to the order's requested refunds and qty
+	// to the refunded quantity of each line in lines (by index), but only
+	// if the requested total is still `requested` — so two refunds issued
+	// at once can't both be worked out from the same remainder. It reports
+	// whether it applied.
+	RequestRefund(ctx context.Context, orderID primitive.ObjectID, requested, amount float64, lines map[int]int) (bool, error)
+	// RecordRefundProcessed adds a processed refund to the order's
+	// refunded totals and moves its payment status to refunded or
+	// partially_refunded.
+	RecordRefundProcessed(ctx context.Context, orderID primitive.ObjectID, amount, food float64) error
+	// MarkWalletPaymentReturned records how much of the order's wallet
+	// payment was handed back, if none has been yet, and reports whether
+	// this call recorded it.
+	MarkWalletPaymentReturned(ctx context.Context, orderID primitive.ObjectID, amount float64, at time.Time) (bool, error)
+	SetCheckout(ctx context.Context, orderID primitive.ObjectID, checkout models.OrderCheckout) error
+	// ReleasePaidOrder marks an awaiting_payment order paid and releases
+	// it to drivers, reporting whether this call did (false if it had
+	// already been released or cancelled).
+	ReleasePaidOrder(ctx context.Context, orderID primitive.ObjectID, verification *models.PaymentVerification, at time.Time) (bool, error)
+	// FindAwaitingPayment returns the orders whose checkout hasn't been
+	// paid yet, oldest first.
+	FindAwaitingPayment(ctx context.Context) ([]models.Order, error)
+	// FindPendingPaymentReviews lists orders whose payment is waiting for
+	// an admin, longest waiting first.
+	FindPendingPaymentReviews(ctx context.Context, pagination Pagination) ([]models.Order, int64, error)
+	// FindRecentByCustomers returns, for each of the customers with any
+	// orders, their last perCustomer orders newest first and how many
+	// they have in all, in one query. The orders carry only their ID,
+	// number, payment method and status, total, creation time and payment
+	// verification status and reference.
+	FindRecentByCustomers(ctx context.Context, customerIDs []primitive.ObjectID, perCustomer int) (map[primitive.ObjectID]CustomerOrders, error)
+	// ClaimPaymentReview gives adminID the order's pending payment review
+	// until `until`, unless another admin holds an unexpired claim, and
+	// reports whether it did. An admin can renew their own claim.
+	ClaimPaymentReview(ctx context.Context, orderID, adminID primitive.ObjectID, now, until time.Time) (bool, error)
+	// ReleasePaymentReview drops adminID's claim on the order's payment
+	// review, reporting whether they held one.
+	ReleasePaymentReview(ctx context.Context, orderID, adminID primitive.ObjectID) (bool, error)
+}
+
+// CustomerOrders is one customer's share of FindRecentByCustomers.
+type CustomerOrders struct {
+	Recent []models.Order `bson:"recent"`
+	Total  int64          `bson:"total"`
 }
 
 type Pagination struct {
@@ -204,7 +299,7 @@ func (r *orderRepository) FindByRestaurantID(ctx context.Context, restaurantID p
 	return orders, total, nil
 }
 
-func (r *orderRepository) FindAvailableOrders(ctx context.Context, driverID primitive.ObjectID, location models.GeoLocation, radius float64) ([]models.Order, error) {
+func (r *orderRepository) FindAvailableOrders(ctx context.Context, driverID primitive.ObjectID, location models.GeoLocation, radius float64, excludeCash bool) ([]models.Order, error) {
 	filter := bson.M{
 		"status":    models.OrderAccepted,
 		"driver_id": nil,
@@ -218,7 +313,9 @@ func (r *orderRepository) FindAvailableOrders(ctx context.Context, driverID prim
 		// guard here so a stale order never shows up as "available" in
 		// the few seconds/minutes before that sweep runs, and so this
 		// endpoint stays correct even if the sweep is ever disabled.
-		"created_at": bson.M{"$gte": time.Now().Add(-30 * time.Minute)},
+		// (Measured from when the order was released to drivers, which
+		// for a hosted checkout is when it was paid.)
+		"$or": releasedWithin(bson.M{"$gte": time.Now().Add(-30 * time.Minute)}),
 		// NOTE: was "restaurant.location", a field that never existed on the
 		// Order document (orders only store RestaurantID, not an embedded
 		// restaurant object). That made $near match zero documents, silently,
@@ -232,6 +329,9 @@ func (r *orderRepository) FindAvailableOrders(ctx context.Context, driverID prim
 			},
 		},
 	}
+	if excludeCash {
+		filter["payment_method"] = bson.M{"$not": cashPaymentMethod}
+	}
 	// Newest-first: a driver should see the freshest orders (shortest
 	// wait, food most likely still hot) at the top. The previous
 	// oldest-first sort meant that, combined with no expiry filter, the
@@ -255,9 +355,9 @@ func (r *orderRepository) FindAvailableOrders(ctx context.Context, driverID prim
 // `cutoff`. See the auto-cancel sweep in main.go.
 func (r *orderRepository) FindStaleUnassignedOrders(ctx context.Context, cutoff time.Time) ([]models.Order, error) {
 	filter := bson.M{
-		"status":     models.OrderAccepted,
-		"driver_id":  nil,
-		"created_at": bson.M{"$lt": cutoff},
+		"status":    models.OrderAccepted,
+		"driver_id": nil,
+		"$or":       releasedWithin(bson.M{"$lt": cutoff}),
 	}
 	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
@@ -271,27 +371,59 @@ func (r *orderRepository) FindStaleUnassignedOrders(ctx context.Context, cutoff
 	return orders, nil
 }
 
+// releasedWithin matches orders whose release to drivers meets cond:
+// released_at, or created_at for orders from before it was recorded.
+func releasedWithin(cond bson.M) bson.A {
+	return bson.A{
+		bson.M{"released_at": cond},
+		bson.M{"released_at": nil, "created_at": cond},
+	}
+}
+
+func (r *orderRepository) CountUnassignedWithin(ctx context.Context, area models.GeoPolygon, since time.Time) (int64, error) {
+	filter := bson.M{
+		"status":    models.OrderAccepted,
+		"driver_id": nil,
+		"$or":       releasedWithin(bson.M{"$gte": since}),
+		"restaurant_location": bson.M{
+			"$geoWithin": bson.M{"$geometry": area},
+		},
+	}
+	return r.collection.CountDocuments(ctx, filter)
+}
+
 // RejectOrder adds the driver to the order's rejected_by_drivers list.
 // The order stays available for other drivers — only this driver won't see it again.
-func (r *orderRepository) RejectOrder(ctx context.Context, orderID, driverID primitive.ObjectID) error {
+func (r *orderRepository) RejectOrder(ctx context.Context, orderID, driverID primitive.ObjectID) (bool, error) {
 	result, err := r.collection.UpdateOne(
 		ctx,
 		bson.M{
-			"_id":    orderID,
-			"status": models.OrderAccepted,
+			"_id":                 orderID,
+			"status":              models.OrderAccepted,
+			"rejected_by_drivers": bson.M{"$ne": driverID},
 		},
 		bson.M{
-			"$addToSet": bson.M{"rejected_by_drivers": driverID},
-			"$set":      bson.M{"updated_at": time.Now()},
+			"$push": bson.M{"rejected_by_drivers": driverID},
+			"$set":  bson.M{"updated_at": time.Now()},
 		},
 	)
 	if err != nil {
-		return err
+		return false, err
 	}
-	if result.MatchedCount == 0 {
-		return errors.New("order not found or no longer available")
+	if result.MatchedCount > 0 {
+		return true, nil
 	}
-	return nil
+
+	// Nothing matched: either this driver already rejected it (fine, a
+	// no-op as before) or it's gone.
+	available, err := r.collection.CountDocuments(ctx, bson.M{"_id": orderID, "status": models.OrderAccepted})
+	if err != nil {
+		return false, err
+	}
+	if available == 0 {
+		return false, errors.New("order not found or no longer available")
+	}
+	return false, nil
 }
 
 func (r *orderRepository) UpdateStatus(ctx context.Context, orderID primitive.ObjectID, status models.OrderStatus, actorID primitive.ObjectID, actorType string) error {
@@ -301,11 +433,95 @@ func (r *orderRepository) UpdateStatus(ctx context.Context, orderID primitive.Ob
 		ActorID:   actorID,
 		ActorType: actorType,
 	}
-	update := bson.M{
-		"$set":  bson.M{"status": status, "updated_at": time.Now()},
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": orderID}, statusUpdate(event))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("order not found")
+	}
+	return nil
+}
+
+func (r *orderRepository) AdvanceStatus(ctx context.Context, orderID primitive.ObjectID, from models.OrderStatus, event models.OrderEvent) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": orderID, "status": from}, statusUpdate(event))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("order not found or status already changed")
+	}
+	return nil
+}
+
+// statusUpdate builds the $set/$push for a status change. Delivered orders
+// also get delivery_info.actual_delivery stamped — AverageDeliveryTime
+// reads that field, and nothing used to set it, so the dashboard's
+// average delivery time was always computed over missing values.
+func statusUpdate(event models.OrderEvent) bson.M {
+	set := bson.M{"status": event.Status, "updated_at": event.Timestamp}
+	if event.Status == models.OrderDelivered {
+		set["delivery_info.actual_delivery"] = event.Timestamp
+	}
+	return bson.M{
+		"$set":  set,
 		"$push": bson.M{"timeline": event},
 	}
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": orderID}, update)
+}
+
+func (r *orderRepository) FindActiveByDriverID(ctx context.Context, driverID primitive.ObjectID) ([]models.Order, error) {
+	filter := bson.M{
+		"driver_id": driverID,
+		"status": bson.M{
+			"$in": []models.OrderStatus{
+				models.OrderAccepted,
+				models.OrderPreparing,
+				models.OrderReady,
+				models.OrderPickedUp,
+				models.OrderOnTheWay,
+			},
+		},
+	}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var orders []models.Order
+	if err = cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *orderRepository) RecordArrival(ctx context.Context, orderID primitive.ObjectID, fence string, at time.Time) (bool, error) {
+	field := "arrivals." + fence
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": orderID, field: nil},
+		bson.M{"$set": bson.M{field: at, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *orderRepository) AddFlag(ctx context.Context, orderID primitive.ObjectID, flag models.OrderFlag) error {
+	if flag.CreatedAt.IsZero() {
+		flag.CreatedAt = time.Now()
+	}
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": orderID},
+		bson.M{
+			"$push": bson.M{"flags": flag},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
 	if err != nil {
 		return err
 	}
@@ -397,6 +613,10 @@ func (r *orderRepository) UpdatePaymentStatus(ctx context.Context, orderID primi
 }
 
 func (r *orderRepository) UpdatePaymentVerification(ctx context.Context, orderID primitive.ObjectID, paymentStatus string, verification *models.PaymentVerification) error {
+	settled := bson.A{"paid", "partially_refunded", "refunded"}
+	if paymentStatus == "paid" {
+		settled = bson.A{"partially_refunded", "refunded"}
+	}
 	update := bson.M{
 		"$set": bson.M{
 			"payment_status":       paymentStatus,
@@ -405,17 +625,42 @@ func (r *orderRepository) UpdatePaymentVerification(ctx context.Context, orderID
 		},
 	}
 
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": orderID}, update)
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": orderID, "payment_status": bson.M{"$nin": settled}}, update)
 	if err != nil {
 		return err
 	}
 	if result.MatchedCount == 0 {
-		return errors.New("order not found")
+		exists, err := r.collection.CountDocuments(ctx, bson.M{"_id": orderID})
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			return errors.New("order not found")
+		}
 	}
 	return nil
 }
 
 func (r *orderRepository) CancelOrder(ctx context.Context, orderID primitive.ObjectID, cancellation models.CancellationInfo) error {
+	cancelled, err := r.cancelIn(ctx, orderID, cancellation, []models.OrderStatus{
+		models.OrderAwaitingPayment, models.OrderPending, models.OrderAccepted, models.OrderPreparing,
+	})
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return errors.New("order cannot be cancelled at this stage")
+	}
+	return nil
+}
+
+func (r *orderRepository) CancelAwaitingPayment(ctx context.Context, orderID primitive.ObjectID, cancellation models.CancellationInfo) (bool, error) {
+	return r.cancelIn(ctx, orderID, cancellation, []models.OrderStatus{models.OrderAwaitingPayment})
+}
+
+// cancelIn cancels the order if its status is one of statuses and reports
+// whether it did.
+func (r *orderRepository) cancelIn(ctx context.Context, orderID primitive.ObjectID, cancellation models.CancellationInfo, statuses []models.OrderStatus) (bool, error) {
 	cancellation.Timestamp = time.Now()
 	update := bson.M{
 		"$set": bson.M{
@@ -434,19 +679,14 @@ func (r *orderRepository) CancelOrder(ctx context.Context, orderID primitive.Obj
 		},
 	}
 	filter := bson.M{
-		"_id": orderID,
-		"status": bson.M{
-			"$in": []models.OrderStatus{models.OrderPending, models.OrderAccepted, models.OrderPreparing},
-		},
+		"_id":    orderID,
+		"status": bson.M{"$in": statuses},
 	}
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return err
+		return false, err
 	}
-	if result.MatchedCount == 0 {
-		return errors.New("order cannot be cancelled at this stage")
-	}
-	return nil
+	return result.MatchedCount == 1, nil
 }
 
 func (r *orderRepository) FindActiveOrders(ctx context.Context) ([]models.Order, error) {
@@ -623,4 +863,364 @@ func (r *orderRepository) GetAllOrders(ctx context.Context, pagination Paginatio
 		return nil, 0, err
 	}
 	return orders, total, nil
+}
+
+func (r *orderRepository) ForEach(ctx context.Context, fn func(order *models.Order) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var order models.Order
+		if err := cursor.Decode(&order); err != nil {
+			return err
+		}
+		if err := fn(&order); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// unbilledDeliveries matches delivered orders in [from, to) that no
+// statement has claimed yet. Orders delivered before actual_delivery was
+// stamped are placed by their last update instead.
+func unbilledDeliveries(from, to time.Time) bson.M {
+	return bson.M{
+		"status":       models.OrderDelivered,
+		"statement_id": nil,
+		"$or": []bson.M{
+			{"delivery_info.actual_delivery": bson.M{"$gte": from, "$lt": to}},
+			{"delivery_info.actual_delivery": nil, "updated_at": bson.M{"$gte": from, "$lt": to}},
+		},
+	}
+}
+
+func (r *orderRepository) RestaurantsWithUnbilledDeliveries(ctx context.Context, from, to time.Time) ([]primitive.ObjectID, error) {
+	values, err := r.collection.Distinct(ctx, "restaurant_id", unbilledDeliveries(from, to))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *orderRepository) ClaimForStatement(ctx context.Context, restaurantID, statementID primitive.ObjectID, from, to time.Time) (int64, error) {
+	filter := unbilledDeliveries(from, to)
+	filter["restaurant_id"] = restaurantID
+
+	result, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"statement_id": statementID}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *orderRepository) FindByStatement(ctx context.Context, statementID primitive.ObjectID) ([]models.Order, error) {
+	opts := options.Find().SetSort(bson.D{
+		{Key: "delivery_info.actual_delivery", Value: 1},
+		{Key: "updated_at", Value: 1},
+	})
+	cursor, err := r.collection.Find(ctx, bson.M{"statement_id": statementID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []models.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *orderRepository) ReleaseStatement(ctx context.Context, statementID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"statement_id": statementID},
+		bson.M{"$unset": bson.M{"statement_id": ""}},
+	)
+	return err
+}
+
+// cashPaymentMethod matches cash orders. payment_method is whatever the
+// app sent, so older orders may have it in any case or padded.
+var cashPaymentMethod = primitive.Regex{Pattern: `^\s*cash\s*$`, Options: "i"}
+
+func (r *orderRepository) RecordCashCollection(ctx context.Context, orderID primitive.ObjectID, collection models.CashCollection) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": orderID, "cash_collection": nil},
+		bson.M{"$set": bson.M{"cash_collection": collection, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *orderRepository) AddTip(ctx context.Context, orderID primitive.ObjectID, tip float64) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":              orderID,
+			"status":           models.OrderDelivered,
+			"total_amount.tip": bson.M{"$in": bson.A{nil, 0}},
+		},
+		bson.M{
+			"$set": bson.M{"total_amount.tip": tip, "updated_at": time.Now()},
+			"$inc": bson.M{"total_amount.total": tip},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *orderRepository) RequestRefund(ctx context.Context, orderID primitive.ObjectID, requested, amount float64, lines map[int]int) (bool, error) {
+	inc := bson.M{"refunds.requested": amount}
+	for line, quantity := range lines {
+		inc[fmt.Sprintf("items.%d.refunded_quantity", line)] = quantity
+	}
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": orderID,
+			"$expr": bson.M{"$eq": bson.A{
+				bson.M{"$ifNull": bson.A{"$refunds.requested", 0}},
+				requested,
+			}},
+		},
+		bson.M{
+			"$inc": inc,
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// RecordRefundProcessed runs as an update pipeline so the payment status
+// can be worked out from the new refunded total, and a cancelled order's
+// cancellation.refund_amount kept in step with it.
+func (r *orderRepository) RecordRefundProcessed(ctx context.Context, orderID primitive.ObjectID, amount, food float64) error {
+	addRounded := func(field string, delta float64) bson.M {
+		return bson.M{"$round": bson.A{bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + field, 0}}, delta}}, 2}}
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": orderID}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"refunds.refunded": addRounded("refunds.refunded", amount),
+			"refunds.food":     addRounded("refunds.food", food),
+			"updated_at":       time.Now(),
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"payment_status": bson.M{"$cond": bson.A{
+				bson.M{"$gte": bson.A{"$refunds.refunded", bson.M{"$subtract": bson.A{"$total_amount.total", 0.005}}}},
+				"refunded",
+				"partially_refunded",
+			}},
+			"cancellation": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{bson.M{"$type": "$cancellation"}, "object"}},
+				bson.M{"$mergeObjects": bson.A{"$cancellation", bson.M{"refund_amount": "$refunds.refunded"}}},
+				"$$REMOVE",
+			}},
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("order not found")
+	}
+	return nil
+}
+
+func (r *orderRepository) MarkWalletPaymentReturned(ctx context.Context, orderID primitive.ObjectID, amount float64, at time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":                        orderID,
+			"wallet_payment":             bson.M{"$ne": nil},
+			"wallet_payment.returned_at": nil,
+		},
+		bson.M{"$set": bson.M{
+			"wallet_payment.returned":    amount,
+			"wallet_payment.returned_at": at,
+			"updated_at":                 time.Now(),
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *orderRepository) SetCheckout(ctx context.Context, orderID primitive.ObjectID, checkout models.OrderCheckout) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": orderID},
+		bson.M{"$set": bson.M{"checkout": checkout, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+func (r *orderRepository) ReleasePaidOrder(ctx context.Context, orderID primitive.ObjectID, verification *models.PaymentVerification, at time.Time) (bool, error) {
+	set := bson.M{
+		"status":               models.OrderAccepted,
+		"payment_status":       "paid",
+		"payment_verification": verification,
+		"released_at":          at,
+		"updated_at":           time.Now(),
+	}
+	if verification.TransactionReference != "" {
+		set["checkout.provider_reference"] = verification.TransactionReference
+	}
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": orderID, "status": models.OrderAwaitingPayment},
+		bson.M{
+			"$set": set,
+			"$push": bson.M{"timeline": models.OrderEvent{
+				Status:    models.OrderAccepted,
+				Timestamp: at,
+				ActorType: "system",
+				Notes:     "Payment confirmed",
+			}},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *orderRepository) FindAwaitingPayment(ctx context.Context) ([]models.Order, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"status": models.OrderAwaitingPayment}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []models.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *orderRepository) FindPendingPaymentReviews(ctx context.Context, pagination Pagination) ([]models.Order, int64, error) {
+	filter := bson.M{"payment_verification.status": "pending_review"}
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSkip((pagination.Page - 1) * pagination.Limit).
+		SetLimit(pagination.Limit).
+		SetSort(bson.D{{Key: "payment_verification.review_requested_at", Value: 1}, {Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []models.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, 0, err
+	}
+	return orders, total, nil
+}
+
+func (r *orderRepository) ClaimPaymentReview(ctx context.Context, orderID, adminID primitive.ObjectID, now, until time.Time) (bool, error) {
+	filter := bson.M{
+		"_id":                         orderID,
+		"payment_verification.status": "pending_review",
+		"$or": bson.A{
+			bson.M{"payment_verification.claimed_until": bson.M{"$exists": false}},
+			bson.M{"payment_verification.claimed_until": bson.M{"$lt": now}},
+			bson.M{"payment_verification.claimed_by": adminID},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"payment_verification.claimed_by":    adminID,
+		"payment_verification.claimed_until": until,
+		"updated_at":                         now,
+	}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *orderRepository) ReleasePaymentReview(ctx context.Context, orderID, adminID primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": orderID, "payment_verification.claimed_by": adminID}
+	update := bson.M{
+		"$unset": bson.M{"payment_verification.claimed_by": "", "payment_verification.claimed_until": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *orderRepository) FindRecentByCustomers(ctx context.Context, customerIDs []primitive.ObjectID, perCustomer int) (map[primitive.ObjectID]CustomerOrders, error) {
+	result := make(map[primitive.ObjectID]CustomerOrders, len(customerIDs))
+	if len(customerIDs) == 0 {
+		return result, nil
+	}
+	pipeline := []bson.M{
+		{"$match": bson.M{"customer_id": bson.M{"$in": customerIDs}}},
+		{"$group": bson.M{
+			"_id":   "$customer_id",
+			"total": bson.M{"$sum": 1},
+			"recent": bson.M{"$topN": bson.M{
+				"n":      perCustomer,
+				"sortBy": bson.M{"created_at": -1},
+				"output": bson.M{
+					"_id":            "$_id",
+					"order_number":   "$order_number",
+					"payment_method": "$payment_method",
+					"payment_status": "$payment_status",
+					"total_amount":   bson.M{"total": "$total_amount.total"},
+					"created_at":     "$created_at",
+					"payment_verification": bson.M{
+						"status":                "$payment_verification.status",
+						"transaction_reference": "$payment_verification.transaction_reference",
+					},
+				},
+			}},
+		}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var group struct {
+			CustomerID     primitive.ObjectID `bson:"_id"`
+			CustomerOrders `bson:",inline"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return nil, err
+		}
+		result[group.CustomerID] = group.CustomerOrders
+	}
+	return result, cursor.Err()
 }
\ No newline at end of file
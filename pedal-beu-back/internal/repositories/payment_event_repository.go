@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PaymentEventFilter narrows a payment event listing; zero values match
+// all.
+type PaymentEventFilter struct {
+	Provider string
+	Status   string
+	OrderID  *primitive.ObjectID
+}
+
+type PaymentEventRepository interface {
+	// Record stores a newly delivered event and returns it. If the
+	// provider's event is already stored, it counts the delivery and
+	// returns the stored event instead, with duplicate set.
+	Record(ctx context.Context, event *models.PaymentEvent) (stored *models.PaymentEvent, duplicate bool, err error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.PaymentEvent, error)
+	// Find returns events newest first.
+	Find(ctx context.Context, filter PaymentEventFilter, pagination Pagination) ([]models.PaymentEvent, int64, error)
+	// Claim takes an event to apply until lockedUntil: one that's
+	// received or failed, or one whose last claim has lapsed. It reports
+	// whether it got it; replayedBy, if set, records an admin replay.
+	Claim(ctx context.Context, id primitive.ObjectID, lockedUntil time.Time, replayedBy *primitive.ObjectID) (bool, error)
+	// Finish records how applying a claimed event went: processed, or
+	// failed with errMsg.
+	Finish(ctx context.Context, id primitive.ObjectID, status, errMsg string, at time.Time) error
+}
+
+type paymentEventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPaymentEventRepository() PaymentEventRepository {
+	collections := database.GetCollections()
+	return &paymentEventRepository{
+		collection: collections.PaymentEvents,
+	}
+}
+
+func (r *paymentEventRepository) Record(ctx context.Context, event *models.PaymentEvent) (*models.PaymentEvent, bool, error) {
+	now := time.Now()
+	event.Status = models.PaymentEventReceived
+	event.Deliveries = 1
+	event.ReceivedAt = now
+	event.LastDeliveredAt = now
+	event.UpdatedAt = now
+
+	result, err := r.collection.InsertOne(ctx, event)
+	if err == nil {
+		event.ID = result.InsertedID.(primitive.ObjectID)
+		return event, false, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, false, err
+	}
+
+	var stored models.PaymentEvent
+	err = r.collection.FindOneAndUpdate(ctx,
+		bson.M{"provider": event.Provider, "event_id": event.EventID},
+		bson.M{
+			"$inc": bson.M{"deliveries": 1},
+			"$set": bson.M{"last_delivered_at": now, "updated_at": now},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&stored)
+	if err != nil {
+		return nil, false, err
+	}
+	return &stored, true, nil
+}
+
+func (r *paymentEventRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.PaymentEvent, error) {
+	var event models.PaymentEvent
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&event)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("payment event not found")
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *paymentEventRepository) Find(ctx context.Context, filter PaymentEventFilter, pagination Pagination) ([]models.PaymentEvent, int64, error) {
+	query := bson.M{}
+	if filter.Provider != "" {
+		query["provider"] = filter.Provider
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.OrderID != nil {
+		query["order_id"] = *filter.OrderID
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skip := (pagination.Page - 1) * pagination.Limit
+	opts := options.Find().
+		SetSkip(skip).
+		SetLimit(pagination.Limit).
+		SetSort(bson.D{{Key: "received_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []models.PaymentEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+func (r *paymentEventRepository) Claim(ctx context.Context, id primitive.ObjectID, lockedUntil time.Time, replayedBy *primitive.ObjectID) (bool, error) {
+	now := time.Now()
+	set := bson.M{
+		"status":       models.PaymentEventProcessing,
+		"locked_until": lockedUntil,
+		"updated_at":   now,
+	}
+	if replayedBy != nil {
+		set["replayed_by"] = *replayedBy
+		set["replayed_at"] = now
+	}
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{
+			"_id": id,
+			"$or": bson.A{
+				bson.M{"status": bson.M{"$in": bson.A{models.PaymentEventReceived, models.PaymentEventFailed}}},
+				bson.M{"status": models.PaymentEventProcessing, "locked_until": bson.M{"$lt": now}},
+			},
+		},
+		bson.M{"$set": set, "$inc": bson.M{"attempts": 1}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *paymentEventRepository) Finish(ctx context.Context, id primitive.ObjectID, status, errMsg string, at time.Time) error {
+	set := bson.M{"status": status, "updated_at": at}
+	unset := bson.M{"locked_until": ""}
+	if status == models.PaymentEventProcessed {
+		set["processed_at"] = at
+		unset["error"] = ""
+	} else {
+		set["error"] = errMsg
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set, "$unset": unset})
+	return err
+}
@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PaymentVerificationJobRepository interface {
+	// Enqueue queues a job for its order, replacing any the order had.
+	Enqueue(ctx context.Context, job *models.PaymentVerificationJob) error
+	// ClaimDue takes the queued job that's been due longest, or a running
+	// one whose claim has lapsed, until lockedUntil, counting the attempt.
+	// It returns nil if nothing is due.
+	ClaimDue(ctx context.Context, now, lockedUntil time.Time) (*models.PaymentVerificationJob, error)
+	// Reschedule puts a claimed job back in the queue for next. Like
+	// Resolve, it does nothing if the job was replaced since the claim.
+	Reschedule(ctx context.Context, job *models.PaymentVerificationJob, next time.Time, lastError string) error
+	// Resolve closes a claimed job with a final status.
+	Resolve(ctx context.Context, job *models.PaymentVerificationJob, status, lastError string, at time.Time) error
+	// CountByStatus counts jobs by status.
+	CountByStatus(ctx context.Context) (map[string]int64, error)
+}
+
+type paymentVerificationJobRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPaymentVerificationJobRepository() PaymentVerificationJobRepository {
+	collections := database.GetCollections()
+	return &paymentVerificationJobRepository{
+		collection: collections.PaymentJobs,
+	}
+}
+
+func (r *paymentVerificationJobRepository) Enqueue(ctx context.Context, job *models.PaymentVerificationJob) error {
+	now := time.Now()
+	job.ID = primitive.NilObjectID
+	job.Status = models.VerificationJobQueued
+	job.Attempts = 0
+	job.LockedUntil = nil
+	job.ResolvedAt = nil
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	opts := options.FindOneAndReplace().SetUpsert(true).SetReturnDocument(options.After)
+	var stored models.PaymentVerificationJob
+	err := r.collection.FindOneAndReplace(ctx, bson.M{"order_id": job.OrderID}, job, opts).Decode(&stored)
+	if err != nil {
+		return err
+	}
+	job.ID = stored.ID
+	return nil
+}
+
+func (r *paymentVerificationJobRepository) ClaimDue(ctx context.Context, now, lockedUntil time.Time) (*models.PaymentVerificationJob, error) {
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"status": models.VerificationJobQueued, "next_attempt_at": bson.M{"$lte": now}},
+			bson.M{"status": models.VerificationJobRunning, "locked_until": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       models.VerificationJobRunning,
+			"locked_until": lockedUntil,
+			"updated_at":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job models.PaymentVerificationJob
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// claimedJob matches a job as it was claimed: still running the same
+// attempt, not replaced by a newer job for the order.
+func claimedJob(job *models.PaymentVerificationJob) bson.M {
+	return bson.M{"_id": job.ID, "status": models.VerificationJobRunning, "attempts": job.Attempts, "created_at": job.CreatedAt}
+}
+
+func (r *paymentVerificationJobRepository) Reschedule(ctx context.Context, job *models.PaymentVerificationJob, next time.Time, lastError string) error {
+	_, err := r.collection.UpdateOne(ctx, claimedJob(job), bson.M{
+		"$set": bson.M{
+			"status":          models.VerificationJobQueued,
+			"next_attempt_at": next,
+			"last_error":      lastError,
+			"updated_at":      time.Now(),
+		},
+		"$unset": bson.M{"locked_until": ""},
+	})
+	return err
+}
+
+func (r *paymentVerificationJobRepository) Resolve(ctx context.Context, job *models.PaymentVerificationJob, status, lastError string, at time.Time) error {
+	set := bson.M{"status": status, "resolved_at": at, "updated_at": at}
+	if lastError != "" {
+		set["last_error"] = lastError
+	}
+	_, err := r.collection.UpdateOne(ctx, claimedJob(job), bson.M{
+		"$set":   set,
+		"$unset": bson.M{"locked_until": ""},
+	})
+	return err
+}
+
+func (r *paymentVerificationJobRepository) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Status string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{}
+	for _, group := range groups {
+		counts[group.Status] = group.Count
+	}
+	return counts, nil
+}
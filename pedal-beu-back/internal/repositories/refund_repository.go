@@ -0,0 +1,224 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RefundFilter narrows a refund listing; zero values match all.
+type RefundFilter struct {
+	OrderID *primitive.ObjectID
+	Status  string
+}
+
+type RefundRepository interface {
+	Create(ctx context.Context, refund *models.Refund) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Refund, error)
+	// FindByOrder returns an order's refunds, oldest first.
+	FindByOrder(ctx context.Context, orderID primitive.ObjectID) ([]models.Refund, error)
+	// Find returns refunds newest first.
+	Find(ctx context.Context, filter RefundFilter, pagination Pagination) ([]models.Refund, int64, error)
+	// MarkProcessed moves a pending refund to processed, returning the
+	// updated refund. It fails with ErrRefundProcessed if it already was.
+	MarkProcessed(ctx context.Context, id primitive.ObjectID, processedBy primitive.ObjectID, reference string, at time.Time) (*models.Refund, error)
+	// FindUnbilled returns the restaurant's processed refunds with food to
+	// deduct that no statement has deducted yet, processed before `before`.
+	FindUnbilled(ctx context.Context, restaurantID primitive.ObjectID, before time.Time) ([]models.Refund, error)
+	// RestaurantsWithUnbilledRefunds lists the restaurants FindUnbilled
+	// would return anything for.
+	RestaurantsWithUnbilledRefunds(ctx context.Context, before time.Time) ([]primitive.ObjectID, error)
+	// ClaimForStatement marks those of ids that no statement has claimed
+	// yet as deducted on statementID and returns how many it marked.
+	ClaimForStatement(ctx context.Context, ids []primitive.ObjectID, statementID primitive.ObjectID) (int64, error)
+	FindByStatement(ctx context.Context, statementID primitive.ObjectID) ([]models.Refund, error)
+	// ReleaseStatement hands a statement's refunds back to be deducted
+	// again.
+	ReleaseStatement(ctx context.Context, statementID primitive.ObjectID) error
+}
+
+// ErrRefundProcessed is returned when processing a refund that has
+// already been processed.
+var ErrRefundProcessed = errors.New("refund has already been processed")
+
+type refundRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRefundRepository() RefundRepository {
+	collections := database.GetCollections()
+	return &refundRepository{
+		collection: collections.Refunds,
+	}
+}
+
+func (r *refundRepository) Create(ctx context.Context, refund *models.Refund) error {
+	refund.CreatedAt = time.Now()
+	refund.UpdatedAt = refund.CreatedAt
+
+	result, err := r.collection.InsertOne(ctx, refund)
+	if err != nil {
+		return err
+	}
+
+	refund.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *refundRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Refund, error) {
+	var refund models.Refund
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&refund)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("refund not found")
+		}
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (r *refundRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Refund, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var refunds []models.Refund
+	if err := cursor.All(ctx, &refunds); err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+func (r *refundRepository) FindByOrder(ctx context.Context, orderID primitive.ObjectID) ([]models.Refund, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	return r.find(ctx, bson.M{"order_id": orderID}, opts)
+}
+
+func (r *refundRepository) Find(ctx context.Context, filter RefundFilter, pagination Pagination) ([]models.Refund, int64, error) {
+	query := bson.M{}
+	if filter.OrderID != nil {
+		query["order_id"] = *filter.OrderID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skip := (pagination.Page - 1) * pagination.Limit
+	opts := options.Find().
+		SetSkip(skip).
+		SetLimit(pagination.Limit).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	refunds, err := r.find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return refunds, total, nil
+}
+
+func (r *refundRepository) MarkProcessed(ctx context.Context, id primitive.ObjectID, processedBy primitive.ObjectID, reference string, at time.Time) (*models.Refund, error) {
+	set := bson.M{
+		"status":       models.RefundProcessed,
+		"processed_by": processedBy,
+		"processed_at": at,
+		"updated_at":   time.Now(),
+	}
+	if reference != "" {
+		set["reference"] = reference
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var refund models.Refund
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": models.RefundPending}, bson.M{"$set": set}, opts).Decode(&refund)
+	if err == nil {
+		return &refund, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	if _, err := r.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return nil, ErrRefundProcessed
+}
+
+// unbilledRefunds matches processed refunds with food no statement has
+// deducted yet.
+func unbilledRefunds(before time.Time) bson.M {
+	return bson.M{
+		"status":       models.RefundProcessed,
+		"statement_id": nil,
+		"food":         bson.M{"$gt": 0},
+		"processed_at": bson.M{"$lt": before},
+	}
+}
+
+func (r *refundRepository) FindUnbilled(ctx context.Context, restaurantID primitive.ObjectID, before time.Time) ([]models.Refund, error) {
+	filter := unbilledRefunds(before)
+	filter["restaurant_id"] = restaurantID
+	opts := options.Find().SetSort(bson.D{{Key: "processed_at", Value: 1}})
+	return r.find(ctx, filter, opts)
+}
+
+func (r *refundRepository) RestaurantsWithUnbilledRefunds(ctx context.Context, before time.Time) ([]primitive.ObjectID, error) {
+	values, err := r.collection.Distinct(ctx, "restaurant_id", unbilledRefunds(before))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *refundRepository) ClaimForStatement(ctx context.Context, ids []primitive.ObjectID, statementID primitive.ObjectID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "statement_id": nil},
+		bson.M{"$set": bson.M{"statement_id": statementID, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *refundRepository) FindByStatement(ctx context.Context, statementID primitive.ObjectID) ([]models.Refund, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "processed_at", Value: 1}})
+	return r.find(ctx, bson.M{"statement_id": statementID}, opts)
+}
+
+func (r *refundRepository) ReleaseStatement(ctx context.Context, statementID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"statement_id": statementID},
+		bson.M{
+			"$unset": bson.M{"statement_id": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
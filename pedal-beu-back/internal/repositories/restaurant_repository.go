@@ -27,18 +27,27 @@ type RestaurantRepository interface {
 	Create(ctx context.Context, restaurant *models.Restaurant) error
 	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Restaurant, error)
 	FindByOwnerID(ctx context.Context, ownerID primitive.ObjectID) ([]models.Restaurant, error)
-	FindNearby(ctx context.Context, location models.GeoLocation, radius float64, pagination Pagination) ([]models.Restaurant, int64, error)
+	// FindNearby lists active, verified restaurants within radius meters
+	// of location, nearest first; within, if set, is an area they also
+	// have to be inside.
+	FindNearby(ctx context.Context, location models.GeoLocation, radius float64, within *models.GeoPolygon, pagination Pagination) ([]models.Restaurant, int64, error)
 	FindAll(ctx context.Context, pagination Pagination) ([]models.Restaurant, int64, error)
 	FindAllAdmin(ctx context.Context, pagination Pagination) ([]models.Restaurant, int64, error)
+	// FindAllVisible returns every active, verified restaurant unpaginated —
+	// for the in-process search index, which needs the whole catalogue.
+	FindAllVisible(ctx context.Context) ([]models.Restaurant, error)
 	Update(ctx context.Context, id primitive.ObjectID, update interface{}) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
 	UpdateStatus(ctx context.Context, id primitive.ObjectID, isActive bool) error
 	UpdateVerification(ctx context.Context, id primitive.ObjectID, isVerified bool) error
-	AddMenuItem(ctx context.Context, restaurantID primitive.ObjectID, item *models.MenuItem) error
-	UpdateMenuItem(ctx context.Context, restaurantID, itemID primitive.ObjectID, update interface{}) error
-	DeleteMenuItem(ctx context.Context, restaurantID, itemID primitive.ObjectID) error
-	GetMenuItems(ctx context.Context, restaurantID primitive.ObjectID) ([]models.MenuItem, error)
-	Search(ctx context.Context, query string, location models.GeoLocation, radius float64) ([]models.Restaurant, error)
+	// AddRating folds one customer rating into the restaurant's rating sum
+	// and total_reviews and recomputes Rating from them.
+	AddRating(ctx context.Context, id primitive.ObjectID, rating int) error
+	// ResetRatings zeroes every restaurant's rating; SetRating then writes
+	// one restaurant's rebuilt sum and count. Aggregate rebuild only.
+	ResetRatings(ctx context.Context) error
+	SetRating(ctx context.Context, id primitive.ObjectID, sum, count int) error
+	Search(ctx context.Context, query string, location models.GeoLocation, radius float64, within *models.GeoPolygon) ([]models.Restaurant, error)
 	FindTopByOrders(ctx context.Context, limit int, duration time.Duration) ([]map[string]interface{}, error)
 }
 
@@ -158,6 +167,24 @@ func (r *restaurantRepository) FindAll(ctx context.Context, pagination Paginatio
 	return restaurants, total, nil
 }
 
+func (r *restaurantRepository) FindAllVisible(ctx context.Context) ([]models.Restaurant, error) {
+	filter := bson.M{
+		"is_active":   true,
+		"is_verified": true,
+	}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var restaurants []models.Restaurant
+	if err := cursor.All(ctx, &restaurants); err != nil {
+		return nil, err
+	}
+	return restaurants, nil
+}
+
 // FindAllAdmin returns every restaurant regardless of is_active/is_verified,
 // so the admin dashboard can see (and verify) newly-created restaurants that
 // aren't visible to customers yet.
@@ -204,7 +231,7 @@ func (r *restaurantRepository) FindAllAdmin(ctx context.Context, pagination Pagi
 	return restaurants, total, nil
 }
 
-func (r *restaurantRepository) FindNearby(ctx context.Context, location models.GeoLocation, radius float64, pagination Pagination) ([]models.Restaurant, int64, error) {
+func (r *restaurantRepository) FindNearby(ctx context.Context, location models.GeoLocation, radius float64, within *models.GeoPolygon, pagination Pagination) ([]models.Restaurant, int64, error) {
 	log.Printf("📍 Repository: FindNearby called, location=%v, radius=%.0f", location, radius)
 
 	// Create geospatial query
@@ -218,6 +245,9 @@ func (r *restaurantRepository) FindNearby(ctx context.Context, location models.G
 		"is_active":   true,
 		"is_verified": true,
 	}
+	if within != nil {
+		filter["$and"] = []bson.M{{"location": bson.M{"$geoWithin": bson.M{"$geometry": within}}}}
+	}
 
 	// Get total count
 	total, err := r.collection.CountDocuments(ctx, filter)
@@ -334,21 +364,8 @@ func (r *restaurantRepository) UpdateVerification(ctx context.Context, id primit
 	return nil
 }
 
-func (r *restaurantRepository) AddMenuItem(ctx context.Context, restaurantID primitive.ObjectID, item *models.MenuItem) error {
-	item.ID = primitive.NewObjectID()
-	item.CreatedAt = time.Now()
-	item.UpdatedAt = time.Now()
-
-	update := bson.M{
-		"$push": bson.M{
-			"menu": item,
-		},
-		"$set": bson.M{
-			"updated_at": time.Now(),
-		},
-	}
-
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": restaurantID}, update)
+func (r *restaurantRepository) AddRating(ctx context.Context, id primitive.ObjectID, rating int) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, addRatingPipeline("total_reviews", rating))
 	if err != nil {
 		return err
 	}
@@ -360,76 +377,35 @@ func (r *restaurantRepository) AddMenuItem(ctx context.Context, restaurantID pri
 	return nil
 }
 
-func (r *restaurantRepository) UpdateMenuItem(ctx context.Context, restaurantID, itemID primitive.ObjectID, update interface{}) error {
-	updateFields := bson.M{}
-	if updateMap, ok := update.(bson.M); ok {
-		for k, v := range updateMap {
-			updateFields["menu.$."+k] = v
-		}
-	}
-
-	updateFields["menu.$.updated_at"] = time.Now()
-	updateFields["updated_at"] = time.Now()
-
-	updateDoc := bson.M{
-		"$set": updateFields,
-	}
-
-	filter := bson.M{
-		"_id":      restaurantID,
-		"menu._id": itemID,
-	}
-
-	result, err := r.collection.UpdateOne(ctx, filter, updateDoc)
-	if err != nil {
-		return err
-	}
-
-	if result.MatchedCount == 0 {
-		return errors.New("menu item not found")
-	}
-
-	return nil
-}
-
-func (r *restaurantRepository) DeleteMenuItem(ctx context.Context, restaurantID, itemID primitive.ObjectID) error {
-	update := bson.M{
-		"$pull": bson.M{
-			"menu": bson.M{"_id": itemID},
-		},
+func (r *restaurantRepository) ResetRatings(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{}, bson.M{
 		"$set": bson.M{
-			"updated_at": time.Now(),
+			"rating":        0.0,
+			"rating_sum":    0,
+			"total_reviews": 0,
+			"updated_at":    time.Now(),
 		},
-	}
-
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": restaurantID}, update)
-	if err != nil {
-		return err
-	}
-
-	if result.MatchedCount == 0 {
-		return errors.New("restaurant not found")
-	}
-
-	return nil
+	})
+	return err
 }
 
-func (r *restaurantRepository) GetMenuItems(ctx context.Context, restaurantID primitive.ObjectID) ([]models.MenuItem, error) {
-	var restaurant models.Restaurant
-	projection := bson.M{"menu": 1}
-
-	err := r.collection.FindOne(ctx, bson.M{"_id": restaurantID}, options.FindOne().SetProjection(projection)).Decode(&restaurant)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("restaurant not found")
-		}
-		return nil, err
+func (r *restaurantRepository) SetRating(ctx context.Context, id primitive.ObjectID, sum, count int) error {
+	rating := 0.0
+	if count > 0 {
+		rating = float64(sum) / float64(count)
 	}
-
-	return restaurant.Menu, nil
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{
+			"rating":        rating,
+			"rating_sum":    sum,
+			"total_reviews": count,
+			"updated_at":    time.Now(),
+		},
+	})
+	return err
 }
 
-func (r *restaurantRepository) Search(ctx context.Context, query string, location models.GeoLocation, radius float64) ([]models.Restaurant, error) {
+func (r *restaurantRepository) Search(ctx context.Context, query string, location models.GeoLocation, radius float64, within *models.GeoPolygon) ([]models.Restaurant, error) {
 	filter := bson.M{
 		"$and": []bson.M{
 			{
@@ -454,6 +430,9 @@ func (r *restaurantRepository) Search(ctx context.Context, query string, locatio
 			},
 		}
 	}
+	if within != nil {
+		filter["$and"] = append(filter["$and"].([]bson.M), bson.M{"location": bson.M{"$geoWithin": bson.M{"$geometry": within}}})
+	}
 
 	opts := options.Find().SetLimit(50).SetSort(bson.D{{Key: "rating", Value: -1}})
 
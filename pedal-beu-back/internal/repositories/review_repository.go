@@ -0,0 +1,240 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReviewFilter narrows FindByRestaurant and FindForModeration. Zero values
+// don't filter.
+type ReviewFilter struct {
+	RestaurantID *primitive.ObjectID
+	Status       string // a Review* status
+	FlaggedOnly  bool
+	Rating       int
+	WithPhotos   bool
+	// Sort is "newest" (the default), "oldest", "highest" or "lowest".
+	Sort string
+}
+
+// ReviewSummary is a restaurant's published reviews at a glance. Histogram
+// always has the keys "1" to "5".
+type ReviewSummary struct {
+	Average   float64          `json:"average"`
+	Total     int64            `json:"total"`
+	Histogram map[string]int64 `json:"histogram"`
+}
+
+type ReviewRepository interface {
+	// Create fails with ErrDuplicateReview if the order already has one.
+	Create(ctx context.Context, review *models.Review) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Review, error)
+	Find(ctx context.Context, filter ReviewFilter, pagination Pagination) ([]models.Review, int64, error)
+	Summary(ctx context.Context, restaurantID primitive.ObjectID) (*ReviewSummary, error)
+	SetReply(ctx context.Context, id primitive.ObjectID, reply models.ReviewReply) error
+	// AddFlag records userID's report, once per user; it returns false if
+	// they had already flagged the review.
+	AddFlag(ctx context.Context, id primitive.ObjectID, flag models.ReviewFlag) (bool, error)
+	// Moderate applies an admin decision: hide or unhide sets the status,
+	// and every decision clears the outstanding flags.
+	Moderate(ctx context.Context, id primitive.ObjectID, status string, moderation models.ReviewModeration) error
+}
+
+// ErrDuplicateReview is returned by Create for an order that's already
+// been reviewed.
+var ErrDuplicateReview = errors.New("order already reviewed")
+
+type reviewRepository struct {
+	collection *mongo.Collection
+}
+
+func NewReviewRepository() ReviewRepository {
+	collections := database.GetCollections()
+	return &reviewRepository{
+		collection: collections.Reviews,
+	}
+}
+
+func (r *reviewRepository) Create(ctx context.Context, review *models.Review) error {
+	review.CreatedAt = time.Now()
+	review.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, review)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrDuplicateReview
+		}
+		return err
+	}
+
+	review.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *reviewRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Review, error) {
+	var review models.Review
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&review)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("review not found")
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *reviewRepository) Find(ctx context.Context, filter ReviewFilter, pagination Pagination) ([]models.Review, int64, error) {
+	query := bson.M{}
+	if filter.RestaurantID != nil {
+		query["restaurant_id"] = *filter.RestaurantID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.FlaggedOnly {
+		query["flag_count"] = bson.M{"$gt": 0}
+	}
+	if filter.Rating > 0 {
+		query["rating"] = filter.Rating
+	}
+	if filter.WithPhotos {
+		query["photos.0"] = bson.M{"$exists": true}
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return []models.Review{}, 0, err
+	}
+
+	sort := bson.D{{Key: "created_at", Value: -1}}
+	switch filter.Sort {
+	case "oldest":
+		sort = bson.D{{Key: "created_at", Value: 1}}
+	case "highest":
+		sort = bson.D{{Key: "rating", Value: -1}, {Key: "created_at", Value: -1}}
+	case "lowest":
+		sort = bson.D{{Key: "rating", Value: 1}, {Key: "created_at", Value: -1}}
+	}
+
+	skip := (pagination.Page - 1) * pagination.Limit
+	opts := options.Find().
+		SetSort(append(sort, bson.E{Key: "_id", Value: -1})).
+		SetSkip(skip).
+		SetLimit(pagination.Limit)
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return []models.Review{}, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	reviews := []models.Review{}
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return []models.Review{}, 0, err
+	}
+	return reviews, total, nil
+}
+
+func (r *reviewRepository) Summary(ctx context.Context, restaurantID primitive.ObjectID) (*ReviewSummary, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"restaurant_id": restaurantID, "status": models.ReviewPublished}},
+		{"$group": bson.M{"_id": "$rating", "count": bson.M{"$sum": 1}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []struct {
+		Rating int   `bson:"_id"`
+		Count  int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, err
+	}
+
+	summary := &ReviewSummary{Histogram: map[string]int64{}}
+	for stars := 1; stars <= 5; stars++ {
+		summary.Histogram[strconv.Itoa(stars)] = 0
+	}
+	sum := int64(0)
+	for _, bucket := range buckets {
+		summary.Histogram[strconv.Itoa(bucket.Rating)] += bucket.Count
+		summary.Total += bucket.Count
+		sum += int64(bucket.Rating) * bucket.Count
+	}
+	if summary.Total > 0 {
+		summary.Average = float64(sum) / float64(summary.Total)
+	}
+	return summary, nil
+}
+
+func (r *reviewRepository) SetReply(ctx context.Context, id primitive.ObjectID, reply models.ReviewReply) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"reply": reply, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("review not found")
+	}
+	return nil
+}
+
+func (r *reviewRepository) AddFlag(ctx context.Context, id primitive.ObjectID, flag models.ReviewFlag) (bool, error) {
+	flag.CreatedAt = time.Now()
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "flags.user_id": bson.M{"$ne": flag.UserID}},
+		bson.M{
+			"$push": bson.M{"flags": flag},
+			"$inc":  bson.M{"flag_count": 1},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := r.FindByID(ctx, id); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+func (r *reviewRepository) Moderate(ctx context.Context, id primitive.ObjectID, status string, moderation models.ReviewModeration) error {
+	moderation.ModeratedAt = time.Now()
+	set := bson.M{
+		"moderation": moderation,
+		"flag_count": 0,
+		"updated_at": time.Now(),
+	}
+	if status != "" {
+		set["status"] = status
+	}
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": set, "$unset": bson.M{"flags": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("review not found")
+	}
+	return nil
+}
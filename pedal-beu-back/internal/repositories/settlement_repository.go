@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SettlementRepository stores settlement runs and the per-driver payout
+// batches they produce.
+type SettlementRepository interface {
+	CreateRun(ctx context.Context, run *models.SettlementRun) error
+	// SaveRun overwrites a run with its final figures and status.
+	SaveRun(ctx context.Context, run *models.SettlementRun) error
+	FindRunByID(ctx context.Context, id primitive.ObjectID) (*models.SettlementRun, error)
+	// FindRuns returns runs newest first.
+	FindRuns(ctx context.Context, pagination Pagination) ([]models.SettlementRun, int64, error)
+	CreateBatch(ctx context.Context, batch *models.PayoutBatch) error
+	// FindBatches returns a run's payout batches by driver name.
+	FindBatches(ctx context.Context, settlementID primitive.ObjectID) ([]models.PayoutBatch, error)
+}
+
+type settlementRepository struct {
+	runs    *mongo.Collection
+	batches *mongo.Collection
+}
+
+func NewSettlementRepository() SettlementRepository {
+	collections := database.GetCollections()
+	return &settlementRepository{
+		runs:    collections.SettlementRuns,
+		batches: collections.PayoutBatches,
+	}
+}
+
+func (r *settlementRepository) CreateRun(ctx context.Context, run *models.SettlementRun) error {
+	run.CreatedAt = time.Now()
+
+	result, err := r.runs.InsertOne(ctx, run)
+	if err != nil {
+		return err
+	}
+
+	run.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *settlementRepository) SaveRun(ctx context.Context, run *models.SettlementRun) error {
+	result, err := r.runs.ReplaceOne(ctx, bson.M{"_id": run.ID}, run)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("settlement run not found")
+	}
+	return nil
+}
+
+func (r *settlementRepository) FindRunByID(ctx context.Context, id primitive.ObjectID) (*models.SettlementRun, error) {
+	var run models.SettlementRun
+	err := r.runs.FindOne(ctx, bson.M{"_id": id}).Decode(&run)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("settlement run not found")
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *settlementRepository) FindRuns(ctx context.Context, pagination Pagination) ([]models.SettlementRun, int64, error) {
+	total, err := r.runs.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skip := (pagination.Page - 1) * pagination.Limit
+	opts := options.Find().
+		SetSkip(skip).
+		SetLimit(pagination.Limit).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.runs.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var runs []models.SettlementRun
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, 0, err
+	}
+	return runs, total, nil
+}
+
+func (r *settlementRepository) CreateBatch(ctx context.Context, batch *models.PayoutBatch) error {
+	batch.CreatedAt = time.Now()
+
+	result, err := r.batches.InsertOne(ctx, batch)
+	if err != nil {
+		return err
+	}
+
+	batch.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *settlementRepository) FindBatches(ctx context.Context, settlementID primitive.ObjectID) ([]models.PayoutBatch, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "driver_name", Value: 1}})
+	cursor, err := r.batches.Find(ctx, bson.M{"settlement_id": settlementID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var batches []models.PayoutBatch
+	if err := cursor.All(ctx, &batches); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
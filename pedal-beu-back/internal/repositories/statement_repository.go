@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StatementFilter narrows a statement listing; zero values match all.
+type StatementFilter struct {
+	RestaurantID *primitive.ObjectID
+	Status       string
+}
+
+type StatementRepository interface {
+	// Create inserts a statement, keeping its ID if it already has one.
+	Create(ctx context.Context, statement *models.RestaurantStatement) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.RestaurantStatement, error)
+	// Find returns statements newest first.
+	Find(ctx context.Context, filter StatementFilter, pagination Pagination) ([]models.RestaurantStatement, int64, error)
+	// AddAdjustment appends an adjustment to an unpaid statement and moves
+	// its adjustment total and net payable by the amount, returning the
+	// updated statement. It fails with ErrStatementPaid once it's paid.
+	AddAdjustment(ctx context.Context, id primitive.ObjectID, adjustment models.StatementAdjustment) (*models.RestaurantStatement, error)
+	// MarkPaid moves an unpaid statement to paid, returning the updated
+	// statement. It fails with ErrStatementPaid if it already was.
+	MarkPaid(ctx context.Context, id, adminID primitive.ObjectID, reference string, at time.Time) (*models.RestaurantStatement, error)
+}
+
+// ErrStatementPaid is returned when changing a statement that has already
+// been paid.
+var ErrStatementPaid = errors.New("statement has already been paid")
+
+type statementRepository struct {
+	collection *mongo.Collection
+}
+
+func NewStatementRepository() StatementRepository {
+	collections := database.GetCollections()
+	return &statementRepository{
+		collection: collections.Statements,
+	}
+}
+
+func (r *statementRepository) Create(ctx context.Context, statement *models.RestaurantStatement) error {
+	statement.CreatedAt = time.Now()
+	statement.UpdatedAt = statement.CreatedAt
+
+	result, err := r.collection.InsertOne(ctx, statement)
+	if err != nil {
+		return err
+	}
+
+	statement.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *statementRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.RestaurantStatement, error) {
+	var statement models.RestaurantStatement
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&statement)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("statement not found")
+		}
+		return nil, err
+	}
+	return &statement, nil
+}
+
+func (r *statementRepository) Find(ctx context.Context, filter StatementFilter, pagination Pagination) ([]models.RestaurantStatement, int64, error) {
+	query := bson.M{}
+	if filter.RestaurantID != nil {
+		query["restaurant_id"] = *filter.RestaurantID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skip := (pagination.Page - 1) * pagination.Limit
+	opts := options.Find().
+		SetSkip(skip).
+		SetLimit(pagination.Limit).
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetProjection(bson.M{"orders": 0})
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var statements []models.RestaurantStatement
+	if err := cursor.All(ctx, &statements); err != nil {
+		return nil, 0, err
+	}
+	return statements, total, nil
+}
+
+// updateUnpaid applies update to a statement only while it's unpaid.
+func (r *statementRepository) updateUnpaid(ctx context.Context, id primitive.ObjectID, update interface{}) (*models.RestaurantStatement, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var statement models.RestaurantStatement
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": models.StatementUnpaid}, update, opts).Decode(&statement)
+	if err == nil {
+		return &statement, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	if _, err := r.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return nil, ErrStatementPaid
+}
+
+// AddAdjustment runs as an update pipeline so the totals can be rounded
+// to the cent as they're moved.
+func (r *statementRepository) AddAdjustment(ctx context.Context, id primitive.ObjectID, adjustment models.StatementAdjustment) (*models.RestaurantStatement, error) {
+	addRounded := func(field string) bson.M {
+		return bson.M{"$round": bson.A{bson.M{"$add": bson.A{"$" + field, adjustment.Amount}}, 2}}
+	}
+	return r.updateUnpaid(ctx, id, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"adjustments": bson.M{"$concatArrays": bson.A{
+				bson.M{"$ifNull": bson.A{"$adjustments", bson.A{}}},
+				bson.A{bson.M{"$literal": adjustment}},
+			}},
+			"adjustment_total": addRounded("adjustment_total"),
+			"net_payable":      addRounded("net_payable"),
+			"updated_at":       time.Now(),
+		}}},
+	})
+}
+
+func (r *statementRepository) MarkPaid(ctx context.Context, id, adminID primitive.ObjectID, reference string, at time.Time) (*models.RestaurantStatement, error) {
+	set := bson.M{
+		"status":     models.StatementPaid,
+		"paid_at":    at,
+		"paid_by":    adminID,
+		"updated_at": time.Now(),
+	}
+	if reference != "" {
+		set["payment_reference"] = reference
+	}
+	return r.updateUnpaid(ctx, id, bson.M{"$set": set})
+}
@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RunInTransaction runs fn in a MongoDB transaction: the repositories'
+// writes made with the context fn is given are committed together, or
+// not at all if fn returns an error. fn may be run again if the
+// transaction hits a transient error, so it mustn't keep state between
+// runs. Transactions need a replica set, which Atlas always is.
+func RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := database.GetClient().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WalletRepository interface {
+	// FindByUserID returns the user's wallet projection, or nil if they've
+	// never had one.
+	FindByUserID(ctx context.Context, userID primitive.ObjectID) (*models.Wallet, error)
+	// LatestTransaction returns the user's last wallet transaction, or nil
+	// if they've had none.
+	LatestTransaction(ctx context.Context, userID primitive.ObjectID) (*models.WalletTransaction, error)
+	// FindTransactionByKey returns the transaction recorded under an
+	// idempotency key, or nil if there's none.
+	FindTransactionByKey(ctx context.Context, key string) (*models.WalletTransaction, error)
+	// AppendTransaction adds a transaction to the log. It fails with
+	// ErrWalletSeqTaken if another transaction took its Seq first, and
+	// with ErrDuplicateWalletTransaction if one with the same idempotency
+	// key was already recorded.
+	AppendTransaction(ctx context.Context, txn *models.WalletTransaction) error
+	// Project brings the user's wallet projection up to txn, unless it's
+	// already past it.
+	Project(ctx context.Context, txn *models.WalletTransaction) error
+	// FindTransactions returns a user's transactions, newest first.
+	FindTransactions(ctx context.Context, userID primitive.ObjectID, pagination Pagination) ([]models.WalletTransaction, int64, error)
+	// FindWithExpiredLots returns the wallets holding credit that expired
+	// before now.
+	FindWithExpiredLots(ctx context.Context, now time.Time) ([]models.Wallet, error)
+}
+
+var (
+	// ErrWalletSeqTaken is returned by AppendTransaction when another
+	// change to the same wallet got in first.
+	ErrWalletSeqTaken = errors.New("wallet changed concurrently")
+	// ErrDuplicateWalletTransaction is returned by AppendTransaction when
+	// the event the transaction records has already been recorded.
+	ErrDuplicateWalletTransaction = errors.New("wallet transaction already recorded")
+)
+
+type walletRepository struct {
+	wallets      *mongo.Collection
+	transactions *mongo.Collection
+}
+
+func NewWalletRepository() WalletRepository {
+	collections := database.GetCollections()
+	return &walletRepository{
+		wallets:      collections.Wallets,
+		transactions: collections.WalletEntries,
+	}
+}
+
+func (r *walletRepository) FindByUserID(ctx context.Context, userID primitive.ObjectID) (*models.Wallet, error) {
+	var wallet models.Wallet
+	err := r.wallets.FindOne(ctx, bson.M{"user_id": userID}).Decode(&wallet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (r *walletRepository) findTransaction(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) (*models.WalletTransaction, error) {
+	var txn models.WalletTransaction
+	err := r.transactions.FindOne(ctx, filter, opts...).Decode(&txn)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &txn, nil
+}
+
+func (r *walletRepository) LatestTransaction(ctx context.Context, userID primitive.ObjectID) (*models.WalletTransaction, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}})
+	return r.findTransaction(ctx, bson.M{"user_id": userID}, opts)
+}
+
+func (r *walletRepository) FindTransactionByKey(ctx context.Context, key string) (*models.WalletTransaction, error) {
+	return r.findTransaction(ctx, bson.M{"idempotency_key": key})
+}
+
+func (r *walletRepository) AppendTransaction(ctx context.Context, txn *models.WalletTransaction) error {
+	if txn.CreatedAt.IsZero() {
+		txn.CreatedAt = time.Now()
+	}
+
+	result, err := r.transactions.InsertOne(ctx, txn)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			if strings.Contains(err.Error(), "idempotency_key") {
+				return ErrDuplicateWalletTransaction
+			}
+			return ErrWalletSeqTaken
+		}
+		return err
+	}
+
+	txn.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *walletRepository) Project(ctx context.Context, txn *models.WalletTransaction) error {
+	lots := txn.Lots
+	if lots == nil {
+		lots = []models.WalletLot{}
+	}
+	_, err := r.wallets.UpdateOne(
+		ctx,
+		bson.M{"user_id": txn.UserID, "version": bson.M{"$lt": txn.Seq}},
+		bson.M{"$set": bson.M{
+			"balance":    txn.BalanceAfter,
+			"lots":       lots,
+			"version":    txn.Seq,
+			"updated_at": time.Now(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	// A duplicate key means the upsert found the projection already past
+	// txn and tried to insert a second one for the user.
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *walletRepository) FindTransactions(ctx context.Context, userID primitive.ObjectID, pagination Pagination) ([]models.WalletTransaction, int64, error) {
+	filter := bson.M{"user_id": userID}
+
+	total, err := r.transactions.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skip := (pagination.Page - 1) * pagination.Limit
+	opts := options.Find().
+		SetSkip(skip).
+		SetLimit(pagination.Limit).
+		SetSort(bson.D{{Key: "seq", Value: -1}})
+
+	cursor, err := r.transactions.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var txns []models.WalletTransaction
+	if err := cursor.All(ctx, &txns); err != nil {
+		return nil, 0, err
+	}
+	return txns, total, nil
+}
+
+func (r *walletRepository) FindWithExpiredLots(ctx context.Context, now time.Time) ([]models.Wallet, error) {
+	cursor, err := r.wallets.Find(ctx, bson.M{"lots.expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var wallets []models.Wallet
+	if err := cursor.All(ctx, &wallets); err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ZoneRepository interface {
+	Create(ctx context.Context, zone *models.ServiceZone) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.ServiceZone, error)
+	FindAll(ctx context.Context) ([]models.ServiceZone, error)
+	// FindContaining returns the active zones whose area contains the
+	// point, oldest first (so overlapping zones resolve deterministically).
+	FindContaining(ctx context.Context, point models.GeoLocation) ([]models.ServiceZone, error)
+	CountActive(ctx context.Context) (int64, error)
+	Replace(ctx context.Context, zone *models.ServiceZone) error
+	// UpdateLiveSurge stores the surge engine's latest reading without
+	// touching the admin-managed fields (or updated_at).
+	UpdateLiveSurge(ctx context.Context, id primitive.ObjectID, surge models.ZoneSurge) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type zoneRepository struct {
+	collection *mongo.Collection
+}
+
+func NewZoneRepository() ZoneRepository {
+	collections := database.GetCollections()
+	return &zoneRepository{
+		collection: collections.ServiceZones,
+	}
+}
+
+func (r *zoneRepository) Create(ctx context.Context, zone *models.ServiceZone) error {
+	zone.CreatedAt = time.Now()
+	zone.UpdatedAt = time.Now()
+	zone.Area.Type = "Polygon"
+
+	result, err := r.collection.InsertOne(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	zone.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *zoneRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.ServiceZone, error) {
+	var zone models.ServiceZone
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&zone)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("zone not found")
+		}
+		return nil, err
+	}
+	return &zone, nil
+}
+
+func (r *zoneRepository) FindAll(ctx context.Context) ([]models.ServiceZone, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	zones := []models.ServiceZone{}
+	if err := cursor.All(ctx, &zones); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+func (r *zoneRepository) FindContaining(ctx context.Context, point models.GeoLocation) ([]models.ServiceZone, error) {
+	filter := bson.M{
+		"is_active": true,
+		"area": bson.M{
+			"$geoIntersects": bson.M{
+				"$geometry": bson.M{
+					"type":        "Point",
+					"coordinates": point.Coordinates,
+				},
+			},
+		},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var zones []models.ServiceZone
+	if err := cursor.All(ctx, &zones); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+func (r *zoneRepository) CountActive(ctx context.Context) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"is_active": true})
+}
+
+func (r *zoneRepository) Replace(ctx context.Context, zone *models.ServiceZone) error {
+	zone.UpdatedAt = time.Now()
+	zone.Area.Type = "Polygon"
+
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": zone.ID}, zone)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("zone not found")
+	}
+	return nil
+}
+
+func (r *zoneRepository) UpdateLiveSurge(ctx context.Context, id primitive.ObjectID, surge models.ZoneSurge) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"live_surge": surge}},
+	)
+	return err
+}
+
+func (r *zoneRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("zone not found")
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	geofenceRestaurant = "restaurant"
+	geofenceDropoff    = "dropoff"
+)
+
+// GeofenceEvent is one arrival detected by ProcessDriverLocation. The
+// websocket layer broadcasts these as order:arrived (and, when the order
+// was auto-advanced, order:status_update) — services never talk to the
+// Hub directly.
+type GeofenceEvent struct {
+	OrderID    primitive.ObjectID `json:"orderId"`
+	CustomerID primitive.ObjectID `json:"customerId"`
+	DriverID   primitive.ObjectID `json:"driverId"`
+	Fence      string             `json:"fence"` // "restaurant" or "dropoff"
+	DistanceM  float64            `json:"distance_m"`
+	ArrivedAt  time.Time          `json:"arrived_at"`
+	// AdvancedTo is the status the order was automatically moved to, or
+	// empty if auto-advance is off or the transition wasn't allowed.
+	AdvancedTo models.OrderStatus `json:"advanced_to,omitempty"`
+}
+
+// geofenceTracker remembers when a driver first entered each order's
+// fence so an arrival only fires after they've dwelled there — a driver
+// riding past the restaurant on the way somewhere else shouldn't count.
+// It's in-memory on purpose: losing it on restart just means the dwell
+// clock starts again from the next ping, and RecordArrival on the order
+// itself is what guarantees an arrival only ever fires once.
+type geofenceTracker struct {
+	mu        sync.Mutex
+	enteredAt map[string]time.Time
+}
+
+func newGeofenceTracker() *geofenceTracker {
+	return &geofenceTracker{enteredAt: make(map[string]time.Time)}
+}
+
+// observe records whether the driver is inside the fence identified by key
+// and returns how long they've been continuously inside it (0 if outside).
+func (t *geofenceTracker) observe(key string, inside bool, at time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Orders that finish without ever triggering an arrival leave entries
+	// behind; nobody dwells at a restaurant for hours, so drop those.
+	for k, entered := range t.enteredAt {
+		if at.Sub(entered) > 6*time.Hour {
+			delete(t.enteredAt, k)
+		}
+	}
+
+	if !inside {
+		delete(t.enteredAt, key)
+		return 0
+	}
+	entered, ok := t.enteredAt[key]
+	if !ok {
+		t.enteredAt[key] = at
+		return 0
+	}
+	return at.Sub(entered)
+}
+
+func (t *geofenceTracker) forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.enteredAt, key)
+}
+
+// ProcessDriverLocation checks a driver's GPS ping against every active
+// order assigned to them. While the order is still heading to pickup the
+// fence is the restaurant (RestaurantLocation); once picked up it's the
+// customer's drop-off (DeliveryInfo.Address.Location). Once the driver has
+// been inside a fence for the configured dwell time, the arrival is
+// stamped on the order and returned — and if GEOFENCE_AUTO_ADVANCE is on,
+// the order is moved along with actor type "system", exactly as if the
+// driver had tapped "Arrived at Restaurant" / "Delivered" themselves.
+func (s *orderService) ProcessDriverLocation(ctx context.Context, driverID primitive.ObjectID, lat, lng float64) ([]GeofenceEvent, error) {
+	orders, err := s.orderRepo.FindActiveByDriverID(ctx, driverID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var events []GeofenceEvent
+
+	for _, order := range orders {
+		fence, center, radius := geofenceFor(&order)
+		if fence == "" || len(center.Coordinates) != 2 {
+			continue
+		}
+
+		distanceM := calculateDistance(lat, lng, center.Coordinates[1], center.Coordinates[0]) * 1000
+		key := order.ID.Hex() + ":" + fence
+		if s.geofence.observe(key, distanceM <= radius, now) < geofenceDwell() {
+			continue
+		}
+
+		recorded, err := s.orderRepo.RecordArrival(ctx, order.ID, fence, now)
+		s.geofence.forget(key)
+		if err != nil {
+			log.Printf("⚠️  Failed to record %s arrival for order %s: %v", fence, order.ID.Hex(), err)
+			continue
+		}
+		if !recorded {
+			continue
+		}
+
+		event := GeofenceEvent{
+			OrderID:    order.ID,
+			CustomerID: order.CustomerID,
+			DriverID:   driverID,
+			Fence:      fence,
+			DistanceM:  distanceM,
+			ArrivedAt:  now,
+		}
+
+		if geofenceAutoAdvance() {
+			next := geofenceNextStatus(fence)
+			// A cash order waits for the driver to confirm the cash
+			// before it can be delivered.
+			awaitingCash := next == models.OrderDelivered && isCashOrder(&order) && order.CashCollection == nil
+			if !awaitingCash && s.isValidStatusTransition(order.Status, next, "system") {
+				err := s.orderRepo.AdvanceStatus(ctx, order.ID, order.Status, models.OrderEvent{
+					Status:    next,
+					Timestamp: now,
+					ActorType: "system",
+					Notes:     fmt.Sprintf("Driver within %.0fm of %s for %s", distanceM, fence, geofenceDwell()),
+				})
+				if err != nil {
+					// Most likely the driver tapped the button themselves
+					// between our read and this write — the arrival is still
+					// worth reporting, there's just nothing to advance.
+					log.Printf("⚠️  Geofence auto-advance skipped for order %s: %v", order.ID.Hex(), err)
+				} else {
+					event.AdvancedTo = next
+					if next == models.OrderDelivered {
+						s.recordDelivery(ctx, &order, now)
+					}
+				}
+			}
+		}
+
+		log.Printf("📍 Driver %s arrived at %s for order %s (%.0fm)", driverID.Hex(), fence, order.ID.Hex(), distanceM)
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// flagDeliveredFarFromDropoff is called after a driver marks an order
+// delivered. It compares the driver's last known position against the
+// drop-off and, if they're further away than
+// GEOFENCE_DELIVERED_MAX_DISTANCE_M, flags the order for admin review. The
+// delivery itself is never blocked — GPS is noisy enough that refusing the
+// tap would strand honest drivers — it just leaves a trail.
+func (s *orderService) flagDeliveredFarFromDropoff(ctx context.Context, order *models.Order, driverID primitive.ObjectID) {
+	dropoff := order.DeliveryInfo.Address.Location
+	if len(dropoff.Coordinates) != 2 {
+		return
+	}
+
+	driver, err := s.driverRepo.FindByUserID(ctx, driverID)
+	if err != nil || driver == nil || len(driver.Location.Coordinates) != 2 {
+		return
+	}
+
+	distanceM := calculateDistance(
+		driver.Location.Coordinates[1], driver.Location.Coordinates[0],
+		dropoff.Coordinates[1], dropoff.Coordinates[0],
+	) * 1000
+	if distanceM <= geofenceDeliveredMaxDistance() {
+		return
+	}
+
+	flag := models.OrderFlag{
+		Type:      "delivered_far_from_dropoff",
+		Reason:    fmt.Sprintf("Marked delivered %.0fm from the drop-off (last driver location)", distanceM),
+		DistanceM: distanceM,
+		ActorID:   driverID,
+	}
+	if err := s.orderRepo.AddFlag(ctx, order.ID, flag); err != nil {
+		log.Printf("⚠️  Failed to flag order %s: %v", order.ID.Hex(), err)
+		return
+	}
+	log.Printf("⚠️  Order %s marked delivered %.0fm from drop-off by driver %s", order.ID.Hex(), distanceM, driverID.Hex())
+}
+
+// geofenceFor picks which fence applies to an order in its current state.
+// Returns an empty fence if the relevant arrival has already been recorded.
+func geofenceFor(order *models.Order) (string, models.GeoLocation, float64) {
+	switch order.Status {
+	case models.OrderAccepted, models.OrderPreparing, models.OrderReady:
+		if order.Arrivals != nil && order.Arrivals.Restaurant != nil {
+			return "", models.GeoLocation{}, 0
+		}
+		return geofenceRestaurant, order.RestaurantLocation, geofenceRestaurantRadius()
+	case models.OrderPickedUp, models.OrderOnTheWay:
+		if order.Arrivals != nil && order.Arrivals.Dropoff != nil {
+			return "", models.GeoLocation{}, 0
+		}
+		return geofenceDropoff, order.DeliveryInfo.Address.Location, geofenceDropoffRadius()
+	}
+	return "", models.GeoLocation{}, 0
+}
+
+func geofenceNextStatus(fence string) models.OrderStatus {
+	if fence == geofenceRestaurant {
+		return models.OrderReady
+	}
+	return models.OrderDelivered
+}
+
+func geofenceRestaurantRadius() float64 {
+	return envFloat("GEOFENCE_RESTAURANT_RADIUS_M", 75)
+}
+
+func geofenceDropoffRadius() float64 {
+	return envFloat("GEOFENCE_DROPOFF_RADIUS_M", 75)
+}
+
+func geofenceDeliveredMaxDistance() float64 {
+	return envFloat("GEOFENCE_DELIVERED_MAX_DISTANCE_M", 300)
+}
+
+func geofenceDwell() time.Duration {
+	return time.Duration(envFloat("GEOFENCE_DWELL_SECONDS", 20)) * time.Second
+}
+
+func geofenceAutoAdvance() bool {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv("GEOFENCE_AUTO_ADVANCE")))
+	return value == "true" || value == "1"
+}
+
+// envFloat reads a positive number from the environment, falling back to
+// def when it's unset or not a valid positive number.
+func envFloat(key string, def float64) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(key)), 64)
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
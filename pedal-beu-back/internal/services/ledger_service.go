@@ -0,0 +1,626 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LedgerService keeps the driver payout ledger: every delivery, tip,
+// bonus and cash deposit credits the driver's account, cash they collect,
+// penalties and refunds charged back to them debit it, and settlement runs
+// pay out what's owed and settle the entries behind it.
+type LedgerService interface {
+	// RecordDelivery credits a delivered order's earnings to its driver.
+	// Posting the same order twice is a no-op.
+	RecordDelivery(ctx context.Context, order *models.Order) error
+	// RecordTip credits an order's tip to its driver. Posting the same
+	// order twice is a no-op.
+	RecordTip(ctx context.Context, order *models.Order) error
+	// RecordRefundChargeback debits the delivery fee and tip a refund gave
+	// back to the customer from the order's driver. Posting the same
+	// refund twice is a no-op.
+	RecordRefundChargeback(ctx context.Context, refund *models.Refund) error
+	// RecordCashCollected debits the cash a driver took for a cash order
+	// and adds it to the cash they hold. Posting the same order twice is a
+	// no-op.
+	RecordCashCollected(ctx context.Context, order *models.Order, amount float64) error
+	// PostCashDeposit records cash the driver (by Driver document ID)
+	// handed in, crediting it back and taking it off their cash balance.
+	PostCashDeposit(ctx context.Context, driverID string, adminID primitive.ObjectID, req models.CashDepositRequest) (*models.LedgerEntry, error)
+	// GetCashAccount returns a driver's cash position by User._id;
+	// GetDriverCashAccount by Driver document ID.
+	GetCashAccount(ctx context.Context, userID primitive.ObjectID) (*CashAccount, error)
+	GetDriverCashAccount(ctx context.Context, driverID string) (*CashAccount, error)
+	SetCashLimit(ctx context.Context, driverID string, limit *float64) (*CashAccount, error)
+	// PostAdjustment records an admin's bonus or penalty for the driver
+	// whose Driver document ID is driverID.
+	PostAdjustment(ctx context.Context, driverID string, adminID primitive.ObjectID, req models.LedgerAdjustmentRequest) (*models.LedgerEntry, error)
+	// GetDriverLedger returns a page of the driver's entries, newest
+	// first, and their unsettled balance. driverID is the Driver document
+	// ID, as in the other admin driver routes.
+	GetDriverLedger(ctx context.Context, driverID string, page, limit int64) ([]models.LedgerEntry, int64, float64, error)
+	// GetDriverEntries returns a driver's latest entries by User._id.
+	GetDriverEntries(ctx context.Context, userID primitive.ObjectID, limit int64) ([]models.LedgerEntry, error)
+	GetPayoutAccount(ctx context.Context, userID primitive.ObjectID) (*models.PayoutAccount, error)
+	UpdatePayoutAccount(ctx context.Context, userID primitive.ObjectID, account models.PayoutAccount) (*models.PayoutAccount, error)
+	RunSettlement(ctx context.Context, adminID primitive.ObjectID, req models.RunSettlementRequest) (*models.SettlementRun, error)
+	GetSettlements(ctx context.Context, page, limit int64) ([]models.SettlementRun, int64, error)
+	GetSettlement(ctx context.Context, settlementID string) (*models.SettlementRun, []models.PayoutBatch, error)
+}
+
+type ledgerService struct {
+	ledgerRepo     repositories.LedgerRepository
+	settlementRepo repositories.SettlementRepository
+	driverRepo     repositories.DriverRepository
+	userRepo       repositories.UserRepository
+}
+
+func NewLedgerService(
+	ledgerRepo repositories.LedgerRepository,
+	settlementRepo repositories.SettlementRepository,
+	driverRepo repositories.DriverRepository,
+	userRepo repositories.UserRepository,
+) LedgerService {
+	return &ledgerService{
+		ledgerRepo:     ledgerRepo,
+		settlementRepo: settlementRepo,
+		driverRepo:     driverRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// driverLedgerAccount is a driver's own account in the ledger.
+func driverLedgerAccount(userID primitive.ObjectID) string {
+	return "driver:" + userID.Hex()
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func isCashOrder(order *models.Order) bool {
+	return strings.EqualFold(strings.TrimSpace(order.PaymentMethod), "cash")
+}
+
+// CashAccount is a driver's cash-on-delivery position: the cash they hold
+// for the platform and the limit past which they're offered no more cash
+// orders.
+type CashAccount struct {
+	Balance        float64 `json:"cash_balance"`
+	Limit          float64 `json:"cash_limit"`
+	LimitIsDefault bool    `json:"limit_is_default"`
+	OverLimit      bool    `json:"over_limit"`
+}
+
+// driverCashLimit is the driver's own cash limit, or DRIVER_CASH_LIMIT
+// (default 3000 ETB) if they have none.
+func driverCashLimit(driver *models.Driver) float64 {
+	if driver.CashLimit != nil {
+		return *driver.CashLimit
+	}
+	return envFloat("DRIVER_CASH_LIMIT", 3000)
+}
+
+// cashLimitReached reports whether the driver holds too much cash to take
+// another cash order.
+func cashLimitReached(driver *models.Driver) bool {
+	return driver.CashBalance >= driverCashLimit(driver)
+}
+
+func cashAccountOf(driver *models.Driver) *CashAccount {
+	return &CashAccount{
+		Balance:        roundMoney(driver.CashBalance),
+		Limit:          driverCashLimit(driver),
+		LimitIsDefault: driver.CashLimit == nil,
+		OverLimit:      cashLimitReached(driver),
+	}
+}
+
+// credit posts an entry that adds amount to what the platform owes the
+// driver, taken from the platform account `from`.
+func (s *ledgerService) credit(ctx context.Context, entry models.LedgerEntry, from string) (*models.LedgerEntry, error) {
+	entry.DebitAccount = from
+	entry.CreditAccount = driverLedgerAccount(entry.DriverID)
+	entry.Net = entry.Amount
+	return s.post(ctx, entry)
+}
+
+// debit posts an entry that takes amount off what the platform owes the
+// driver, into the platform account `to`.
+func (s *ledgerService) debit(ctx context.Context, entry models.LedgerEntry, to string) (*models.LedgerEntry, error) {
+	entry.DebitAccount = driverLedgerAccount(entry.DriverID)
+	entry.CreditAccount = to
+	entry.Net = -entry.Amount
+	return s.post(ctx, entry)
+}
+
+// post writes the entry and moves the driver's pending balance
+// (earnings.pending) by its net, and their cash balance by any cash it
+// collects or deposits. A driver's cash balance is thus always the cash
+// collected less cash deposited across their unsettled entries.
+func (s *ledgerService) post(ctx context.Context, entry models.LedgerEntry) (*models.LedgerEntry, error) {
+	entry.Amount = roundMoney(entry.Amount)
+	entry.Net = roundMoney(entry.Net)
+	if err := s.ledgerRepo.Create(ctx, &entry); err != nil {
+		return nil, err
+	}
+	if err := s.driverRepo.AddPendingEarnings(ctx, entry.DriverID, entry.Net); err != nil {
+		fmt.Printf("⚠️  Failed to update pending earnings for driver %s: %v\n", entry.DriverID.Hex(), err)
+	}
+
+	cash := 0.0
+	switch entry.Type {
+	case models.LedgerCashCollected:
+		cash = entry.Amount
+	case models.LedgerCashDeposit:
+		cash = -entry.Amount
+	}
+	if cash != 0 {
+		if err := s.driverRepo.AddCashBalance(ctx, entry.DriverID, cash); err != nil {
+			fmt.Printf("⚠️  Failed to update cash balance for driver %s: %v\n", entry.DriverID.Hex(), err)
+		}
+	}
+	return &entry, nil
+}
+
+func (s *ledgerService) RecordDelivery(ctx context.Context, order *models.Order) error {
+	if order.DriverID == nil {
+		return nil
+	}
+	orderID := order.ID
+
+	earned := order.TotalAmount.DeliveryFee + order.TotalAmount.SurgeFee
+	if earned > 0 {
+		_, err := s.credit(ctx, models.LedgerEntry{
+			DriverID:       *order.DriverID,
+			Type:           models.LedgerDelivery,
+			Amount:         earned,
+			SurgeBonus:     order.TotalAmount.SurgeFee,
+			OrderID:        &orderID,
+			OrderNumber:    order.OrderNumber,
+			Description:    "Delivery " + order.OrderNumber,
+			IdempotencyKey: "delivery:" + orderID.Hex(),
+		}, models.LedgerAccountDeliveryFees)
+		if err != nil && !errors.Is(err, repositories.ErrDuplicateLedgerEntry) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ledgerService) RecordTip(ctx context.Context, order *models.Order) error {
+	if order.DriverID == nil || roundMoney(order.TotalAmount.Tip) <= 0 {
+		return nil
+	}
+	orderID := order.ID
+
+	_, err := s.credit(ctx, models.LedgerEntry{
+		DriverID:       *order.DriverID,
+		Type:           models.LedgerTip,
+		Amount:         order.TotalAmount.Tip,
+		OrderID:        &orderID,
+		OrderNumber:    order.OrderNumber,
+		Description:    "Tip for " + order.OrderNumber,
+		IdempotencyKey: "tip:" + orderID.Hex(),
+	}, models.LedgerAccountTips)
+	if err != nil && !errors.Is(err, repositories.ErrDuplicateLedgerEntry) {
+		return err
+	}
+	return nil
+}
+
+func (s *ledgerService) RecordRefundChargeback(ctx context.Context, refund *models.Refund) error {
+	amount := roundMoney(refund.Delivery + refund.Tip)
+	if refund.DriverID == nil || amount <= 0 {
+		return nil
+	}
+	orderID := refund.OrderID
+
+	_, err := s.debit(ctx, models.LedgerEntry{
+		DriverID:       *refund.DriverID,
+		Type:           models.LedgerRefund,
+		Amount:         amount,
+		OrderID:        &orderID,
+		OrderNumber:    refund.OrderNumber,
+		Description:    "Refund on " + refund.OrderNumber,
+		IdempotencyKey: "refund:" + refund.ID.Hex(),
+	}, models.LedgerAccountRefunds)
+	if err != nil && !errors.Is(err, repositories.ErrDuplicateLedgerEntry) {
+		return err
+	}
+	return nil
+}
+
+func (s *ledgerService) RecordCashCollected(ctx context.Context, order *models.Order, amount float64) error {
+	if order.DriverID == nil || roundMoney(amount) <= 0 {
+		return nil
+	}
+	orderID := order.ID
+
+	_, err := s.debit(ctx, models.LedgerEntry{
+		DriverID:       *order.DriverID,
+		Type:           models.LedgerCashCollected,
+		Amount:         amount,
+		OrderID:        &orderID,
+		OrderNumber:    order.OrderNumber,
+		Description:    "Cash collected for " + order.OrderNumber,
+		IdempotencyKey: "cash:" + orderID.Hex(),
+	}, models.LedgerAccountCash)
+	if err != nil && !errors.Is(err, repositories.ErrDuplicateLedgerEntry) {
+		return err
+	}
+	return nil
+}
+
+func (s *ledgerService) PostCashDeposit(ctx context.Context, driverID string, adminID primitive.ObjectID, req models.CashDepositRequest) (*models.LedgerEntry, error) {
+	userID, err := s.driverUserID(ctx, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if roundMoney(req.Amount) <= 0 {
+		return nil, errors.New("amount must be at least 0.01")
+	}
+
+	description := "Cash deposit"
+	if reference := strings.TrimSpace(req.Reference); reference != "" {
+		description += " (ref " + reference + ")"
+	}
+	if note := strings.TrimSpace(req.Note); note != "" {
+		description += ": " + note
+	}
+	return s.credit(ctx, models.LedgerEntry{
+		DriverID:    userID,
+		Type:        models.LedgerCashDeposit,
+		Amount:      req.Amount,
+		Description: description,
+		CreatedBy:   &adminID,
+	}, models.LedgerAccountCash)
+}
+
+func (s *ledgerService) GetCashAccount(ctx context.Context, userID primitive.ObjectID) (*CashAccount, error) {
+	driver, err := s.driverRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("driver not found")
+	}
+	return cashAccountOf(driver), nil
+}
+
+func (s *ledgerService) GetDriverCashAccount(ctx context.Context, driverID string) (*CashAccount, error) {
+	userID, err := s.driverUserID(ctx, driverID)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCashAccount(ctx, userID)
+}
+
+func (s *ledgerService) SetCashLimit(ctx context.Context, driverID string, limit *float64) (*CashAccount, error) {
+	userID, err := s.driverUserID(ctx, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if limit != nil {
+		if *limit < 0 {
+			return nil, errors.New("cash_limit can't be negative")
+		}
+		rounded := roundMoney(*limit)
+		limit = &rounded
+	}
+	if err := s.driverRepo.SetCashLimit(ctx, userID, limit); err != nil {
+		return nil, err
+	}
+	return s.GetCashAccount(ctx, userID)
+}
+
+// driverUserID resolves a Driver document ID (what the admin driver routes
+// take) to the User._id the ledger is keyed on.
+func (s *ledgerService) driverUserID(ctx context.Context, driverID string) (primitive.ObjectID, error) {
+	objectID, err := primitive.ObjectIDFromHex(driverID)
+	if err != nil {
+		return primitive.NilObjectID, errors.New("invalid driver ID")
+	}
+	driver, err := s.driverRepo.FindByID(ctx, objectID)
+	if err != nil {
+		return primitive.NilObjectID, errors.New("driver not found")
+	}
+	return driver.UserID, nil
+}
+
+func (s *ledgerService) PostAdjustment(ctx context.Context, driverID string, adminID primitive.ObjectID, req models.LedgerAdjustmentRequest) (*models.LedgerEntry, error) {
+	userID, err := s.driverUserID(ctx, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if roundMoney(req.Amount) <= 0 {
+		return nil, errors.New("amount must be at least 0.01")
+	}
+	description := strings.TrimSpace(req.Description)
+	if description == "" {
+		return nil, errors.New("description is required")
+	}
+
+	entry := models.LedgerEntry{
+		DriverID:    userID,
+		Type:        req.Type,
+		Amount:      req.Amount,
+		Description: description,
+		CreatedBy:   &adminID,
+	}
+	switch req.Type {
+	case models.LedgerBonus:
+		return s.credit(ctx, entry, models.LedgerAccountBonuses)
+	case models.LedgerPenalty:
+		return s.debit(ctx, entry, models.LedgerAccountPenalties)
+	default:
+		return nil, errors.New("type must be bonus or penalty")
+	}
+}
+
+func (s *ledgerService) GetDriverLedger(ctx context.Context, driverID string, page, limit int64) ([]models.LedgerEntry, int64, float64, error) {
+	userID, err := s.driverUserID(ctx, driverID)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	entries, total, err := s.ledgerRepo.FindByDriver(ctx, userID, listPagination(page, limit))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	balance, err := s.ledgerRepo.Balance(ctx, userID)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return entries, total, roundMoney(balance), nil
+}
+
+func (s *ledgerService) GetDriverEntries(ctx context.Context, userID primitive.ObjectID, limit int64) ([]models.LedgerEntry, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 5
+	}
+	entries, _, err := s.ledgerRepo.FindByDriver(ctx, userID, repositories.Pagination{Page: 1, Limit: limit})
+	return entries, err
+}
+
+func (s *ledgerService) GetPayoutAccount(ctx context.Context, userID primitive.ObjectID) (*models.PayoutAccount, error) {
+	driver, err := s.driverRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("driver not found")
+	}
+	return driver.PayoutAccount, nil
+}
+
+func (s *ledgerService) UpdatePayoutAccount(ctx context.Context, userID primitive.ObjectID, account models.PayoutAccount) (*models.PayoutAccount, error) {
+	account.AccountName = strings.TrimSpace(account.AccountName)
+	if account.AccountName == "" {
+		return nil, errors.New("account_name is required")
+	}
+
+	switch account.Method {
+	case "bank":
+		account.BankName = strings.TrimSpace(account.BankName)
+		account.AccountNumber = strings.ReplaceAll(strings.TrimSpace(account.AccountNumber), " ", "")
+		if account.BankName == "" || account.AccountNumber == "" {
+			return nil, errors.New("bank_name and account_number are required for bank payouts")
+		}
+		account.Phone = ""
+	case "telebirr":
+		if strings.TrimSpace(account.Phone) == "" {
+			return nil, errors.New("phone is required for telebirr payouts")
+		}
+		account.Phone = normalizePhone(account.Phone)
+		account.BankName, account.AccountNumber = "", ""
+	default:
+		return nil, errors.New("method must be bank or telebirr")
+	}
+
+	if err := s.driverRepo.SetPayoutAccount(ctx, userID, account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// RunSettlement settles every unsettled entry posted in the period. The
+// entries are claimed for the run first, so two runs can never settle the
+// same entry; drivers who end up owed nothing, owe the platform, or have
+// no payout account get their entries handed back for a later run.
+func (s *ledgerService) RunSettlement(ctx context.Context, adminID primitive.ObjectID, req models.RunSettlementRequest) (*models.SettlementRun, error) {
+	from, to, err := parsePeriod(req.PeriodStart, req.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &models.SettlementRun{
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Status:      models.SettlementProcessing,
+		CreatedBy:   adminID,
+	}
+	if err := s.settlementRepo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if _, err := s.ledgerRepo.ClaimUnsettled(ctx, run.ID, from, to, now); err != nil {
+		return s.failSettlement(ctx, run, nil, err)
+	}
+	totals, err := s.ledgerRepo.SettlementTotals(ctx, run.ID)
+	if err != nil {
+		return s.failSettlement(ctx, run, nil, err)
+	}
+
+	period := from.Format("Jan 2") + " – " + to.AddDate(0, 0, -1).Format("Jan 2, 2006")
+	for i, total := range totals {
+		amount := roundMoney(total.Net)
+		skip := ""
+		var driver *models.Driver
+		switch {
+		case amount < 0:
+			skip = fmt.Sprintf("driver owes %.2f", -amount)
+		case amount == 0:
+			skip = "nothing owed"
+		default:
+			driver, err = s.driverRepo.FindByUserID(ctx, total.DriverID)
+			if err != nil {
+				skip = "driver not found"
+			} else if driver.PayoutAccount == nil {
+				skip = "no payout account"
+			}
+		}
+		if skip != "" {
+			if err := s.ledgerRepo.Release(ctx, run.ID, total.DriverID); err != nil {
+				return s.failSettlement(ctx, run, totals[i:], err)
+			}
+			run.Skipped = append(run.Skipped, models.SettlementSkip{DriverID: total.DriverID, Balance: amount, Reason: skip})
+			continue
+		}
+
+		runID := run.ID
+		payout, err := s.debit(ctx, models.LedgerEntry{
+			DriverID:       total.DriverID,
+			Type:           models.LedgerPayout,
+			Amount:         amount,
+			Description:    "Payout for " + period,
+			IdempotencyKey: "payout:" + runID.Hex() + ":" + total.DriverID.Hex(),
+			SettlementID:   &runID,
+			SettledAt:      &now,
+			CreatedBy:      &adminID,
+		}, models.LedgerAccountPayouts)
+		if err != nil {
+			return s.failSettlement(ctx, run, totals[i:], err)
+		}
+
+		batch := &models.PayoutBatch{
+			SettlementID:  run.ID,
+			DriverID:      total.DriverID,
+			DriverName:    driver.PayoutAccount.AccountName,
+			Amount:        amount,
+			EntryCount:    total.Entries,
+			Account:       *driver.PayoutAccount,
+			LedgerEntryID: payout.ID,
+		}
+		if user, err := s.userRepo.FindByID(ctx, total.DriverID); err == nil && user != nil {
+			batch.DriverName = userDisplayName(user)
+		}
+		if err := s.settlementRepo.CreateBatch(ctx, batch); err != nil {
+			return s.failSettlement(ctx, run, totals[i+1:], err)
+		}
+		if err := s.driverRepo.RecordPayout(ctx, total.DriverID, now); err != nil {
+			fmt.Printf("⚠️  Failed to stamp last payout for driver %s: %v\n", total.DriverID.Hex(), err)
+		}
+		// The cash still held from these entries has been netted off the
+		// payout, so the driver no longer owes it.
+		if cash := roundMoney(total.Cash); cash != 0 {
+			if err := s.driverRepo.AddCashBalance(ctx, total.DriverID, -cash); err != nil {
+				fmt.Printf("⚠️  Failed to update cash balance for driver %s: %v\n", total.DriverID.Hex(), err)
+			}
+		}
+
+		run.PayoutCount++
+		run.EntryCount += total.Entries
+		run.TotalAmount = roundMoney(run.TotalAmount + amount)
+	}
+
+	completedAt := time.Now()
+	run.Status = models.SettlementCompleted
+	run.CompletedAt = &completedAt
+	if err := s.settlementRepo.SaveRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// failSettlement marks a run failed and hands back the entries of the
+// drivers it hadn't paid yet, so they aren't stuck settled with no payout.
+func (s *ledgerService) failSettlement(ctx context.Context, run *models.SettlementRun, unpaid []repositories.SettlementTotal, cause error) (*models.SettlementRun, error) {
+	for _, total := range unpaid {
+		if err := s.ledgerRepo.Release(ctx, run.ID, total.DriverID); err != nil {
+			fmt.Printf("⚠️  Failed to release ledger entries of driver %s from settlement %s: %v\n", total.DriverID.Hex(), run.ID.Hex(), err)
+		}
+	}
+	run.Status = models.SettlementFailed
+	run.Error = cause.Error()
+	if err := s.settlementRepo.SaveRun(ctx, run); err != nil {
+		fmt.Printf("⚠️  Failed to save settlement %s: %v\n", run.ID.Hex(), err)
+	}
+	return nil, cause
+}
+
+func (s *ledgerService) GetSettlements(ctx context.Context, page, limit int64) ([]models.SettlementRun, int64, error) {
+	return s.settlementRepo.FindRuns(ctx, listPagination(page, limit))
+}
+
+func (s *ledgerService) GetSettlement(ctx context.Context, settlementID string) (*models.SettlementRun, []models.PayoutBatch, error) {
+	objectID, err := primitive.ObjectIDFromHex(settlementID)
+	if err != nil {
+		return nil, nil, errors.New("invalid settlement ID")
+	}
+	run, err := s.settlementRepo.FindRunByID(ctx, objectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	batches, err := s.settlementRepo.FindBatches(ctx, objectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return run, batches, nil
+}
+
+// WritePayoutFile writes a settlement's payouts as a bulk-payment CSV for
+// the given channel: "bank" lists the bank-account payouts for the bank's
+// bulk transfer upload, "telebirr" the wallet payouts for telebirr's bulk
+// payment upload. Each row's reference is the payout batch ID, so
+// payments can be matched back to it.
+func WritePayoutFile(w io.Writer, format string, batches []models.PayoutBatch) error {
+	writer := csv.NewWriter(w)
+	switch format {
+	case "bank":
+		if err := writer.Write([]string{"account_name", "bank_name", "account_number", "amount", "reference"}); err != nil {
+			return err
+		}
+		for _, batch := range batches {
+			if batch.Account.Method != "bank" {
+				continue
+			}
+			if err := writer.Write([]string{
+				batch.Account.AccountName,
+				batch.Account.BankName,
+				batch.Account.AccountNumber,
+				fmt.Sprintf("%.2f", batch.Amount),
+				batch.ID.Hex(),
+			}); err != nil {
+				return err
+			}
+		}
+	case "telebirr":
+		if err := writer.Write([]string{"phone", "name", "amount", "reference"}); err != nil {
+			return err
+		}
+		for _, batch := range batches {
+			if batch.Account.Method != "telebirr" {
+				continue
+			}
+			if err := writer.Write([]string{
+				batch.Account.Phone,
+				batch.Account.AccountName,
+				fmt.Sprintf("%.2f", batch.Amount),
+				batch.ID.Hex(),
+			}); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New("format must be bank or telebirr")
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LocationAnomalyDetector sanity-checks driver GPS pings before anything
+// trusts them. Pings arrive as raw lat/lng floats over the socket, so a
+// mock-location app can put a driver anywhere — including parked on a
+// restaurant's doorstep to trip the geofence. Flagged pings are still
+// reported to admins, but the websocket layer doesn't persist them as the
+// driver's location (which ETA and the delivered-distance check read) or
+// feed them to the geofence engine.
+type LocationAnomalyDetector interface {
+	Check(ctx context.Context, driverID primitive.ObjectID, lat, lng float64, at time.Time) *LocationCheck
+	// LastPingFlagged reports whether the driver's most recent ping was
+	// flagged, for callers that relay the driver's position elsewhere
+	// (e.g. the per-order location_update stream).
+	LastPingFlagged(driverID primitive.ObjectID) bool
+}
+
+// LocationCheck is the verdict on one ping. RiskScore is only meaningful
+// when Anomalies is non-empty (it's the driver's score after this ping).
+type LocationCheck struct {
+	Anomalies []models.GPSAnomaly
+	RiskScore float64
+}
+
+func (c *LocationCheck) Flagged() bool {
+	return c != nil && len(c.Anomalies) > 0
+}
+
+// driverPingState is what the detector remembers per driver between pings.
+// lastAccepted is the reference for speed/teleport checks — comparing
+// against the last *good* ping rather than the last raw one means a spoofed
+// jump can't become the baseline for the next ping, however many fake
+// pings follow it. A genuine jump (tunnel, GPS dropout) heals on its own:
+// once enough time has passed since the anchor, the new position is
+// within reach of it and stops being flagged.
+//
+// The one exception is an anchor nothing has vouched for yet — the first
+// ping of a session, which may be a bad cold-start fix. Until a plausible
+// ping follows it, GPS_REANCHOR_PINGS flagged pings in a row that agree
+// with each other move the anchor to them. A jump is one episode, and
+// only its first ping adds to the driver's risk score.
+type driverPingState struct {
+	lastAccepted    *gpsPing
+	anchorTrusted   bool // a plausible ping has followed lastAccepted
+	lastRaw         *gpsPing
+	repeatCount     int
+	agreeing        int  // flagged pings in a row consistent with the one before
+	inEpisode       bool // a movement anomaly has been scored since the last good ping
+	lastPingFlagged bool
+}
+
+type gpsPing struct {
+	lat, lng float64
+	at       time.Time
+}
+
+type locationAnomalyDetector struct {
+	driverRepo repositories.DriverRepository
+
+	mu        sync.Mutex
+	states    map[primitive.ObjectID]*driverPingState
+	lastSweep time.Time
+}
+
+func NewLocationAnomalyDetector(driverRepo repositories.DriverRepository) LocationAnomalyDetector {
+	return &locationAnomalyDetector{
+		driverRepo: driverRepo,
+		states:     make(map[primitive.ObjectID]*driverPingState),
+	}
+}
+
+// anomalyWeights is how much each kind of anomaly adds to a driver's risk
+// score. Teleports and out-of-area points are the classic mock-location
+// signatures; a single fast hop between two pings is more often GPS noise.
+var anomalyWeights = map[string]float64{
+	"teleport":             25,
+	"out_of_service_area":  20,
+	"impossible_speed":     15,
+	"repeated_coordinates": 10,
+}
+
+func (d *locationAnomalyDetector) Check(ctx context.Context, driverID primitive.ObjectID, lat, lng float64, at time.Time) *LocationCheck {
+	anomalies, scored := d.evaluate(driverID, lat, lng, at)
+	if len(anomalies) == 0 {
+		return &LocationCheck{}
+	}
+
+	var score float64
+	if len(scored) > 0 {
+		score = d.raiseRiskScore(ctx, driverID, scored, at)
+	} else if driver, err := d.driverRepo.FindByUserID(ctx, driverID); err == nil && driver != nil {
+		score = CurrentRiskScore(driver, at)
+	}
+	for _, a := range anomalies {
+		log.Printf("⚠️  GPS anomaly for driver %s: %s (%s) — risk %.0f", driverID.Hex(), a.Type, a.Details, score)
+	}
+	return &LocationCheck{Anomalies: anomalies, RiskScore: score}
+}
+
+func (d *locationAnomalyDetector) LastPingFlagged(driverID primitive.ObjectID) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	state, ok := d.states[driverID]
+	return ok && state.lastPingFlagged
+}
+
+// evaluate runs the plausibility checks and updates the in-memory state.
+// It returns every anomaly in the ping, and those of them that should add
+// to the risk score: not the later pings of a movement episode that has
+// already been scored.
+func (d *locationAnomalyDetector) evaluate(driverID primitive.ObjectID, lat, lng float64, at time.Time) ([]models.GPSAnomaly, []models.GPSAnomaly) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.evictIdle(at)
+	state, ok := d.states[driverID]
+	if !ok {
+		state = &driverPingState{}
+		d.states[driverID] = state
+	}
+
+	ping := &gpsPing{lat: lat, lng: lng, at: at}
+	var anomalies, scored []models.GPSAnomaly
+	flag := func(kind, details string, score bool) {
+		anomaly := models.GPSAnomaly{
+			Type:       kind,
+			Details:    details,
+			Lat:        lat,
+			Lng:        lng,
+			DetectedAt: at,
+		}
+		anomalies = append(anomalies, anomaly)
+		if score {
+			scored = append(scored, anomaly)
+		}
+	}
+
+	if math.Abs(lat) > 90 || math.Abs(lng) > 180 || (lat == 0 && lng == 0) {
+		flag("out_of_service_area", "coordinates are not a real position", true)
+	} else if distanceKm := calculateDistance(lat, lng, gpsServiceAreaLat(), gpsServiceAreaLng()); distanceKm > gpsServiceAreaRadiusKm() {
+		flag("out_of_service_area", fmt.Sprintf("%.1fkm from the service area centre", distanceKm), true)
+	}
+
+	reanchored := false
+	if kind, details := movementAnomaly(state.lastAccepted, ping); kind == "" {
+		state.agreeing = 0
+	} else {
+		if next, _ := movementAnomaly(state.lastRaw, ping); next == "" {
+			state.agreeing++
+		} else {
+			state.agreeing = 0
+		}
+		if !state.anchorTrusted && state.agreeing >= gpsReanchorPings() {
+			reanchored = true
+		} else {
+			flag(kind, details, !state.inEpisode)
+			state.inEpisode = true
+		}
+	}
+
+	// Real GPS jitters in the last decimal places even when standing still;
+	// a long run of bit-for-bit identical fixes is what mock-location apps
+	// send. Flag when the run hits the limit, then again each time it
+	// doubles, rather than on every single repeat.
+	if prev := state.lastRaw; prev != nil && prev.lat == lat && prev.lng == lng {
+		state.repeatCount++
+	} else {
+		state.repeatCount = 0
+	}
+	if limit := gpsRepeatLimit(); state.repeatCount >= limit && state.repeatCount%limit == 0 {
+		flag("repeated_coordinates", fmt.Sprintf("%d identical fixes in a row", state.repeatCount+1), true)
+	}
+
+	state.lastRaw = ping
+	state.lastPingFlagged = len(anomalies) > 0
+	if reanchored {
+		// The pings agree the driver is here now, not at the unvouched
+		// anchor. Move it, but leave it untrusted: it's only the pings'
+		// word, and if this one is flagged for something else the episode
+		// goes on.
+		state.lastAccepted = ping
+		state.anchorTrusted = false
+		state.agreeing = 0
+		state.inEpisode = len(anomalies) > 0
+	} else if len(anomalies) == 0 {
+		state.anchorTrusted = state.lastAccepted != nil
+		state.lastAccepted = ping
+		state.agreeing = 0
+		state.inEpisode = false
+	}
+	return anomalies, scored
+}
+
+// evictIdle forgets drivers who haven't pinged for GPS_IDLE_MINUTES, at
+// most once a minute. A driver coming back starts a new session, with an
+// untrusted anchor.
+func (d *locationAnomalyDetector) evictIdle(now time.Time) {
+	if now.Sub(d.lastSweep) < time.Minute {
+		return
+	}
+	d.lastSweep = now
+	idle := gpsIdleTimeout()
+	for driverID, state := range d.states {
+		if state.lastRaw == nil || now.Sub(state.lastRaw.at) > idle {
+			delete(d.states, driverID)
+		}
+	}
+}
+
+// movementAnomaly checks the hop from one ping to the next, returning the
+// kind of anomaly it is and its details, or "" if it's plausible (or
+// there's no earlier ping).
+func movementAnomaly(from, to *gpsPing) (string, string) {
+	if from == nil {
+		return "", ""
+	}
+	distanceM := calculateDistance(from.lat, from.lng, to.lat, to.lng) * 1000
+	elapsed := to.at.Sub(from.at)
+	switch {
+	case distanceM > gpsTeleportDistanceM() && elapsed < gpsTeleportWindow():
+		return "teleport", fmt.Sprintf("moved %.0fm in %s", distanceM, elapsed.Round(time.Second))
+	case elapsed > 0 && distanceM/elapsed.Hours()/1000 > gpsMaxSpeedKmh():
+		return "impossible_speed", fmt.Sprintf("%.0fkm/h over %.0fm", distanceM/elapsed.Hours()/1000, distanceM)
+	}
+	return "", ""
+}
+
+// CurrentRiskScore is the driver's stored risk score decayed by the time
+// since their last anomaly (half-life GPS_RISK_HALF_LIFE_HOURS). The
+// stored score is only rewritten when an anomaly raises it, so anything
+// showing a driver's risk should read it through this.
+func CurrentRiskScore(driver *models.Driver, at time.Time) float64 {
+	score := driver.RiskScore
+	if driver.LastGPSAnomaly != nil {
+		halfLives := at.Sub(driver.LastGPSAnomaly.DetectedAt).Hours() / gpsRiskHalfLifeHours()
+		if halfLives > 0 {
+			score *= math.Pow(0.5, halfLives)
+		}
+	}
+	return math.Round(score*100) / 100
+}
+
+// raiseRiskScore adds this ping's weight to the driver's current score
+// and persists the result, capped at 100.
+func (d *locationAnomalyDetector) raiseRiskScore(ctx context.Context, driverID primitive.ObjectID, anomalies []models.GPSAnomaly, at time.Time) float64 {
+	score := 0.0
+	if driver, err := d.driverRepo.FindByUserID(ctx, driverID); err == nil && driver != nil {
+		score = CurrentRiskScore(driver, at)
+	}
+
+	for _, a := range anomalies {
+		score += anomalyWeights[a.Type]
+	}
+	if score > 100 {
+		score = 100
+	}
+
+	if err := d.driverRepo.UpdateRiskScore(ctx, driverID, score, anomalies[len(anomalies)-1]); err != nil {
+		log.Printf("⚠️  Failed to update risk score for driver %s: %v", driverID.Hex(), err)
+	}
+	return score
+}
+
+func gpsMaxSpeedKmh() float64 {
+	return envFloat("GPS_MAX_SPEED_KMH", 120)
+}
+
+func gpsTeleportDistanceM() float64 {
+	return envFloat("GPS_TELEPORT_DISTANCE_M", 2000)
+}
+
+func gpsTeleportWindow() time.Duration {
+	return time.Duration(envFloat("GPS_TELEPORT_WINDOW_SECONDS", 30)) * time.Second
+}
+
+func gpsReanchorPings() int {
+	return int(envFloat("GPS_REANCHOR_PINGS", 3))
+}
+
+func gpsIdleTimeout() time.Duration {
+	return time.Duration(envFloat("GPS_IDLE_MINUTES", 30) * float64(time.Minute))
+}
+
+func gpsRepeatLimit() int {
+	return int(envFloat("GPS_REPEAT_LIMIT", 10))
+}
+
+func gpsServiceAreaLat() float64 {
+	return envCoordinate("GPS_SERVICE_AREA_LAT", 9.032)
+}
+
+func gpsServiceAreaLng() float64 {
+	return envCoordinate("GPS_SERVICE_AREA_LNG", 38.746)
+}
+
+func gpsServiceAreaRadiusKm() float64 {
+	return envFloat("GPS_SERVICE_AREA_RADIUS_KM", 60)
+}
+
+func gpsRiskHalfLifeHours() float64 {
+	return envFloat("GPS_RISK_HALF_LIFE_HOURS", 24)
+}
+
+// envCoordinate is envFloat for lat/lng values, which can legitimately be
+// zero or negative.
+func envCoordinate(key string, def float64) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(key)), 64)
+	if err != nil {
+		return def
+	}
+	return value
+}
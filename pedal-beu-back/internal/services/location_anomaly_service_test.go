@@ -0,0 +1,132 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testPing is a ping dLat degrees north of the service area centre (0.001°
+// is about 111m), sec seconds into the test.
+type testPing struct {
+	dLat float64
+	sec  int
+}
+
+func TestEvaluatePings(t *testing.T) {
+	still := func(n int, dLat float64, from, every int) []testPing {
+		pings := make([]testPing, n)
+		for i := range pings {
+			pings[i] = testPing{dLat: dLat, sec: from + i*every}
+		}
+		return pings
+	}
+
+	tests := []struct {
+		name   string
+		pings  []testPing
+		want   [][]string // anomaly types per ping
+		scored int
+	}{
+		{
+			name:  "steady driving",
+			pings: []testPing{{0, 0}, {0.001, 10}, {0.002, 20}, {0.003, 30}},
+			want:  [][]string{nil, nil, nil, nil},
+		},
+		{
+			name:   "teleport and back",
+			pings:  []testPing{{0, 0}, {0.001, 10}, {0.05, 15}, {0.002, 20}},
+			want:   [][]string{nil, nil, {"teleport"}, nil},
+			scored: 1,
+		},
+		{
+			name:   "impossible speed",
+			pings:  []testPing{{0, 0}, {0.03, 60}},
+			want:   [][]string{nil, {"impossible_speed"}},
+			scored: 1,
+		},
+		{
+			name:   "repeated coordinates",
+			pings:  still(11, 0.001, 0, 5),
+			want:   [][]string{nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, {"repeated_coordinates"}},
+			scored: 1,
+		},
+		{
+			// The first fix was 11km off; the pings after it agree
+			// with each other, so the anchor moves to them.
+			name:   "bad first fix is reanchored",
+			pings:  []testPing{{0.1, 0}, {0, 5}, {0.0002, 10}, {0.0004, 15}, {0.0006, 20}, {0.0008, 25}},
+			want:   [][]string{nil, {"teleport"}, {"teleport"}, {"teleport"}, nil, nil},
+			scored: 1,
+		},
+		{
+			// A mock-location app sending a steady stream of positions
+			// away from a confirmed anchor never becomes the anchor.
+			name:   "spoofed stream stays flagged",
+			pings:  []testPing{{0, 0}, {0.0005, 10}, {0.05, 15}, {0.05, 20}, {0.05, 25}, {0.05, 30}, {0.05, 35}, {0.05, 40}},
+			want:   [][]string{nil, nil, {"teleport"}, {"teleport"}, {"teleport"}, {"teleport"}, {"teleport"}, {"impossible_speed"}},
+			scored: 1,
+		},
+		{
+			// A real 2.2km gap (tunnel, dropout) is within reach once
+			// enough time has passed.
+			name:   "genuine jump heals with time",
+			pings:  []testPing{{0, 0}, {0.0005, 10}, {0.02, 20}, {0.02, 120}},
+			want:   [][]string{nil, nil, {"teleport"}, nil},
+			scored: 1,
+		},
+	}
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &locationAnomalyDetector{states: map[primitive.ObjectID]*driverPingState{}}
+			driverID := primitive.NewObjectID()
+			scored := 0
+			for i, ping := range tt.pings {
+				anomalies, scoredNow := d.evaluate(driverID, gpsServiceAreaLat()+ping.dLat, gpsServiceAreaLng(), base.Add(time.Duration(ping.sec)*time.Second))
+				var kinds []string
+				for _, a := range anomalies {
+					kinds = append(kinds, a.Type)
+				}
+				if !reflect.DeepEqual(kinds, tt.want[i]) {
+					t.Errorf("ping %d: anomalies %v, want %v", i, kinds, tt.want[i])
+				}
+				if flagged := d.LastPingFlagged(driverID); flagged != (len(tt.want[i]) > 0) {
+					t.Errorf("ping %d: flagged %v", i, flagged)
+				}
+				scored += len(scoredNow)
+			}
+			if scored != tt.scored {
+				t.Errorf("scored %d anomalies, want %d", scored, tt.scored)
+			}
+		})
+	}
+}
+
+func TestEvaluateOutOfServiceArea(t *testing.T) {
+	d := &locationAnomalyDetector{states: map[primitive.ObjectID]*driverPingState{}}
+	anomalies, scored := d.evaluate(primitive.NewObjectID(), 0, 0, time.Now())
+	if len(anomalies) != 1 || anomalies[0].Type != "out_of_service_area" || len(scored) != 1 {
+		t.Fatalf("anomalies %v, want one scored out_of_service_area", anomalies)
+	}
+}
+
+func TestEvaluateForgetsIdleDrivers(t *testing.T) {
+	d := &locationAnomalyDetector{states: map[primitive.ObjectID]*driverPingState{}}
+	idle, active := primitive.NewObjectID(), primitive.NewObjectID()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	d.evaluate(idle, gpsServiceAreaLat(), gpsServiceAreaLng(), start)
+	d.evaluate(active, gpsServiceAreaLat(), gpsServiceAreaLng(), start.Add(20*time.Minute))
+	d.evaluate(active, gpsServiceAreaLat(), gpsServiceAreaLng(), start.Add(31*time.Minute))
+
+	if _, ok := d.states[idle]; ok {
+		t.Error("idle driver still tracked")
+	}
+	if _, ok := d.states[active]; !ok {
+		t.Error("active driver forgotten")
+	}
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MenuSection is one category of the grouped menu with the items that can
+// be ordered from it right now.
+type MenuSection struct {
+	Category models.MenuCategory `json:"category"`
+	Items    []models.MenuItem   `json:"items"`
+}
+
+// RestaurantMenu is what GET /restaurants/:id/menu returns by default: the
+// categories that are being served at the moment, in display order.
+// Sections for items whose free-text category has no MenuCategory behind it
+// come last, sorted by name, with a zero category ID.
+type RestaurantMenu struct {
+	RestaurantID primitive.ObjectID `json:"restaurant_id"`
+	Timezone     string             `json:"timezone"`
+	IsOpen       bool               `json:"is_open"`
+	Categories   []MenuSection      `json:"categories"`
+}
+
+func (s *restaurantService) GetMenu(ctx context.Context, restaurantID string, at time.Time, query models.MenuItemQuery) (*RestaurantMenu, error) {
+	objectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return nil, errors.New("invalid restaurant ID")
+	}
+
+	restaurant, err := s.repo.FindByID(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.categoryRepo.FindByRestaurant(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	dietaryTags, allergens, err := parseDietaryFilter(query.Diet, query.ExcludeAllergens)
+	if err != nil {
+		return nil, err
+	}
+	available := true
+	items, err := s.menuRepo.FindAllByRestaurant(ctx, objectID, repositories.MenuItemFilter{
+		Category:         query.Category,
+		Available:        &available,
+		Search:           query.Q,
+		DietaryTags:      dietaryTags,
+		ExcludeAllergens: allergens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	loc := restaurantTimezone(restaurant)
+	sections := make([]MenuSection, len(categories))
+	for i, category := range categories {
+		sections[i] = MenuSection{Category: category, Items: []models.MenuItem{}}
+	}
+	loose := make(map[string]*MenuSection)
+
+	for _, item := range items {
+		if i := menuItemCategoryIndex(item, categories); i >= 0 {
+			sections[i].Items = append(sections[i].Items, item)
+			continue
+		}
+		key := strings.ToLower(item.Category)
+		if loose[key] == nil {
+			loose[key] = &MenuSection{
+				Category: models.MenuCategory{RestaurantID: objectID, Name: item.Category, IsActive: true},
+				Items:    []models.MenuItem{},
+			}
+		}
+		loose[key].Items = append(loose[key].Items, item)
+	}
+
+	menu := &RestaurantMenu{
+		RestaurantID: objectID,
+		Timezone:     loc.String(),
+		IsOpen:       openingHoursAllowIn(restaurant.OpeningHours, at, loc),
+		Categories:   []MenuSection{},
+	}
+	for i := range sections {
+		if len(sections[i].Items) > 0 && menuCategoryServing(&sections[i].Category, at, loc) {
+			menu.Categories = append(menu.Categories, sections[i])
+		}
+	}
+
+	var extra []MenuSection
+	for _, section := range loose {
+		extra = append(extra, *section)
+	}
+	sort.Slice(extra, func(i, j int) bool {
+		return extra[i].Category.Name < extra[j].Category.Name
+	})
+	menu.Categories = append(menu.Categories, extra...)
+
+	return menu, nil
+}
+
+func (s *restaurantService) ListMenuCategories(ctx context.Context, restaurantID string) ([]models.MenuCategory, error) {
+	objectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return nil, errors.New("invalid restaurant ID")
+	}
+	return s.categoryRepo.FindByRestaurant(ctx, objectID)
+}
+
+func (s *restaurantService) CreateMenuCategory(ctx context.Context, restaurantID string, req models.MenuCategoryRequest) (*models.MenuCategory, error) {
+	objectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return nil, errors.New("invalid restaurant ID")
+	}
+	if _, err := s.repo.FindByID(ctx, objectID); err != nil {
+		return nil, err
+	}
+	if err := validateOpeningHours(req.Schedule); err != nil {
+		return nil, err
+	}
+
+	category := &models.MenuCategory{
+		RestaurantID: objectID,
+		Name:         strings.TrimSpace(req.Name),
+		Description:  req.Description,
+		Image:        req.Image,
+		SortOrder:    req.SortOrder,
+		Schedule:     req.Schedule,
+		IsActive:     req.IsActive == nil || *req.IsActive,
+	}
+	if err := s.categoryRepo.Create(ctx, category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *restaurantService) UpdateMenuCategory(ctx context.Context, restaurantID, categoryID string, req models.MenuCategoryRequest) (*models.MenuCategory, error) {
+	restaurantObjectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return nil, errors.New("invalid restaurant ID")
+	}
+	categoryObjectID, err := primitive.ObjectIDFromHex(categoryID)
+	if err != nil {
+		return nil, errors.New("invalid menu category ID")
+	}
+	if err := validateOpeningHours(req.Schedule); err != nil {
+		return nil, err
+	}
+
+	category, err := s.categoryRepo.FindByID(ctx, restaurantObjectID, categoryObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed := strings.TrimSpace(req.Name) != category.Name
+	category.Name = strings.TrimSpace(req.Name)
+	category.Description = req.Description
+	category.Image = req.Image
+	category.SortOrder = req.SortOrder
+	category.Schedule = req.Schedule
+	if req.IsActive != nil {
+		category.IsActive = *req.IsActive
+	}
+
+	if err := s.categoryRepo.Replace(ctx, category); err != nil {
+		return nil, err
+	}
+	if renamed {
+		if err := s.menuRepo.RenameCategory(ctx, restaurantObjectID, categoryObjectID, category.Name); err != nil {
+			return nil, err
+		}
+	}
+	return category, nil
+}
+
+func (s *restaurantService) DeleteMenuCategory(ctx context.Context, restaurantID, categoryID string) error {
+	restaurantObjectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return errors.New("invalid restaurant ID")
+	}
+	categoryObjectID, err := primitive.ObjectIDFromHex(categoryID)
+	if err != nil {
+		return errors.New("invalid menu category ID")
+	}
+
+	if err := s.categoryRepo.Delete(ctx, restaurantObjectID, categoryObjectID); err != nil {
+		return err
+	}
+	return s.menuRepo.ClearCategory(ctx, restaurantObjectID, categoryObjectID)
+}
+
+// categoryForItem resolves the category fields for a new or edited item:
+// category_id must name one of the restaurant's categories; a bare name is
+// linked to the category of that name if there is one and kept as free
+// text otherwise.
+func categoryForItem(categories []models.MenuCategory, categoryIDHex, name string) (string, *primitive.ObjectID, error) {
+	if categoryIDHex != "" {
+		categoryID, err := primitive.ObjectIDFromHex(categoryIDHex)
+		if err != nil {
+			return "", nil, errors.New("invalid menu category ID")
+		}
+		for _, category := range categories {
+			if category.ID == categoryID {
+				return category.Name, &category.ID, nil
+			}
+		}
+		return "", nil, errors.New("menu category not found")
+	}
+
+	if strings.TrimSpace(name) == "" {
+		return "", nil, errors.New("category or category_id is required")
+	}
+	for _, category := range categories {
+		if strings.EqualFold(category.Name, strings.TrimSpace(name)) {
+			return category.Name, &category.ID, nil
+		}
+	}
+	return name, nil, nil
+}
+
+// menuItemCategoryIndex finds the item's category among categories: by ID
+// when the item has one, otherwise by name (items created before
+// categories existed only have the name). -1 if neither matches.
+func menuItemCategoryIndex(item models.MenuItem, categories []models.MenuCategory) int {
+	if item.CategoryID != nil {
+		for i := range categories {
+			if categories[i].ID == *item.CategoryID {
+				return i
+			}
+		}
+	}
+	for i := range categories {
+		if strings.EqualFold(categories[i].Name, item.Category) {
+			return i
+		}
+	}
+	return -1
+}
+
+// menuCategoryServing reports whether a category's items can be ordered at
+// t: it has to be active and inside its schedule in the restaurant's
+// timezone. A nil category (free-text only) is always serving.
+func menuCategoryServing(category *models.MenuCategory, t time.Time, loc *time.Location) bool {
+	if category == nil {
+		return true
+	}
+	return category.IsActive && openingHoursAllowIn(category.Schedule, t, loc)
+}
+
+var timezoneCache sync.Map // IANA name -> *time.Location
+
+// restaurantTimezone is the location a restaurant's hours and menu
+// schedules are read in: its own Timezone if set and known, otherwise the
+// service timezone.
+func restaurantTimezone(restaurant *models.Restaurant) *time.Location {
+	if restaurant == nil || restaurant.Timezone == "" {
+		return serviceLocation
+	}
+	if loc, ok := timezoneCache.Load(restaurant.Timezone); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(restaurant.Timezone)
+	if err != nil {
+		return serviceLocation
+	}
+	timezoneCache.Store(restaurant.Timezone, loc)
+	return loc
+}
+
+func validateTimezone(name string) error {
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("unknown timezone %q", name)
+	}
+	return nil
+}
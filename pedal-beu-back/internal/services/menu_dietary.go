@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidDietaryFilter wraps an unknown diet or allergen in a menu or
+// search query, so handlers can answer 400 rather than 404.
+var ErrInvalidDietaryFilter = errors.New("invalid dietary filter")
+
+var dietaryTags = []string{
+	models.DietVegan,
+	models.DietVegetarian,
+	models.DietFasting,
+	models.DietHalal,
+	models.DietGlutenFree,
+}
+
+var allergenTags = []string{
+	models.AllergenNuts,
+	models.AllergenDairy,
+	models.AllergenEggs,
+	models.AllergenGluten,
+	models.AllergenSoy,
+	models.AllergenFish,
+	models.AllergenShellfish,
+	models.AllergenSesame,
+}
+
+// tagAliases maps the other ways restaurants (and customers) write a tag
+// to its canonical name, after lowercasing and turning spaces and dashes
+// into underscores.
+var tagAliases = map[string]string{
+	"tsom":      models.DietFasting,
+	"ፆም":        models.DietFasting,
+	"ጾም":        models.DietFasting,
+	"veg":       models.DietVegetarian,
+	"nut":       models.AllergenNuts,
+	"peanuts":   models.AllergenNuts,
+	"tree_nuts": models.AllergenNuts,
+	"milk":      models.AllergenDairy,
+	"lactose":   models.AllergenDairy,
+	"egg":       models.AllergenEggs,
+	"wheat":     models.AllergenGluten,
+	"soya":      models.AllergenSoy,
+}
+
+// normalizeTags canonicalizes and de-duplicates tags, rejecting any that
+// aren't in known. kind names the field in the error.
+func normalizeTags(kind string, tags, known []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		tag = strings.NewReplacer(" ", "_", "-", "_").Replace(tag)
+		if tag == "" {
+			continue
+		}
+		if alias, ok := tagAliases[tag]; ok {
+			tag = alias
+		}
+		if !containsString(known, tag) {
+			return nil, fmt.Errorf("unknown %s %q (expected one of %s)", kind, tag, strings.Join(known, ", "))
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// normalizeDietary validates a menu item's dietary tags and allergens
+// together. Vegan food is also vegetarian and fasting food, so those tags
+// are added; a vegan or fasting dish can't contain dairy or eggs, and a
+// gluten-free one can't contain gluten.
+func normalizeDietary(tags, allergens []string) ([]string, []string, error) {
+	tags, err := normalizeTags("dietary tag", tags, dietaryTags)
+	if err != nil {
+		return nil, nil, err
+	}
+	allergens, err = normalizeTags("allergen", allergens, allergenTags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if containsString(tags, models.DietVegan) {
+		for _, implied := range []string{models.DietVegetarian, models.DietFasting} {
+			if !containsString(tags, implied) {
+				tags = append(tags, implied)
+			}
+		}
+	}
+
+	for _, tag := range []string{models.DietVegan, models.DietFasting} {
+		if !containsString(tags, tag) {
+			continue
+		}
+		for _, allergen := range []string{models.AllergenDairy, models.AllergenEggs} {
+			if containsString(allergens, allergen) {
+				return nil, nil, fmt.Errorf("a %s item can't contain %s", tag, allergen)
+			}
+		}
+	}
+	if containsString(tags, models.DietGlutenFree) && containsString(allergens, models.AllergenGluten) {
+		return nil, nil, fmt.Errorf("a gluten-free item can't contain gluten")
+	}
+	return tags, allergens, nil
+}
+
+// parseTagList reads a comma-separated diet or exclude_allergens query
+// parameter.
+func parseTagList(kind, value string, known []string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	return normalizeTags(kind, strings.Split(value, ","), known)
+}
+
+// parseDietaryFilter reads the diet and exclude_allergens query
+// parameters shared by the menu and search endpoints.
+func parseDietaryFilter(diet, excludeAllergens string) (tags, allergens []string, err error) {
+	tags, err = parseTagList("diet", diet, dietaryTags)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDietaryFilter, err)
+	}
+	allergens, err = parseTagList("allergen", excludeAllergens, allergenTags)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDietaryFilter, err)
+	}
+	return tags, allergens, nil
+}
+
+// fitsDietaryFilter reports whether item carries every one of tags and
+// none of allergens — the in-memory twin of the repository's filter.
+func fitsDietaryFilter(item *models.MenuItem, tags, allergens []string) bool {
+	for _, tag := range tags {
+		if !containsString(item.DietaryTags, tag) {
+			return false
+		}
+	}
+	for _, allergen := range allergens {
+		if containsString(item.Allergens, allergen) {
+			return false
+		}
+	}
+	return true
+}
+
+// menuFitsDietaryFilter reports whether at least one available item on
+// menu fits the filter.
+func menuFitsDietaryFilter(menu []models.MenuItem, tags, allergens []string) bool {
+	for i := range menu {
+		if menu[i].IsAvailable && menu[i].ArchivedAt == nil && fitsDietaryFilter(&menu[i], tags, allergens) {
+			return true
+		}
+	}
+	return false
+}
+
+// dietaryFlags turns the dietary tags found on a restaurant's available
+// items into its listing flags.
+func dietaryFlags(tags []string) *models.DietaryFlags {
+	return &models.DietaryFlags{
+		HasVeganOptions:      containsString(tags, models.DietVegan),
+		HasVegetarianOptions: containsString(tags, models.DietVegetarian),
+		HasFastingOptions:    containsString(tags, models.DietFasting),
+		HasHalalOptions:      containsString(tags, models.DietHalal),
+		HasGlutenFreeOptions: containsString(tags, models.DietGlutenFree),
+	}
+}
+
+// menuDietaryFlags is dietaryFlags for a menu already in hand.
+func menuDietaryFlags(menu []models.MenuItem) *models.DietaryFlags {
+	var tags []string
+	for _, item := range menu {
+		if item.IsAvailable && item.ArchivedAt == nil {
+			tags = append(tags, item.DietaryTags...)
+		}
+	}
+	return dietaryFlags(tags)
+}
+
+// attachDietaryFlags fills in DietaryFlags on a page of restaurants with
+// one query for the lot.
+func (s *restaurantService) attachDietaryFlags(ctx context.Context, restaurants []models.Restaurant) error {
+	if len(restaurants) == 0 {
+		return nil
+	}
+	ids := make([]primitive.ObjectID, len(restaurants))
+	for i := range restaurants {
+		ids[i] = restaurants[i].ID
+	}
+
+	tags, err := s.menuRepo.DietaryTagsByRestaurant(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range restaurants {
+		restaurants[i].DietaryFlags = dietaryFlags(tags[restaurants[i].ID])
+	}
+	return nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
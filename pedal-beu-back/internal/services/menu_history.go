@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"reflect"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Menu history actions (MenuItemChange.Action).
+const (
+	menuActionCreated    = "created"
+	menuActionUpdated    = "updated"
+	menuActionArchived   = "archived"
+	menuActionUnarchived = "unarchived"
+	menuActionRestored   = "restored"
+)
+
+// menuItemDiff lists the fields a person edits that differ between before
+// and after, by their JSON names. Stock and timestamps aren't included:
+// stock moves with every order and has its own alerts.
+func menuItemDiff(before, after *models.MenuItem) []models.MenuFieldChange {
+	fields := []struct {
+		name     string
+		from, to interface{}
+	}{
+		{"sku", before.SKU, after.SKU},
+		{"name", before.Name, after.Name},
+		{"description", before.Description, after.Description},
+		{"price", before.Price, after.Price},
+		{"image", before.Image, after.Image},
+		{"category", before.Category, after.Category},
+		{"is_available", before.IsAvailable, after.IsAvailable},
+		{"ingredients", before.Ingredients, after.Ingredients},
+		{"dietary_tags", before.DietaryTags, after.DietaryTags},
+		{"allergens", before.Allergens, after.Allergens},
+		{"addons", before.Addons, after.Addons},
+		{"variants", before.Variants, after.Variants},
+		{"modifier_groups", before.ModifierGroups, after.ModifierGroups},
+		{"preparation_time", before.PreparationTime, after.PreparationTime},
+		{"archived", before.ArchivedAt != nil, after.ArchivedAt != nil},
+	}
+
+	var changes []models.MenuFieldChange
+	for _, field := range fields {
+		if !reflect.DeepEqual(field.from, field.to) {
+			changes = append(changes, models.MenuFieldChange{Field: field.name, From: field.from, To: field.to})
+		}
+	}
+	return changes
+}
+
+// menuSystemActor is the ChangedBy of changes nobody made by hand: an item
+// switched off because it sold out, or back on by a cancellation or the
+// daily stock reset.
+var menuSystemActor = primitive.NilObjectID
+
+// recordMenuChange writes a history entry for item. before is the item as
+// it was (nil for a new item); an update that didn't change anything isn't
+// recorded. The edit itself has already been saved by the time this runs,
+// so a failure to record it is logged rather than returned.
+func (s *restaurantService) recordMenuChange(ctx context.Context, action string, before, after *models.MenuItem, actorID primitive.ObjectID) {
+	writeMenuChange(ctx, s.historyRepo, action, before, after, actorID)
+}
+
+// writeMenuChange is recordMenuChange for services other than the
+// restaurant service, which change items as a side effect (stock).
+func writeMenuChange(ctx context.Context, historyRepo repositories.MenuHistoryRepository, action string, before, after *models.MenuItem, actorID primitive.ObjectID) {
+	change := &models.MenuItemChange{
+		RestaurantID: after.RestaurantID,
+		MenuItemID:   after.ID,
+		ItemName:     after.Name,
+		Action:       action,
+		Previous:     before,
+		ChangedBy:    actorID,
+	}
+	if before != nil {
+		change.Changes = menuItemDiff(before, after)
+		if action == menuActionUpdated && len(change.Changes) == 0 {
+			return
+		}
+	}
+
+	if err := historyRepo.Create(ctx, change); err != nil {
+		log.Printf("⚠️  Failed to record %s of menu item %s: %v", action, after.ID.Hex(), err)
+	}
+}
+
+func (s *restaurantService) DeleteMenuItem(ctx context.Context, restaurantID, itemID string, actorID primitive.ObjectID) error {
+	return s.setMenuItemArchived(ctx, restaurantID, itemID, true, actorID)
+}
+
+func (s *restaurantService) UnarchiveMenuItem(ctx context.Context, restaurantID, itemID string, actorID primitive.ObjectID) error {
+	return s.setMenuItemArchived(ctx, restaurantID, itemID, false, actorID)
+}
+
+func (s *restaurantService) setMenuItemArchived(ctx context.Context, restaurantID, itemID string, archived bool, actorID primitive.ObjectID) error {
+	restaurantObjectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return errors.New("invalid restaurant ID")
+	}
+	itemObjectID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return errors.New("invalid menu item ID")
+	}
+
+	before, err := s.menuRepo.FindByID(ctx, restaurantObjectID, itemObjectID)
+	if err != nil {
+		return err
+	}
+
+	action := menuActionArchived
+	if archived {
+		err = s.menuRepo.Archive(ctx, restaurantObjectID, itemObjectID)
+	} else {
+		action = menuActionUnarchived
+		err = s.menuRepo.Unarchive(ctx, restaurantObjectID, itemObjectID)
+	}
+	if err != nil {
+		return err
+	}
+
+	after, err := s.menuRepo.FindByID(ctx, restaurantObjectID, itemObjectID)
+	if err != nil {
+		return err
+	}
+	s.recordMenuChange(ctx, action, before, after, actorID)
+	return nil
+}
+
+func (s *restaurantService) GetMenuHistory(ctx context.Context, restaurantID string, query models.MenuHistoryQuery) ([]models.MenuItemChange, int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return nil, 0, errors.New("invalid restaurant ID")
+	}
+
+	filter := repositories.MenuHistoryFilter{Field: query.Field}
+	if query.ItemID != "" {
+		itemID, err := primitive.ObjectIDFromHex(query.ItemID)
+		if err != nil {
+			return nil, 0, errors.New("invalid menu item ID")
+		}
+		filter.MenuItemID = &itemID
+	}
+
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+	return s.historyRepo.FindByRestaurant(ctx, objectID, filter, repositories.Pagination{Page: query.Page, Limit: query.Limit})
+}
+
+// RestoreMenuItemVersion puts an item back to how it was before the given
+// history entry — including archived or not. The item keeps its current
+// SKU and stock; those aren't part of a version. The restore is itself
+// recorded, so it can be undone the same way.
+func (s *restaurantService) RestoreMenuItemVersion(ctx context.Context, restaurantID, changeID string, actorID primitive.ObjectID) (*models.MenuItem, error) {
+	restaurantObjectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return nil, errors.New("invalid restaurant ID")
+	}
+	changeObjectID, err := primitive.ObjectIDFromHex(changeID)
+	if err != nil {
+		return nil, errors.New("invalid menu history ID")
+	}
+
+	change, err := s.historyRepo.FindByID(ctx, restaurantObjectID, changeObjectID)
+	if err != nil {
+		return nil, err
+	}
+	if change.Previous == nil {
+		return nil, errors.New("this change created the item; there's no earlier version to restore")
+	}
+
+	current, err := s.menuRepo.FindByID(ctx, restaurantObjectID, change.MenuItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	restored := *change.Previous
+	restored.ID = current.ID
+	restored.RestaurantID = current.RestaurantID
+	restored.SKU = current.SKU
+	restored.Stock = current.Stock
+	restored.CreatedAt = current.CreatedAt
+	if err := s.menuRepo.Replace(ctx, &restored); err != nil {
+		return nil, err
+	}
+
+	s.recordMenuChange(ctx, menuActionRestored, current, &restored, actorID)
+	return &restored, nil
+}
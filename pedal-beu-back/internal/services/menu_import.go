@@ -0,0 +1,618 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// menuCSVColumns is the CSV export's header, in order. Imports match
+// columns by header name, so they can come in any order and only sku, name
+// and price are required. Ingredients, dietary tags and allergens are
+// separated by ";", addons are "name:price" (":inactive" appended for a
+// switched-off one) separated by ";", with any ":", ";" or "\" in an
+// addon's name escaped with a "\". Category schedules, variants and
+// modifier groups are JSON-only.
+var menuCSVColumns = []string{
+	"sku", "name", "description", "category", "price", "is_available",
+	"preparation_time", "image", "ingredients", "addons", "dietary_tags",
+	"allergens",
+}
+
+// MenuImportError is one problem found while validating an import. Row is
+// the item's 1-based position in the file — for CSV, the data row after the
+// header. Category problems have Row 0 and name the category instead.
+type MenuImportError struct {
+	Row      int    `json:"row,omitempty"`
+	SKU      string `json:"sku,omitempty"`
+	Category string `json:"category,omitempty"`
+	Error    string `json:"error"`
+}
+
+// MenuImportReport is the outcome of a menu import. An import is all or
+// nothing: if Errors is non-empty nothing was written, and a dry run never
+// writes. The counts are what was (or, for a dry run or a failed import,
+// would have been) created and updated.
+type MenuImportReport struct {
+	DryRun            bool              `json:"dry_run"`
+	Applied           bool              `json:"applied"`
+	Rows              int               `json:"rows"`
+	Created           int               `json:"created"`
+	Updated           int               `json:"updated"`
+	CategoriesCreated int               `json:"categories_created"`
+	CategoriesUpdated int               `json:"categories_updated"`
+	Errors            []MenuImportError `json:"errors"`
+}
+
+func (s *restaurantService) ExportMenu(ctx context.Context, restaurantID string) (*models.MenuFile, error) {
+	objectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return nil, errors.New("invalid restaurant ID")
+	}
+	if _, err := s.repo.FindByID(ctx, objectID); err != nil {
+		return nil, err
+	}
+
+	categories, err := s.categoryRepo.FindByRestaurant(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.menuRepo.FindAllByRestaurant(ctx, objectID, repositories.MenuItemFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	file := &models.MenuFile{
+		RestaurantID: objectID,
+		ExportedAt:   &now,
+		Categories:   make([]models.MenuCategoryRequest, 0, len(categories)),
+		Items:        make([]models.MenuFileItem, 0, len(items)),
+	}
+	for _, category := range categories {
+		isActive := category.IsActive
+		file.Categories = append(file.Categories, models.MenuCategoryRequest{
+			Name:        category.Name,
+			Description: category.Description,
+			Image:       category.Image,
+			SortOrder:   category.SortOrder,
+			Schedule:    category.Schedule,
+			IsActive:    &isActive,
+		})
+	}
+	for _, item := range items {
+		// Items added before SKUs existed are exported under their ID,
+		// which the import also matches, so an export/import round trip
+		// never duplicates them.
+		sku := item.SKU
+		if sku == "" {
+			sku = item.ID.Hex()
+		}
+		isAvailable := item.IsAvailable
+		file.Items = append(file.Items, models.MenuFileItem{
+			SKU:             sku,
+			Name:            item.Name,
+			Description:     item.Description,
+			Category:        item.Category,
+			Price:           item.Price,
+			IsAvailable:     &isAvailable,
+			PreparationTime: item.PreparationTime,
+			Image:           item.Image,
+			Ingredients:     item.Ingredients,
+			DietaryTags:     item.DietaryTags,
+			Allergens:       item.Allergens,
+			Addons:          item.Addons,
+			Variants:        item.Variants,
+			ModifierGroups:  item.ModifierGroups,
+		})
+	}
+	return file, nil
+}
+
+func (s *restaurantService) ImportMenu(ctx context.Context, restaurantID string, file *models.MenuFile, dryRun bool, actorID primitive.ObjectID) (*MenuImportReport, error) {
+	return s.importMenu(ctx, restaurantID, file, nil, dryRun, actorID)
+}
+
+func (s *restaurantService) ImportMenuCSV(ctx context.Context, restaurantID string, r io.Reader, dryRun bool, actorID primitive.ObjectID) (*MenuImportReport, error) {
+	file, rowErrors, err := ReadMenuCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.importMenu(ctx, restaurantID, file, rowErrors, dryRun, actorID)
+}
+
+// importMenu validates every category and item in file (on top of any
+// errors the CSV parser already found), works out which items are new and
+// which update an existing one, and — unless it's a dry run or something
+// failed — writes the lot. Archived items still match by SKU, and an
+// import brings them back onto the menu.
+func (s *restaurantService) importMenu(ctx context.Context, restaurantID string, file *models.MenuFile, rowErrors []MenuImportError, dryRun bool, actorID primitive.ObjectID) (*MenuImportReport, error) {
+	objectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return nil, errors.New("invalid restaurant ID")
+	}
+	if _, err := s.repo.FindByID(ctx, objectID); err != nil {
+		return nil, err
+	}
+
+	categories, err := s.categoryRepo.FindByRestaurant(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.menuRepo.FindAllByRestaurant(ctx, objectID, repositories.MenuItemFilter{IncludeArchived: true})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MenuImportReport{
+		DryRun: dryRun,
+		Rows:   len(file.Items),
+		Errors: append([]MenuImportError{}, rowErrors...),
+	}
+
+	// Categories: matched to existing ones by name
+	categoryByName := make(map[string]*models.MenuCategory, len(categories))
+	for i := range categories {
+		categoryByName[strings.ToLower(categories[i].Name)] = &categories[i]
+	}
+	seenCategories := make(map[string]bool)
+	for _, req := range file.Categories {
+		name := strings.TrimSpace(req.Name)
+		key := strings.ToLower(name)
+		switch {
+		case name == "":
+			report.Errors = append(report.Errors, MenuImportError{Error: "category name is required"})
+			continue
+		case seenCategories[key]:
+			report.Errors = append(report.Errors, MenuImportError{Category: name, Error: "category appears more than once"})
+			continue
+		}
+		seenCategories[key] = true
+		if err := validateOpeningHours(req.Schedule); err != nil {
+			report.Errors = append(report.Errors, MenuImportError{Category: name, Error: err.Error()})
+			continue
+		}
+		if categoryByName[key] != nil {
+			report.CategoriesUpdated++
+		} else {
+			report.CategoriesCreated++
+		}
+	}
+
+	// Items: matched by SKU, or by ID for items exported before they had one
+	bySKU := make(map[string]*models.MenuItem, len(existing))
+	byID := make(map[primitive.ObjectID]*models.MenuItem, len(existing))
+	for i := range existing {
+		if existing[i].SKU != "" {
+			bySKU[existing[i].SKU] = &existing[i]
+		}
+		byID[existing[i].ID] = &existing[i]
+	}
+	failedRows := make(map[int]bool, len(rowErrors))
+	for _, rowError := range rowErrors {
+		failedRows[rowError.Row] = true
+	}
+
+	matches := make([]*models.MenuItem, len(file.Items))
+	seenSKUs := make(map[string]int)
+	for i := range file.Items {
+		row := i + 1
+		item := &file.Items[i]
+		item.SKU = strings.TrimSpace(item.SKU)
+		item.Name = strings.TrimSpace(item.Name)
+		item.Category = strings.TrimSpace(item.Category)
+
+		if first, dup := seenSKUs[item.SKU]; dup && item.SKU != "" {
+			report.Errors = append(report.Errors, MenuImportError{Row: row, SKU: item.SKU, Error: fmt.Sprintf("duplicate SKU (also on row %d)", first)})
+			continue
+		}
+		seenSKUs[item.SKU] = row
+		if failedRows[row] {
+			continue
+		}
+		if err := validateMenuFileItem(item); err != nil {
+			report.Errors = append(report.Errors, MenuImportError{Row: row, SKU: item.SKU, Error: err.Error()})
+			continue
+		}
+
+		matches[i] = bySKU[item.SKU]
+		if matches[i] == nil {
+			if id, err := primitive.ObjectIDFromHex(item.SKU); err == nil && byID[id] != nil && byID[id].SKU == "" {
+				matches[i] = byID[id]
+			}
+		}
+		if matches[i] != nil {
+			report.Updated++
+		} else {
+			report.Created++
+		}
+	}
+
+	if len(report.Errors) > 0 || dryRun {
+		return report, nil
+	}
+
+	// Every write goes in one transaction, so a failure part-way leaves
+	// the menu as it was.
+	err = repositories.RunInTransaction(ctx, func(ctx context.Context) error {
+		for _, req := range file.Categories {
+			if err := s.importMenuCategory(ctx, objectID, categoryByName[strings.ToLower(strings.TrimSpace(req.Name))], req); err != nil {
+				return err
+			}
+		}
+		categories, err := s.categoryRepo.FindByRestaurant(ctx, objectID)
+		if err != nil {
+			return err
+		}
+
+		var created []models.MenuItem
+		for i, item := range file.Items {
+			categoryName, categoryID, err := categoryForItem(categories, "", item.Category)
+			if err != nil {
+				return err
+			}
+
+			if matches[i] == nil {
+				created = append(created, newMenuItemFromFile(objectID, item, categoryName, categoryID))
+				continue
+			}
+			update := menuItemUpdateFromFile(matches[i], item)
+			update["category"] = categoryName
+			update["category_id"] = categoryID
+			if err := s.menuRepo.Update(ctx, objectID, matches[i].ID, update); err != nil {
+				return err
+			}
+			action := menuActionUpdated
+			if matches[i].ArchivedAt != nil {
+				action = menuActionUnarchived
+			}
+			updated, err := s.menuRepo.FindByID(ctx, objectID, matches[i].ID)
+			if err != nil {
+				return err
+			}
+			s.recordMenuChange(ctx, action, matches[i], updated, actorID)
+		}
+		if err := s.menuRepo.CreateMany(ctx, created); err != nil {
+			return err
+		}
+		for i := range created {
+			s.recordMenuChange(ctx, menuActionCreated, nil, &created[i], actorID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Applied = true
+	return report, nil
+}
+
+func (s *restaurantService) importMenuCategory(ctx context.Context, restaurantID primitive.ObjectID, current *models.MenuCategory, req models.MenuCategoryRequest) error {
+	if current == nil {
+		category := &models.MenuCategory{
+			RestaurantID: restaurantID,
+			Name:         strings.TrimSpace(req.Name),
+			Description:  req.Description,
+			Image:        req.Image,
+			SortOrder:    req.SortOrder,
+			Schedule:     req.Schedule,
+			IsActive:     req.IsActive == nil || *req.IsActive,
+		}
+		return s.categoryRepo.Create(ctx, category)
+	}
+
+	current.Description = req.Description
+	current.Image = req.Image
+	current.SortOrder = req.SortOrder
+	current.Schedule = req.Schedule
+	if req.IsActive != nil {
+		current.IsActive = *req.IsActive
+	}
+	return s.categoryRepo.Replace(ctx, current)
+}
+
+func validateMenuFileItem(item *models.MenuFileItem) error {
+	if item.SKU == "" {
+		return errors.New("sku is required")
+	}
+	if item.Name == "" {
+		return errors.New("name is required")
+	}
+	if item.Category == "" {
+		return errors.New("category is required")
+	}
+	if item.Price <= 0 && len(item.Variants) == 0 {
+		return errors.New("price must be greater than 0")
+	}
+	if item.PreparationTime < 0 {
+		return errors.New("preparation_time can't be negative")
+	}
+	for _, addon := range item.Addons {
+		if strings.TrimSpace(addon.Name) == "" {
+			return errors.New("addon name is required")
+		}
+		if addon.Price < 0 {
+			return fmt.Errorf("addon %s has a negative price", addon.Name)
+		}
+	}
+	tags, allergens, err := normalizeDietary(item.DietaryTags, item.Allergens)
+	if err != nil {
+		return err
+	}
+	item.DietaryTags, item.Allergens = tags, allergens
+	return prepareMenuOptions(item.Variants, item.ModifierGroups)
+}
+
+func newMenuItemFromFile(restaurantID primitive.ObjectID, item models.MenuFileItem, categoryName string, categoryID *primitive.ObjectID) models.MenuItem {
+	return models.MenuItem{
+		RestaurantID:    restaurantID,
+		SKU:             item.SKU,
+		Name:            item.Name,
+		Description:     item.Description,
+		Price:           item.Price,
+		Image:           item.Image,
+		Category:        categoryName,
+		CategoryID:      categoryID,
+		IsAvailable:     item.IsAvailable == nil || *item.IsAvailable,
+		Ingredients:     item.Ingredients,
+		DietaryTags:     item.DietaryTags,
+		Allergens:       item.Allergens,
+		Addons:          importedAddons(nil, item.Addons),
+		Variants:        item.Variants,
+		ModifierGroups:  item.ModifierGroups,
+		PreparationTime: item.PreparationTime,
+	}
+}
+
+// menuItemUpdateFromFile is the $set for an existing item from an import
+// row. Stock, ID and creation time are never touched by an import. An
+// item matched by ID takes that ID as its SKU from then on.
+func menuItemUpdateFromFile(current *models.MenuItem, item models.MenuFileItem) bson.M {
+	update := bson.M{
+		"sku":              item.SKU,
+		"name":             item.Name,
+		"description":      item.Description,
+		"price":            item.Price,
+		"image":            item.Image,
+		"ingredients":      item.Ingredients,
+		"dietary_tags":     item.DietaryTags,
+		"allergens":        item.Allergens,
+		"addons":           importedAddons(current.Addons, item.Addons),
+		"preparation_time": item.PreparationTime,
+		"archived_at":      nil,
+	}
+	if item.IsAvailable != nil {
+		update["is_available"] = *item.IsAvailable
+	}
+	if item.Variants != nil {
+		update["variants"] = item.Variants
+	}
+	if item.ModifierGroups != nil {
+		update["modifier_groups"] = item.ModifierGroups
+	}
+	return update
+}
+
+// importedAddons gives imported addons IDs, reusing the ID of a current
+// addon with the same name so open carts that reference it stay valid.
+func importedAddons(current, imported []models.Addon) []models.Addon {
+	addons := make([]models.Addon, len(imported))
+	for i, addon := range imported {
+		addon.Name = strings.TrimSpace(addon.Name)
+		if addon.ID.IsZero() {
+			for _, old := range current {
+				if strings.EqualFold(old.Name, addon.Name) {
+					addon.ID = old.ID
+					break
+				}
+			}
+		}
+		if addon.ID.IsZero() {
+			addon.ID = primitive.NewObjectID()
+		}
+		addons[i] = addon
+	}
+	return addons
+}
+
+// ReadMenuCSV parses a CSV menu. Every data row becomes an item (so row
+// numbers line up with the JSON format's item positions); rows with
+// unparseable cells are also reported as errors. It only fails outright
+// if the file isn't CSV or the header lacks a required column.
+func ReadMenuCSV(r io.Reader) (*models.MenuFile, []MenuImportError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("the CSV file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"sku", "name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("the CSV header has no %s column", required)
+		}
+	}
+
+	file := &models.MenuFile{}
+	var rowErrors []MenuImportError
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV on row %d: %v", row, err)
+		}
+
+		cell := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		item := models.MenuFileItem{
+			SKU:         cell("sku"),
+			Name:        cell("name"),
+			Description: cell("description"),
+			Category:    cell("category"),
+			Image:       cell("image"),
+		}
+		var problems []string
+		if item.Price, err = strconv.ParseFloat(cell("price"), 64); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid price %q", cell("price")))
+		}
+		if value := cell("is_available"); value != "" {
+			available, err := strconv.ParseBool(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("invalid is_available %q", value))
+			}
+			item.IsAvailable = &available
+		}
+		if value := cell("preparation_time"); value != "" {
+			if item.PreparationTime, err = strconv.Atoi(value); err != nil {
+				problems = append(problems, fmt.Sprintf("invalid preparation_time %q", value))
+			}
+		}
+		item.Ingredients = splitCSVList(cell("ingredients"))
+		item.DietaryTags = splitCSVList(cell("dietary_tags"))
+		item.Allergens = splitCSVList(cell("allergens"))
+		addons, err := parseCSVAddons(cell("addons"))
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		item.Addons = addons
+
+		if len(problems) > 0 {
+			rowErrors = append(rowErrors, MenuImportError{Row: row, SKU: item.SKU, Error: strings.Join(problems, "; ")})
+		}
+		file.Items = append(file.Items, item)
+	}
+	return file, rowErrors, nil
+}
+
+// splitCSVList splits a ";"-separated cell, dropping empty entries.
+func splitCSVList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ";") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+func parseCSVAddons(value string) ([]models.Addon, error) {
+	var addons []models.Addon
+	for _, part := range splitEscaped(value, ';') {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		fields := splitEscaped(part, ':')
+		if len(fields) < 2 || len(fields) > 3 || (len(fields) == 3 && fields[2] != "inactive") {
+			return nil, fmt.Errorf("invalid addon %q (want name:price)", part)
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid addon price in %q", part)
+		}
+		addons = append(addons, models.Addon{
+			Name:     strings.TrimSpace(unescapeAddonName(fields[0])),
+			Price:    price,
+			IsActive: len(fields) == 2,
+		})
+	}
+	return addons, nil
+}
+
+var addonNameEscaper = strings.NewReplacer(`\`, `\\`, ":", `\:`, ";", `\;`)
+
+// splitEscaped splits value on every sep that isn't escaped with a "\",
+// leaving the escapes in the parts.
+func splitEscaped(value string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// unescapeAddonName undoes addonNameEscaper.
+func unescapeAddonName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+1 < len(name) {
+			i++
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// WriteMenuCSV writes file's items in the CSV format ReadMenuCSV reads.
+func WriteMenuCSV(w io.Writer, file *models.MenuFile) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(menuCSVColumns); err != nil {
+		return err
+	}
+
+	for _, item := range file.Items {
+		addons := make([]string, 0, len(item.Addons))
+		for _, addon := range item.Addons {
+			entry := addonNameEscaper.Replace(addon.Name) + ":" + strconv.FormatFloat(addon.Price, 'f', -1, 64)
+			if !addon.IsActive {
+				entry += ":inactive"
+			}
+			addons = append(addons, entry)
+		}
+		available := item.IsAvailable == nil || *item.IsAvailable
+
+		record := []string{
+			item.SKU,
+			item.Name,
+			item.Description,
+			item.Category,
+			strconv.FormatFloat(item.Price, 'f', -1, 64),
+			strconv.FormatBool(available),
+			strconv.Itoa(item.PreparationTime),
+			item.Image,
+			strings.Join(item.Ingredients, ";"),
+			strings.Join(addons, ";"),
+			strings.Join(item.DietaryTags, ";"),
+			strings.Join(item.Allergens, ";"),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
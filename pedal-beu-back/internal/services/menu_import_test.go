@@ -0,0 +1,54 @@
+package services
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+)
+
+func TestMenuCSVAddonsRoundTrip(t *testing.T) {
+	addons := []models.Addon{
+		{Name: "Extra injera", Price: 10, IsActive: true},
+		{Name: "Sauce: mitmita; hot", Price: 5.5, IsActive: true},
+		{Name: `Back\slash`, Price: 0, IsActive: false},
+	}
+	file := &models.MenuFile{Items: []models.MenuFileItem{{SKU: "TIBS-1", Name: "Tibs", Price: 250, Addons: addons}}}
+
+	var buf bytes.Buffer
+	if err := WriteMenuCSV(&buf, file); err != nil {
+		t.Fatal(err)
+	}
+	read, rowErrors, err := ReadMenuCSV(&buf)
+	if err != nil || len(rowErrors) > 0 {
+		t.Fatalf("ReadMenuCSV: %v %v", err, rowErrors)
+	}
+	if got := read.Items[0].Addons; !reflect.DeepEqual(got, addons) {
+		t.Fatalf("addons %+v, want %+v", got, addons)
+	}
+}
+
+func TestParseCSVAddons(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []models.Addon
+		wantErr bool
+	}{
+		{value: "Cheese:15; Egg:10:inactive", want: []models.Addon{{Name: "Cheese", Price: 15, IsActive: true}, {Name: "Egg", Price: 10}}},
+		{value: `Half\:half:20`, want: []models.Addon{{Name: "Half:half", Price: 20, IsActive: true}}},
+		{value: "Cheese", wantErr: true},
+		{value: "Cheese:free", wantErr: true},
+		{value: "Cheese:15:off", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCSVAddons(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: error %v", tt.value, err)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
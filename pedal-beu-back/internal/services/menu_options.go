@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxModifierDepth caps how deeply option groups can nest (item -> group
+// -> option -> group ...). Three levels covers "burger -> add cheese ->
+// which cheese"; anything deeper is a menu the app can't display anyway.
+const maxModifierDepth = 3
+
+// prepareMenuOptions validates a menu item's variants and modifier groups
+// as submitted by the restaurant and gives every variant, group and option
+// without one an ID (orders refer to them by ID). It edits the slices in
+// place.
+func prepareMenuOptions(variants []models.MenuItemVariant, groups []models.ModifierGroup) error {
+	for i := range variants {
+		if variants[i].Name == "" {
+			return errors.New("variant name is required")
+		}
+		if variants[i].Price <= 0 {
+			return fmt.Errorf("variant %s needs a price", variants[i].Name)
+		}
+		if variants[i].ID.IsZero() {
+			variants[i].ID = primitive.NewObjectID()
+		}
+	}
+	return prepareModifierGroups(groups, 1)
+}
+
+func prepareModifierGroups(groups []models.ModifierGroup, depth int) error {
+	if len(groups) > 0 && depth > maxModifierDepth {
+		return fmt.Errorf("modifier groups can be nested at most %d deep", maxModifierDepth)
+	}
+
+	for i := range groups {
+		group := &groups[i]
+		if group.Name == "" {
+			return errors.New("modifier group name is required")
+		}
+		if len(group.Options) == 0 {
+			return fmt.Errorf("modifier group %s has no options", group.Name)
+		}
+		if group.MinSelect < 0 || group.MaxSelect < 0 {
+			return fmt.Errorf("modifier group %s has a negative selection limit", group.Name)
+		}
+		if group.MaxSelect > 0 && group.MaxSelect < group.MinSelect {
+			return fmt.Errorf("modifier group %s: max_select is below min_select", group.Name)
+		}
+		if group.MinSelect > len(group.Options) {
+			return fmt.Errorf("modifier group %s requires more choices than it has options", group.Name)
+		}
+		if group.ID.IsZero() {
+			group.ID = primitive.NewObjectID()
+		}
+
+		for j := range group.Options {
+			option := &group.Options[j]
+			if option.Name == "" {
+				return fmt.Errorf("modifier group %s has an option without a name", group.Name)
+			}
+			if option.Price < 0 {
+				return fmt.Errorf("option %s has a negative price", option.Name)
+			}
+			if option.ID.IsZero() {
+				option.ID = primitive.NewObjectID()
+			}
+			if err := prepareModifierGroups(option.Groups, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveVariant returns the base unit price for an order line: the chosen
+// variant's price if the item has variants (choosing one is then
+// required), otherwise the item's own price.
+func resolveVariant(item *models.MenuItem, variantIDHex string) (float64, *models.OrderItemVariant, error) {
+	if len(item.Variants) == 0 {
+		if variantIDHex != "" {
+			return 0, nil, fmt.Errorf("%s has no variants to choose from", item.Name)
+		}
+		return item.Price, nil, nil
+	}
+
+	if variantIDHex == "" {
+		return 0, nil, fmt.Errorf("choose a variant of %s", item.Name)
+	}
+	variantID, err := primitive.ObjectIDFromHex(variantIDHex)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid variant ID: %s", variantIDHex)
+	}
+
+	for _, variant := range item.Variants {
+		if variant.ID != variantID {
+			continue
+		}
+		if variant.SoldOut {
+			return 0, nil, fmt.Errorf("%s (%s) is sold out", item.Name, variant.Name)
+		}
+		return variant.Price, &models.OrderItemVariant{
+			VariantID: variant.ID,
+			Name:      variant.Name,
+			Price:     variant.Price,
+		}, nil
+	}
+	return 0, nil, fmt.Errorf("variant not found: %s", variantIDHex)
+}
+
+// resolveModifiers checks the customer's picks against groups — every pick
+// names a real, in-stock option, no option is picked twice, and each
+// group's min/max selection counts hold — and returns the snapshot to
+// store on the order plus the per-unit price they add. Nested groups are
+// only checked under options that were actually picked.
+func resolveModifiers(groups []models.ModifierGroup, picks []models.OrderModifierRequest) ([]models.OrderModifier, float64, error) {
+	byID := make(map[primitive.ObjectID]*models.ModifierGroup, len(groups))
+	for i := range groups {
+		byID[groups[i].ID] = &groups[i]
+	}
+
+	counts := make(map[primitive.ObjectID]int)
+	picked := make(map[primitive.ObjectID]bool)
+	var modifiers []models.OrderModifier
+	var price float64
+
+	for _, pick := range picks {
+		groupID, err := primitive.ObjectIDFromHex(pick.GroupID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid modifier group ID: %s", pick.GroupID)
+		}
+		optionID, err := primitive.ObjectIDFromHex(pick.OptionID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid modifier option ID: %s", pick.OptionID)
+		}
+
+		group, ok := byID[groupID]
+		if !ok {
+			return nil, 0, fmt.Errorf("modifier group not found: %s", pick.GroupID)
+		}
+
+		var option *models.ModifierOption
+		for i := range group.Options {
+			if group.Options[i].ID == optionID {
+				option = &group.Options[i]
+				break
+			}
+		}
+		if option == nil {
+			return nil, 0, fmt.Errorf("option not found in %s: %s", group.Name, pick.OptionID)
+		}
+		if option.SoldOut {
+			return nil, 0, fmt.Errorf("%s is sold out", option.Name)
+		}
+		if picked[optionID] {
+			return nil, 0, fmt.Errorf("%s was chosen more than once", option.Name)
+		}
+		picked[optionID] = true
+		counts[groupID]++
+
+		nested, nestedPrice, err := resolveModifiers(option.Groups, pick.Modifiers)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		modifiers = append(modifiers, models.OrderModifier{
+			GroupID:   group.ID,
+			GroupName: group.Name,
+			OptionID:  option.ID,
+			Name:      option.Name,
+			Price:     option.Price,
+			Modifiers: nested,
+		})
+		price += option.Price + nestedPrice
+	}
+
+	for _, group := range groups {
+		count := counts[group.ID]
+		if count < group.MinSelect {
+			return nil, 0, fmt.Errorf("choose at least %d from %s", group.MinSelect, group.Name)
+		}
+		if group.MaxSelect > 0 && count > group.MaxSelect {
+			return nil, 0, fmt.Errorf("choose at most %d from %s", group.MaxSelect, group.Name)
+		}
+	}
+
+	return modifiers, price, nil
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StockAlert is raised when an order takes a stock-tracked item to or below
+// its low-stock threshold, or sells it out. main.go pushes it to the admin
+// room and the restaurant's room.
+type StockAlert struct {
+	RestaurantID  primitive.ObjectID `json:"restaurant_id"`
+	MenuItemID    primitive.ObjectID `json:"menu_item_id"`
+	Name          string             `json:"name"`
+	Remaining     int                `json:"remaining"`
+	DailyQuantity int                `json:"daily_quantity"`
+	SoldOut       bool               `json:"sold_out"`
+}
+
+// stockReservation is one item's quantity taken by an order in progress,
+// kept so it can be given back if the order doesn't go through.
+type stockReservation struct {
+	itemID primitive.ObjectID
+	qty    int
+}
+
+// applyStockRequest turns the daily_stock / low_stock_threshold fields of a
+// menu item request into the item's new stock. A daily_stock of 0 stops
+// tracking (nil); any other value refills Remaining to it, which is how a
+// restaurant restocks mid-day.
+func applyStockRequest(current *models.MenuItemStock, dailyStock, threshold *int) (*models.MenuItemStock, error) {
+	if dailyStock != nil && *dailyStock < 0 {
+		return nil, errors.New("daily_stock can't be negative")
+	}
+	if threshold != nil && *threshold < 0 {
+		return nil, errors.New("low_stock_threshold can't be negative")
+	}
+
+	if dailyStock != nil {
+		if *dailyStock == 0 {
+			return nil, nil
+		}
+		stock := &models.MenuItemStock{
+			DailyQuantity: *dailyStock,
+			Remaining:     *dailyStock,
+			ResetAt:       time.Now(),
+		}
+		if current != nil {
+			stock.LowStockThreshold = current.LowStockThreshold
+		}
+		current = stock
+	}
+
+	if current != nil && threshold != nil {
+		current.LowStockThreshold = *threshold
+	}
+	return current, nil
+}
+
+// reserveStock takes the ordered quantities from every stock-tracked item
+// in draft, all or nothing: if one item has run out in the meantime the
+// ones already taken are put back. It returns what was taken (for
+// releaseReservations) and any alerts to raise once the order is stored.
+func (s *orderService) reserveStock(ctx context.Context, draft *orderDraft) ([]stockReservation, []StockAlert, error) {
+	quantities := make(map[primitive.ObjectID]int)
+	var order []primitive.ObjectID
+	for _, item := range draft.items {
+		menuItem, ok := draft.menu[item.MenuItemID]
+		if !ok || menuItem.Stock == nil {
+			continue
+		}
+		if _, seen := quantities[item.MenuItemID]; !seen {
+			order = append(order, item.MenuItemID)
+		}
+		quantities[item.MenuItemID] += item.Quantity
+	}
+
+	var reserved []stockReservation
+	var alerts []StockAlert
+	for _, itemID := range order {
+		qty := quantities[itemID]
+		item, switchedOff, err := s.menuRepo.ReserveStock(ctx, draft.restaurant.ID, itemID, qty)
+		if err != nil {
+			s.releaseReservations(ctx, reserved)
+			if errors.Is(err, repositories.ErrInsufficientStock) {
+				return nil, nil, fmt.Errorf("not enough %s left", draft.menu[itemID].Name)
+			}
+			return nil, nil, err
+		}
+		reserved = append(reserved, stockReservation{itemID: itemID, qty: qty})
+		if switchedOff {
+			before := *item
+			before.IsAvailable = true
+			writeMenuChange(ctx, s.historyRepo, menuActionUpdated, &before, item, menuSystemActor)
+		}
+
+		// Only alert on the order that crosses the threshold, not on
+		// every order after it.
+		before := item.Stock.Remaining + qty
+		if item.Stock.Remaining == 0 || (before > item.Stock.LowStockThreshold && item.Stock.Remaining <= item.Stock.LowStockThreshold) {
+			alerts = append(alerts, StockAlert{
+				RestaurantID:  item.RestaurantID,
+				MenuItemID:    item.ID,
+				Name:          item.Name,
+				Remaining:     item.Stock.Remaining,
+				DailyQuantity: item.Stock.DailyQuantity,
+				SoldOut:       item.Stock.Remaining == 0,
+			})
+		}
+	}
+	return reserved, alerts, nil
+}
+
+func (s *orderService) releaseReservations(ctx context.Context, reserved []stockReservation) {
+	now := time.Now()
+	for _, r := range reserved {
+		restocked, err := s.menuRepo.ReleaseStock(ctx, r.itemID, r.qty, now)
+		if err != nil {
+			log.Printf("⚠️  Failed to return %d of menu item %s to stock: %v", r.qty, r.itemID.Hex(), err)
+			continue
+		}
+		s.recordRestock(ctx, restocked)
+	}
+}
+
+// releaseOrderStock puts a cancelled or rejected order's items back in
+// stock. Items that aren't stock-tracked are no-ops in the repository, and
+// failures are only logged — the cancellation itself already happened.
+func (s *orderService) releaseOrderStock(ctx context.Context, order *models.Order) {
+	for _, item := range order.Items {
+		restocked, err := s.menuRepo.ReleaseStock(ctx, item.MenuItemID, item.Quantity, order.CreatedAt)
+		if err != nil {
+			log.Printf("⚠️  Failed to return stock for order %s: %v", order.ID.Hex(), err)
+			continue
+		}
+		s.recordRestock(ctx, restocked)
+	}
+}
+
+// recordRestock records a sold-out item that returned stock switched back
+// on. before is what ReleaseStock returned: nil when nothing was switched.
+func (s *orderService) recordRestock(ctx context.Context, before *models.MenuItem) {
+	if before == nil {
+		return
+	}
+	writeMenuChange(ctx, s.historyRepo, menuActionUpdated, before, switchedBackOn(before), menuSystemActor)
+}
+
+// switchedBackOn is a sold-out item as it is once stock has come back.
+func switchedBackOn(before *models.MenuItem) *models.MenuItem {
+	after := *before
+	after.IsAvailable = true
+	if before.Stock != nil {
+		stock := *before.Stock
+		stock.AutoDisabled = false
+		after.Stock = &stock
+	}
+	return &after
+}
+
+func (s *orderService) OnStockAlert(fn func(StockAlert)) {
+	s.stockAlert = fn
+}
+
+func (s *orderService) raiseStockAlerts(alerts []StockAlert) {
+	if s.stockAlert == nil {
+		return
+	}
+	for _, alert := range alerts {
+		s.stockAlert(alert)
+	}
+}
+
+// stockResetClock is when daily stock refills, in the service timezone —
+// STOCK_RESET_TIME as HH:MM, 04:00 by default (after the latest close,
+// before the earliest breakfast).
+func stockResetClock() (hour, minute int) {
+	hour, minute = 4, 0
+	if value := os.Getenv("STOCK_RESET_TIME"); value != "" {
+		if t, err := time.Parse("15:04", value); err == nil {
+			hour, minute = t.Hour(), t.Minute()
+		} else {
+			log.Printf("⚠️  Ignoring invalid STOCK_RESET_TIME %q", value)
+		}
+	}
+	return hour, minute
+}
+
+// lastStockReset is the most recent reset time at or before now.
+func lastStockReset(now time.Time) time.Time {
+	hour, minute := stockResetClock()
+	local := now.In(serviceLocation)
+	reset := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, serviceLocation)
+	if reset.After(local) {
+		reset = reset.AddDate(0, 0, -1)
+	}
+	return reset
+}
+
+func (s *restaurantService) ResetDailyStock(ctx context.Context, now time.Time) (int64, error) {
+	reset, reenabled, err := s.menuRepo.ResetDailyStock(ctx, lastStockReset(now))
+	if err != nil {
+		return 0, err
+	}
+	for i := range reenabled {
+		s.recordMenuChange(ctx, menuActionUpdated, &reenabled[i], switchedBackOn(&reenabled[i]), menuSystemActor)
+	}
+	return reset, nil
+}
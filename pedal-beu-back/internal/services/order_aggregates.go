@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ratings and earnings are kept as running totals rather than recomputed
+// from order history on every read: each driver and restaurant document
+// carries its rating sum and count, each driver their trips, order
+// decisions and lifetime earnings, and driver_earnings_daily holds one
+// bucket per driver per day. They're bumped as orders are assigned,
+// rejected, delivered and rated; RebuildAggregates recomputes them all
+// from scratch (`pedal-delivery rebuild-aggregates`, or POST
+// /api/v1/admin/aggregates/rebuild) after a deploy that predates them or
+// if they ever drift.
+
+// aggregateDayLayout is how driver_earnings_daily names its days.
+const aggregateDayLayout = "2006-01-02"
+
+// aggregateDay is the service-area day t falls on.
+func aggregateDay(t time.Time) string {
+	return t.In(serviceLocation).Format(aggregateDayLayout)
+}
+
+func parseAggregateDay(day string) (time.Time, error) {
+	return time.ParseInLocation(aggregateDayLayout, day, serviceLocation)
+}
+
+// deliveredAt is when an order was delivered; orders delivered before
+// actual_delivery was stamped fall back to their last update.
+func deliveredAt(order *models.Order) time.Time {
+	if order.DeliveryInfo.ActualDelivery != nil {
+		return *order.DeliveryInfo.ActualDelivery
+	}
+	return order.UpdatedAt
+}
+
+// recordDelivery adds a just-delivered order, and any tip given at
+// checkout, to its driver's totals and daily bucket and posts them to the
+// payout ledger. A cash order delivered
+// without the driver confirming the cash (an admin closing it out) is
+// taken to have been paid in full. Failures are logged rather than
+// returned: the delivery itself has already happened, and a rebuild
+// restores any totals that were missed.
+func (s *orderService) recordDelivery(ctx context.Context, order *models.Order, at time.Time) {
+	if order.DriverID == nil {
+		return
+	}
+	if isCashOrder(order) && order.CashCollection == nil {
+		if _, err := s.recordCashCollection(ctx, order, amountDue(order), false); err != nil {
+			fmt.Printf("⚠️  Failed to record cash collected for order %s: %v\n", order.ID.Hex(), err)
+		}
+	}
+	baseFee, surgeBonus := order.TotalAmount.DeliveryFee, order.TotalAmount.SurgeFee
+
+	if err := s.driverRepo.RecordDelivery(ctx, *order.DriverID, baseFee, surgeBonus); err != nil {
+		fmt.Printf("⚠️  Failed to record delivery for driver %s: %v\n", order.DriverID.Hex(), err)
+	}
+	if err := s.earningsRepo.AddDelivery(ctx, *order.DriverID, aggregateDay(at), baseFee, surgeBonus); err != nil {
+		fmt.Printf("⚠️  Failed to update daily earnings for driver %s: %v\n", order.DriverID.Hex(), err)
+	}
+	if err := s.ledger.RecordDelivery(ctx, order); err != nil {
+		fmt.Printf("⚠️  Failed to post delivery of order %s to the ledger: %v\n", order.ID.Hex(), err)
+	}
+	s.creditTip(ctx, order, at, false)
+}
+
+// recordRating folds a new order rating into the driver's and restaurant's
+// averages, which are surfaced on the customer's live tracking card
+// (DriverContact) and the restaurant listing/detail screens.
+func (s *orderService) recordRating(ctx context.Context, order *models.Order, rating models.OrderRating) {
+	if order.DriverID != nil && rating.DeliveryRating > 0 {
+		if err := s.driverRepo.AddRating(ctx, *order.DriverID, rating.DeliveryRating); err != nil {
+			fmt.Printf("⚠️  Failed to update driver rating for %s: %v\n", order.DriverID.Hex(), err)
+		}
+	}
+	if rating.RestaurantRating > 0 {
+		if err := s.restaurantRepo.AddRating(ctx, order.RestaurantID, rating.RestaurantRating); err != nil {
+			fmt.Printf("⚠️  Failed to update restaurant rating for %s: %v\n", order.RestaurantID.Hex(), err)
+		}
+	}
+}
+
+// recordOrderDecision counts an order a driver took or turned down towards
+// their acceptance rate.
+func (s *orderService) recordOrderDecision(ctx context.Context, driverID primitive.ObjectID, accepted bool) {
+	if err := s.driverRepo.RecordOrderDecision(ctx, driverID, accepted); err != nil {
+		fmt.Printf("⚠️  Failed to update order counts for driver %s: %v\n", driverID.Hex(), err)
+	}
+}
+
+// AggregateRebuildReport says what RebuildAggregates went through.
+type AggregateRebuildReport struct {
+	Orders      int `json:"orders"`
+	Deliveries  int `json:"deliveries"`
+	Ratings     int `json:"ratings"`
+	Drivers     int `json:"drivers"`
+	Restaurants int `json:"restaurants"`
+	EarningDays int `json:"earning_days"`
+}
+
+type ratingTotals struct {
+	sum, count int
+}
+
+type driverDay struct {
+	driverID primitive.ObjectID
+	day      string
+}
+
+// RebuildAggregates streams every order once, recomputes all the running
+// totals in memory and then overwrites the stored ones. Orders delivered
+// or rated while it runs can be missed, so run it when things are quiet;
+// running it again is always safe.
+func (s *orderService) RebuildAggregates(ctx context.Context) (*AggregateRebuildReport, error) {
+	report := &AggregateRebuildReport{}
+	drivers := make(map[primitive.ObjectID]*repositories.DriverAggregates)
+	restaurants := make(map[primitive.ObjectID]*ratingTotals)
+	days := make(map[driverDay]*models.DriverEarningsDay)
+
+	driver := func(id primitive.ObjectID) *repositories.DriverAggregates {
+		if drivers[id] == nil {
+			drivers[id] = &repositories.DriverAggregates{}
+		}
+		return drivers[id]
+	}
+
+	err := s.orderRepo.ForEach(ctx, func(order *models.Order) error {
+		report.Orders++
+
+		for _, rejectedBy := range order.RejectedByDrivers {
+			driver(rejectedBy).RejectedOrders++
+		}
+
+		if order.DriverID != nil {
+			d := driver(*order.DriverID)
+			d.AcceptedOrders++
+
+			if order.Status == models.OrderDelivered {
+				report.Deliveries++
+				baseFee, surgeBonus, tip := order.TotalAmount.DeliveryFee, order.TotalAmount.SurgeFee, order.TotalAmount.Tip
+				d.TotalTrips++
+				d.Earnings += baseFee + surgeBonus + tip
+				d.SurgeBonus += surgeBonus
+				d.Tips += tip
+
+				key := driverDay{*order.DriverID, aggregateDay(deliveredAt(order))}
+				if days[key] == nil {
+					days[key] = &models.DriverEarningsDay{DriverID: key.driverID, Day: key.day}
+				}
+				days[key].Deliveries++
+				days[key].Earnings += baseFee + surgeBonus + tip
+				days[key].BaseFees += baseFee
+				days[key].SurgeBonus += surgeBonus
+				days[key].Tips += tip
+			}
+
+			if order.Rating != nil && order.Rating.DeliveryRating > 0 {
+				d.RatingSum += order.Rating.DeliveryRating
+				d.RatingCount++
+			}
+		}
+
+		if order.Rating != nil {
+			report.Ratings++
+			if order.Rating.RestaurantRating > 0 {
+				if restaurants[order.RestaurantID] == nil {
+					restaurants[order.RestaurantID] = &ratingTotals{}
+				}
+				restaurants[order.RestaurantID].sum += order.Rating.RestaurantRating
+				restaurants[order.RestaurantID].count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.driverRepo.ResetAggregates(ctx); err != nil {
+		return nil, err
+	}
+	for id, aggregates := range drivers {
+		if err := s.driverRepo.SetAggregates(ctx, id, *aggregates); err != nil {
+			return nil, err
+		}
+	}
+	report.Drivers = len(drivers)
+
+	if err := s.restaurantRepo.ResetRatings(ctx); err != nil {
+		return nil, err
+	}
+	for id, totals := range restaurants {
+		if err := s.restaurantRepo.SetRating(ctx, id, totals.sum, totals.count); err != nil {
+			return nil, err
+		}
+	}
+	report.Restaurants = len(restaurants)
+
+	buckets := make([]models.DriverEarningsDay, 0, len(days))
+	for _, day := range days {
+		buckets = append(buckets, *day)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].DriverID != buckets[j].DriverID {
+			return buckets[i].DriverID.Hex() < buckets[j].DriverID.Hex()
+		}
+		return buckets[i].Day < buckets[j].Day
+	})
+	if err := s.earningsRepo.ReplaceAll(ctx, buckets); err != nil {
+		return nil, err
+	}
+	report.EarningDays = len(buckets)
+
+	return report, nil
+}
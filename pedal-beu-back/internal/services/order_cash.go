@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cash-on-delivery: the driver confirms the cash they took before marking
+// a cash order delivered. That posts a cash_collected debit to their
+// ledger and adds to their cash balance, which comes back down as they
+// deposit cash with an admin or have it netted off a settlement payout.
+// Drivers at their cash limit aren't offered or allowed to take cash
+// orders until it does.
+
+var (
+	// ErrCashNotConfirmed is returned when a driver marks a cash order
+	// delivered without confirming the cash they collected.
+	ErrCashNotConfirmed = errors.New("confirm the cash collected before marking this order delivered")
+	// ErrCashLimitReached is returned when a driver holding cash up to
+	// their limit tries to take another cash order.
+	ErrCashLimitReached = errors.New("you're holding too much cash to take cash orders; deposit it first")
+)
+
+func (s *orderService) ConfirmCashCollected(ctx context.Context, orderID, driverID primitive.ObjectID, req models.ConfirmCashRequest) (*models.CashCollection, error) {
+	order, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.DriverID == nil || *order.DriverID != driverID {
+		return nil, errors.New("this order isn't assigned to you")
+	}
+	if !isCashOrder(order) {
+		return nil, errors.New("this order isn't paid in cash")
+	}
+	switch order.Status {
+	case models.OrderPickedUp, models.OrderOnTheWay, models.OrderDelivered:
+	default:
+		return nil, errors.New("cash can only be confirmed once the order is picked up")
+	}
+	if order.CashCollection != nil {
+		return nil, errors.New("cash for this order has already been confirmed")
+	}
+
+	amount := roundMoney(req.Amount)
+	if amount <= 0 {
+		return nil, errors.New("amount must be at least 0.01")
+	}
+	if due := amountDue(order); amount > due {
+		return nil, fmt.Errorf("amount can't be more than the %.2f due on the order", due)
+	}
+
+	collection, err := s.recordCashCollection(ctx, order, amount, true)
+	if err != nil {
+		return nil, err
+	}
+	if collection == nil {
+		return nil, errors.New("cash for this order has already been confirmed")
+	}
+	return collection, nil
+}
+
+// recordCashCollection stamps the cash collected on the order and posts it
+// to the driver's ledger, flagging the order if it came up short. It
+// returns nil if the order's cash had already been recorded.
+func (s *orderService) recordCashCollection(ctx context.Context, order *models.Order, amount float64, confirmed bool) (*models.CashCollection, error) {
+	collection := models.CashCollection{
+		Amount:      roundMoney(amount),
+		Expected:    amountDue(order),
+		Confirmed:   confirmed,
+		DriverID:    *order.DriverID,
+		CollectedAt: time.Now(),
+	}
+	recorded, err := s.orderRepo.RecordCashCollection(ctx, order.ID, collection)
+	if err != nil || !recorded {
+		return nil, err
+	}
+	order.CashCollection = &collection
+
+	if err := s.ledger.RecordCashCollected(ctx, order, collection.Amount); err != nil {
+		fmt.Printf("⚠️  Failed to post cash collected for order %s to the ledger: %v\n", order.ID.Hex(), err)
+	}
+
+	if collection.Amount < collection.Expected {
+		flag := models.OrderFlag{
+			Type:    "cash_short",
+			Reason:  fmt.Sprintf("Driver collected %.2f of %.2f in cash", collection.Amount, collection.Expected),
+			ActorID: collection.DriverID,
+		}
+		if err := s.orderRepo.AddFlag(ctx, order.ID, flag); err != nil {
+			fmt.Printf("⚠️  Failed to flag order %s: %v\n", order.ID.Hex(), err)
+		}
+	}
+	return &collection, nil
+}
+
+// checkCashLimit stops a driver at their cash limit from taking a cash
+// order.
+func (s *orderService) checkCashLimit(ctx context.Context, orderID, driverID primitive.ObjectID) error {
+	order, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil || !isCashOrder(order) {
+		return nil
+	}
+	driver, err := s.driverRepo.FindByUserID(ctx, driverID)
+	if err != nil || driver == nil {
+		return nil
+	}
+	if cashLimitReached(driver) {
+		return ErrCashLimitReached
+	}
+	return nil
+}
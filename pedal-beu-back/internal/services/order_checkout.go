@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Hosted checkout: an order paid through a payment provider (its payment
+// method is the provider's name, e.g. "chapa") is placed awaiting_payment,
+// which drivers don't see, and CreateOrder returns it with the provider's
+// checkout URL for the customer to pay at. The provider confirms the
+// payment by webhook, which PaymentEventService stores and hands to
+// ApplyPaymentUpdate, and PollCheckouts asks it directly about any it
+// hasn't heard back on. The first confirmation releases the order to
+// drivers; a failed checkout, or one still unpaid when it expires,
+// cancels the order.
+
+// startCheckout opens the provider checkout for a newly saved order.
+func (s *orderService) startCheckout(ctx context.Context, order *models.Order, provider PaymentProvider, customer *models.User) error {
+	amount := amountDue(order)
+	session, err := provider.InitiateCheckout(ctx, CheckoutRequest{
+		Reference:   order.ID.Hex(),
+		Amount:      amount,
+		Currency:    "ETB",
+		Email:       customer.Email,
+		FirstName:   customer.Profile.FirstName,
+		LastName:    customer.Profile.LastName,
+		Phone:       customer.Phone,
+		Description: "Order " + order.OrderNumber,
+		CallbackURL: paymentCallbackURL(provider.Name()),
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	checkout := models.OrderCheckout{
+		Provider:    provider.Name(),
+		Reference:   session.Reference,
+		CheckoutURL: session.CheckoutURL,
+		Amount:      amount,
+		InitiatedAt: now,
+		ExpiresAt:   now.Add(checkoutTTL()),
+	}
+	if err := s.orderRepo.SetCheckout(ctx, order.ID, checkout); err != nil {
+		return err
+	}
+	order.Checkout = &checkout
+	return nil
+}
+
+// cancelUnpaidOrder cancels an order whose checkout won't be paid, if it's
+// still awaiting payment, and puts back what it held.
+func (s *orderService) cancelUnpaidOrder(ctx context.Context, order *models.Order, reason string) error {
+	cancelled, err := s.orderRepo.CancelAwaitingPayment(ctx, order.ID, models.CancellationInfo{
+		Reason:      reason,
+		CancelledBy: primitive.NilObjectID,
+		Role:        "system",
+	})
+	if err != nil || !cancelled {
+		return err
+	}
+	s.releaseOrderStock(ctx, order)
+	s.returnWalletPayment(ctx, order)
+	return nil
+}
+
+// checkoutVerification is the payment verification a provider's update
+// leaves on an order.
+func checkoutVerification(provider string, update *PaymentUpdate, status string, at time.Time) *models.PaymentVerification {
+	verification := &models.PaymentVerification{
+		Method:               provider,
+		Status:               status,
+		TransactionReference: update.ProviderReference,
+		ProviderStatus:       update.ProviderStatus,
+		CheckedAt:            &at,
+		RawResponse:          update.Raw,
+	}
+	if status == "verified" {
+		verification.VerifiedAt = &at
+	}
+	return verification
+}
+
+// ApplyPaymentUpdate acts on what a provider reported about an order's
+// checkout. Updates about orders no longer awaiting payment change
+// nothing, so repeated and late updates are harmless — except a payment
+// for an order already cancelled, which is flagged for a refund.
+func (s *orderService) ApplyPaymentUpdate(ctx context.Context, providerName string, update *PaymentUpdate) error {
+	orderID, err := primitive.ObjectIDFromHex(update.Reference)
+	if err != nil {
+		return fmt.Errorf("payment reference %q isn't one of our orders", update.Reference)
+	}
+	order, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Checkout == nil || order.Checkout.Provider != providerName {
+		return fmt.Errorf("order %s isn't paid through %s", order.OrderNumber, providerName)
+	}
+
+	now := time.Now()
+	if order.Status != models.OrderAwaitingPayment {
+		if update.Status == PaymentSucceeded && order.Status == models.OrderCancelled && order.PaymentStatus != "paid" {
+			s.flagPayment(ctx, order, "paid_after_cancellation",
+				fmt.Sprintf("%s payment %s came in after the order was cancelled; refund it", providerName, update.ProviderReference))
+			return s.orderRepo.UpdatePaymentVerification(ctx, order.ID, "paid", checkoutVerification(providerName, update, "verified", now))
+		}
+		return nil
+	}
+
+	switch update.Status {
+	case PaymentSucceeded:
+		if !amountMatches(update.Amount, order.Checkout.Amount) {
+			verification := checkoutVerification(providerName, update, "failed", now)
+			verification.FailureReason = fmt.Sprintf("paid amount %.2f did not match the checkout amount %.2f", update.Amount, order.Checkout.Amount)
+			s.flagPayment(ctx, order, "payment_amount_mismatch", verification.FailureReason)
+			return s.orderRepo.UpdatePaymentVerification(ctx, order.ID, "pending", verification)
+		}
+
+		released, err := s.orderRepo.ReleasePaidOrder(ctx, order.ID, checkoutVerification(providerName, update, "verified", now), now)
+		if err != nil {
+			return err
+		}
+		if released && s.orderReleased != nil {
+			order.Status = models.OrderAccepted
+			order.PaymentStatus = "paid"
+			order.ReleasedAt = &now
+			s.orderReleased(*order)
+		}
+		return nil
+	case PaymentFailed:
+		verification := checkoutVerification(providerName, update, "failed", now)
+		verification.FailureReason = "the payment provider reported the payment failed"
+		if err := s.orderRepo.UpdatePaymentVerification(ctx, order.ID, "failed", verification); err != nil {
+			return err
+		}
+		return s.cancelUnpaidOrder(ctx, order, "Payment failed")
+	default:
+		return nil
+	}
+}
+
+func (s *orderService) flagPayment(ctx context.Context, order *models.Order, flagType, reason string) {
+	fmt.Printf("⚠️  Order %s: %s\n", order.ID.Hex(), reason)
+	if err := s.orderRepo.AddFlag(ctx, order.ID, models.OrderFlag{Type: flagType, Reason: reason}); err != nil {
+		fmt.Printf("⚠️  Failed to flag order %s: %v\n", order.ID.Hex(), err)
+	}
+}
+
+// PollCheckouts asks the providers about every order still awaiting
+// payment, applying what they report, and cancels those whose checkout
+// has expired unpaid. It returns how many orders it settled either way.
+func (s *orderService) PollCheckouts(ctx context.Context) (int, error) {
+	orders, err := s.orderRepo.FindAwaitingPayment(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	now := time.Now()
+	for i := range orders {
+		order := &orders[i]
+		expired := now.After(order.CreatedAt.Add(checkoutTTL()))
+		if order.Checkout != nil {
+			expired = now.After(order.Checkout.ExpiresAt)
+			if provider := s.payments.Get(order.Checkout.Provider); provider != nil {
+				update, err := provider.FetchStatus(ctx, order.Checkout.Reference)
+				if err != nil {
+					fmt.Printf("⚠️  Failed to check payment for order %s with %s: %v\n", order.ID.Hex(), provider.Name(), err)
+				} else if update.Status != PaymentPending {
+					if err := s.ApplyPaymentUpdate(ctx, provider.Name(), update); err != nil {
+						fmt.Printf("⚠️  Failed to apply payment for order %s: %v\n", order.ID.Hex(), err)
+						continue
+					}
+					settled++
+					continue
+				}
+			}
+		}
+
+		if expired {
+			if err := s.cancelUnpaidOrder(ctx, order, "Payment wasn't completed in time"); err != nil {
+				fmt.Printf("⚠️  Failed to cancel unpaid order %s: %v\n", order.ID.Hex(), err)
+				continue
+			}
+			settled++
+		}
+	}
+	return settled, nil
+}
+
+func (s *orderService) OnOrderReleased(fn func(models.Order)) {
+	s.orderReleased = fn
+}
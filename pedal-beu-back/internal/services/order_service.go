@@ -11,12 +11,12 @@ import (
 	"github.com/haile-paa/pedal-delivery/internal/models"
 	"github.com/haile-paa/pedal-delivery/internal/repositories"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type OrderService interface {
 	CreateOrder(ctx context.Context, customerID primitive.ObjectID, req *models.CreateOrderRequest) (*models.Order, error)
+	QuoteOrder(ctx context.Context, customerID primitive.ObjectID, req *models.OrderQuoteRequest) (*models.OrderQuote, error)
 	GetOrderByID(ctx context.Context, orderID primitive.ObjectID, userID primitive.ObjectID, userRole string) (*OrderWithDriver, error)
 	GetCustomerOrders(ctx context.Context, customerID primitive.ObjectID, page, limit int64) ([]models.Order, int64, error)
 	GetDriverOrders(ctx context.Context, driverID primitive.ObjectID, page, limit int64) ([]models.Order, int64, error)
@@ -24,6 +24,9 @@ type OrderService interface {
 	UpdateOrderStatus(ctx context.Context, orderID primitive.ObjectID, status models.OrderStatus, actorID primitive.ObjectID, actorRole string) error
 	AssignDriver(ctx context.Context, orderID, driverID primitive.ObjectID) error
 	RejectOrder(ctx context.Context, orderID, driverID primitive.ObjectID) error
+	// ConfirmCashCollected records the cash the driver took for a cash
+	// order (see order_cash.go); they must before marking it delivered.
+	ConfirmCashCollected(ctx context.Context, orderID, driverID primitive.ObjectID, req models.ConfirmCashRequest) (*models.CashCollection, error)
 	GetAvailableOrders(ctx context.Context, driverID primitive.ObjectID, driverLocation models.GeoLocation, radius float64) ([]OrderWithRestaurant, error)
 	CancelOrder(ctx context.Context, orderID primitive.ObjectID, userID primitive.ObjectID, userRole, reason string) error
 	RateOrder(ctx context.Context, orderID primitive.ObjectID, rating *models.OrderRating) error
@@ -35,6 +38,21 @@ type OrderService interface {
 	SubmitPaymentProof(ctx context.Context, orderID primitive.ObjectID, customerID primitive.ObjectID, req *models.SubmitPaymentProofRequest) (*models.Order, error)
 	ReviewPaymentProof(ctx context.Context, orderID primitive.ObjectID, adminID primitive.ObjectID, req *models.ReviewPaymentProofRequest) (*models.Order, error)
 	GetPaymentVerificationHealth(ctx context.Context) map[string]interface{}
+	// RetryPaymentVerifications retries the transfer verifications that
+	// are due (see payment_verification_retry.go), and
+	// OnPaymentVerificationUpdated registers the callback for each change
+	// they make; main.go sets it to tell the order's room.
+	RetryPaymentVerifications(ctx context.Context) (int, error)
+	OnPaymentVerificationUpdated(fn func(models.Order))
+	// The payment review queue (see payment_review.go): orders whose
+	// payment waits on an admin, claimed one admin at a time.
+	// OnPaymentReviewUpdated registers the callback for each claim,
+	// release and decision; main.go sets it to tell the admin room.
+	GetPaymentReviewQueue(ctx context.Context, adminID primitive.ObjectID, page, limit int64) ([]PaymentReviewItem, int64, error)
+	ClaimPaymentReview(ctx context.Context, orderID, adminID primitive.ObjectID) (*models.Order, error)
+	ReleasePaymentReview(ctx context.Context, orderID, adminID primitive.ObjectID) error
+	BulkReviewPaymentProof(ctx context.Context, adminID primitive.ObjectID, req *models.BulkReviewPaymentProofRequest) []PaymentReviewResult
+	OnPaymentReviewUpdated(fn func(models.Order))
 	GetDriverStats(ctx context.Context, driverID primitive.ObjectID) (map[string]interface{}, error)
 	GetDriverEarningsChart(ctx context.Context, driverID primitive.ObjectID, rangeType string) (map[string]interface{}, error)
 	GetDriverEarningsTransactions(ctx context.Context, driverID primitive.ObjectID, limit int64) ([]map[string]interface{}, error)
@@ -42,26 +60,93 @@ type OrderService interface {
 	// `olderThan` and returns the orders it cancelled, so the caller
 	// (main.go's sweep ticker) can broadcast order:cancelled for each one.
 	AutoCancelStaleOrders(ctx context.Context, olderThan time.Duration) ([]models.Order, error)
+	// ProcessDriverLocation runs a driver's GPS ping through the geofence
+	// engine and returns any arrivals it detected, for the websocket layer
+	// to broadcast. See geofence_service.go.
+	ProcessDriverLocation(ctx context.Context, driverID primitive.ObjectID, lat, lng float64) ([]GeofenceEvent, error)
+	// OnStockAlert registers the callback for low-stock / sold-out alerts
+	// raised by CreateOrder (see menu_stock.go). main.go sets it to push
+	// them over the websocket hub.
+	OnStockAlert(fn func(StockAlert))
+	// GetTipPresets returns the suggested tips and tip limits, and AddTip
+	// tips the driver of a delivered order (see order_tips.go).
+	GetTipPresets() TipPresets
+	AddTip(ctx context.Context, orderID, customerID primitive.ObjectID, req models.TipRequest) (*models.Order, error)
+	// OnTipReceived registers the callback for tips credited to a driver;
+	// main.go sets it to push them to the driver over the websocket hub.
+	OnTipReceived(fn func(TipReceived))
+	// ApplyPaymentUpdate acts on what a payment provider reported about
+	// an order's checkout, as PaymentEventService does for webhooks, and
+	// PollCheckouts asks the providers about checkouts still unpaid (see
+	// order_checkout.go).
+	ApplyPaymentUpdate(ctx context.Context, provider string, update *PaymentUpdate) error
+	PollCheckouts(ctx context.Context) (int, error)
+	// OnOrderReleased registers the callback for orders released to
+	// drivers once their checkout is paid; main.go sets it to announce
+	// them to drivers the way CreateOrder's handler does.
+	OnOrderReleased(fn func(models.Order))
+	// RebuildAggregates recomputes every driver's and restaurant's rating
+	// and earnings aggregates from order history (see order_aggregates.go).
+	RebuildAggregates(ctx context.Context) (*AggregateRebuildReport, error)
 }
 
 type orderService struct {
 	orderRepo      repositories.OrderRepository
 	restaurantRepo repositories.RestaurantRepository
+	menuRepo       repositories.MenuItemRepository
+	categoryRepo   repositories.MenuCategoryRepository
+	historyRepo    repositories.MenuHistoryRepository
 	userRepo       repositories.UserRepository
 	driverRepo     repositories.DriverRepository
+	earningsRepo   repositories.DriverEarningsRepository
+	refundRepo     repositories.RefundRepository
+	zones          ZoneService
+	ledger         LedgerService
+	wallet         WalletService
+	payments       PaymentProviders
+	verifyJobs     repositories.PaymentVerificationJobRepository
+	verifyStats    *verificationStats
+	geofence       *geofenceTracker
+	stockAlert     func(StockAlert)
+	tipReceived    func(TipReceived)
+	orderReleased  func(models.Order)
+	paymentChecked func(models.Order)
+	reviewChanged  func(models.Order)
 }
 
 func NewOrderService(
 	orderRepo repositories.OrderRepository,
 	restaurantRepo repositories.RestaurantRepository,
+	menuRepo repositories.MenuItemRepository,
+	categoryRepo repositories.MenuCategoryRepository,
+	historyRepo repositories.MenuHistoryRepository,
 	userRepo repositories.UserRepository,
 	driverRepo repositories.DriverRepository,
+	earningsRepo repositories.DriverEarningsRepository,
+	refundRepo repositories.RefundRepository,
+	zones ZoneService,
+	ledger LedgerService,
+	wallet WalletService,
+	payments PaymentProviders,
+	verifyJobs repositories.PaymentVerificationJobRepository,
 ) OrderService {
 	return &orderService{
 		orderRepo:      orderRepo,
 		restaurantRepo: restaurantRepo,
+		menuRepo:       menuRepo,
+		categoryRepo:   categoryRepo,
+		historyRepo:    historyRepo,
 		userRepo:       userRepo,
 		driverRepo:     driverRepo,
+		earningsRepo:   earningsRepo,
+		refundRepo:     refundRepo,
+		zones:          zones,
+		ledger:         ledger,
+		wallet:         wallet,
+		payments:       payments,
+		verifyJobs:     verifyJobs,
+		verifyStats:    newVerificationStats(),
+		geofence:       newGeofenceTracker(),
 	}
 }
 
@@ -76,6 +161,21 @@ type OrderWithDriver struct {
 	*models.Order
 	Driver     *DriverContact  `json:"driver,omitempty"`
 	Restaurant *RestaurantInfo `json:"restaurant,omitempty"`
+	Refunds    []RefundInfo    `json:"refund_history,omitempty"`
+}
+
+// RefundInfo is what the order detail shows of each refund on the order:
+// what was refunded, why, where the money went and whether it's there yet.
+type RefundInfo struct {
+	ID          string              `json:"id"`
+	Type        string              `json:"type"`
+	Items       []models.RefundItem `json:"items,omitempty"`
+	Amount      float64             `json:"amount"`
+	ReasonCode  string              `json:"reason_code"`
+	Destination string              `json:"destination"`
+	Status      string              `json:"status"`
+	CreatedAt   time.Time           `json:"created_at"`
+	ProcessedAt *time.Time          `json:"processed_at,omitempty"`
 }
 
 // RestaurantInfo is the pickup-location subset of a restaurant attached to
@@ -111,9 +211,26 @@ type DriverContact struct {
 	CompletedDeliveries int     `json:"completed_deliveries"`
 }
 
-func (s *orderService) CreateOrder(ctx context.Context, customerID primitive.ObjectID, req *models.CreateOrderRequest) (*models.Order, error) {
+// orderDraft is a fully validated and priced cart — everything CreateOrder
+// needs to store an order, and everything QuoteOrder shows the customer.
+type orderDraft struct {
+	restaurant *models.Restaurant
+	customer   *models.User
+	address    models.Address
+	zone       *models.ServiceZone // nil when no service zones are configured
+	items      []models.OrderItem
+	menu       map[primitive.ObjectID]models.MenuItem // the ordered menu items, by ID
+	amount     models.OrderAmount
+	distanceKm float64 // -1 if either end has no coordinates
+}
+
+// priceOrder validates a cart against the restaurant's menu, the customer's
+// saved address and the service zones, and prices it. CreateOrder and
+// QuoteOrder both go through here so the quote the customer sees at
+// checkout is computed exactly the way the stored order will be.
+func (s *orderService) priceOrder(ctx context.Context, customerID primitive.ObjectID, restaurantIDHex string, items []models.OrderItemRequest, addressIDHex string) (*orderDraft, error) {
 	// Validate restaurant
-	restaurantID, err := primitive.ObjectIDFromHex(req.RestaurantID)
+	restaurantID, err := primitive.ObjectIDFromHex(restaurantIDHex)
 	if err != nil {
 		return nil, errors.New("invalid restaurant ID")
 	}
@@ -127,35 +244,72 @@ func (s *orderService) CreateOrder(ctx context.Context, customerID primitive.Obj
 		return nil, errors.New("restaurant is not available")
 	}
 
-	// Validate menu items
-	var orderItems []models.OrderItem
-	var subtotal float64
-
-	for _, itemReq := range req.Items {
+	// Load just the ordered items rather than the whole menu
+	menuItemIDs := make([]primitive.ObjectID, 0, len(items))
+	for _, itemReq := range items {
 		menuItemID, err := primitive.ObjectIDFromHex(itemReq.MenuItemID)
 		if err != nil {
 			return nil, fmt.Errorf("invalid menu item ID: %s", itemReq.MenuItemID)
 		}
+		menuItemIDs = append(menuItemIDs, menuItemID)
+	}
 
-		// Find menu item in restaurant
-		var menuItem *models.MenuItem
-		for _, m := range restaurant.Menu {
-			if m.ID == menuItemID {
-				menuItem = &m
-				break
-			}
-		}
+	menuItems, err := s.menuRepo.FindByIDs(ctx, restaurantID, menuItemIDs)
+	if err != nil {
+		return nil, err
+	}
+	menuByID := make(map[primitive.ObjectID]models.MenuItem, len(menuItems))
+	for _, m := range menuItems {
+		menuByID[m.ID] = m
+	}
+
+	// Scheduled menus (breakfast until 11:00 etc.) are checked in the
+	// restaurant's own timezone
+	categories, err := s.categoryRepo.FindByRestaurant(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	restaurantLoc := restaurantTimezone(restaurant)
+	now := time.Now()
+
+	// Validate menu items
+	var orderItems []models.OrderItem
+	var subtotal float64
+
+	for i, itemReq := range items {
+		menuItemID := menuItemIDs[i]
 
-		if menuItem == nil {
+		m, found := menuByID[menuItemID]
+		if !found {
 			return nil, fmt.Errorf("menu item not found: %s", itemReq.MenuItemID)
 		}
+		menuItem := &m
 
 		if !menuItem.IsAvailable {
 			return nil, fmt.Errorf("menu item not available: %s", menuItem.Name)
 		}
 
+		if menuItem.Stock != nil && menuItem.Stock.Remaining < itemReq.Quantity {
+			return nil, fmt.Errorf("only %d %s left", menuItem.Stock.Remaining, menuItem.Name)
+		}
+
+		if i := menuItemCategoryIndex(*menuItem, categories); i >= 0 && !menuCategoryServing(&categories[i], now, restaurantLoc) {
+			return nil, fmt.Errorf("%s isn't available right now (%s is not being served)", menuItem.Name, categories[i].Name)
+		}
+
+		// Base price (the chosen variant's, if the item has sizes) plus
+		// any modifiers, per unit
+		unitPrice, variant, err := resolveVariant(menuItem, itemReq.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		modifiers, modifiersPrice, err := resolveModifiers(menuItem.ModifierGroups, itemReq.Modifiers)
+		if err != nil {
+			return nil, err
+		}
+
 		// Calculate item total
-		itemTotal := menuItem.Price * float64(itemReq.Quantity)
+		itemTotal := (unitPrice + modifiersPrice) * float64(itemReq.Quantity)
 
 		// Add addons if any
 		var addons []models.OrderItemAddon
@@ -182,8 +336,10 @@ func (s *orderService) CreateOrder(ctx context.Context, customerID primitive.Obj
 			MenuItemID: menuItemID,
 			Name:       menuItem.Name,
 			Quantity:   itemReq.Quantity,
-			Price:      menuItem.Price,
+			Price:      unitPrice,
 			Addons:     addons,
+			Variant:    variant,
+			Modifiers:  modifiers,
 			Total:      itemTotal,
 			Notes:      itemReq.Notes,
 		}
@@ -204,7 +360,7 @@ func (s *orderService) CreateOrder(ctx context.Context, customerID primitive.Obj
 	}
 
 	var deliveryAddress models.Address
-	addressID, err := primitive.ObjectIDFromHex(req.AddressID)
+	addressID, err := primitive.ObjectIDFromHex(addressIDHex)
 	if err != nil {
 		return nil, errors.New("invalid address ID")
 	}
@@ -220,6 +376,35 @@ func (s *orderService) CreateOrder(ctx context.Context, customerID primitive.Obj
 		return nil, errors.New("address not found")
 	}
 
+	// Service zones: when any are configured, the drop-off has to be inside
+	// an active one, the zone has to be within its active hours, and the
+	// restaurant has to be inside the zone and within its max delivery
+	// distance.
+	zone, err := s.zones.ZoneFor(ctx, deliveryAddress.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	distanceKm := -1.0
+	if len(restaurant.Location.Coordinates) == 2 && len(deliveryAddress.Location.Coordinates) == 2 {
+		distanceKm = calculateDistance(
+			restaurant.Location.Coordinates[1], restaurant.Location.Coordinates[0],
+			deliveryAddress.Location.Coordinates[1], deliveryAddress.Location.Coordinates[0],
+		)
+	}
+
+	if zone != nil {
+		if !zoneOpenAt(zone, time.Now()) {
+			return nil, fmt.Errorf("delivery in %s is closed right now", zone.Name)
+		}
+		if !zoneContains(zone, restaurant.Location) {
+			return nil, fmt.Errorf("%s doesn't deliver to %s", restaurant.Name, zone.Name)
+		}
+		if zone.MaxDeliveryDistanceKm > 0 && distanceKm > zone.MaxDeliveryDistanceKm {
+			return nil, fmt.Errorf("%s is too far away to deliver to this address (%.1fkm, max %.1fkm)", restaurant.Name, distanceKm, zone.MaxDeliveryDistanceKm)
+		}
+	}
+
 	// Delivery fee: use the restaurant's own configured delivery_fee when
 	// one is set (this is the exact number the customer already saw and
 	// agreed to in the cart/checkout screens), and only fall back to the
@@ -228,24 +413,87 @@ func (s *orderService) CreateOrder(ctx context.Context, customerID primitive.Obj
 	// silently overriding whatever the customer was shown at checkout —
 	// which is why the stored order total could differ from the price the
 	// customer approved.
+	//
+	// A zone's fee bands take over from the generic distance estimate, and
+	// its surge multiplier applies on top of whichever fee was chosen.
 	deliveryFee := restaurant.DeliveryFee
 	if deliveryFee <= 0 {
-		calculatedFee, err := s.CalculateDeliveryFee(ctx, restaurant.Location, deliveryAddress.Location)
-		if err != nil {
-			return nil, err
+		if bandFee, ok := zoneDeliveryFee(zone, distanceKm); ok {
+			deliveryFee = bandFee
+		} else {
+			calculatedFee, err := s.CalculateDeliveryFee(ctx, restaurant.Location, deliveryAddress.Location)
+			if err != nil {
+				return nil, err
+			}
+			deliveryFee = calculatedFee
 		}
-		deliveryFee = calculatedFee
 	}
+	// Surge is kept as its own line rather than folded into DeliveryFee,
+	// so the checkout can show it and driver earnings can report it as a
+	// bonus. It applies to the delivery fee only, never the food.
+	surgeMultiplier := zoneSurgeMultiplier(zone)
+	surgeFee := math.Round(deliveryFee*(surgeMultiplier-1)*100) / 100
 
 	// Calculate total amount
 	totalAmount := models.OrderAmount{
-		Subtotal:      subtotal,
-		DeliveryFee:   deliveryFee,
-		ServiceCharge: subtotal * 0.05, // 5% service charge
-		Discount:      0,
-		Tax:           subtotal * 0.10, // 10% tax
-		Total:         subtotal + deliveryFee + (subtotal * 0.05) + (subtotal * 0.10),
+		Subtotal:        subtotal,
+		DeliveryFee:     deliveryFee,
+		SurgeMultiplier: surgeMultiplier,
+		SurgeFee:        surgeFee,
+		ServiceCharge:   subtotal * 0.05, // 5% service charge
+		Discount:        0,
+		Tax:             subtotal * 0.10, // 10% tax
+		Total:           subtotal + deliveryFee + surgeFee + (subtotal * 0.05) + (subtotal * 0.10),
+	}
+
+	return &orderDraft{
+		restaurant: restaurant,
+		customer:   customer,
+		address:    deliveryAddress,
+		zone:       zone,
+		items:      orderItems,
+		menu:       menuByID,
+		amount:     totalAmount,
+		distanceKm: distanceKm,
+	}, nil
+}
+
+// QuoteOrder prices a cart without placing it. Backs POST /orders/quote,
+// which the checkout screen calls so the customer sees the delivery fee,
+// any surge and the total before committing.
+func (s *orderService) QuoteOrder(ctx context.Context, customerID primitive.ObjectID, req *models.OrderQuoteRequest) (*models.OrderQuote, error) {
+	draft, err := s.priceOrder(ctx, customerID, req.RestaurantID, req.Items, req.AddressID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyTip(&draft.amount, req.Tip); err != nil {
+		return nil, err
+	}
+
+	quote := &models.OrderQuote{
+		RestaurantID:      draft.restaurant.ID,
+		Items:             draft.items,
+		Amount:            draft.amount,
+		EstimatedDelivery: time.Now().Add(time.Duration(draft.restaurant.DeliveryTime) * time.Minute),
+	}
+	if draft.zone != nil {
+		quote.ZoneID = &draft.zone.ID
 	}
+	if draft.distanceKm >= 0 {
+		quote.DistanceKm = math.Round(draft.distanceKm*100) / 100
+	}
+	return quote, nil
+}
+
+func (s *orderService) CreateOrder(ctx context.Context, customerID primitive.ObjectID, req *models.CreateOrderRequest) (*models.Order, error) {
+	draft, err := s.priceOrder(ctx, customerID, req.RestaurantID, req.Items, req.AddressID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyTip(&draft.amount, req.Tip); err != nil {
+		return nil, err
+	}
+	restaurant, customer, zone := draft.restaurant, draft.customer, draft.zone
 
 	// Create order
 	// Status starts as "accepted" (not "pending") so it's immediately
@@ -256,29 +504,68 @@ func (s *orderService) CreateOrder(ctx context.Context, customerID primitive.Obj
 	// from the admin site if needed (see order_service.go transition table).
 	order := &models.Order{
 		CustomerID:         customerID,
-		RestaurantID:       restaurantID,
+		RestaurantID:       restaurant.ID,
 		RestaurantLocation: restaurant.Location,
-		Items:              orderItems,
+		Items:              draft.items,
 		Status:             models.OrderAccepted,
-		TotalAmount:        totalAmount,
+		TotalAmount:        draft.amount,
 		DeliveryInfo: models.DeliveryInfo{
-			Address:           deliveryAddress,
+			Address:           draft.address,
 			Notes:             req.Notes,
 			ContactName:       fmt.Sprintf("%s %s", customer.Profile.FirstName, customer.Profile.LastName),
 			ContactPhone:      customer.Phone,
 			EstimatedDelivery: time.Now().Add(time.Duration(restaurant.DeliveryTime) * time.Minute),
 		},
-		PaymentMethod: req.PaymentMethod,
+		PaymentMethod: strings.ToLower(strings.TrimSpace(req.PaymentMethod)),
 		PaymentStatus: "pending",
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
+	if zone != nil {
+		order.ZoneID = &zone.ID
+	}
+	if err := applyWalletPayment(order, req); err != nil {
+		return nil, err
+	}
+	// Orders paid at a provider's hosted checkout wait for the payment
+	// before drivers see them (see order_checkout.go).
+	provider := s.payments.Get(order.PaymentMethod)
+	if provider != nil {
+		order.Status = models.OrderAwaitingPayment
+	} else {
+		releasedAt := time.Now()
+		order.ReleasedAt = &releasedAt
+	}
+
+	// Take stock-tracked items out of today's count. priceOrder already
+	// checked there was enough, but another order may have got there
+	// first, so this is the step that actually decides.
+	reserved, alerts, err := s.reserveStock(ctx, draft)
+	if err != nil {
+		return nil, err
+	}
+
+	// Spend any wallet credit going towards it (see order_wallet.go)
+	if err := s.payFromWallet(ctx, order); err != nil {
+		s.releaseReservations(ctx, reserved)
+		return nil, err
+	}
 
 	// Save order
 	if err := s.orderRepo.Create(ctx, order); err != nil {
+		s.releaseReservations(ctx, reserved)
+		s.returnWalletPayment(ctx, order)
 		return nil, err
 	}
 
+	if provider != nil {
+		if err := s.startCheckout(ctx, order, provider, customer); err != nil {
+			s.cancelUnpaidOrder(ctx, order, "Checkout could not be started")
+			return nil, fmt.Errorf("couldn't start the %s checkout: %w", provider.Name(), err)
+		}
+	}
+
+	s.raiseStockAlerts(alerts)
 	return order, nil
 }
 
@@ -343,6 +630,26 @@ func (s *orderService) GetOrderByID(ctx context.Context, orderID primitive.Objec
 		}
 	}
 
+	if order.Refunds != nil {
+		refunds, err := s.refundRepo.FindByOrder(ctx, order.ID)
+		if err != nil {
+			fmt.Printf("⚠️  Failed to load refunds for order %s: %v\n", order.ID.Hex(), err)
+		}
+		for _, refund := range refunds {
+			result.Refunds = append(result.Refunds, RefundInfo{
+				ID:          refund.ID.Hex(),
+				Type:        refund.Type,
+				Items:       refund.Items,
+				Amount:      refund.Amount,
+				ReasonCode:  refund.ReasonCode,
+				Destination: refund.Destination,
+				Status:      refund.Status,
+				CreatedAt:   refund.CreatedAt,
+				ProcessedAt: refund.ProcessedAt,
+			})
+		}
+	}
+
 	return result, nil
 }
 
@@ -389,12 +696,44 @@ func (s *orderService) UpdateOrderStatus(ctx context.Context, orderID primitive.
 	if !s.isValidStatusTransition(order.Status, status, actorRole) {
 		return errors.New("invalid status transition")
 	}
+	if status == models.OrderDelivered && actorRole == "driver" && isCashOrder(order) && order.CashCollection == nil {
+		return ErrCashNotConfirmed
+	}
+
+	// Guarded on the status we validated against, so two taps racing each
+	// other can't both deliver the order (and count its earnings twice).
+	now := time.Now()
+	if err := s.orderRepo.AdvanceStatus(ctx, orderID, order.Status, models.OrderEvent{
+		Status:    status,
+		Timestamp: now,
+		ActorID:   actorID,
+		ActorType: actorRole,
+	}); err != nil {
+		return err
+	}
+
+	if status == models.OrderCancelled || status == models.OrderRejected {
+		s.releaseOrderStock(ctx, order)
+		s.returnWalletPayment(ctx, order)
+	}
+
+	if status == models.OrderDelivered {
+		s.recordDelivery(ctx, order, now)
+		if actorRole == "driver" {
+			s.flagDeliveredFarFromDropoff(ctx, order, actorID)
+		}
+	}
 
-	return s.orderRepo.UpdateStatus(ctx, orderID, status, actorID, actorRole)
+	return nil
 }
 
 func (s *orderService) isValidStatusTransition(current, new models.OrderStatus, actorRole string) bool {
 	transitions := map[models.OrderStatus]map[string][]models.OrderStatus{
+		// Released to drivers by the payment itself, never by hand.
+		models.OrderAwaitingPayment: {
+			"customer": {models.OrderCancelled},
+			"admin":    {models.OrderCancelled},
+		},
 		models.OrderPending: {
 			"restaurant": {models.OrderAccepted, models.OrderRejected},
 			"customer":   {models.OrderCancelled},
@@ -418,10 +757,15 @@ func (s *orderService) isValidStatusTransition(current, new models.OrderStatus,
 			// "Arrived at Restaurant" button sends.
 			"driver": {models.OrderReady, models.OrderCancelled},
 			"admin":  {models.OrderPreparing, models.OrderCancelled},
+			// "system" is the geofence engine (geofence_service.go): with
+			// GEOFENCE_AUTO_ADVANCE on, a driver dwelling at the restaurant
+			// counts as the same "Arrived at Restaurant" tap as above.
+			"system": {models.OrderReady},
 		},
 		models.OrderPreparing: {
 			"restaurant": {models.OrderReady, models.OrderCancelled},
 			"admin":      {models.OrderReady, models.OrderCancelled},
+			"system":     {models.OrderReady},
 		},
 		models.OrderReady: {
 			"driver": {models.OrderPickedUp},
@@ -435,10 +779,12 @@ func (s *orderService) isValidStatusTransition(current, new models.OrderStatus,
 			// but isn't required.
 			"driver": {models.OrderOnTheWay, models.OrderDelivered},
 			"admin":  {models.OrderCancelled},
+			"system": {models.OrderDelivered},
 		},
 		models.OrderOnTheWay: {
 			"driver": {models.OrderDelivered},
 			"admin":  {models.OrderCancelled},
+			"system": {models.OrderDelivered},
 		},
 	}
 
@@ -456,15 +802,35 @@ func (s *orderService) isValidStatusTransition(current, new models.OrderStatus,
 }
 
 func (s *orderService) AssignDriver(ctx context.Context, orderID, driverID primitive.ObjectID) error {
-	return s.orderRepo.AssignDriver(ctx, orderID, driverID)
+	if err := s.checkCashLimit(ctx, orderID, driverID); err != nil {
+		return err
+	}
+	if err := s.orderRepo.AssignDriver(ctx, orderID, driverID); err != nil {
+		return err
+	}
+	s.recordOrderDecision(ctx, driverID, true)
+	return nil
 }
 
 func (s *orderService) RejectOrder(ctx context.Context, orderID, driverID primitive.ObjectID) error {
-	return s.orderRepo.RejectOrder(ctx, orderID, driverID)
+	rejected, err := s.orderRepo.RejectOrder(ctx, orderID, driverID)
+	if err != nil {
+		return err
+	}
+	if rejected {
+		s.recordOrderDecision(ctx, driverID, false)
+	}
+	return nil
 }
 
 func (s *orderService) GetAvailableOrders(ctx context.Context, driverID primitive.ObjectID, driverLocation models.GeoLocation, radius float64) ([]OrderWithRestaurant, error) {
-	orders, err := s.orderRepo.FindAvailableOrders(ctx, driverID, driverLocation, radius)
+	// Drivers holding too much cash only see orders that are paid for.
+	excludeCash := false
+	if driver, err := s.driverRepo.FindByUserID(ctx, driverID); err == nil && driver != nil {
+		excludeCash = cashLimitReached(driver)
+	}
+
+	orders, err := s.orderRepo.FindAvailableOrders(ctx, driverID, driverLocation, radius, excludeCash)
 	if err != nil {
 		return nil, err
 	}
@@ -493,7 +859,17 @@ func (s *orderService) CancelOrder(ctx context.Context, orderID primitive.Object
 		Role:        userRole,
 	}
 
-	return s.orderRepo.CancelOrder(ctx, orderID, cancellation)
+	order, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if err := s.orderRepo.CancelOrder(ctx, orderID, cancellation); err != nil {
+		return err
+	}
+
+	s.releaseOrderStock(ctx, order)
+	s.returnWalletPayment(ctx, order)
+	return nil
 }
 
 // AutoCancelStaleOrders finds unassigned orders older than `olderThan` and
@@ -524,6 +900,8 @@ func (s *orderService) AutoCancelStaleOrders(ctx context.Context, olderThan time
 			// stop the rest of the sweep from processing.
 			continue
 		}
+		s.releaseOrderStock(ctx, &order)
+		s.returnWalletPayment(ctx, &order)
 		cancelled = append(cancelled, order)
 	}
 
@@ -548,79 +926,11 @@ func (s *orderService) RateOrder(ctx context.Context, orderID primitive.ObjectID
 		return err
 	}
 
-	// Recompute and persist the driver's/restaurant's average rating so
-	// they reflect real customer feedback everywhere they're surfaced —
-	// not just in GetDriverStats (which averages live from order history
-	// on every call), but also the static Driver.Rating/Restaurant.Rating
-	// fields used by DriverContact (customer's live tracking card) and the
-	// restaurant listing/detail screens.
-	if order.DriverID != nil {
-		if avg, count, derr := s.averageDriverRating(ctx, *order.DriverID); derr == nil && count > 0 {
-			if uerr := s.driverRepo.UpdateRating(ctx, *order.DriverID, avg); uerr != nil {
-				fmt.Printf("⚠️  Failed to update driver rating for %s: %v\n", order.DriverID.Hex(), uerr)
-			}
-		}
-	}
-
-	if avg, count, rerr := s.averageRestaurantRating(ctx, order.RestaurantID); rerr == nil && count > 0 {
-		if uerr := s.restaurantRepo.Update(ctx, order.RestaurantID, bson.M{
-			"rating":        avg,
-			"total_reviews": count,
-		}); uerr != nil {
-			fmt.Printf("⚠️  Failed to update restaurant rating for %s: %v\n", order.RestaurantID.Hex(), uerr)
-		}
-	}
+	s.recordRating(ctx, order, *rating)
 
 	return nil
 }
 
-// averageDriverRating averages DeliveryRating across every rated order this
-// driver has completed. userID is the driver's User._id (matches
-// order.driver_id — see UpdateRating).
-func (s *orderService) averageDriverRating(ctx context.Context, userID primitive.ObjectID) (float64, int, error) {
-	orders, _, err := s.orderRepo.FindByDriverID(ctx, userID, repositories.Pagination{
-		Page: 1, Limit: 10000, SortBy: "created_at", SortDir: -1,
-	})
-	if err != nil {
-		return 0, 0, err
-	}
-
-	sum, count := 0, 0
-	for _, o := range orders {
-		if o.Rating != nil && o.Rating.DeliveryRating > 0 {
-			sum += o.Rating.DeliveryRating
-			count++
-		}
-	}
-	if count == 0 {
-		return 0, 0, nil
-	}
-	return float64(sum) / float64(count), count, nil
-}
-
-// averageRestaurantRating averages RestaurantRating across every rated order
-// placed with this restaurant.
-func (s *orderService) averageRestaurantRating(ctx context.Context, restaurantID primitive.ObjectID) (float64, int, error) {
-	orders, _, err := s.orderRepo.FindByRestaurantID(ctx, restaurantID, repositories.Pagination{
-		Page: 1, Limit: 10000, SortBy: "created_at", SortDir: -1,
-	})
-	if err != nil {
-		return 0, 0, err
-	}
-
-	sum, count := 0, 0
-	for _, o := range orders {
-		if o.Rating != nil && o.Rating.RestaurantRating > 0 {
-			sum += o.Rating.RestaurantRating
-			count++
-		}
-	}
-	if count == 0 {
-		return 0, 0, nil
-	}
-	return float64(sum) / float64(count), count, nil
-}
-
 func (s *orderService) CalculateDeliveryFee(ctx context.Context, restaurantLocation, deliveryLocation models.GeoLocation) (float64, error) {
 	// Calculate distance using Haversine formula
 	distance := calculateDistance(
@@ -681,9 +991,25 @@ func (s *orderService) GetOrderStatistics(ctx context.Context, restaurantID prim
 	var totalRevenue float64
 	statusCounts := map[string]int{}
 
-	for _, order := range orders {
+	// What the restaurant itself earns: food sales on delivered orders
+	// less refunds and the platform's commission, as statements bill it.
+	restaurant, err := s.restaurantRepo.FindByID(ctx, restaurantID)
+	if err != nil {
+		restaurant = nil
+	}
+	commissionRate := restaurantCommission(restaurant)
+	var foodSales, refunds, commission, tips float64
+
+	for i, order := range orders {
 		totalRevenue += order.TotalAmount.Total
+		tips += order.TotalAmount.Tip
 		statusCounts[string(order.Status)]++
+
+		if order.Status == models.OrderDelivered {
+			foodSales += order.TotalAmount.Subtotal
+			refunds += orderRefunded(&orders[i])
+			commission += orderCommission(commissionRate, &orders[i])
+		}
 	}
 
 	if len(orders) > 0 {
@@ -692,6 +1018,12 @@ func (s *orderService) GetOrderStatistics(ctx context.Context, restaurantID prim
 
 	stats["total_revenue"] = totalRevenue
 	stats["status_counts"] = statusCounts
+	stats["food_sales"] = roundMoney(foodSales)
+	stats["commission"] = roundMoney(commission)
+	stats["refunds"] = roundMoney(refunds)
+	stats["commission_rate"] = commissionRate
+	stats["net_payable"] = roundMoney(foodSales - refunds - commission)
+	stats["tips"] = roundMoney(tips) // in total_revenue, but paid to drivers
 
 	return stats, nil
 }
@@ -771,164 +1103,165 @@ func (s *orderService) GetAllOrdersEnriched(ctx context.Context, page, limit int
 	return views, total, nil
 }
 
-// GetDriverStats computes a driver's delivery/earnings/rating stats live
-// from their order history. Backs GET /api/v1/driver/stats — used by
-// DriverDashboard, DriverProfileScreen, and AvailableOrdersScreen in the app.
+// GetDriverStats reads a driver's delivery/earnings/rating stats from the
+// running totals on their driver document and their daily earnings
+// buckets (see order_aggregates.go) — no order history is scanned. Backs
+// GET /api/v1/driver/stats — used by DriverDashboard, DriverProfileScreen,
+// and AvailableOrdersScreen in the app.
 func (s *orderService) GetDriverStats(ctx context.Context, driverID primitive.ObjectID) (map[string]interface{}, error) {
-	orders, _, err := s.orderRepo.FindByDriverID(ctx, driverID, repositories.Pagination{
-		Page:    1,
-		Limit:   10000,
-		SortBy:  "created_at",
-		SortDir: -1,
-	})
+	driver, err := s.driverRepo.FindByUserID(ctx, driverID)
 	if err != nil {
 		return nil, err
 	}
 
-	now := time.Now()
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	now := time.Now().In(serviceLocation)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, serviceLocation)
 	startOfWeek := startOfDay.AddDate(0, 0, -int(now.Weekday()))
-	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-
-	var totalDeliveries int
-	var totalEarnings, todayEarnings, weekEarnings, monthEarnings float64
-	var ratingSum, ratingCount int
-
-	for _, o := range orders {
-		if o.Status == models.OrderDelivered {
-			totalDeliveries++
-			fee := o.TotalAmount.DeliveryFee
-			totalEarnings += fee
-			if o.UpdatedAt.After(startOfDay) {
-				todayEarnings += fee
-			}
-			if o.UpdatedAt.After(startOfWeek) {
-				weekEarnings += fee
-			}
-			if o.UpdatedAt.After(startOfMonth) {
-				monthEarnings += fee
-			}
-		}
-		if o.Rating != nil && o.Rating.DeliveryRating > 0 {
-			ratingSum += o.Rating.DeliveryRating
-			ratingCount++
-		}
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, serviceLocation)
+	from := startOfWeek
+	if startOfMonth.Before(from) {
+		from = startOfMonth
 	}
 
-	// Acceptance rate: orders this driver ended up assigned to vs. orders
-	// they were offered and rejected (tracked in rejected_by_drivers).
-	acceptedCount := int64(len(orders))
-	rejectedCount, err := s.orderRepo.CountOrders(ctx, bson.M{"rejected_by_drivers": driverID})
+	days, err := s.earningsRepo.FindRange(ctx, driverID, aggregateDay(from), aggregateDay(now))
 	if err != nil {
-		rejectedCount = 0
+		return nil, err
 	}
+
+	var todayEarnings, weekEarnings, monthEarnings float64
+	for _, d := range days {
+		day, err := parseAggregateDay(d.Day)
+		if err != nil {
+			continue
+		}
+		if !day.Before(startOfDay) {
+			todayEarnings += d.Earnings
+		}
+		if !day.Before(startOfWeek) {
+			weekEarnings += d.Earnings
+		}
+		if !day.Before(startOfMonth) {
+			monthEarnings += d.Earnings
+		}
+	}
+
+	// Acceptance rate: orders this driver took vs. orders they were
+	// offered and rejected.
 	acceptanceRate := 100.0
-	if acceptedCount+rejectedCount > 0 {
-		acceptanceRate = float64(acceptedCount) / float64(acceptedCount+rejectedCount) * 100
+	if decided := driver.AcceptedOrders + driver.RejectedOrders; decided > 0 {
+		acceptanceRate = float64(driver.AcceptedOrders) / float64(decided) * 100
 	}
 
 	averageRating := 5.0
-	if ratingCount > 0 {
-		averageRating = float64(ratingSum) / float64(ratingCount)
+	if driver.RatingCount > 0 {
+		averageRating = float64(driver.RatingSum) / float64(driver.RatingCount)
 	}
 
 	averageEarnings := 0.0
-	if totalDeliveries > 0 {
-		averageEarnings = totalEarnings / float64(totalDeliveries)
+	if driver.TotalTrips > 0 {
+		averageEarnings = driver.Earnings.Total / float64(driver.TotalTrips)
 	}
 
 	return map[string]interface{}{
-		"totalDeliveries": totalDeliveries,
+		"totalDeliveries": driver.TotalTrips,
 		"averageRating":   averageRating,
-		"rating":          averageRating, // DriverProfileScreen reads this key
-		"ratingCount":     ratingCount,   // 0 means no customer has rated this driver yet — the app should show "New" instead of a fake 5.0
+		"rating":          averageRating,      // DriverProfileScreen reads this key
+		"ratingCount":     driver.RatingCount, // 0 means no customer has rated this driver yet — the app should show "New" instead of a fake 5.0
 		"averageEarnings": averageEarnings,
 		"acceptanceRate":  acceptanceRate,
 		"todayEarnings":   todayEarnings,
 		"weekEarnings":    weekEarnings,
 		"earnings": map[string]interface{}{
-			"total":     totalEarnings,
-			"thisMonth": monthEarnings,
-			"today":     todayEarnings,
+			"total":      driver.Earnings.Total,
+			"thisMonth":  monthEarnings,
+			"today":      todayEarnings,
+			"surgeBonus": driver.Earnings.SurgeBonus, // already included in the totals above
+			"tips":       driver.Earnings.Tips,       // likewise
+			"pending":    driver.Earnings.Pending,    // ledger balance not yet paid out
 		},
 	}, nil
 }
 
-// GetDriverEarningsChart buckets a driver's delivered-order earnings into a
+// driverEarning is what a delivered order paid its driver: the delivery
+// fee plus any surge bonus (see OrderAmount.SurgeFee).
+func driverEarning(o models.Order) float64 {
+	return o.TotalAmount.DeliveryFee + o.TotalAmount.SurgeFee
+}
+
+// GetDriverEarningsChart buckets a driver's daily earnings into a
 // bar-chart-friendly {data, labels} shape for the given range. Backs GET
 // /api/v1/driver/earnings/chart — used by EarningsScreen.
 func (s *orderService) GetDriverEarningsChart(ctx context.Context, driverID primitive.ObjectID, rangeType string) (map[string]interface{}, error) {
-	orders, _, err := s.orderRepo.FindByDriverID(ctx, driverID, repositories.Pagination{
-		Page:    1,
-		Limit:   10000,
-		SortBy:  "created_at",
-		SortDir: -1,
-	})
+	now := time.Now().In(serviceLocation)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, serviceLocation)
+
+	var from time.Time
+	switch rangeType {
+	case "month":
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, serviceLocation)
+	case "year":
+		from = time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, serviceLocation)
+	default: // "week"
+		from = today.AddDate(0, 0, -6)
+	}
+
+	days, err := s.earningsRepo.FindRange(ctx, driverID, aggregateDay(from), aggregateDay(now))
 	if err != nil {
 		return nil, err
 	}
 
-	now := time.Now()
 	var labels []string
 	var data []float64
 
 	switch rangeType {
 	case "month":
 		// Bucket the current month into weekly chunks (Week 1..Week n).
-		startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-		daysInMonth := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location()).AddDate(0, 0, -1).Day()
+		daysInMonth := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, serviceLocation).AddDate(0, 0, -1).Day()
 		numWeeks := (daysInMonth + 6) / 7
 		data = make([]float64, numWeeks)
 		labels = make([]string, numWeeks)
 		for i := 0; i < numWeeks; i++ {
 			labels[i] = fmt.Sprintf("Week %d", i+1)
 		}
-		for _, o := range orders {
-			if o.Status != models.OrderDelivered {
-				continue
-			}
-			if o.UpdatedAt.Before(startOfMonth) || o.UpdatedAt.Month() != now.Month() || o.UpdatedAt.Year() != now.Year() {
+		for _, d := range days {
+			day, err := parseAggregateDay(d.Day)
+			if err != nil {
 				continue
 			}
-			weekIdx := (o.UpdatedAt.Day() - 1) / 7
+			weekIdx := (day.Day() - 1) / 7
 			if weekIdx >= numWeeks {
 				weekIdx = numWeeks - 1
 			}
-			data[weekIdx] += o.TotalAmount.DeliveryFee
+			data[weekIdx] += d.Earnings
 		}
 	case "year":
 		monthNames := []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
 		data = make([]float64, 12)
 		labels = monthNames
-		for _, o := range orders {
-			if o.Status != models.OrderDelivered {
-				continue
-			}
-			if o.UpdatedAt.Year() != now.Year() {
+		for _, d := range days {
+			day, err := parseAggregateDay(d.Day)
+			if err != nil {
 				continue
 			}
-			data[int(o.UpdatedAt.Month())-1] += o.TotalAmount.DeliveryFee
+			data[int(day.Month())-1] += d.Earnings
 		}
 	default: // "week"
 		dayNames := []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
 		data = make([]float64, 7)
 		labels = make([]string, 7)
-		startOfRange := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -6)
 		for i := 0; i < 7; i++ {
-			labels[i] = dayNames[int(startOfRange.AddDate(0, 0, i).Weekday())]
+			labels[i] = dayNames[int(from.AddDate(0, 0, i).Weekday())]
 		}
-		for _, o := range orders {
-			if o.Status != models.OrderDelivered {
-				continue
-			}
-			if o.UpdatedAt.Before(startOfRange) {
+		for _, d := range days {
+			day, err := parseAggregateDay(d.Day)
+			if err != nil {
 				continue
 			}
-			dayIdx := int(o.UpdatedAt.Sub(startOfRange).Hours() / 24)
+			dayIdx := int(math.Round(day.Sub(from).Hours() / 24))
 			if dayIdx < 0 || dayIdx > 6 {
 				continue
 			}
-			data[dayIdx] += o.TotalAmount.DeliveryFee
+			data[dayIdx] += d.Earnings
 		}
 	}
 
@@ -938,42 +1271,34 @@ func (s *orderService) GetDriverEarningsChart(ctx context.Context, driverID prim
 	}, nil
 }
 
-// GetDriverEarningsTransactions returns the driver's most recent delivered
-// or cancelled orders formatted as earnings transactions. Backs GET
-// /api/v1/driver/earnings/transactions — used by EarningsScreen.
+// GetDriverEarningsTransactions returns the driver's latest ledger entries
+// (deliveries, tips, bonuses, cash collected, penalties, refunds and payouts)
+// formatted as earnings transactions; amount is signed, negative for
+// debits. Backs GET /api/v1/driver/earnings/transactions — used by
+// EarningsScreen.
 func (s *orderService) GetDriverEarningsTransactions(ctx context.Context, driverID primitive.ObjectID, limit int64) ([]map[string]interface{}, error) {
-	orders, _, err := s.orderRepo.FindByDriverID(ctx, driverID, repositories.Pagination{
-		Page:    1,
-		Limit:   10000,
-		SortBy:  "updated_at",
-		SortDir: -1,
-	})
+	entries, err := s.ledger.GetDriverEntries(ctx, driverID, limit)
 	if err != nil {
 		return nil, err
 	}
 
-	if limit <= 0 {
-		limit = 5
-	}
-
-	transactions := make([]map[string]interface{}, 0, limit)
-	for _, o := range orders {
-		if int64(len(transactions)) >= limit {
-			break
-		}
-		if o.Status != models.OrderDelivered && o.Status != models.OrderCancelled {
-			continue
-		}
-		status := "completed"
-		if o.Status == models.OrderCancelled {
-			status = "failed"
+	transactions := make([]map[string]interface{}, 0, len(entries))
+	for _, e := range entries {
+		baseFee := 0.0
+		if e.Type == models.LedgerDelivery {
+			baseFee = e.Amount - e.SurgeBonus
 		}
 		transactions = append(transactions, map[string]interface{}{
-			"id":      o.ID.Hex(),
-			"date":    o.UpdatedAt.Format("Jan 2, 2006"),
-			"amount":  o.TotalAmount.DeliveryFee,
-			"orderId": o.OrderNumber,
-			"status":  status,
+			"id":          e.ID.Hex(),
+			"date":        e.CreatedAt.Format("Jan 2, 2006"),
+			"amount":      e.Net,
+			"baseFee":     baseFee,
+			"surgeBonus":  e.SurgeBonus,
+			"orderId":     e.OrderNumber,
+			"status":      "completed",
+			"type":        e.Type,
+			"description": e.Description,
+			"settled":     e.SettledAt != nil,
 		})
 	}
 
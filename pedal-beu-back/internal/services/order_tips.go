@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tips: a customer can tip their driver at checkout or for a while after
+// the order is delivered, when it's paid from their wallet. The tip is its
+// own OrderAmount line, part of the order total but credited to the
+// driver in full — their earnings totals, daily bucket and a tip entry on
+// their ledger — and never part of the restaurant's commission or
+// statements, which only bill the food subtotal.
+
+// TipPresets are the tips the checkout and order screens suggest, and the
+// limits a tip has to fit within.
+type TipPresets struct {
+	Fixed       []float64 `json:"fixed"`        // amounts
+	Percent     []float64 `json:"percent"`      // percentages of the food subtotal
+	Max         float64   `json:"max"`          // the largest tip accepted
+	WindowHours float64   `json:"window_hours"` // how long after delivery an order can still be tipped
+}
+
+// TipReceived is raised whenever a driver is credited a tip, for main.go
+// to push to them as a tip_received event.
+type TipReceived struct {
+	DriverID      primitive.ObjectID
+	OrderID       primitive.ObjectID
+	OrderNumber   string
+	Amount        float64
+	AfterDelivery bool // tipped from the order screen rather than at checkout
+}
+
+func tipPresets() TipPresets {
+	return TipPresets{
+		Fixed:       envFloats("TIP_PRESETS_FIXED", []float64{10, 20, 50}),
+		Percent:     envFloats("TIP_PRESETS_PERCENT", []float64{5, 10, 15}),
+		Max:         envFloat("TIP_MAX", 1000),
+		WindowHours: envFloat("TIP_WINDOW_HOURS", 24),
+	}
+}
+
+// envFloats reads a comma-separated list of positive numbers, falling back
+// to def if it's unset or any entry isn't one.
+func envFloats(key string, def []float64) []float64 {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return def
+	}
+	var values []float64
+	for _, part := range strings.Split(raw, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || value <= 0 {
+			return def
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// tipAmount is the tip req asks for on an order whose food comes to
+// subtotal. A nil request is no tip.
+func tipAmount(req *models.TipRequest, subtotal float64) (float64, error) {
+	if req == nil {
+		return 0, nil
+	}
+	if req.Amount > 0 && req.Percent > 0 {
+		return 0, errors.New("set either a tip amount or a tip percentage, not both")
+	}
+
+	tip := roundMoney(req.Amount)
+	if req.Percent > 0 {
+		tip = roundMoney(subtotal * req.Percent / 100)
+	}
+	if max := tipPresets().Max; tip > max {
+		return 0, fmt.Errorf("tips can't be more than %.2f", max)
+	}
+	return tip, nil
+}
+
+// applyTip adds the tip req asks for to a priced order.
+func applyTip(amount *models.OrderAmount, req *models.TipRequest) error {
+	tip, err := tipAmount(req, amount.Subtotal)
+	if err != nil {
+		return err
+	}
+	amount.Tip = tip
+	amount.Total += tip
+	return nil
+}
+
+func (s *orderService) GetTipPresets() TipPresets {
+	return tipPresets()
+}
+
+// AddTip tips the driver of a delivered order that wasn't tipped at
+// checkout. The tip is taken from the customer's wallet first and only
+// credited to the driver once it has been.
+func (s *orderService) AddTip(ctx context.Context, orderID, customerID primitive.ObjectID, req models.TipRequest) (*models.Order, error) {
+	order, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.CustomerID != customerID {
+		return nil, errors.New("unauthorized")
+	}
+	if order.Status != models.OrderDelivered || order.DriverID == nil {
+		return nil, errors.New("order must be delivered before tipping")
+	}
+	if order.TotalAmount.Tip > 0 {
+		return nil, errors.New("order already tipped")
+	}
+	if s.wallet == nil {
+		return nil, ErrWalletPaymentsUnavailable
+	}
+	window := time.Duration(tipPresets().WindowHours * float64(time.Hour))
+	if time.Since(deliveredAt(order)) > window {
+		return nil, errors.New("it's too late to tip this order")
+	}
+
+	tip, err := tipAmount(&req, order.TotalAmount.Subtotal)
+	if err != nil {
+		return nil, err
+	}
+	if tip <= 0 {
+		return nil, errors.New("tip must be at least 0.01")
+	}
+
+	// Paying is keyed on the order, so a retry after a failure below
+	// doesn't charge again, and two tips racing are charged only once.
+	tip, err = s.wallet.PayTip(ctx, customerID, orderID, tip)
+	if errors.Is(err, ErrInsufficientWalletBalance) {
+		return nil, errors.New("not enough wallet balance to pay this tip")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := s.orderRepo.AddTip(ctx, orderID, tip)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, errors.New("order already tipped")
+	}
+	order.TotalAmount.Tip = tip
+	order.TotalAmount.Total += tip
+
+	s.creditTip(ctx, order, deliveredAt(order), true)
+	return order, nil
+}
+
+// creditTip adds an order's tip to its driver's earnings — on the day the
+// order was delivered, whenever it was given — posts it to their ledger
+// and lets them know. Failures are logged, as in recordDelivery.
+func (s *orderService) creditTip(ctx context.Context, order *models.Order, delivered time.Time, afterDelivery bool) {
+	tip := order.TotalAmount.Tip
+	if order.DriverID == nil || tip <= 0 {
+		return
+	}
+
+	if err := s.driverRepo.AddTip(ctx, *order.DriverID, tip); err != nil {
+		fmt.Printf("⚠️  Failed to add tip to driver %s's earnings: %v\n", order.DriverID.Hex(), err)
+	}
+	if err := s.earningsRepo.AddTip(ctx, *order.DriverID, aggregateDay(delivered), tip); err != nil {
+		fmt.Printf("⚠️  Failed to update daily earnings for driver %s: %v\n", order.DriverID.Hex(), err)
+	}
+	if err := s.ledger.RecordTip(ctx, order); err != nil {
+		fmt.Printf("⚠️  Failed to post tip on order %s to the ledger: %v\n", order.ID.Hex(), err)
+	}
+
+	if s.tipReceived != nil {
+		s.tipReceived(TipReceived{
+			DriverID:      *order.DriverID,
+			OrderID:       order.ID,
+			OrderNumber:   order.OrderNumber,
+			Amount:        tip,
+			AfterDelivery: afterDelivery,
+		})
+	}
+}
+
+func (s *orderService) OnTipReceived(fn func(TipReceived)) {
+	s.tipReceived = fn
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTipAmount(t *testing.T) {
+	t.Setenv("TIP_MAX", "100")
+
+	tests := []struct {
+		name     string
+		req      *models.TipRequest
+		subtotal float64
+		want     float64
+		wantErr  bool
+	}{
+		{name: "no tip", req: nil, subtotal: 250, want: 0},
+		{name: "fixed", req: &models.TipRequest{Amount: 20}, subtotal: 250, want: 20},
+		{name: "fixed rounds to cents", req: &models.TipRequest{Amount: 12.345}, subtotal: 250, want: 12.35},
+		{name: "percentage of the food", req: &models.TipRequest{Percent: 10}, subtotal: 245, want: 24.5},
+		{name: "fixed at the max", req: &models.TipRequest{Amount: 100}, subtotal: 250, want: 100},
+		{name: "fixed above the max", req: &models.TipRequest{Amount: 100.01}, subtotal: 250, wantErr: true},
+		{name: "percentage above the max", req: &models.TipRequest{Percent: 15}, subtotal: 1000, wantErr: true},
+		{name: "both", req: &models.TipRequest{Amount: 20, Percent: 10}, subtotal: 250, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tipAmount(tt.req, tt.subtotal)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error: %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("tip = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// tipWallet holds balance for tips and records what it was asked to pay.
+type tipWallet struct {
+	WalletService
+	balance float64
+	asked   []float64
+}
+
+func (w *tipWallet) PayTip(ctx context.Context, userID, orderID primitive.ObjectID, amount float64) (float64, error) {
+	w.asked = append(w.asked, amount)
+	if amount > w.balance {
+		return 0, ErrInsufficientWalletBalance
+	}
+	w.balance -= amount
+	return amount, nil
+}
+
+func TestAddTipAfterDelivery(t *testing.T) {
+	t.Setenv("TIP_MAX", "100")
+
+	tests := []struct {
+		name    string
+		req     models.TipRequest
+		balance float64
+		err     string
+		asked   []float64
+	}{
+		{
+			name:    "not enough wallet balance",
+			req:     models.TipRequest{Amount: 50},
+			balance: 30,
+			err:     "not enough wallet balance to pay this tip",
+			asked:   []float64{50},
+		},
+		{
+			name:    "percentage over the wallet balance",
+			req:     models.TipRequest{Percent: 10},
+			balance: 19.99,
+			err:     "not enough wallet balance to pay this tip",
+			asked:   []float64{20},
+		},
+		{
+			name:    "above the max isn't charged",
+			req:     models.TipRequest{Amount: 150},
+			balance: 500,
+			err:     "tips can't be more than 100.00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customerID, driverID := primitive.NewObjectID(), primitive.NewObjectID()
+			delivered := time.Now().Add(-time.Hour)
+			order := &models.Order{
+				ID:           primitive.NewObjectID(),
+				CustomerID:   customerID,
+				DriverID:     &driverID,
+				Status:       models.OrderDelivered,
+				DeliveryInfo: models.DeliveryInfo{ActualDelivery: &delivered},
+				TotalAmount:  models.OrderAmount{Subtotal: 200, DeliveryFee: 40, Total: 240},
+			}
+			orders := &memOrderRepo{orders: map[primitive.ObjectID]*models.Order{order.ID: order}}
+			wallet := &tipWallet{balance: tt.balance}
+			s := &orderService{orderRepo: orders, wallet: wallet}
+
+			_, err := s.AddTip(context.Background(), order.ID, customerID, tt.req)
+			if err == nil || err.Error() != tt.err {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+			if len(wallet.asked) != len(tt.asked) || (len(tt.asked) > 0 && wallet.asked[0] != tt.asked[0]) {
+				t.Errorf("wallet asked for %v, want %v", wallet.asked, tt.asked)
+			}
+			if tip := orders.get(order.ID).TotalAmount.Tip; tip != 0 {
+				t.Errorf("order tip = %v, want none", tip)
+			}
+		})
+	}
+}
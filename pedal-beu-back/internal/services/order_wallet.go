@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Wallet payments: at checkout a customer can pay from their wallet, for
+// the whole order (payment method "wallet") or part of it (wallet_amount,
+// the rest by the order's payment method). The credit is spent before the
+// order is saved and handed straight back if it can't be, or if the order
+// is later cancelled or rejected. A cash order's driver only collects
+// what the wallet didn't cover.
+
+const walletPaymentMethod = "wallet"
+
+// ErrWalletPaymentsUnavailable is returned for a checkout that uses the
+// wallet when there's no wallet service.
+var ErrWalletPaymentsUnavailable = errors.New("wallet payments aren't available")
+
+// amountDue is what the customer still has to pay on an order besides
+// its wallet payment.
+func amountDue(order *models.Order) float64 {
+	if order.WalletPayment == nil {
+		return roundMoney(order.TotalAmount.Total)
+	}
+	return roundMoney(order.TotalAmount.Total - order.WalletPayment.Amount)
+}
+
+// walletReturned is how much of an order's wallet payment was handed back
+// when it was cancelled.
+func walletReturned(order *models.Order) float64 {
+	if order.WalletPayment == nil || order.WalletPayment.ReturnedAt == nil {
+		return 0
+	}
+	return order.WalletPayment.Returned
+}
+
+// applyWalletPayment puts the wallet credit req asks for towards a priced
+// order. An order the wallet covers in full is paid by wallet, whatever
+// other method was asked for.
+func applyWalletPayment(order *models.Order, req *models.CreateOrderRequest) error {
+	total := roundMoney(order.TotalAmount.Total)
+	amount := roundMoney(req.WalletAmount)
+	if order.PaymentMethod == walletPaymentMethod {
+		amount = total
+	}
+	if amount > total {
+		return fmt.Errorf("wallet amount can't be more than the order total of %.2f", total)
+	}
+	if amount <= 0 {
+		return nil
+	}
+
+	order.WalletPayment = &models.OrderWalletPayment{Amount: amount}
+	if amount == total {
+		order.PaymentMethod = walletPaymentMethod
+		order.PaymentStatus = "paid"
+	}
+	return nil
+}
+
+// payFromWallet spends an order's wallet payment before it's saved. The
+// payment is keyed on the order, so the order's ID is picked here.
+func (s *orderService) payFromWallet(ctx context.Context, order *models.Order) error {
+	if order.WalletPayment == nil {
+		return nil
+	}
+	if s.wallet == nil {
+		return ErrWalletPaymentsUnavailable
+	}
+	order.ID = primitive.NewObjectID()
+	return s.wallet.PayForOrder(ctx, order.CustomerID, order.ID, order.WalletPayment.Amount)
+}
+
+// returnWalletPayment hands the wallet payment of an order that was
+// cancelled, rejected or never saved back to the customer, less anything
+// already refunded on it. Failures are logged; returning it again is a
+// no-op, so it's safe to retry.
+func (s *orderService) returnWalletPayment(ctx context.Context, order *models.Order) {
+	if order.WalletPayment == nil || order.WalletPayment.ReturnedAt != nil || s.wallet == nil {
+		return
+	}
+
+	amount := order.WalletPayment.Amount
+	if order.Refunds != nil {
+		amount = math.Max(0, math.Min(amount, roundMoney(order.TotalAmount.Total-order.Refunds.Requested)))
+	}
+	if amount > 0 {
+		if err := s.wallet.ReturnOrderPayment(ctx, order.CustomerID, order.ID, amount); err != nil {
+			fmt.Printf("⚠️  Failed to return wallet payment on order %s: %v\n", order.ID.Hex(), err)
+			return
+		}
+	}
+
+	now := time.Now()
+	if _, err := s.orderRepo.MarkWalletPaymentReturned(ctx, order.ID, amount, now); err != nil {
+		fmt.Printf("⚠️  Failed to mark wallet payment on order %s returned: %v\n", order.ID.Hex(), err)
+	}
+	order.WalletPayment.Returned = amount
+	order.WalletPayment.ReturnedAt = &now
+}
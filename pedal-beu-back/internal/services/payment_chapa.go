@@ -0,0 +1,216 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChapaConfig is how to reach Chapa. BaseURL can point at a
+// paymenttest.FakeChapaServer instead of the real API.
+type ChapaConfig struct {
+	BaseURL       string
+	SecretKey     string
+	WebhookSecret string
+	ReturnURL     string
+}
+
+// ChapaConfigFromEnv reads CHAPA_BASE_URL, CHAPA_SECRET_KEY,
+// CHAPA_WEBHOOK_SECRET and CHAPA_RETURN_URL. It returns nil if there's no
+// secret key, leaving Chapa switched off.
+func ChapaConfigFromEnv() *ChapaConfig {
+	secret := strings.TrimSpace(os.Getenv("CHAPA_SECRET_KEY"))
+	if secret == "" {
+		return nil
+	}
+	return &ChapaConfig{
+		BaseURL:       firstNonEmpty(strings.TrimSpace(os.Getenv("CHAPA_BASE_URL")), "https://api.chapa.co"),
+		SecretKey:     secret,
+		WebhookSecret: strings.TrimSpace(os.Getenv("CHAPA_WEBHOOK_SECRET")),
+		ReturnURL:     strings.TrimSpace(os.Getenv("CHAPA_RETURN_URL")),
+	}
+}
+
+type chapaProvider struct {
+	config     ChapaConfig
+	httpClient *http.Client
+}
+
+// NewChapaProvider returns the Chapa provider, or nil for a nil config so
+// the result can go straight into NewPaymentProviders.
+func NewChapaProvider(config *ChapaConfig) PaymentProvider {
+	if config == nil {
+		return nil
+	}
+	return &chapaProvider{
+		config:     *config,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *chapaProvider) Name() string {
+	return "chapa"
+}
+
+// chapaResponse is the envelope every Chapa API response comes in.
+type chapaResponse struct {
+	Status  string          `json:"status"`
+	Message json.RawMessage `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (p *chapaProvider) do(ctx context.Context, method, path string, body interface{}) (*chapaResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(p.config.BaseURL, "/")+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.SecretKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	var envelope chapaResponse
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return nil, fmt.Errorf("chapa returned %s", resp.Status)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || envelope.Status != "success" {
+		// message is a string, or an object of field errors.
+		return nil, fmt.Errorf("chapa returned %s: %s", resp.Status, strings.Trim(string(envelope.Message), `"`))
+	}
+	return &envelope, nil
+}
+
+func (p *chapaProvider) InitiateCheckout(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error) {
+	body := map[string]interface{}{
+		"amount":       strconv.FormatFloat(req.Amount, 'f', 2, 64),
+		"currency":     firstNonEmpty(req.Currency, "ETB"),
+		"email":        req.Email,
+		"first_name":   req.FirstName,
+		"last_name":    req.LastName,
+		"phone_number": req.Phone,
+		"tx_ref":       req.Reference,
+		"callback_url": req.CallbackURL,
+		"return_url":   firstNonEmpty(req.ReturnURL, p.config.ReturnURL),
+		"customization": map[string]string{
+			"title":       "Pedal Delivery",
+			"description": req.Description,
+		},
+	}
+
+	envelope, err := p.do(ctx, http.MethodPost, "/v1/transaction/initialize", body)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		CheckoutURL string `json:"checkout_url"`
+	}
+	if err := json.Unmarshal(envelope.Data, &data); err != nil || data.CheckoutURL == "" {
+		return nil, errors.New("chapa didn't return a checkout URL")
+	}
+	return &CheckoutSession{Reference: req.Reference, CheckoutURL: data.CheckoutURL}, nil
+}
+
+// chapaStatus maps Chapa's payment statuses onto ours.
+func chapaStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "success", "successful":
+		return PaymentSucceeded
+	case "failed", "cancelled", "failed/cancelled", "reversed":
+		return PaymentFailed
+	default:
+		return PaymentPending
+	}
+}
+
+// chapaUpdate reads a payment update out of a webhook body or the data of
+// a verify response, which carry the same fields.
+func chapaUpdate(payload map[string]interface{}) *PaymentUpdate {
+	providerStatus := stringFromMap(payload, "status")
+	return &PaymentUpdate{
+		Reference:         stringFromMap(payload, "tx_ref"),
+		ProviderReference: stringFromMap(payload, "reference"),
+		Status:            chapaStatus(providerStatus),
+		ProviderStatus:    providerStatus,
+		Amount:            amountFromMap(payload, "amount"),
+		Currency:          stringFromMap(payload, "currency"),
+		Raw:               payload,
+	}
+}
+
+// ParseWebhook checks x-chapa-signature, the hex HMAC-SHA256 of the body
+// keyed with the webhook secret set on the Chapa dashboard.
+func (p *chapaProvider) ParseWebhook(header http.Header, body []byte) (*PaymentUpdate, error) {
+	if p.config.WebhookSecret == "" {
+		return nil, errors.New("chapa webhook secret isn't configured")
+	}
+	mac := hmac.New(sha256.New, []byte(p.config.WebhookSecret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	signature := strings.ToLower(strings.TrimSpace(header.Get("x-chapa-signature")))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.New("webhook body isn't JSON")
+	}
+	update := chapaUpdate(payload)
+	if update.Reference == "" {
+		return nil, errors.New("webhook has no tx_ref")
+	}
+	// Chapa events carry no ID of their own; the event, the transaction
+	// and where it got to identify one.
+	update.EventID = strings.Join([]string{
+		firstNonEmpty(stringFromMap(payload, "event"), "charge"),
+		update.Reference,
+		update.ProviderReference,
+		update.ProviderStatus,
+	}, ":")
+	return update, nil
+}
+
+func (p *chapaProvider) FetchStatus(ctx context.Context, reference string) (*PaymentUpdate, error) {
+	envelope, err := p.do(ctx, http.MethodGet, "/v1/transaction/verify/"+url.PathEscape(reference), nil)
+	if err != nil {
+		return nil, err
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(envelope.Data, &payload); err != nil || payload == nil {
+		return nil, errors.New("chapa didn't return the transaction")
+	}
+	update := chapaUpdate(payload)
+	update.Reference = firstNonEmpty(update.Reference, reference)
+	return update, nil
+}
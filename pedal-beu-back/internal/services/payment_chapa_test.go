@@ -0,0 +1,414 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/paymenttest"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const testChapaWebhookSecret = "test-webhook-secret"
+
+// chapaTest is an order service taking Chapa checkouts from a
+// paymenttest.FakeChapaServer, whose webhooks reach a PaymentEventService
+// over HTTP. The repositories it needs are kept in memory.
+type chapaTest struct {
+	chapa    *paymenttest.FakeChapaServer
+	orders   *memOrderRepo
+	events   *memPaymentEventRepo
+	service  *orderService
+	webhooks PaymentEventService
+
+	customerID   primitive.ObjectID
+	restaurantID primitive.ObjectID
+	menuItemID   primitive.ObjectID
+	addressID    primitive.ObjectID
+}
+
+func newChapaTest(t *testing.T) *chapaTest {
+	t.Helper()
+	ct := &chapaTest{
+		chapa:        paymenttest.NewFakeChapaServer(testChapaWebhookSecret),
+		orders:       &memOrderRepo{orders: map[primitive.ObjectID]*models.Order{}},
+		events:       &memPaymentEventRepo{events: map[primitive.ObjectID]*models.PaymentEvent{}},
+		customerID:   primitive.NewObjectID(),
+		restaurantID: primitive.NewObjectID(),
+		menuItemID:   primitive.NewObjectID(),
+		addressID:    primitive.NewObjectID(),
+	}
+	chapaServer := httptest.NewServer(ct.chapa)
+	t.Cleanup(chapaServer.Close)
+
+	location := models.GeoLocation{Type: "Point", Coordinates: []float64{38.76, 9.01}}
+	payments := NewPaymentProviders(NewChapaProvider(&ChapaConfig{
+		BaseURL:       chapaServer.URL,
+		SecretKey:     "CHASECK_TEST",
+		WebhookSecret: testChapaWebhookSecret,
+	}))
+	ct.service = &orderService{
+		orderRepo: ct.orders,
+		restaurantRepo: memRestaurantRepo{restaurant: &models.Restaurant{
+			ID:          ct.restaurantID,
+			Name:        "Test Kitchen",
+			Location:    location,
+			DeliveryFee: 30,
+			IsActive:    true,
+			IsVerified:  true,
+		}},
+		menuRepo: memMenuRepo{item: models.MenuItem{
+			ID:           ct.menuItemID,
+			RestaurantID: ct.restaurantID,
+			Name:         "Shiro",
+			Price:        100,
+			IsAvailable:  true,
+		}},
+		categoryRepo: memCategoryRepo{},
+		userRepo: memUserRepo{user: &models.User{
+			ID:    ct.customerID,
+			Email: "abebe@example.com",
+			Profile: models.UserProfile{
+				FirstName: "Abebe",
+				LastName:  "Kebede",
+				Addresses: []models.Address{{ID: ct.addressID, Location: location}},
+			},
+		}},
+		zones:    noZones{},
+		payments: payments,
+	}
+	ct.webhooks = NewPaymentEventService(ct.events, payments, ct.service)
+
+	// Chapa's webhooks come in over HTTP, as in production.
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		provider := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		if _, err := ct.webhooks.HandleWebhook(r.Context(), provider, r.Header, body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(webhookServer.Close)
+	t.Setenv("PAYMENT_CALLBACK_BASE_URL", webhookServer.URL)
+	return ct
+}
+
+// createOrder places a Chapa order for two of the test menu item.
+func (ct *chapaTest) createOrder(t *testing.T) *models.Order {
+	t.Helper()
+	order, err := ct.service.CreateOrder(context.Background(), ct.customerID, &models.CreateOrderRequest{
+		RestaurantID:  ct.restaurantID.Hex(),
+		Items:         []models.OrderItemRequest{{MenuItemID: ct.menuItemID.Hex(), Quantity: 2}},
+		AddressID:     ct.addressID.Hex(),
+		PaymentMethod: "chapa",
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if order.Status != models.OrderAwaitingPayment {
+		t.Fatalf("order status %q, want %q", order.Status, models.OrderAwaitingPayment)
+	}
+	if order.Checkout == nil || !strings.Contains(order.Checkout.CheckoutURL, "/checkout/"+order.ID.Hex()) {
+		t.Fatalf("checkout %+v, want one opened for the order", order.Checkout)
+	}
+	return order
+}
+
+func TestChapaCheckoutReleasesPaidOrder(t *testing.T) {
+	ct := newChapaTest(t)
+	var released []models.Order
+	ct.service.OnOrderReleased(func(order models.Order) { released = append(released, order) })
+
+	order := ct.createOrder(t)
+	if order.Checkout.Amount != order.TotalAmount.Total {
+		t.Fatalf("checkout amount %.2f, want the order total %.2f", order.Checkout.Amount, order.TotalAmount.Total)
+	}
+	if err := ct.chapa.Complete(order.ID.Hex(), true); err != nil {
+		t.Fatalf("webhook: %v", err)
+	}
+
+	stored := ct.orders.get(order.ID)
+	if stored.Status != models.OrderAccepted || stored.PaymentStatus != "paid" {
+		t.Fatalf("order %s / %s, want accepted and paid", stored.Status, stored.PaymentStatus)
+	}
+	if stored.PaymentVerification == nil || stored.PaymentVerification.Status != "verified" {
+		t.Fatalf("payment verification %+v, want verified", stored.PaymentVerification)
+	}
+	if len(released) != 1 || released[0].ID != order.ID {
+		t.Fatalf("released %d orders, want the paid one", len(released))
+	}
+	if event := ct.events.only(t); event.Status != models.PaymentEventProcessed {
+		t.Fatalf("payment event %s, want processed", event.Status)
+	}
+
+	// A redelivered webhook is stored once and releases nothing more.
+	event := ct.events.only(t)
+	header := http.Header{}
+	for name, value := range event.Headers {
+		header.Set(name, value)
+	}
+	if _, err := ct.webhooks.HandleWebhook(context.Background(), "chapa", header, []byte(event.Body)); err != nil {
+		t.Fatalf("redelivered webhook: %v", err)
+	}
+	if len(released) != 1 {
+		t.Fatalf("released %d times, want once", len(released))
+	}
+}
+
+func TestChapaWebhookRejectsBadSignature(t *testing.T) {
+	ct := newChapaTest(t)
+	order := ct.createOrder(t)
+
+	body := []byte(`{"event":"charge.success","tx_ref":"` + order.ID.Hex() + `","reference":"FAKE1","status":"success","amount":"` +
+		strconv.FormatFloat(order.Checkout.Amount, 'f', 2, 64) + `","currency":"ETB"}`)
+	header := http.Header{}
+	header.Set("x-chapa-signature", strings.Repeat("ab", 32))
+
+	_, err := ct.webhooks.HandleWebhook(context.Background(), "chapa", header, body)
+	if !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Fatalf("error %v, want ErrInvalidWebhookSignature", err)
+	}
+	if len(ct.events.events) != 0 {
+		t.Fatalf("stored %d events, want none", len(ct.events.events))
+	}
+	if stored := ct.orders.get(order.ID); stored.Status != models.OrderAwaitingPayment {
+		t.Fatalf("order %s, want still awaiting payment", stored.Status)
+	}
+}
+
+func TestChapaPaymentAmountMismatch(t *testing.T) {
+	ct := newChapaTest(t)
+	var released int
+	ct.service.OnOrderReleased(func(models.Order) { released++ })
+
+	order := ct.createOrder(t)
+	if err := ct.chapa.PayAmount(order.ID.Hex(), "1.00"); err != nil {
+		t.Fatalf("webhook: %v", err)
+	}
+
+	stored := ct.orders.get(order.ID)
+	if stored.Status != models.OrderAwaitingPayment || stored.PaymentStatus == "paid" {
+		t.Fatalf("order %s / %s, want still awaiting payment", stored.Status, stored.PaymentStatus)
+	}
+	if released != 0 {
+		t.Fatalf("released %d orders, want none", released)
+	}
+	if len(stored.Flags) != 1 || stored.Flags[0].Type != "payment_amount_mismatch" {
+		t.Fatalf("flags %+v, want payment_amount_mismatch", stored.Flags)
+	}
+	if verification := stored.PaymentVerification; verification == nil || verification.Status != "failed" {
+		t.Fatalf("payment verification %+v, want failed", verification)
+	}
+}
+
+// In-memory stand-ins for what the checkout path touches. Each embeds
+// its interface, so calling anything else panics.
+
+type memOrderRepo struct {
+	repositories.OrderRepository
+	mu     sync.Mutex
+	orders map[primitive.ObjectID]*models.Order
+}
+
+func (r *memOrderRepo) get(id primitive.ObjectID) models.Order {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.orders[id]
+}
+
+func (r *memOrderRepo) Create(ctx context.Context, order *models.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if order.ID.IsZero() {
+		order.ID = primitive.NewObjectID()
+	}
+	stored := *order
+	r.orders[order.ID] = &stored
+	return nil
+}
+
+func (r *memOrderRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	found := *order
+	return &found, nil
+}
+
+func (r *memOrderRepo) SetCheckout(ctx context.Context, orderID primitive.ObjectID, checkout models.OrderCheckout) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders[orderID].Checkout = &checkout
+	return nil
+}
+
+func (r *memOrderRepo) ReleasePaidOrder(ctx context.Context, orderID primitive.ObjectID, verification *models.PaymentVerification, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order := r.orders[orderID]
+	if order.Status != models.OrderAwaitingPayment {
+		return false, nil
+	}
+	order.Status = models.OrderAccepted
+	order.PaymentStatus = "paid"
+	order.PaymentVerification = verification
+	order.ReleasedAt = &at
+	return true, nil
+}
+
+func (r *memOrderRepo) UpdatePaymentVerification(ctx context.Context, orderID primitive.ObjectID, paymentStatus string, verification *models.PaymentVerification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders[orderID].PaymentStatus = paymentStatus
+	r.orders[orderID].PaymentVerification = verification
+	return nil
+}
+
+func (r *memOrderRepo) AddFlag(ctx context.Context, orderID primitive.ObjectID, flag models.OrderFlag) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders[orderID].Flags = append(r.orders[orderID].Flags, flag)
+	return nil
+}
+
+type memPaymentEventRepo struct {
+	repositories.PaymentEventRepository
+	mu     sync.Mutex
+	events map[primitive.ObjectID]*models.PaymentEvent
+}
+
+// only returns the one stored event.
+func (r *memPaymentEventRepo) only(t *testing.T) models.PaymentEvent {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) != 1 {
+		t.Fatalf("stored %d payment events, want 1", len(r.events))
+	}
+	for _, event := range r.events {
+		return *event
+	}
+	return models.PaymentEvent{}
+}
+
+func (r *memPaymentEventRepo) Record(ctx context.Context, event *models.PaymentEvent) (*models.PaymentEvent, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stored := range r.events {
+		if stored.Provider == event.Provider && stored.EventID == event.EventID {
+			stored.Deliveries++
+			found := *stored
+			return &found, true, nil
+		}
+	}
+	stored := *event
+	stored.ID = primitive.NewObjectID()
+	stored.Status = models.PaymentEventReceived
+	stored.Deliveries = 1
+	r.events[stored.ID] = &stored
+	found := stored
+	return &found, false, nil
+}
+
+func (r *memPaymentEventRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.PaymentEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event, ok := r.events[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	found := *event
+	return &found, nil
+}
+
+func (r *memPaymentEventRepo) Claim(ctx context.Context, id primitive.ObjectID, lockedUntil time.Time, replayedBy *primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event := r.events[id]
+	if event.Status == models.PaymentEventProcessed || event.Status == models.PaymentEventProcessing {
+		return false, nil
+	}
+	event.Status = models.PaymentEventProcessing
+	event.Attempts++
+	event.LockedUntil = &lockedUntil
+	return true, nil
+}
+
+func (r *memPaymentEventRepo) Finish(ctx context.Context, id primitive.ObjectID, status, errMsg string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events[id].Status = status
+	r.events[id].Error = errMsg
+	r.events[id].LockedUntil = nil
+	return nil
+}
+
+type memRestaurantRepo struct {
+	repositories.RestaurantRepository
+	restaurant *models.Restaurant
+}
+
+func (r memRestaurantRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Restaurant, error) {
+	if id != r.restaurant.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	found := *r.restaurant
+	return &found, nil
+}
+
+type memMenuRepo struct {
+	repositories.MenuItemRepository
+	item models.MenuItem
+}
+
+func (r memMenuRepo) FindByIDs(ctx context.Context, restaurantID primitive.ObjectID, ids []primitive.ObjectID) ([]models.MenuItem, error) {
+	for _, id := range ids {
+		if id == r.item.ID && restaurantID == r.item.RestaurantID {
+			return []models.MenuItem{r.item}, nil
+		}
+	}
+	return nil, nil
+}
+
+type memCategoryRepo struct {
+	repositories.MenuCategoryRepository
+}
+
+func (memCategoryRepo) FindByRestaurant(ctx context.Context, restaurantID primitive.ObjectID) ([]models.MenuCategory, error) {
+	return nil, nil
+}
+
+type memUserRepo struct {
+	repositories.UserRepository
+	user *models.User
+}
+
+func (r memUserRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	if id != r.user.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	found := *r.user
+	return &found, nil
+}
+
+// noZones has no service zones configured, so every address is served.
+type noZones struct {
+	ZoneService
+}
+
+func (noZones) ZoneFor(ctx context.Context, location models.GeoLocation) (*models.ServiceZone, error) {
+	return nil, nil
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PaymentEventService takes payment providers' webhooks. Every webhook
+// whose signature checks out is stored in payment_events before it's
+// applied, and a provider's event is stored and applied once however
+// often it's delivered, so retried and duplicated callbacks are harmless;
+// callbacks arriving out of order are the order service's concern (see
+// ApplyPaymentUpdate). An event that failed to apply is tried again when
+// the provider redelivers it, or when an admin replays it.
+type PaymentEventService interface {
+	// HandleWebhook verifies, stores and applies a webhook. It fails with
+	// ErrUnknownPaymentProvider or ErrInvalidWebhookSignature before
+	// storing anything; if applying the stored event fails, the error is
+	// returned along with the event.
+	HandleWebhook(ctx context.Context, provider string, header http.Header, body []byte) (*models.PaymentEvent, error)
+	// GetEvents lists events newest first; provider, status and orderID
+	// are optional filters.
+	GetEvents(ctx context.Context, provider, status, orderID string, page, limit int64) ([]models.PaymentEvent, int64, error)
+	GetEvent(ctx context.Context, eventID string) (*models.PaymentEvent, error)
+	// ReplayEvent applies a failed event again, checking its signature
+	// afresh. It fails with ErrPaymentEventNotReplayable for an event
+	// that was processed or is being applied.
+	ReplayEvent(ctx context.Context, eventID string, adminID primitive.ObjectID) (*models.PaymentEvent, error)
+}
+
+// ErrPaymentEventNotReplayable is returned when replaying an event that
+// didn't fail.
+var ErrPaymentEventNotReplayable = errors.New("payment event was processed or is being applied")
+
+// paymentEventLease is how long applying an event may take before another
+// delivery or a replay can take it over.
+const paymentEventLease = 2 * time.Minute
+
+type paymentEventService struct {
+	eventRepo repositories.PaymentEventRepository
+	payments  PaymentProviders
+	orders    OrderService
+}
+
+func NewPaymentEventService(
+	eventRepo repositories.PaymentEventRepository,
+	payments PaymentProviders,
+	orders OrderService,
+) PaymentEventService {
+	return &paymentEventService{
+		eventRepo: eventRepo,
+		payments:  payments,
+		orders:    orders,
+	}
+}
+
+func (s *paymentEventService) HandleWebhook(ctx context.Context, providerName string, header http.Header, body []byte) (*models.PaymentEvent, error) {
+	provider := s.payments.Get(providerName)
+	if provider == nil {
+		return nil, ErrUnknownPaymentProvider
+	}
+	update, err := provider.ParseWebhook(header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	eventID := update.EventID
+	if eventID == "" {
+		sum := sha256.Sum256(body)
+		eventID = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	event := &models.PaymentEvent{
+		Provider:       provider.Name(),
+		EventID:        eventID,
+		Reference:      update.Reference,
+		ProviderStatus: update.ProviderStatus,
+		Headers:        webhookHeaders(header),
+		Body:           string(body),
+	}
+	if orderID, err := primitive.ObjectIDFromHex(update.Reference); err == nil {
+		event.OrderID = &orderID
+	}
+
+	stored, _, err := s.eventRepo.Record(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	event, _, err = s.apply(ctx, provider, stored, update, nil)
+	return event, err
+}
+
+// apply claims a stored event and applies its update, recording how it
+// went. It reports whether it got the claim; without it nothing is done,
+// as the event was processed already or another delivery is applying it.
+func (s *paymentEventService) apply(ctx context.Context, provider PaymentProvider, event *models.PaymentEvent, update *PaymentUpdate, replayedBy *primitive.ObjectID) (*models.PaymentEvent, bool, error) {
+	claimed, err := s.eventRepo.Claim(ctx, event.ID, time.Now().Add(paymentEventLease), replayedBy)
+	if err != nil || !claimed {
+		return event, false, err
+	}
+
+	applyErr := s.orders.ApplyPaymentUpdate(ctx, provider.Name(), update)
+	status, errMsg := models.PaymentEventProcessed, ""
+	if applyErr != nil {
+		status, errMsg = models.PaymentEventFailed, applyErr.Error()
+		fmt.Printf("⚠️  Payment event %s from %s failed: %v\n", event.EventID, provider.Name(), applyErr)
+	}
+	if err := s.eventRepo.Finish(ctx, event.ID, status, errMsg, time.Now()); err != nil {
+		return event, true, err
+	}
+
+	if finished, err := s.eventRepo.FindByID(ctx, event.ID); err == nil {
+		event = finished
+	}
+	return event, true, applyErr
+}
+
+func (s *paymentEventService) GetEvents(ctx context.Context, provider, status, orderID string, page, limit int64) ([]models.PaymentEvent, int64, error) {
+	filter := repositories.PaymentEventFilter{Provider: strings.ToLower(strings.TrimSpace(provider))}
+	switch status {
+	case "", models.PaymentEventReceived, models.PaymentEventProcessing, models.PaymentEventProcessed, models.PaymentEventFailed:
+		filter.Status = status
+	default:
+		return nil, 0, errors.New("status must be received, processing, processed or failed")
+	}
+	if orderID != "" {
+		objectID, err := primitive.ObjectIDFromHex(orderID)
+		if err != nil {
+			return nil, 0, errors.New("invalid order ID")
+		}
+		filter.OrderID = &objectID
+	}
+	return s.eventRepo.Find(ctx, filter, listPagination(page, limit))
+}
+
+func (s *paymentEventService) GetEvent(ctx context.Context, eventID string) (*models.PaymentEvent, error) {
+	objectID, err := primitive.ObjectIDFromHex(eventID)
+	if err != nil {
+		return nil, errors.New("invalid payment event ID")
+	}
+	return s.eventRepo.FindByID(ctx, objectID)
+}
+
+func (s *paymentEventService) ReplayEvent(ctx context.Context, eventID string, adminID primitive.ObjectID) (*models.PaymentEvent, error) {
+	event, err := s.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status == models.PaymentEventProcessed {
+		return nil, ErrPaymentEventNotReplayable
+	}
+	provider := s.payments.Get(event.Provider)
+	if provider == nil {
+		return nil, ErrUnknownPaymentProvider
+	}
+
+	header := http.Header{}
+	for name, value := range event.Headers {
+		header.Set(name, value)
+	}
+	update, err := provider.ParseWebhook(header, []byte(event.Body))
+	if err != nil {
+		return nil, fmt.Errorf("stored event no longer parses: %w", err)
+	}
+
+	event, claimed, err := s.apply(ctx, provider, event, update, &adminID)
+	if err != nil {
+		return event, err
+	}
+	if !claimed {
+		return nil, ErrPaymentEventNotReplayable
+	}
+	return event, nil
+}
+
+// webhookHeaders keeps a webhook's headers for replaying it, leaving out
+// any whose name can't be a document key.
+func webhookHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		if strings.ContainsAny(name, ".$") {
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PaymentProvider is a hosted-checkout payment gateway: we send the
+// customer to the provider's checkout page, and the provider tells us how
+// it went by webhook or when asked. Each provider is keyed by its Name,
+// which is both the payment method an order pays with and the :provider
+// of its webhook URL. Chapa is the one we have (payment_chapa.go);
+// paymenttest.FakeChapaServer stands in for it in tests.
+type PaymentProvider interface {
+	Name() string
+	// InitiateCheckout opens a checkout for a payment and returns the
+	// page to send the customer to.
+	InitiateCheckout(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error)
+	// ParseWebhook checks a callback's signature and reads the payment
+	// update it carries. It fails with ErrInvalidWebhookSignature if the
+	// signature doesn't check out.
+	ParseWebhook(header http.Header, body []byte) (*PaymentUpdate, error)
+	// FetchStatus asks the provider where the checkout for reference
+	// stands.
+	FetchStatus(ctx context.Context, reference string) (*PaymentUpdate, error)
+}
+
+// CheckoutRequest is a payment to take through a provider's checkout.
+// Reference is ours and identifies the payment to the provider from then
+// on; it's the order ID.
+type CheckoutRequest struct {
+	Reference   string
+	Amount      float64
+	Currency    string
+	Email       string
+	FirstName   string
+	LastName    string
+	Phone       string
+	Description string
+	CallbackURL string // where the provider sends webhooks
+	ReturnURL   string // where the customer lands after paying
+}
+
+type CheckoutSession struct {
+	Reference   string
+	CheckoutURL string
+}
+
+// Payment outcomes (PaymentUpdate.Status).
+const (
+	PaymentSucceeded = "succeeded"
+	PaymentPending   = "pending"
+	PaymentFailed    = "failed"
+)
+
+// PaymentUpdate is what a provider reports about a checkout.
+type PaymentUpdate struct {
+	// EventID identifies the webhook event the update came from; empty
+	// for updates fetched by polling.
+	EventID string
+	// Reference is ours, from CheckoutRequest; ProviderReference is the
+	// provider's own transaction reference, once it has one.
+	Reference         string
+	ProviderReference string
+	Status            string
+	ProviderStatus    string // the status as the provider put it
+	Amount            float64
+	Currency          string
+	Raw               map[string]interface{}
+}
+
+// ErrInvalidWebhookSignature is returned for a webhook whose signature
+// doesn't match.
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// ErrUnknownPaymentProvider is returned for a webhook from a provider that
+// isn't configured.
+var ErrUnknownPaymentProvider = errors.New("unknown payment provider")
+
+// PaymentProviders are the configured providers, by name.
+type PaymentProviders map[string]PaymentProvider
+
+// NewPaymentProviders collects the providers that are configured; nil
+// ones (not configured) are left out.
+func NewPaymentProviders(providers ...PaymentProvider) PaymentProviders {
+	registry := PaymentProviders{}
+	for _, provider := range providers {
+		if provider != nil {
+			registry[provider.Name()] = provider
+		}
+	}
+	return registry
+}
+
+// Get returns the provider an order's payment method names, or nil if
+// the method isn't a hosted checkout.
+func (p PaymentProviders) Get(method string) PaymentProvider {
+	return p[strings.ToLower(strings.TrimSpace(method))]
+}
+
+// Names lists the configured providers.
+func (p PaymentProviders) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkoutTTL is how long a customer has to finish a hosted checkout
+// before the order is cancelled.
+func checkoutTTL() time.Duration {
+	return time.Duration(envFloat("PAYMENT_CHECKOUT_TTL_MIN", 30) * float64(time.Minute))
+}
+
+// paymentCallbackURL is the webhook URL for a provider.
+func paymentCallbackURL(provider string) string {
+	base := strings.TrimRight(strings.TrimSpace(os.Getenv("PAYMENT_CALLBACK_BASE_URL")), "/")
+	if base == "" {
+		base = "https://pedal-delivery-back.onrender.com"
+	}
+	return base + "/api/v1/webhooks/payments/" + provider
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+// Receipt parsing: live verification fetches the receipt page CBE or
+// telebirr publishes for a transfer and reads it with that provider's
+// parser, which takes named fields out of the receipt's tables by their
+// labels. A page missing any of them fails verification — it isn't a
+// receipt, or the layout has changed — rather than being guessed at.
+// testdata/receipts holds saved pages of each layout, which
+// payment_receipts_test.go runs the parsers over.
+
+// ErrReceiptIncomplete is returned for a receipt page missing fields its
+// parser expects.
+var ErrReceiptIncomplete = errors.New("receipt is missing expected fields")
+
+// receiptTimezone is the zone receipt times are printed in (EAT).
+var receiptTimezone = time.FixedZone("EAT", 3*60*60)
+
+var receiptAmountPattern = regexp.MustCompile(`\d[\d,]*(?:\.\d+)?`)
+
+// ParseReceipt reads a cbe_transfer or telebirr_transfer receipt page.
+func ParseReceipt(method string, body []byte) (*models.PaymentReceipt, error) {
+	rows, err := receiptTableRows(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var receipt models.PaymentReceipt
+	switch method {
+	case "cbe_transfer":
+		receipt = parseCBEReceipt(rows)
+	case "telebirr_transfer":
+		receipt = parseTelebirrReceipt(rows)
+	default:
+		return nil, errors.New("unsupported payment method")
+	}
+
+	if missing := receiptMissing(method, receipt); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrReceiptIncomplete, strings.Join(missing, ", "))
+	}
+	return &receipt, nil
+}
+
+// parseCBEReceipt reads CBE's transfer receipt, a table of label/value
+// rows. Both parties are followed by an "Account" row, so an account
+// belongs to the party before it.
+func parseCBEReceipt(rows receiptRows) models.PaymentReceipt {
+	var receipt models.PaymentReceipt
+	party := ""
+	for _, row := range rows {
+		for i := 0; i+1 < len(row); i++ {
+			value := row[i+1]
+			switch receiptLabel(row[i]) {
+			case "payer":
+				receipt.Payer, party = value, "payer"
+			case "receiver":
+				receipt.ReceiverName, party = value, "receiver"
+			case "account":
+				if party == "payer" {
+					receipt.PayerAccount = value
+				} else if party == "receiver" {
+					receipt.ReceiverAccount = value
+				}
+				party = ""
+			case "payment date & time":
+				receipt.Date = parseReceiptDate(value, "1/2/2006, 3:04:05 PM", "1/2/2006 3:04:05 PM", "1/2/2006, 15:04:05")
+			case "reference no. (vat invoice no)", "reference no.":
+				receipt.Reference = value
+			case "transferred amount":
+				receipt.Amount = parseReceiptAmount(value)
+			default:
+				continue
+			}
+			i++
+		}
+	}
+	return receipt
+}
+
+// parseTelebirrReceipt reads telebirr's receipt: label/value rows for the
+// parties and status (labels in Amharic and English, "ስም/Name"), then an
+// invoice table whose header row names the columns under it.
+func parseTelebirrReceipt(rows receiptRows) models.PaymentReceipt {
+	receipt := models.PaymentReceipt{
+		Payer:           rows.after("payer name"),
+		PayerAccount:    rows.after("payer telebirr no."),
+		ReceiverName:    rows.after("credited party name"),
+		ReceiverAccount: rows.after("credited party account no"),
+		Status:          rows.after("transaction status"),
+		Reference:       rows.below("invoice no."),
+		Date:            parseReceiptDate(rows.below("payment date"), "02-01-2006 15:04:05", "02-01-2006 15:04"),
+		Amount:          parseReceiptAmount(rows.below("settled amount")),
+	}
+	if receipt.Amount == 0 {
+		receipt.Amount = parseReceiptAmount(rows.after("total paid amount"))
+	}
+	return receipt
+}
+
+// receiptMissing lists the fields a parsed receipt should have but
+// doesn't.
+func receiptMissing(method string, receipt models.PaymentReceipt) []string {
+	fields := []struct {
+		name    string
+		present bool
+	}{
+		{"payer", receipt.Payer != ""},
+		{"receiver name", receipt.ReceiverName != ""},
+		{"receiver account", receipt.ReceiverAccount != ""},
+		{"amount", receipt.Amount > 0},
+		{"date", !receipt.Date.IsZero()},
+		{"reference", receipt.Reference != ""},
+		{"status", receipt.Status != "" || method != "telebirr_transfer"},
+	}
+	var missing []string
+	for _, field := range fields {
+		if !field.present {
+			missing = append(missing, field.name)
+		}
+	}
+	return missing
+}
+
+// receiptRows is a page's table rows as the text of their cells.
+type receiptRows [][]string
+
+func receiptTableRows(body []byte) (receiptRows, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows receiptRows
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			var cells []string
+			for cell := n.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+					cells = append(cells, nodeText(cell))
+				}
+			}
+			if len(cells) > 0 {
+				rows = append(rows, cells)
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return rows, nil
+}
+
+// nodeText is the text inside a node with its whitespace collapsed.
+func nodeText(n *html.Node) string {
+	var parts []string
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			parts = append(parts, n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
+
+// receiptLabel normalises a label cell for matching: the English part of
+// a bilingual "አማርኛ/English" label, lower-cased, without a trailing
+// colon.
+func receiptLabel(cell string) string {
+	if i := strings.LastIndex(cell, "/"); i >= 0 {
+		cell = cell[i+1:]
+	}
+	return strings.ToLower(strings.TrimRight(strings.TrimSpace(cell), ": "))
+}
+
+// after returns the cell next to the first cell labelled label.
+func (rows receiptRows) after(label string) string {
+	for _, row := range rows {
+		for i := 0; i+1 < len(row); i++ {
+			if receiptLabel(row[i]) == label {
+				return row[i+1]
+			}
+		}
+	}
+	return ""
+}
+
+// below returns the cell under the first column headed label.
+func (rows receiptRows) below(label string) string {
+	for r := 0; r+1 < len(rows); r++ {
+		for i, cell := range rows[r] {
+			if receiptLabel(cell) == label && i < len(rows[r+1]) {
+				return rows[r+1][i]
+			}
+		}
+	}
+	return ""
+}
+
+// parseReceiptAmount reads the number in "1,250.00 ETB"; 0 if none.
+func parseReceiptAmount(text string) float64 {
+	match := receiptAmountPattern.FindString(text)
+	if match == "" {
+		return 0
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(match, ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	return amount
+}
+
+// parseReceiptDate reads a receipt time in the first layout that fits;
+// the zero time if none does.
+func parseReceiptDate(text string, layouts ...string) time.Time {
+	text = strings.TrimSpace(text)
+	for _, layout := range layouts {
+		if at, err := time.ParseInLocation(layout, text, receiptTimezone); err == nil {
+			return at
+		}
+	}
+	return time.Time{}
+}
+
+// receiptAccountMatches reports whether an account as a receipt shows it
+// is ours. Receipts mask the middle of it (1****7072), so a masked
+// account matches if our account starts and ends with the digits shown,
+// as long as at least four are shown.
+func receiptAccountMatches(shown, ours string) bool {
+	shown, ours = strings.TrimSpace(shown), strings.TrimSpace(ours)
+	if shown == "" || ours == "" {
+		return false
+	}
+	masked := strings.IndexAny(shown, "*xX")
+	if masked < 0 {
+		return strings.Contains(shown, ours) || (len(shown) >= 4 && strings.HasSuffix(ours, shown))
+	}
+	prefix := shown[:masked]
+	suffix := strings.TrimLeft(shown[masked:], "*xX")
+	return len(prefix)+len(suffix) >= 4 && strings.HasPrefix(ours, prefix) && strings.HasSuffix(ours, suffix)
+}
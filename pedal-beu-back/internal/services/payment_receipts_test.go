@@ -0,0 +1,73 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+)
+
+// receiptFixture is what a saved receipt page should parse to: the page
+// name.html has its expectation in name.json beside it, either the
+// receipt or text the parse error should contain.
+type receiptFixture struct {
+	Method  string                 `json:"method"`
+	Receipt *models.PaymentReceipt `json:"receipt"`
+	Error   string                 `json:"error"`
+}
+
+func TestParseReceiptFixtures(t *testing.T) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "receipts", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) == 0 {
+		t.Fatal("no receipt pages in testdata/receipts")
+	}
+
+	for _, page := range pages {
+		path := strings.TrimSuffix(page, ".html")
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			body, err := os.ReadFile(page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw, err := os.ReadFile(path + ".json")
+			if err != nil {
+				t.Fatalf("no expectation: %v", err)
+			}
+			var want receiptFixture
+			if err := json.Unmarshal(raw, &want); err != nil {
+				t.Fatalf("bad expectation: %v", err)
+			}
+
+			got, err := ParseReceipt(want.Method, body)
+			switch {
+			case want.Error != "":
+				if err == nil {
+					t.Fatalf("parsed, want an error containing %q", want.Error)
+				}
+				if !strings.Contains(err.Error(), want.Error) {
+					t.Fatalf("error %q, want one containing %q", err, want.Error)
+				}
+				return
+			case err != nil:
+				t.Fatalf("error: %v", err)
+			case want.Receipt == nil:
+				t.Fatal("expectation has neither receipt nor error")
+			}
+
+			// Compare times by instant, not by how they were written.
+			gotReceipt, wantReceipt := *got, *want.Receipt
+			gotReceipt.Date, wantReceipt.Date = gotReceipt.Date.UTC(), wantReceipt.Date.UTC()
+			if !reflect.DeepEqual(gotReceipt, wantReceipt) {
+				gotJSON, _ := json.Marshal(got)
+				t.Fatalf("parsed %s", gotJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Payment review queue: orders whose payment waits on an admin — a proof
+// the customer uploaded, or a transfer the provider never answered for —
+// listed longest waiting first with what an admin needs to decide. An
+// admin claims an order before reviewing it so two don't work the same
+// one; the claim lapses after PAYMENT_REVIEW_CLAIM_MIN. Each order should
+// be decided within PAYMENT_REVIEW_SLA_MIN of going to review.
+
+// ErrPaymentReviewClaimed is returned when another admin is reviewing the
+// order.
+var ErrPaymentReviewClaimed = errors.New("another admin is reviewing this payment")
+
+// paymentReviewHistoryLimit is how many of the customer's other orders
+// each queue entry shows.
+const paymentReviewHistoryLimit = 10
+
+func paymentReviewSLA() time.Duration {
+	return time.Duration(envFloat("PAYMENT_REVIEW_SLA_MIN", 30) * float64(time.Minute))
+}
+
+func paymentReviewClaimLease() time.Duration {
+	return time.Duration(envFloat("PAYMENT_REVIEW_CLAIM_MIN", 15) * float64(time.Minute))
+}
+
+// PaymentReviewItem is an order in the review queue. The order's
+// payment_verification holds the proof image, the parsed receipt and the
+// provider's raw response.
+type PaymentReviewItem struct {
+	Order           models.Order   `json:"order"`
+	WaitingSince    time.Time      `json:"waiting_since"`
+	SLADueAt        time.Time      `json:"sla_due_at"`
+	SLARemainingSec int64          `json:"sla_remaining_sec"` // negative once overdue
+	Overdue         bool           `json:"overdue"`
+	Claimed         bool           `json:"claimed"` // by an admin, and not lapsed
+	ClaimedByMe     bool           `json:"claimed_by_me"`
+	CustomerHistory PaymentHistory `json:"customer_history"`
+}
+
+// PaymentHistory is how the customer's other orders were paid, most
+// recent first.
+type PaymentHistory struct {
+	Orders   int64                 `json:"orders"`   // all their orders, this one included
+	Rejected int                   `json:"rejected"` // payments rejected or failed among Recent
+	Recent   []PaymentHistoryEntry `json:"recent"`
+}
+
+type PaymentHistoryEntry struct {
+	OrderID              primitive.ObjectID `json:"order_id"`
+	OrderNumber          string             `json:"order_number"`
+	PaymentMethod        string             `json:"payment_method"`
+	PaymentStatus        string             `json:"payment_status"`
+	VerificationStatus   string             `json:"verification_status,omitempty"`
+	TransactionReference string             `json:"transaction_reference,omitempty"`
+	Amount               float64            `json:"amount"`
+	CreatedAt            time.Time          `json:"created_at"`
+}
+
+// PaymentReviewResult is one order's outcome in a bulk review.
+type PaymentReviewResult struct {
+	OrderID string        `json:"order_id"`
+	Order   *models.Order `json:"order,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+func (s *orderService) GetPaymentReviewQueue(ctx context.Context, adminID primitive.ObjectID, page, limit int64) ([]PaymentReviewItem, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	orders, total, err := s.orderRepo.FindPendingPaymentReviews(ctx, repositories.Pagination{Page: page, Limit: limit})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	histories, err := s.customerPaymentHistories(ctx, orders)
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now()
+	sla := paymentReviewSLA()
+	items := make([]PaymentReviewItem, 0, len(orders))
+	for _, order := range orders {
+		verification := order.PaymentVerification
+		waitingSince := order.CreatedAt
+		if verification.ReviewRequestedAt != nil {
+			waitingSince = *verification.ReviewRequestedAt
+		} else if verification.CheckedAt != nil {
+			waitingSince = *verification.CheckedAt
+		}
+		due := waitingSince.Add(sla)
+		claimed := verification.ClaimedBy != nil && verification.ClaimedUntil != nil && verification.ClaimedUntil.After(now)
+
+		items = append(items, PaymentReviewItem{
+			Order:           order,
+			WaitingSince:    waitingSince,
+			SLADueAt:        due,
+			SLARemainingSec: int64(due.Sub(now).Seconds()),
+			Overdue:         now.After(due),
+			Claimed:         claimed,
+			ClaimedByMe:     claimed && *verification.ClaimedBy == adminID,
+			CustomerHistory: paymentHistory(order, histories[order.CustomerID]),
+		})
+	}
+	return items, total, nil
+}
+
+// customerPaymentHistories looks up the recent orders of every customer
+// on a page of the queue at once.
+func (s *orderService) customerPaymentHistories(ctx context.Context, orders []models.Order) (map[primitive.ObjectID]repositories.CustomerOrders, error) {
+	seen := make(map[primitive.ObjectID]bool, len(orders))
+	customerIDs := make([]primitive.ObjectID, 0, len(orders))
+	for _, order := range orders {
+		if !seen[order.CustomerID] {
+			seen[order.CustomerID] = true
+			customerIDs = append(customerIDs, order.CustomerID)
+		}
+	}
+	// One more than shown, as the order under review is among them.
+	return s.orderRepo.FindRecentByCustomers(ctx, customerIDs, paymentReviewHistoryLimit+1)
+}
+
+// paymentHistory summarises how the order's customer paid for their
+// other recent orders.
+func paymentHistory(order models.Order, customer repositories.CustomerOrders) PaymentHistory {
+	history := PaymentHistory{Orders: customer.Total, Recent: []PaymentHistoryEntry{}}
+	for _, past := range customer.Recent {
+		if past.ID == order.ID || len(history.Recent) == paymentReviewHistoryLimit {
+			continue
+		}
+		entry := PaymentHistoryEntry{
+			OrderID:       past.ID,
+			OrderNumber:   past.OrderNumber,
+			PaymentMethod: past.PaymentMethod,
+			PaymentStatus: past.PaymentStatus,
+			Amount:        past.TotalAmount.Total,
+			CreatedAt:     past.CreatedAt,
+		}
+		if past.PaymentVerification != nil {
+			entry.VerificationStatus = past.PaymentVerification.Status
+			entry.TransactionReference = past.PaymentVerification.TransactionReference
+		}
+		if past.PaymentStatus == "failed" || entry.VerificationStatus == "rejected" {
+			history.Rejected++
+		}
+		history.Recent = append(history.Recent, entry)
+	}
+	return history
+}
+
+func (s *orderService) ClaimPaymentReview(ctx context.Context, orderID, adminID primitive.ObjectID) (*models.Order, error) {
+	if err := s.claimPaymentReview(ctx, orderID, adminID); err != nil {
+		return nil, err
+	}
+	order, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyPaymentReview(*order)
+	return order, nil
+}
+
+// claimPaymentReview claims (or renews the admin's claim on) an order's
+// pending payment review, saying why not if it can't.
+func (s *orderService) claimPaymentReview(ctx context.Context, orderID, adminID primitive.ObjectID) error {
+	now := time.Now()
+	claimed, err := s.orderRepo.ClaimPaymentReview(ctx, orderID, adminID, now, now.Add(paymentReviewClaimLease()))
+	if err != nil {
+		return err
+	}
+	if claimed {
+		return nil
+	}
+
+	order, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.PaymentVerification == nil || order.PaymentVerification.Status != "pending_review" {
+		return errors.New("payment is not awaiting review")
+	}
+	return ErrPaymentReviewClaimed
+}
+
+func (s *orderService) ReleasePaymentReview(ctx context.Context, orderID, adminID primitive.ObjectID) error {
+	released, err := s.orderRepo.ReleasePaymentReview(ctx, orderID, adminID)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return errors.New("you haven't claimed this payment review")
+	}
+	if order, err := s.orderRepo.FindByID(ctx, orderID); err == nil {
+		s.notifyPaymentReview(*order)
+	}
+	return nil
+}
+
+func (s *orderService) BulkReviewPaymentProof(ctx context.Context, adminID primitive.ObjectID, req *models.BulkReviewPaymentProofRequest) []PaymentReviewResult {
+	review := &models.ReviewPaymentProofRequest{Approved: req.Approved, Notes: strings.TrimSpace(req.Notes)}
+	results := make([]PaymentReviewResult, 0, len(req.OrderIDs))
+	for _, id := range req.OrderIDs {
+		result := PaymentReviewResult{OrderID: id}
+		orderID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			result.Error = "invalid order ID"
+		} else if order, err := s.ReviewPaymentProof(ctx, orderID, adminID, review); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Order = order
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// notifyPaymentReview hands an order whose review was claimed, released
+// or decided to the OnPaymentReviewUpdated callback.
+func (s *orderService) notifyPaymentReview(order models.Order) {
+	if s.reviewChanged != nil {
+		s.reviewChanged(order)
+	}
+}
+
+func (s *orderService) OnPaymentReviewUpdated(fn func(models.Order)) {
+	s.reviewChanged = fn
+}
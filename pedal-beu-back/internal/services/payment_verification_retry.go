@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+)
+
+// Verification retries: when a transfer can't be verified because the
+// provider didn't answer — it timed out, its site was down, or the receipt
+// isn't published yet — VerifyOrderPayment queues the check instead of
+// failing it, leaving the verification "retrying". The retry worker
+// (RetryPaymentVerifications, run by main.go's ticker) asks again with
+// exponential backoff, from PAYMENT_VERIFICATION_RETRY_BASE_SEC doubling
+// up to PAYMENT_VERIFICATION_RETRY_MAX_SEC, for
+// PAYMENT_VERIFICATION_RETRY_WINDOW_MIN; a check still unresolved then goes
+// to pending_review for an admin. Each change is pushed to the order's
+// room as it happens.
+
+// ErrPaymentVerificationQueued is returned with the order when the
+// provider couldn't be asked and the check has been queued for retries.
+var ErrPaymentVerificationQueued = errors.New("the payment provider didn't respond; we'll keep checking and update the order")
+
+const (
+	// verificationRetryBatch caps how many checks one worker run makes.
+	verificationRetryBatch = 20
+	// verificationJobLease is how long a check may take before another
+	// worker run can take it over.
+	verificationJobLease = time.Minute
+)
+
+func verificationRetryWindow() time.Duration {
+	return time.Duration(envFloat("PAYMENT_VERIFICATION_RETRY_WINDOW_MIN", 60) * float64(time.Minute))
+}
+
+// verificationBackoff is how long to wait before the retry after the
+// given number of them.
+func verificationBackoff(retries int) time.Duration {
+	base := time.Duration(envFloat("PAYMENT_VERIFICATION_RETRY_BASE_SEC", 30) * float64(time.Second))
+	max := time.Duration(envFloat("PAYMENT_VERIFICATION_RETRY_MAX_SEC", 600) * float64(time.Second))
+	if retries > 20 {
+		retries = 20
+	}
+	delay := base << retries
+	if delay <= 0 || delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// queueVerification leaves an unresolved check retrying and queues it.
+// If it can't be queued the check fails as it would have before.
+func (s *orderService) queueVerification(ctx context.Context, order *models.Order, req *models.VerifyOrderPaymentRequest, transactionReference, payerPhone string, check transferCheck) (*models.Order, error) {
+	verification := check.verification
+	verification.Status = "retrying"
+	if err := s.orderRepo.UpdatePaymentVerification(ctx, order.ID, "pending", verification); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &models.PaymentVerificationJob{
+		OrderID:              order.ID,
+		CustomerID:           order.CustomerID,
+		Method:               req.Method,
+		TransactionReference: transactionReference,
+		Amount:               req.Amount,
+		PayerPhone:           payerPhone,
+		LastError:            verification.FailureReason,
+		NextAttemptAt:        now.Add(verificationBackoff(0)),
+		DeadlineAt:           now.Add(verificationRetryWindow()),
+	}
+	if err := s.verifyJobs.Enqueue(ctx, job); err != nil {
+		fmt.Printf("⚠️  Failed to queue payment verification for order %s: %v\n", order.ID.Hex(), err)
+		verification.Status = "failed"
+		_ = s.orderRepo.UpdatePaymentVerification(ctx, order.ID, "pending", verification)
+		return nil, check.err
+	}
+
+	updated, err := s.orderRepo.FindByID(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	return updated, ErrPaymentVerificationQueued
+}
+
+func (s *orderService) RetryPaymentVerifications(ctx context.Context) (int, error) {
+	tried := 0
+	for tried < verificationRetryBatch {
+		now := time.Now()
+		job, err := s.verifyJobs.ClaimDue(ctx, now, now.Add(verificationJobLease))
+		if err != nil {
+			return tried, err
+		}
+		if job == nil {
+			break
+		}
+		s.retryVerification(ctx, job)
+		tried++
+	}
+	return tried, nil
+}
+
+// retryVerification makes one more check for a claimed job and settles
+// it: verified, ruled out, escalated to admin review once the window has
+// passed, or queued again.
+func (s *orderService) retryVerification(ctx context.Context, job *models.PaymentVerificationJob) {
+	order, err := s.orderRepo.FindByID(ctx, job.OrderID)
+	if err != nil {
+		s.resolveVerificationJob(ctx, job, models.VerificationJobCancelled, err.Error())
+		return
+	}
+	if verification := order.PaymentVerification; order.Status == models.OrderCancelled || order.PaymentStatus == "paid" ||
+		verification == nil || verification.Status != "retrying" || verification.TransactionReference != job.TransactionReference {
+		// Paid, cancelled, or verified some other way since.
+		s.resolveVerificationJob(ctx, job, models.VerificationJobCancelled, "")
+		return
+	}
+
+	check := s.checkTransfer(ctx, job.Method, job.TransactionReference, job.Amount, job.PayerPhone)
+	verification := check.verification
+	now := time.Now()
+	switch {
+	case check.err == nil:
+		if err := s.orderRepo.UpdatePaymentVerification(ctx, order.ID, "paid", verification); err != nil {
+			fmt.Printf("⚠️  Failed to record verified payment for order %s: %v\n", order.ID.Hex(), err)
+			return
+		}
+		s.resolveVerificationJob(ctx, job, models.VerificationJobSucceeded, "")
+	case !check.retry:
+		if err := s.orderRepo.UpdatePaymentVerification(ctx, order.ID, "pending", verification); err != nil {
+			fmt.Printf("⚠️  Failed to record payment check for order %s: %v\n", order.ID.Hex(), err)
+			return
+		}
+		s.resolveVerificationJob(ctx, job, models.VerificationJobFailed, verification.FailureReason)
+	default:
+		next := now.Add(verificationBackoff(job.Attempts))
+		if next.After(job.DeadlineAt) {
+			verification.Status = "pending_review"
+			verification.ReviewRequestedAt = &now
+			verification.FailureReason = fmt.Sprintf("couldn't verify with the provider after %d tries: %s", job.Attempts+1, verification.FailureReason)
+		} else {
+			verification.Status = "retrying"
+		}
+		if err := s.orderRepo.UpdatePaymentVerification(ctx, order.ID, "pending", verification); err != nil {
+			fmt.Printf("⚠️  Failed to record payment check for order %s: %v\n", order.ID.Hex(), err)
+			return
+		}
+		if verification.Status == "pending_review" {
+			s.resolveVerificationJob(ctx, job, models.VerificationJobEscalated, verification.FailureReason)
+		} else if err := s.verifyJobs.Reschedule(ctx, job, next, verification.FailureReason); err != nil {
+			fmt.Printf("⚠️  Failed to reschedule payment verification for order %s: %v\n", order.ID.Hex(), err)
+		}
+	}
+	s.notifyPaymentChecked(ctx, order)
+}
+
+func (s *orderService) resolveVerificationJob(ctx context.Context, job *models.PaymentVerificationJob, status, lastError string) {
+	if err := s.verifyJobs.Resolve(ctx, job, status, lastError, time.Now()); err != nil {
+		fmt.Printf("⚠️  Failed to close payment verification for order %s: %v\n", job.OrderID.Hex(), err)
+	}
+}
+
+// notifyPaymentChecked hands the order, as it now stands, to the
+// OnPaymentVerificationUpdated callback.
+func (s *orderService) notifyPaymentChecked(ctx context.Context, order *models.Order) {
+	if s.paymentChecked == nil {
+		return
+	}
+	updated, err := s.orderRepo.FindByID(ctx, order.ID)
+	if err != nil {
+		return
+	}
+	s.paymentChecked(*updated)
+}
+
+func (s *orderService) OnPaymentVerificationUpdated(fn func(models.Order)) {
+	s.paymentChecked = fn
+}
+
+// verificationStats counts transfer checks by payment method since the
+// server started, for GetPaymentVerificationHealth.
+type verificationStats struct {
+	mu       sync.Mutex
+	since    time.Time
+	byMethod map[string]*providerVerificationStats
+}
+
+type providerVerificationStats struct {
+	Attempts       int64      `json:"attempts"`
+	Verified       int64      `json:"verified"`
+	Rejected       int64      `json:"rejected"`   // the provider's answer ruled the payment out
+	Unresolved     int64      `json:"unresolved"` // no answer: timeouts, errors, no receipt yet
+	SuccessRate    float64    `json:"success_rate"`
+	AnswerRate     float64    `json:"answer_rate"` // checks the provider gave an answer to, either way
+	LastVerifiedAt *time.Time `json:"last_verified_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorAt    *time.Time `json:"last_error_at,omitempty"`
+}
+
+func newVerificationStats() *verificationStats {
+	return &verificationStats{since: time.Now(), byMethod: map[string]*providerVerificationStats{}}
+}
+
+func (v *verificationStats) record(method string, check transferCheck) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	stats := v.byMethod[method]
+	if stats == nil {
+		stats = &providerVerificationStats{}
+		v.byMethod[method] = stats
+	}
+	now := time.Now()
+	stats.Attempts++
+	switch {
+	case check.err == nil:
+		stats.Verified++
+		stats.LastVerifiedAt = &now
+	case check.retry:
+		stats.Unresolved++
+		stats.LastError = check.verification.FailureReason
+		stats.LastErrorAt = &now
+	default:
+		stats.Rejected++
+	}
+}
+
+func (v *verificationStats) snapshot() map[string]interface{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	providers := make(map[string]providerVerificationStats, len(v.byMethod))
+	for method, stats := range v.byMethod {
+		copied := *stats
+		if copied.Attempts > 0 {
+			copied.SuccessRate = roundMoney(float64(copied.Verified) / float64(copied.Attempts))
+			copied.AnswerRate = roundMoney(float64(copied.Verified+copied.Rejected) / float64(copied.Attempts))
+		}
+		providers[method] = copied
+	}
+	return map[string]interface{}{
+		"since":     v.since,
+		"providers": providers,
+	}
+}
+
+// verificationQueueHealth is the retry queue's part of the health report.
+func (s *orderService) verificationQueueHealth(ctx context.Context) map[string]interface{} {
+	health := map[string]interface{}{
+		"retry_base_sec":   verificationBackoff(0).Seconds(),
+		"retry_max_sec":    verificationBackoff(20).Seconds(),
+		"retry_window_min": verificationRetryWindow().Minutes(),
+	}
+	counts, err := s.verifyJobs.CountByStatus(ctx)
+	if err != nil {
+		health["error"] = err.Error()
+		return health
+	}
+	health["depth"] = counts[models.VerificationJobQueued] + counts[models.VerificationJobRunning]
+	health["by_status"] = counts
+	return health
+}
@@ -9,7 +9,6 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -26,12 +25,10 @@ type providerVerificationResult struct {
 	ReceiverText    string
 	ReceiverDigits  string
 	Amount          float64
+	Receipt         *models.PaymentReceipt
 	RawResponse     map[string]interface{}
 }
 
-var amountPattern = regexp.MustCompile(`(?i)(ETB|Birr)[^\d]*(\d+(?:\.\d+)?)|(\d+(?:\.\d+)?)[^\d]*(ETB|Birr)`)
-var receiverDigitsPattern = regexp.MustCompile(`\d{6,}`)
-
 func (s *orderService) VerifyOrderPayment(ctx context.Context, orderID primitive.ObjectID, customerID primitive.ObjectID, req *models.VerifyOrderPaymentRequest) (*models.Order, error) {
 	order, err := s.orderRepo.FindByID(ctx, orderID)
 	if err != nil {
@@ -60,10 +57,47 @@ func (s *orderService) VerifyOrderPayment(ctx context.Context, orderID primitive
 		}
 	}
 
-	result, verificationErr := verifyTransferWithProvider(ctx, req.Method, transactionReference)
+	check := s.checkTransfer(ctx, req.Method, transactionReference, req.Amount, payerPhone)
+	if check.err != nil {
+		if check.retry {
+			return s.queueVerification(ctx, order, req, transactionReference, payerPhone, check)
+		}
+		_ = s.orderRepo.UpdatePaymentVerification(ctx, orderID, "pending", check.verification)
+		return nil, check.err
+	}
+
+	if err := s.orderRepo.UpdatePaymentVerification(ctx, orderID, "paid", check.verification); err != nil {
+		return nil, err
+	}
+
+	log.Printf("payment verified: order=%s method=%s reference=%s", orderID.Hex(), req.Method, transactionReference)
+	return s.orderRepo.FindByID(ctx, orderID)
+}
+
+// transferCheck is how one attempt to verify a transfer with its provider
+// went: verified if err is nil, otherwise err is what to tell the
+// customer. retry is set when the provider couldn't say either way — it
+// timed out, was down, or hasn't published the receipt yet — so asking
+// again later may still verify it.
+type transferCheck struct {
+	verification *models.PaymentVerification
+	err          error
+	retry        bool
+}
+
+// checkTransfer asks the provider about a transfer and checks what it
+// says against the amount and our merchant account.
+func (s *orderService) checkTransfer(ctx context.Context, method, transactionReference string, amount float64, payerPhone string) transferCheck {
+	check := s.checkTransferWithProvider(ctx, method, transactionReference, amount, payerPhone)
+	s.verifyStats.record(method, check)
+	return check
+}
+
+func (s *orderService) checkTransferWithProvider(ctx context.Context, method, transactionReference string, amount float64, payerPhone string) transferCheck {
+	result, verificationErr := verifyTransferWithProvider(ctx, method, transactionReference)
 	if verificationMode() == "mock" {
-		result.Amount = req.Amount
-		if req.Method == "cbe_transfer" {
+		result.Amount = amount
+		if method == "cbe_transfer" {
 			result.ReceiverDigits = firstNonEmpty(result.ReceiverDigits, os.Getenv("CBE_RECEIVER_ACCOUNT_SUFFIX"))
 			result.ReceiverText = firstNonEmpty(result.ReceiverText, os.Getenv("CBE_RECEIVER_NAME_HINT"))
 		} else {
@@ -73,7 +107,7 @@ func (s *orderService) VerifyOrderPayment(ctx context.Context, orderID primitive
 	}
 
 	verification := &models.PaymentVerification{
-		Method:               req.Method,
+		Method:               method,
 		Status:               "failed",
 		TransactionReference: transactionReference,
 		VerificationURL:      result.VerificationURL,
@@ -81,44 +115,49 @@ func (s *orderService) VerifyOrderPayment(ctx context.Context, orderID primitive
 		ReceiverText:         result.ReceiverText,
 		ReceiverDigits:       result.ReceiverDigits,
 		PayerPhone:           payerPhone,
+		Receipt:              result.Receipt,
 		RawResponse:          result.RawResponse,
 	}
 	now := time.Now()
 	verification.CheckedAt = &now
+	check := transferCheck{verification: verification}
 
 	if verificationErr != nil {
 		verification.FailureReason = verificationErr.Error()
-		_ = s.orderRepo.UpdatePaymentVerification(ctx, orderID, "pending", verification)
-		return nil, verificationErr
+		check.err = verificationErr
+		check.retry = !errors.As(verificationErr, new(finalVerificationError))
+		return check
 	}
 
-	if !amountMatches(result.Amount, req.Amount) {
-		verification.FailureReason = fmt.Sprintf("verified amount %.2f did not match order amount %.2f", result.Amount, req.Amount)
-		_ = s.orderRepo.UpdatePaymentVerification(ctx, orderID, "pending", verification)
-		return nil, errors.New("payment amount does not match the order total")
+	if !amountMatches(result.Amount, amount) {
+		verification.FailureReason = fmt.Sprintf("verified amount %.2f did not match order amount %.2f", result.Amount, amount)
+		check.err = errors.New("payment amount does not match the order total")
+		return check
 	}
 
-	if !receiverMatches(req.Method, result) {
+	if !receiverMatches(method, result) {
 		verification.FailureReason = "payment receiver did not match the configured merchant account"
-		_ = s.orderRepo.UpdatePaymentVerification(ctx, orderID, "pending", verification)
-		return nil, errors.New("payment receiver did not match the merchant account")
+		check.err = errors.New("payment receiver did not match the merchant account")
+		return check
 	}
 
 	if !result.Verified {
 		verification.FailureReason = "provider did not confirm the payment"
-		_ = s.orderRepo.UpdatePaymentVerification(ctx, orderID, "pending", verification)
-		return nil, errors.New("payment could not be verified")
+		check.err = errors.New("payment could not be verified")
+		check.retry = true
+		return check
 	}
 
 	verification.Status = "verified"
 	verification.ProviderStatus = firstNonEmpty(result.ProviderStatus, "verified")
 	verification.VerifiedAt = &now
-	if err := s.orderRepo.UpdatePaymentVerification(ctx, orderID, "paid", verification); err != nil {
-		return nil, err
-	}
+	return check
+}
 
-	log.Printf("payment verified: order=%s method=%s reference=%s", orderID.Hex(), req.Method, transactionReference)
-	return s.orderRepo.FindByID(ctx, orderID)
+// finalVerificationError is a provider answer that rules a payment out,
+// as opposed to one that couldn't be had; asking again won't change it.
+type finalVerificationError struct {
+	error
 }
 
 func (s *orderService) SubmitPaymentProof(ctx context.Context, orderID primitive.ObjectID, customerID primitive.ObjectID, req *models.SubmitPaymentProofRequest) (*models.Order, error) {
@@ -161,6 +200,7 @@ func (s *orderService) SubmitPaymentProof(ctx context.Context, orderID primitive
 		ProofURL:             proofURL,
 		ProviderStatus:       "manual_review",
 		CheckedAt:            &now,
+		ReviewRequestedAt:    &now,
 		RawResponse: map[string]interface{}{
 			"mode":   "manual_proof",
 			"amount": req.Amount,
@@ -178,17 +218,30 @@ func (s *orderService) ReviewPaymentProof(ctx context.Context, orderID primitive
 	if err != nil {
 		return nil, err
 	}
-	if order.PaymentVerification == nil || order.PaymentVerification.ProofURL == "" {
+	// A check the provider never answered is escalated for review without
+	// a proof.
+	if order.PaymentVerification == nil || (order.PaymentVerification.ProofURL == "" && order.PaymentVerification.Status != "pending_review") {
 		return nil, errors.New("payment proof not submitted")
 	}
 	if order.PaymentVerification.Status != "pending_review" {
 		return nil, errors.New("payment proof has already been reviewed")
 	}
+	// Claiming it first means nobody else can be reviewing it at the same
+	// time; the claim goes once it's decided.
+	if err := s.claimPaymentReview(ctx, orderID, adminID); err != nil {
+		return nil, err
+	}
+	if order, err = s.orderRepo.FindByID(ctx, orderID); err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
 	verification := *order.PaymentVerification
 	verification.ReviewedBy = &adminID
 	verification.ReviewedAt = &now
+	verification.ReviewNotes = strings.TrimSpace(req.Notes)
+	verification.ClaimedBy = nil
+	verification.ClaimedUntil = nil
 	if req.Approved {
 		verification.Status = "verified"
 		verification.ProviderStatus = "admin_approved"
@@ -206,7 +259,12 @@ func (s *orderService) ReviewPaymentProof(ctx context.Context, orderID primitive
 		}
 	}
 
-	return s.orderRepo.FindByID(ctx, orderID)
+	updated, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyPaymentReview(*updated)
+	return updated, nil
 }
 
 func (s *orderService) GetPaymentVerificationHealth(ctx context.Context) map[string]interface{} {
@@ -220,8 +278,11 @@ func (s *orderService) GetPaymentVerificationHealth(ctx context.Context) map[str
 		"telebirr_receipt_base_url":      telebirrReceiptBaseURL(),
 		"telebirr_receiver_hint":         os.Getenv("TELEBIRR_RECEIVER_HINT"),
 		"telebirr_receiver_phone_hint":   os.Getenv("TELEBIRR_RECEIVER_PHONE_HINT"),
-		"supported_payment_methods":      []string{"cash", "telebirr", "cbe", "telebirr_transfer", "cbe_transfer"},
+		"supported_payment_methods":      []string{"cash", "telebirr", "cbe", "telebirr_transfer", "cbe_transfer", walletPaymentMethod},
+		"checkout_providers":             s.payments.Names(),
 		"server_side_payment_check_live": verificationMode() == "live",
+		"verification_queue":             s.verificationQueueHealth(ctx),
+		"provider_verification":          s.verifyStats.snapshot(),
 	}
 }
 
@@ -297,15 +358,18 @@ func fetchCBEVerification(ctx context.Context, transactionReference string) (pro
 		return providerVerificationResult{}, errors.New("missing CBE receiver account suffix")
 	}
 	verificationURL := cbeVerificationBaseURL() + transactionReference + suffix
-	return fetchVerificationURL(ctx, verificationURL)
+	return fetchVerificationURL(ctx, "cbe_transfer", transactionReference, verificationURL)
 }
 
 func fetchTelebirrVerification(ctx context.Context, transactionReference string) (providerVerificationResult, error) {
 	verificationURL := telebirrReceiptBaseURL() + transactionReference
-	return fetchVerificationURL(ctx, verificationURL)
+	return fetchVerificationURL(ctx, "telebirr_transfer", transactionReference, verificationURL)
 }
 
-func fetchVerificationURL(ctx context.Context, verificationURL string) (providerVerificationResult, error) {
+// fetchVerificationURL fetches the provider's record of a transfer: JSON
+// from an API, or the receipt page, which is verified only if its parser
+// finds every field it expects and the receipt is for this transfer.
+func fetchVerificationURL(ctx context.Context, method, transactionReference, verificationURL string) (providerVerificationResult, error) {
 	httpClient := &http.Client{Timeout: verificationTimeout()}
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, verificationURL, nil)
 	if err != nil {
@@ -350,79 +414,27 @@ func fetchVerificationURL(ctx context.Context, verificationURL string) (provider
 		}
 	}
 
-	bodyText := string(body)
-	result.Verified = htmlLooksVerified(bodyText)
-	result.ProviderStatus = firstNonEmpty(result.ProviderStatus, parseProviderStatus(bodyText))
-	result.Amount = extractAmount(bodyText)
-	result.ReceiverText = extractReceiverText(bodyText)
-	result.ReceiverDigits = extractReceiverDigits(bodyText)
-	return result, nil
-}
-
-func htmlLooksVerified(body string) bool {
-	lower := strings.ToLower(body)
-	return strings.Contains(lower, "thank you for using telebirr") ||
-		strings.Contains(lower, "successful") ||
-		strings.Contains(lower, "success") ||
-		strings.Contains(lower, "verified")
-}
-
-func parseProviderStatus(body string) string {
-	lower := strings.ToLower(body)
-	switch {
-	case strings.Contains(lower, "successful"), strings.Contains(lower, "success"):
-		return "verified"
-	case strings.Contains(lower, "pending"):
-		return "pending"
-	case strings.Contains(lower, "failed"):
-		return "failed"
-	default:
-		return ""
-	}
-}
-
-func extractAmount(body string) float64 {
-	match := amountPattern.FindStringSubmatch(body)
-	if len(match) == 0 {
-		return 0
-	}
-	for _, candidate := range []string{match[2], match[3]} {
-		if candidate == "" {
-			continue
-		}
-		amount, err := strconv.ParseFloat(candidate, 64)
-		if err == nil {
-			return amount
-		}
-	}
-	return 0
-}
-
-func extractReceiverText(body string) string {
-	lower := strings.ToLower(body)
-	for _, hint := range []string{
-		strings.ToLower(strings.TrimSpace(os.Getenv("TELEBIRR_RECEIVER_HINT"))),
-		strings.ToLower(strings.TrimSpace(os.Getenv("CBE_RECEIVER_NAME_HINT"))),
-	} {
-		if hint != "" && strings.Contains(lower, hint) {
-			return hint
-		}
-	}
-	return ""
-}
-
-func extractReceiverDigits(body string) string {
-	lower := strings.ToLower(body)
-	for _, hint := range []string{
-		strings.TrimSpace(os.Getenv("TELEBIRR_RECEIVER_PHONE_HINT")),
-		strings.TrimSpace(os.Getenv("CBE_RECEIVER_ACCOUNT_SUFFIX")),
-	} {
-		if hint != "" && strings.Contains(lower, strings.ToLower(hint)) {
-			return hint
+	receipt, err := ParseReceipt(method, body)
+	if err != nil {
+		return result, err
+	}
+	result.Receipt = receipt
+	result.Amount = receipt.Amount
+	result.ReceiverText = receipt.ReceiverName
+	result.ReceiverDigits = receipt.ReceiverAccount
+	result.ProviderStatus = firstNonEmpty(strings.ToLower(receipt.Status), "receipt_found")
+	if !strings.EqualFold(strings.TrimSpace(receipt.Reference), transactionReference) {
+		return result, finalVerificationError{fmt.Errorf("receipt is for transaction %s, not %s", receipt.Reference, transactionReference)}
+	}
+	if method == "telebirr_transfer" && !strings.EqualFold(receipt.Status, "completed") {
+		err := fmt.Errorf("telebirr reports the transaction as %s", receipt.Status)
+		if strings.EqualFold(receipt.Status, "pending") {
+			return result, err
 		}
+		return result, finalVerificationError{err}
 	}
-	match := receiverDigitsPattern.FindString(body)
-	return match
+	result.Verified = true
+	return result, nil
 }
 
 func receiverMatches(method string, result providerVerificationResult) bool {
@@ -430,7 +442,7 @@ func receiverMatches(method string, result providerVerificationResult) bool {
 	case "cbe_transfer":
 		expectedSuffix := strings.TrimSpace(os.Getenv("CBE_RECEIVER_ACCOUNT_SUFFIX"))
 		expectedName := strings.ToLower(strings.TrimSpace(os.Getenv("CBE_RECEIVER_NAME_HINT")))
-		if expectedSuffix != "" && strings.Contains(result.ReceiverDigits, expectedSuffix) {
+		if expectedSuffix != "" && receiptAccountMatches(result.ReceiverDigits, expectedSuffix) {
 			return true
 		}
 		if expectedName != "" && strings.Contains(strings.ToLower(result.ReceiverText), expectedName) {
@@ -443,7 +455,7 @@ func receiverMatches(method string, result providerVerificationResult) bool {
 		if expectedName != "" && strings.Contains(strings.ToLower(result.ReceiverText), expectedName) {
 			return true
 		}
-		if expectedPhone != "" && strings.Contains(result.ReceiverDigits, expectedPhone) {
+		if expectedPhone != "" && receiptAccountMatches(result.ReceiverDigits, expectedPhone) {
 			return true
 		}
 		return expectedName == "" && expectedPhone == ""
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefundService issues and processes refunds. Admins issue them, in full
+// or for particular items; each is pending until the money has gone back
+// to the customer, which for wallet credit is at once and for the original
+// payment method is when an admin records the transfer. Processing a refund updates the order's payment status,
+// charges the driver back if asked to, and leaves its food part for the
+// restaurant's next statement to deduct.
+type RefundService interface {
+	CreateRefund(ctx context.Context, actorID primitive.ObjectID, actorRole string, req models.CreateRefundRequest) (*models.Refund, error)
+	// ProcessRefund records that a pending refund has been paid back to
+	// the customer; for wallet refunds it retries the credit.
+	ProcessRefund(ctx context.Context, refundID string, actorID primitive.ObjectID, req models.ProcessRefundRequest) (*models.Refund, error)
+	// GetRefunds lists refunds newest first; orderID and status are
+	// optional filters.
+	GetRefunds(ctx context.Context, orderID, status string, page, limit int64) ([]models.Refund, int64, error)
+	GetRefund(ctx context.Context, refundID string) (*models.Refund, error)
+}
+
+// WalletCreditor puts refunds on customers' wallets. A failed credit is
+// retried when the refund is processed again, so crediting the same
+// refund twice must be a no-op.
+type WalletCreditor interface {
+	CreditRefund(ctx context.Context, refund *models.Refund) error
+}
+
+// ErrWalletUnavailable is returned for a wallet refund when there's no
+// wallet to credit.
+var ErrWalletUnavailable = errors.New("wallet refunds aren't available")
+
+type refundService struct {
+	refundRepo repositories.RefundRepository
+	orderRepo  repositories.OrderRepository
+	ledger     LedgerService
+	wallet     WalletCreditor
+}
+
+// NewRefundService builds the refund service. wallet may be nil, in which
+// case refunds can only go back to the original payment method.
+func NewRefundService(
+	refundRepo repositories.RefundRepository,
+	orderRepo repositories.OrderRepository,
+	ledger LedgerService,
+	wallet WalletCreditor,
+) RefundService {
+	return &refundService{
+		refundRepo: refundRepo,
+		orderRepo:  orderRepo,
+		ledger:     ledger,
+		wallet:     wallet,
+	}
+}
+
+// orderPaid reports whether the customer has paid anything on the order
+// that could be refunded.
+func orderPaid(order *models.Order) bool {
+	switch order.PaymentStatus {
+	case "paid", "partially_refunded":
+		return true
+	}
+	return isCashOrder(order) && order.CashCollection != nil
+}
+
+// unitPrice is what one of an order line cost, options included.
+func unitPrice(item models.OrderItem) float64 {
+	if item.Quantity <= 0 {
+		return 0
+	}
+	return item.Total / float64(item.Quantity)
+}
+
+func (s *refundService) CreateRefund(ctx context.Context, actorID primitive.ObjectID, actorRole string, req models.CreateRefundRequest) (*models.Refund, error) {
+	orderID, err := primitive.ObjectIDFromHex(req.OrderID)
+	if err != nil {
+		return nil, errors.New("invalid order ID")
+	}
+	order, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	// An order paid by wallet is refunded to the wallet either way.
+	destination := req.Destination
+	if order.PaymentMethod == walletPaymentMethod {
+		destination = models.RefundToWallet
+	}
+	if destination == models.RefundToWallet && s.wallet == nil {
+		return nil, ErrWalletUnavailable
+	}
+	if order.PaymentStatus == "refunded" {
+		return nil, errors.New("order has already been refunded in full")
+	}
+	if !orderPaid(order) {
+		return nil, errors.New("nothing has been paid on this order yet")
+	}
+
+	existing, err := s.refundRepo.FindByOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	requested := 0.0
+	if order.Refunds != nil {
+		requested = order.Refunds.Requested
+	}
+	// A wallet payment handed back on cancellation isn't left to refund.
+	remaining := roundMoney(order.TotalAmount.Total - requested - walletReturned(order))
+
+	refund := &models.Refund{
+		OrderID:         order.ID,
+		OrderNumber:     order.OrderNumber,
+		CustomerID:      order.CustomerID,
+		RestaurantID:    order.RestaurantID,
+		DriverID:        order.DriverID,
+		Type:            req.Type,
+		ReasonCode:      req.ReasonCode,
+		Note:            strings.TrimSpace(req.Note),
+		Destination:     destination,
+		PaymentMethod:   order.PaymentMethod,
+		ChargeDriver:    req.ChargeDriver && order.DriverID != nil,
+		Status:          models.RefundPending,
+		RequestedBy:     actorID,
+		RequestedByRole: actorRole,
+	}
+
+	lines := map[int]int{}
+	if req.Type == "full" {
+		var food, delivery, tip float64
+		for _, r := range existing {
+			food += r.Food
+			delivery += r.Delivery
+			tip += r.Tip
+		}
+		refund.Amount = remaining
+		refund.Food = roundMoney(math.Max(0, order.TotalAmount.Subtotal-food))
+		refund.Delivery = roundMoney(math.Max(0, order.TotalAmount.DeliveryFee+order.TotalAmount.SurgeFee-delivery))
+		refund.Tip = roundMoney(math.Max(0, order.TotalAmount.Tip-tip))
+		for i, item := range order.Items {
+			if left := item.Quantity - item.RefundedQuantity; left > 0 {
+				lines[i] = left
+				refund.Items = append(refund.Items, models.RefundItem{
+					Line:       i,
+					MenuItemID: item.MenuItemID,
+					Name:       item.Name,
+					Quantity:   left,
+					Amount:     roundMoney(unitPrice(item) * float64(left)),
+				})
+			}
+		}
+	} else {
+		if len(req.Items) == 0 {
+			return nil, errors.New("choose the items to refund")
+		}
+		for _, line := range req.Items {
+			if line.Line < 0 || line.Line >= len(order.Items) {
+				return nil, fmt.Errorf("order has no item line %d", line.Line)
+			}
+			if _, seen := lines[line.Line]; seen {
+				return nil, fmt.Errorf("item line %d is listed twice", line.Line)
+			}
+			item := order.Items[line.Line]
+			if left := item.Quantity - item.RefundedQuantity; line.Quantity > left {
+				return nil, fmt.Errorf("only %d of %s can still be refunded", left, item.Name)
+			}
+			lines[line.Line] = line.Quantity
+
+			amount := roundMoney(unitPrice(item) * float64(line.Quantity))
+			refund.Items = append(refund.Items, models.RefundItem{
+				Line:       line.Line,
+				MenuItemID: item.MenuItemID,
+				Name:       item.Name,
+				Quantity:   line.Quantity,
+				Amount:     amount,
+			})
+			refund.Food += amount
+		}
+		refund.Food = roundMoney(refund.Food)
+
+		// The items' share of the service charge and tax goes back with
+		// them; the delivery fee and tip don't.
+		charges := 0.0
+		if order.TotalAmount.Subtotal > 0 {
+			charges = (order.TotalAmount.ServiceCharge + order.TotalAmount.Tax - order.TotalAmount.Discount) *
+				refund.Food / order.TotalAmount.Subtotal
+		}
+		refund.Amount = roundMoney(math.Min(remaining, refund.Food+math.Max(0, charges)))
+	}
+	if refund.Amount <= 0 {
+		return nil, errors.New("nothing is left to refund on this order")
+	}
+	// A cancelled or rejected order is never billed to the restaurant nor
+	// earned by a driver, so there's no food sale to take the refund off
+	// and no delivery fee or tip to charge back.
+	if order.Status == models.OrderCancelled || order.Status == models.OrderRejected {
+		refund.Food = 0
+		refund.Delivery = 0
+		refund.Tip = 0
+	}
+
+	applied, err := s.orderRepo.RequestRefund(ctx, orderID, requested, refund.Amount, lines)
+	if err != nil {
+		return nil, err
+	}
+	if !applied {
+		return nil, errors.New("another refund was issued on this order at the same time; try again")
+	}
+	if err := s.refundRepo.Create(ctx, refund); err != nil {
+		s.undoRequest(ctx, orderID, requested+refund.Amount, refund.Amount, lines)
+		return nil, err
+	}
+
+	if refund.Destination == models.RefundToWallet {
+		return s.process(ctx, refund, actorID, "")
+	}
+	return refund, nil
+}
+
+// undoRequest takes a refund that couldn't be saved back off the order's
+// requested total.
+func (s *refundService) undoRequest(ctx context.Context, orderID primitive.ObjectID, requested, amount float64, lines map[int]int) {
+	undo := make(map[int]int, len(lines))
+	for line, quantity := range lines {
+		undo[line] = -quantity
+	}
+	if _, err := s.orderRepo.RequestRefund(ctx, orderID, requested, -amount, undo); err != nil {
+		fmt.Printf("⚠️  Failed to take an unsaved refund off order %s: %v\n", orderID.Hex(), err)
+	}
+}
+
+// process pays a pending refund out — crediting the wallet, for wallet
+// refunds — and marks it processed, then brings the order's totals and
+// the driver's ledger up to date. If the wallet credit fails the refund
+// stays pending to be processed again.
+func (s *refundService) process(ctx context.Context, refund *models.Refund, actorID primitive.ObjectID, reference string) (*models.Refund, error) {
+	if refund.Destination == models.RefundToWallet {
+		if s.wallet == nil {
+			return nil, ErrWalletUnavailable
+		}
+		if err := s.wallet.CreditRefund(ctx, refund); err != nil {
+			return nil, fmt.Errorf("refund saved but the wallet credit failed, process it again to retry: %w", err)
+		}
+	}
+
+	processed, err := s.refundRepo.MarkProcessed(ctx, refund.ID, actorID, reference, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.orderRepo.RecordRefundProcessed(ctx, processed.OrderID, processed.Amount, processed.Food); err != nil {
+		fmt.Printf("⚠️  Failed to add refund %s to order %s: %v\n", processed.ID.Hex(), processed.OrderID.Hex(), err)
+	}
+	if processed.ChargeDriver {
+		if err := s.ledger.RecordRefundChargeback(ctx, processed); err != nil {
+			fmt.Printf("⚠️  Failed to charge refund %s back to the driver: %v\n", processed.ID.Hex(), err)
+		}
+	}
+	return processed, nil
+}
+
+func (s *refundService) ProcessRefund(ctx context.Context, refundID string, actorID primitive.ObjectID, req models.ProcessRefundRequest) (*models.Refund, error) {
+	refund, err := s.GetRefund(ctx, refundID)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status != models.RefundPending {
+		return nil, repositories.ErrRefundProcessed
+	}
+
+	// Cash is handed back in person; anything else needs the reference of
+	// the transfer back.
+	reference := strings.TrimSpace(req.Reference)
+	cash := strings.EqualFold(strings.TrimSpace(refund.PaymentMethod), "cash")
+	if refund.Destination == models.RefundToOriginalMethod && !cash && reference == "" {
+		return nil, errors.New("reference is required for the transfer back to the customer")
+	}
+	return s.process(ctx, refund, actorID, reference)
+}
+
+func (s *refundService) GetRefunds(ctx context.Context, orderID, status string, page, limit int64) ([]models.Refund, int64, error) {
+	var filter repositories.RefundFilter
+	if orderID != "" {
+		objectID, err := primitive.ObjectIDFromHex(orderID)
+		if err != nil {
+			return nil, 0, errors.New("invalid order ID")
+		}
+		filter.OrderID = &objectID
+	}
+	switch status {
+	case "", models.RefundPending, models.RefundProcessed:
+		filter.Status = status
+	default:
+		return nil, 0, errors.New("status must be pending or processed")
+	}
+	return s.refundRepo.Find(ctx, filter, listPagination(page, limit))
+}
+
+func (s *refundService) GetRefund(ctx context.Context, refundID string) (*models.Refund, error) {
+	objectID, err := primitive.ObjectIDFromHex(refundID)
+	if err != nil {
+		return nil, errors.New("invalid refund ID")
+	}
+	return s.refundRepo.FindByID(ctx, objectID)
+}
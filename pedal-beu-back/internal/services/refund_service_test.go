@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// refundOrderRepo serves one order and accepts every refund request.
+type refundOrderRepo struct {
+	repositories.OrderRepository
+	order models.Order
+}
+
+func (r *refundOrderRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error) {
+	order := r.order
+	return &order, nil
+}
+
+func (r *refundOrderRepo) RequestRefund(ctx context.Context, orderID primitive.ObjectID, requested, amount float64, lines map[int]int) (bool, error) {
+	return true, nil
+}
+
+// memRefundRepo holds an order's earlier refunds.
+type memRefundRepo struct {
+	repositories.RefundRepository
+	existing []models.Refund
+}
+
+func (r *memRefundRepo) FindByOrder(ctx context.Context, orderID primitive.ObjectID) ([]models.Refund, error) {
+	return r.existing, nil
+}
+
+func (r *memRefundRepo) Create(ctx context.Context, refund *models.Refund) error {
+	refund.ID = primitive.NewObjectID()
+	return nil
+}
+
+// refundTestOrder is a paid order of 200 in food (two lines of 100), 50
+// delivery with surge, 50 in service charge and tax and a 25 tip.
+func refundTestOrder(status models.OrderStatus) models.Order {
+	return models.Order{
+		ID:            primitive.NewObjectID(),
+		Status:        status,
+		PaymentMethod: "chapa",
+		PaymentStatus: "paid",
+		Items: []models.OrderItem{
+			{MenuItemID: primitive.NewObjectID(), Name: "Tibs", Quantity: 2, Price: 50, Total: 100},
+			{MenuItemID: primitive.NewObjectID(), Name: "Kitfo", Quantity: 1, Price: 100, Total: 100},
+		},
+		TotalAmount: models.OrderAmount{
+			Subtotal:      200,
+			DeliveryFee:   40,
+			SurgeFee:      10,
+			ServiceCharge: 20,
+			Tax:           30,
+			Tip:           25,
+			Total:         325,
+		},
+	}
+}
+
+func TestCreateRefundSplit(t *testing.T) {
+	// A full refund after 1 Tibs was refunded already.
+	afterPartial := refundTestOrder(models.OrderDelivered)
+	afterPartial.Items[0].RefundedQuantity = 1
+	afterPartial.Refunds = &models.OrderRefunds{Requested: 62.5}
+	earlier := []models.Refund{{Type: "partial", Amount: 62.5, Food: 50}}
+
+	oneTibs := []models.RefundItemRequest{{Line: 0, Quantity: 1}}
+
+	tests := []struct {
+		name     string
+		order    models.Order
+		existing []models.Refund
+		kind     string
+		items    []models.RefundItemRequest
+
+		amount, food, delivery, tip float64
+	}{
+		{
+			name:   "full refund of a delivered order",
+			order:  refundTestOrder(models.OrderDelivered),
+			kind:   "full",
+			amount: 325, food: 200, delivery: 50, tip: 25,
+		},
+		{
+			name:   "full refund of a cancelled order",
+			order:  refundTestOrder(models.OrderCancelled),
+			kind:   "full",
+			amount: 325, food: 0, delivery: 0, tip: 0,
+		},
+		{
+			name:   "full refund of a rejected order",
+			order:  refundTestOrder(models.OrderRejected),
+			kind:   "full",
+			amount: 325, food: 0, delivery: 0, tip: 0,
+		},
+		{
+			name:   "partial refund takes its share of the charges",
+			order:  refundTestOrder(models.OrderDelivered),
+			kind:   "partial",
+			items:  oneTibs,
+			amount: 62.5, food: 50, delivery: 0, tip: 0,
+		},
+		{
+			name:   "partial refund of a cancelled order",
+			order:  refundTestOrder(models.OrderCancelled),
+			kind:   "partial",
+			items:  oneTibs,
+			amount: 62.5, food: 0, delivery: 0, tip: 0,
+		},
+		{
+			name:     "full refund after a partial one",
+			order:    afterPartial,
+			existing: earlier,
+			kind:     "full",
+			amount:   262.5, food: 150, delivery: 50, tip: 25,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewRefundService(&memRefundRepo{existing: tt.existing}, &refundOrderRepo{order: tt.order}, nil, nil)
+			refund, err := service.CreateRefund(context.Background(), primitive.NewObjectID(), "admin", models.CreateRefundRequest{
+				OrderID:     tt.order.ID.Hex(),
+				Type:        tt.kind,
+				Items:       tt.items,
+				ReasonCode:  models.RefundReasonQuality,
+				Destination: models.RefundToOriginalMethod,
+			})
+			if err != nil {
+				t.Fatalf("CreateRefund: %v", err)
+			}
+			if refund.Status != models.RefundPending {
+				t.Errorf("status = %q, want %q", refund.Status, models.RefundPending)
+			}
+			if refund.Amount != tt.amount || refund.Food != tt.food || refund.Delivery != tt.delivery || refund.Tip != tt.tip {
+				t.Errorf("amount/food/delivery/tip = %v/%v/%v/%v, want %v/%v/%v/%v",
+					refund.Amount, refund.Food, refund.Delivery, refund.Tip,
+					tt.amount, tt.food, tt.delivery, tt.tip)
+			}
+		})
+	}
+}
@@ -3,7 +3,11 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/haile-paa/pedal-delivery/internal/models"
@@ -20,20 +24,110 @@ type RestaurantService interface {
 	GetAllRestaurantsAdmin(ctx context.Context, query models.RestaurantQuery) ([]models.Restaurant, int64, error)
 	FindNearby(ctx context.Context, location models.GeoLocation, radius float64) ([]models.Restaurant, error)
 	Search(ctx context.Context, query string, location *models.GeoLocation) ([]models.Restaurant, error)
-	GetMenuItems(ctx context.Context, restaurantID string) ([]models.MenuItem, error)
-	UpdateRestaurant(ctx context.Context, id string, update models.UpdateRestaurantRequest) (*models.Restaurant, error)
+	// GetMenuItems returns the restaurant's menu filtered by query. With
+	// no page/limit it's the whole menu (total is its length); otherwise
+	// one page of it.
+	GetMenuItems(ctx context.Context, restaurantID string, query models.MenuItemQuery) ([]models.MenuItem, int64, error)
+	// UpdateRestaurant applies update; a menu in it replaces (archives)
+	// the current menu, recorded in the menu history as actorID's doing.
+	UpdateRestaurant(ctx context.Context, id string, update models.UpdateRestaurantRequest, actorID primitive.ObjectID) (*models.Restaurant, error)
 	SetRestaurantVerification(ctx context.Context, id string, isVerified bool) (*models.Restaurant, error)
 	DeleteRestaurant(ctx context.Context, id string) error
-	AddMenuItem(ctx context.Context, restaurantID string, req models.CreateMenuItemRequest) (*models.MenuItem, error)
-	UpdateMenuItem(ctx context.Context, restaurantID, itemID string, req models.UpdateMenuItemRequest) (*models.MenuItem, error)
+	// AddMenuItem and UpdateMenuItem record the change in the menu
+	// history as made by actorID (see menu_history.go).
+	AddMenuItem(ctx context.Context, restaurantID string, req models.CreateMenuItemRequest, actorID primitive.ObjectID) (*models.MenuItem, error)
+	UpdateMenuItem(ctx context.Context, restaurantID, itemID string, req models.UpdateMenuItemRequest, actorID primitive.ObjectID) (*models.MenuItem, error)
+	// DeleteMenuItem archives the item: it leaves the menu but stays in
+	// the database, so orders that reference it still resolve.
+	// UnarchiveMenuItem puts it back.
+	DeleteMenuItem(ctx context.Context, restaurantID, itemID string, actorID primitive.ObjectID) error
+	UnarchiveMenuItem(ctx context.Context, restaurantID, itemID string, actorID primitive.ObjectID) error
+	// GetMenuHistory lists the restaurant's menu changes, newest first.
+	GetMenuHistory(ctx context.Context, restaurantID string, query models.MenuHistoryQuery) ([]models.MenuItemChange, int64, error)
+	RestoreMenuItemVersion(ctx context.Context, restaurantID, changeID string, actorID primitive.ObjectID) (*models.MenuItem, error)
+	// GetMenu is the customer-facing menu: grouped by category, with only
+	// the categories being served at `at` and only available items. The
+	// query's category, q, diet and exclude_allergens narrow it further.
+	GetMenu(ctx context.Context, restaurantID string, at time.Time, query models.MenuItemQuery) (*RestaurantMenu, error)
+	ListMenuCategories(ctx context.Context, restaurantID string) ([]models.MenuCategory, error)
+	CreateMenuCategory(ctx context.Context, restaurantID string, req models.MenuCategoryRequest) (*models.MenuCategory, error)
+	UpdateMenuCategory(ctx context.Context, restaurantID, categoryID string, req models.MenuCategoryRequest) (*models.MenuCategory, error)
+	DeleteMenuCategory(ctx context.Context, restaurantID, categoryID string) error
+	// ResetDailyStock refills every stock-tracked item that hasn't been
+	// reset since the last STOCK_RESET_TIME before now, returning how many
+	// it refilled. Run by main.go's stock reset ticker.
+	ResetDailyStock(ctx context.Context, now time.Time) (int64, error)
+	// ExportMenu returns the restaurant's categories and items in the
+	// import/export file format (see menu_import.go).
+	ExportMenu(ctx context.Context, restaurantID string) (*models.MenuFile, error)
+	// ImportMenu validates and, unless dryRun, applies a menu file:
+	// categories are upserted by name and items by SKU. Nothing is written
+	// if any row fails; the report lists every failure.
+	ImportMenu(ctx context.Context, restaurantID string, file *models.MenuFile, dryRun bool, actorID primitive.ObjectID) (*MenuImportReport, error)
+	// ImportMenuCSV is ImportMenu for the CSV format.
+	ImportMenuCSV(ctx context.Context, restaurantID string, r io.Reader, dryRun bool, actorID primitive.ObjectID) (*MenuImportReport, error)
 }
 
 type restaurantService struct {
-	repo repositories.RestaurantRepository
+	repo         repositories.RestaurantRepository
+	menuRepo     repositories.MenuItemRepository
+	categoryRepo repositories.MenuCategoryRepository
+	historyRepo  repositories.MenuHistoryRepository
+	zones        ZoneService
 }
 
-func NewRestaurantService(repo repositories.RestaurantRepository) RestaurantService {
-	return &restaurantService{repo: repo}
+func NewRestaurantService(
+	repo repositories.RestaurantRepository,
+	menuRepo repositories.MenuItemRepository,
+	categoryRepo repositories.MenuCategoryRepository,
+	historyRepo repositories.MenuHistoryRepository,
+	zones ZoneService,
+) RestaurantService {
+	return &restaurantService{
+		repo:         repo,
+		menuRepo:     menuRepo,
+		categoryRepo: categoryRepo,
+		historyRepo:  historyRepo,
+		zones:        zones,
+	}
+}
+
+// deliverableRadius narrows a discovery radius (meters) around the
+// customer's location to what can actually be delivered there, the way
+// CreateOrder will judge it: 0 when service zones are configured and none
+// covers the location, or the covering zone is outside its active hours;
+// otherwise the smaller of radius and the zone's max delivery distance.
+// It also returns the covering zone (nil when no zones are configured),
+// whose area the restaurants have to be inside.
+func deliverableRadius(ctx context.Context, zones ZoneService, location models.GeoLocation, radius float64) (float64, *models.ServiceZone, error) {
+	zone, err := zones.ZoneFor(ctx, location)
+	if errors.Is(err, ErrOutsideServiceArea) {
+		log.Printf("📍 Location %v is outside every service zone", location.Coordinates)
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if zone == nil {
+		return radius, nil, nil
+	}
+	if !zoneOpenAt(zone, time.Now()) {
+		log.Printf("📍 Zone %s is outside its active hours", zone.Name)
+		return 0, zone, nil
+	}
+	if zone.MaxDeliveryDistanceKm > 0 {
+		radius = math.Min(radius, zone.MaxDeliveryDistanceKm*1000)
+	}
+	return radius, zone, nil
+}
+
+// zoneArea is the area restaurants have to be inside to deliver in zone,
+// or nil when no zones are configured.
+func zoneArea(zone *models.ServiceZone) *models.GeoPolygon {
+	if zone == nil {
+		return nil
+	}
+	return &zone.Area
 }
 
 func (s *restaurantService) CreateRestaurant(ctx context.Context, ownerID string, req models.CreateRestaurantRequest) (*models.Restaurant, error) {
@@ -42,31 +136,9 @@ func (s *restaurantService) CreateRestaurant(ctx context.Context, ownerID string
 		return nil, errors.New("invalid owner ID")
 	}
 
-	// Convert menu items if provided
-	var menuItems []models.MenuItem
-	if req.Menu != nil {
-		for _, itemReq := range req.Menu {
-			// Default IsAvailable to true if not provided
-			isAvailable := true
-			if itemReq.IsAvailable != nil {
-				isAvailable = *itemReq.IsAvailable
-			}
-
-			menuItem := models.MenuItem{
-				ID:              primitive.NewObjectID(),
-				Name:            itemReq.Name,
-				Description:     itemReq.Description,
-				Price:           itemReq.Price,
-				Category:        itemReq.Category,
-				Ingredients:     itemReq.Ingredients,
-				Addons:          itemReq.Addons,
-				IsAvailable:     isAvailable,
-				PreparationTime: itemReq.PreparationTime,
-				Image:           itemReq.Image,
-				CreatedAt:       time.Now(),
-				UpdatedAt:       time.Now(),
-			}
-			menuItems = append(menuItems, menuItem)
+	if req.Timezone != "" {
+		if err := validateTimezone(req.Timezone); err != nil {
+			return nil, err
 		}
 	}
 
@@ -86,7 +158,7 @@ func (s *restaurantService) CreateRestaurant(ctx context.Context, ownerID string
 			Coordinates: []float64{req.Longitude, req.Latitude},
 		},
 		Images:       req.Images,
-		Menu:         menuItems,
+		Timezone:     req.Timezone,
 		OpeningHours: models.OpeningHours{},
 	}
 
@@ -94,15 +166,117 @@ func (s *restaurantService) CreateRestaurant(ctx context.Context, ownerID string
 		return nil, err
 	}
 
+	// Menu items go to their own collection once the restaurant has an ID
+	// for them to point at.
+	if req.Menu != nil {
+		menuItems, err := menuItemsFromRequest(restaurant.ID, nil, req.Menu)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.menuRepo.CreateMany(ctx, menuItems); err != nil {
+			return nil, err
+		}
+		for i := range menuItems {
+			s.recordMenuChange(ctx, menuActionCreated, nil, &menuItems[i], ownerObjectID)
+		}
+		restaurant.Menu = menuItems
+	}
+
 	return restaurant, nil
 }
+
+// menuItemsFromRequest builds new menu items for restaurantID with
+// menuItemFromRequest, naming the item in any error. SKUs have to be
+// unique within the menu.
+func menuItemsFromRequest(restaurantID primitive.ObjectID, categories []models.MenuCategory, reqs []models.CreateMenuItemRequest) ([]models.MenuItem, error) {
+	menuItems := []models.MenuItem{}
+	skus := map[string]bool{}
+	for _, itemReq := range reqs {
+		item, err := menuItemFromRequest(restaurantID, categories, itemReq)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", itemReq.Name, err)
+		}
+		if item.SKU != "" {
+			if skus[item.SKU] {
+				return nil, fmt.Errorf("%s: SKU %q is used twice in the menu", itemReq.Name, item.SKU)
+			}
+			skus[item.SKU] = true
+		}
+		menuItems = append(menuItems, *item)
+	}
+	return menuItems, nil
+}
+
+// menuItemFromRequest builds a new menu item for restaurantID, defaulting
+// IsAvailable to true where the request leaves it out. categories are the
+// restaurant's menu categories, for items that name one.
+func menuItemFromRequest(restaurantID primitive.ObjectID, categories []models.MenuCategory, req models.CreateMenuItemRequest) (*models.MenuItem, error) {
+	if err := prepareMenuOptions(req.Variants, req.ModifierGroups); err != nil {
+		return nil, err
+	}
+	categoryName, categoryID, err := categoryForItem(categories, req.CategoryID, req.Category)
+	if err != nil {
+		return nil, err
+	}
+	stock, err := applyStockRequest(nil, req.DailyStock, req.LowStockThreshold)
+	if err != nil {
+		return nil, err
+	}
+	dietaryTags, allergens, err := normalizeDietary(req.DietaryTags, req.Allergens)
+	if err != nil {
+		return nil, err
+	}
+
+	isAvailable := true
+	if req.IsAvailable != nil {
+		isAvailable = *req.IsAvailable
+	}
+
+	return &models.MenuItem{
+		ID:              primitive.NewObjectID(),
+		RestaurantID:    restaurantID,
+		SKU:             strings.TrimSpace(req.SKU),
+		Name:            req.Name,
+		Description:     req.Description,
+		Price:           req.Price,
+		Category:        categoryName,
+		CategoryID:      categoryID,
+		Ingredients:     req.Ingredients,
+		DietaryTags:     dietaryTags,
+		Allergens:       allergens,
+		Addons:          req.Addons,
+		Variants:        req.Variants,
+		ModifierGroups:  req.ModifierGroups,
+		Stock:           stock,
+		IsAvailable:     isAvailable,
+		PreparationTime: req.PreparationTime,
+		Image:           req.Image,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}, nil
+}
+
 func (s *restaurantService) GetRestaurantByID(ctx context.Context, id string) (*models.Restaurant, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid restaurant ID")
 	}
 
-	return s.repo.FindByID(ctx, objectID)
+	restaurant, err := s.repo.FindByID(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The detail screen still shows the whole menu inline; list endpoints
+	// don't, which is the point of keeping menus out of the document.
+	menu, err := s.menuRepo.FindAllByRestaurant(ctx, objectID, repositories.MenuItemFilter{})
+	if err != nil {
+		return nil, err
+	}
+	restaurant.Menu = menu
+	restaurant.DietaryFlags = menuDietaryFlags(menu)
+
+	return restaurant, nil
 }
 
 func (s *restaurantService) GetRestaurants(ctx context.Context, query models.RestaurantQuery) ([]models.Restaurant, int64, error) {
@@ -126,10 +300,17 @@ func (s *restaurantService) GetRestaurants(ctx context.Context, query models.Res
 		if radius == 0 {
 			radius = 10000 // Default 10km
 		}
+		radius, zone, err := deliverableRadius(ctx, s.zones, location, radius)
+		if err != nil {
+			return []models.Restaurant{}, 0, err
+		}
+		if radius == 0 {
+			return []models.Restaurant{}, 0, nil
+		}
 		log.Printf("📍 Search radius: %.0f meters", radius)
 
 		// Call repository and handle response
-		restaurants, total, err := s.repo.FindNearby(ctx, location, radius, pagination)
+		restaurants, total, err := s.repo.FindNearby(ctx, location, radius, zoneArea(zone), pagination)
 		if err != nil {
 			log.Printf("❌ Error in FindNearby: %v", err)
 			return []models.Restaurant{}, 0, err
@@ -141,6 +322,9 @@ func (s *restaurantService) GetRestaurants(ctx context.Context, query models.Res
 			return []models.Restaurant{}, 0, nil // Return empty slice, not nil
 		}
 
+		if err := s.attachDietaryFlags(ctx, restaurants); err != nil {
+			return []models.Restaurant{}, 0, err
+		}
 		return restaurants, total, nil
 	}
 
@@ -158,6 +342,9 @@ func (s *restaurantService) GetRestaurants(ctx context.Context, query models.Res
 		return []models.Restaurant{}, 0, nil // Return empty slice, not nil
 	}
 
+	if err := s.attachDietaryFlags(ctx, restaurants); err != nil {
+		return []models.Restaurant{}, 0, err
+	}
 	return restaurants, total, nil
 }
 
@@ -201,12 +388,40 @@ func (s *restaurantService) FindNearby(ctx context.Context, location models.GeoL
 		SortDir: -1,
 	}
 
-	restaurants, _, err := s.repo.FindNearby(ctx, location, radius, pagination)
-	return restaurants, err
+	if radius <= 0 {
+		radius = 10000 // Default 10km (gin doesn't apply the handler's default tag)
+	}
+	radius, zone, err := deliverableRadius(ctx, s.zones, location, radius)
+	if err != nil {
+		return nil, err
+	}
+	if radius == 0 {
+		return []models.Restaurant{}, nil
+	}
+
+	restaurants, _, err := s.repo.FindNearby(ctx, location, radius, zoneArea(zone), pagination)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachDietaryFlags(ctx, restaurants); err != nil {
+		return nil, err
+	}
+	return restaurants, nil
 }
 
 func (s *restaurantService) Search(ctx context.Context, query string, location *models.GeoLocation) ([]models.Restaurant, error) {
 	radius := 10000.0 // 10km default radius
+	var zone *models.ServiceZone
+	if location != nil {
+		var err error
+		radius, zone, err = deliverableRadius(ctx, s.zones, *location, radius)
+		if err != nil {
+			return nil, err
+		}
+		if radius == 0 {
+			return []models.Restaurant{}, nil
+		}
+	}
 	if location == nil {
 		// Search without location - use a default location
 		defaultLocation := models.GeoLocation{
@@ -216,19 +431,62 @@ func (s *restaurantService) Search(ctx context.Context, query string, location *
 		location = &defaultLocation
 	}
 
-	return s.repo.Search(ctx, query, *location, radius)
+	restaurants, err := s.repo.Search(ctx, query, *location, radius, zoneArea(zone))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachDietaryFlags(ctx, restaurants); err != nil {
+		return nil, err
+	}
+	return restaurants, nil
 }
 
-func (s *restaurantService) GetMenuItems(ctx context.Context, restaurantID string) ([]models.MenuItem, error) {
+func (s *restaurantService) GetMenuItems(ctx context.Context, restaurantID string, query models.MenuItemQuery) ([]models.MenuItem, int64, error) {
 	objectID, err := primitive.ObjectIDFromHex(restaurantID)
 	if err != nil {
-		return nil, errors.New("invalid restaurant ID")
+		return nil, 0, errors.New("invalid restaurant ID")
+	}
+
+	if _, err := s.repo.FindByID(ctx, objectID); err != nil {
+		return nil, 0, err
+	}
+
+	dietaryTags, allergens, err := parseDietaryFilter(query.Diet, query.ExcludeAllergens)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter := repositories.MenuItemFilter{
+		Category:         query.Category,
+		Available:        query.Available,
+		Search:           query.Q,
+		DietaryTags:      dietaryTags,
+		ExcludeAllergens: allergens,
+	}
+
+	if query.Page == 0 && query.Limit == 0 {
+		items, err := s.menuRepo.FindAllByRestaurant(ctx, objectID, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		return items, int64(len(items)), nil
+	}
+
+	pagination := repositories.Pagination{
+		Page:  int64(query.Page),
+		Limit: int64(query.Limit),
+	}
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+	if pagination.Limit < 1 || pagination.Limit > 100 {
+		pagination.Limit = 20
 	}
 
-	return s.repo.GetMenuItems(ctx, objectID)
+	return s.menuRepo.FindByRestaurant(ctx, objectID, filter, pagination)
 }
 
-func (s *restaurantService) UpdateRestaurant(ctx context.Context, id string, req models.UpdateRestaurantRequest) (*models.Restaurant, error) {
+func (s *restaurantService) UpdateRestaurant(ctx context.Context, id string, req models.UpdateRestaurantRequest, actorID primitive.ObjectID) (*models.Restaurant, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid restaurant ID")
@@ -274,66 +532,118 @@ func (s *restaurantService) UpdateRestaurant(ctx context.Context, id string, req
 		update["images"] = req.Images
 	}
 
-	// Handle Menu field if provided
+	if req.Timezone != "" {
+		if err := validateTimezone(req.Timezone); err != nil {
+			return nil, err
+		}
+		update["timezone"] = req.Timezone
+	}
+
+	if err := s.repo.Update(ctx, objectID, update); err != nil {
+		return nil, err
+	}
+
+	// A menu in the update replaces the whole menu, as it did when it was
+	// an embedded array. The old items are archived, not deleted, so past
+	// orders still point at something.
 	if req.Menu != nil {
-		var menuItems []models.MenuItem
-		for _, itemReq := range req.Menu {
-			// Default IsAvailable to true if not provided
-			isAvailable := true
-			if itemReq.IsAvailable != nil {
-				isAvailable = *itemReq.IsAvailable
+		categories, err := s.categoryRepo.FindByRestaurant(ctx, objectID)
+		if err != nil {
+			return nil, err
+		}
+		menuItems, err := menuItemsFromRequest(objectID, categories, req.Menu)
+		if err != nil {
+			return nil, err
+		}
+		existing, err := s.menuRepo.FindAllByRestaurant(ctx, objectID, repositories.MenuItemFilter{IncludeArchived: true})
+		if err != nil {
+			return nil, err
+		}
+		// An item whose SKU the restaurant already has (archived or not)
+		// takes over that item, as a menu import would, rather than
+		// colliding with it.
+		bySKU := map[string]*models.MenuItem{}
+		for i := range existing {
+			if existing[i].SKU != "" {
+				bySKU[existing[i].SKU] = &existing[i]
 			}
+		}
+		var created []models.MenuItem
+		replaced := map[primitive.ObjectID]bool{}
+		for i := range menuItems {
+			match := bySKU[menuItems[i].SKU]
+			if menuItems[i].SKU == "" || match == nil {
+				created = append(created, menuItems[i])
+				continue
+			}
+			menuItems[i].ID = match.ID
+			menuItems[i].CreatedAt = match.CreatedAt
+			replaced[match.ID] = true
+		}
 
-			menuItem := models.MenuItem{
-				ID:              primitive.NewObjectID(),
-				Name:            itemReq.Name,
-				Description:     itemReq.Description,
-				Price:           itemReq.Price,
-				Category:        itemReq.Category,
-				Ingredients:     itemReq.Ingredients,
-				Addons:          itemReq.Addons,
-				IsAvailable:     isAvailable,
-				PreparationTime: itemReq.PreparationTime,
-				Image:           itemReq.Image,
-				CreatedAt:       time.Now(),
-				UpdatedAt:       time.Now(),
+		archivedItems, err := s.menuRepo.ArchiveByRestaurant(ctx, objectID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.menuRepo.CreateMany(ctx, created); err != nil {
+			return nil, err
+		}
+		for i := range menuItems {
+			if replaced[menuItems[i].ID] {
+				if err := s.menuRepo.Replace(ctx, &menuItems[i]); err != nil {
+					return nil, err
+				}
 			}
-			menuItems = append(menuItems, menuItem)
 		}
-		update["menu"] = menuItems
-	}
 
-	if err := s.repo.Update(ctx, objectID, update); err != nil {
-		return nil, err
+		archivedAt := time.Now()
+		for i := range archivedItems {
+			if replaced[archivedItems[i].ID] {
+				continue
+			}
+			archived := archivedItems[i]
+			archived.ArchivedAt = &archivedAt
+			s.recordMenuChange(ctx, menuActionArchived, &archivedItems[i], &archived, actorID)
+		}
+		for i := range menuItems {
+			if match := bySKU[menuItems[i].SKU]; replaced[menuItems[i].ID] {
+				action := menuActionUpdated
+				if match.ArchivedAt != nil {
+					action = menuActionUnarchived
+				}
+				s.recordMenuChange(ctx, action, match, &menuItems[i], actorID)
+			} else {
+				s.recordMenuChange(ctx, menuActionCreated, nil, &menuItems[i], actorID)
+			}
+		}
 	}
 
-	return s.repo.FindByID(ctx, objectID)
+	return s.GetRestaurantByID(ctx, id)
 }
-func (s *restaurantService) AddMenuItem(ctx context.Context, restaurantID string, req models.CreateMenuItemRequest) (*models.MenuItem, error) {
+func (s *restaurantService) AddMenuItem(ctx context.Context, restaurantID string, req models.CreateMenuItemRequest, actorID primitive.ObjectID) (*models.MenuItem, error) {
 	objectID, err := primitive.ObjectIDFromHex(restaurantID)
 	if err != nil {
 		return nil, errors.New("invalid restaurant ID")
 	}
 
-	menuItem := &models.MenuItem{
-		ID:              primitive.NewObjectID(),
-		Name:            req.Name,
-		Description:     req.Description,
-		Price:           req.Price,
-		Category:        req.Category,
-		Ingredients:     req.Ingredients,
-		Addons:          req.Addons,
-		IsAvailable:     true,
-		PreparationTime: req.PreparationTime,
-		Image:           req.Image,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+	if _, err := s.repo.FindByID(ctx, objectID); err != nil {
+		return nil, err
 	}
 
-	if err := s.repo.AddMenuItem(ctx, objectID, menuItem); err != nil {
+	categories, err := s.categoryRepo.FindByRestaurant(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	menuItem, err := menuItemFromRequest(objectID, categories, req)
+	if err != nil {
 		return nil, err
 	}
 
+	if err := s.menuRepo.Create(ctx, menuItem); err != nil {
+		return nil, err
+	}
+
+	s.recordMenuChange(ctx, menuActionCreated, nil, menuItem, actorID)
 	return menuItem, nil
 }
 
@@ -351,7 +661,7 @@ func (s *restaurantService) DeleteRestaurant(ctx context.Context, id string) err
 	return s.repo.Update(ctx, objectID, update)
 }
 
-func (s *restaurantService) UpdateMenuItem(ctx context.Context, restaurantID, itemID string, req models.UpdateMenuItemRequest) (*models.MenuItem, error) {
+func (s *restaurantService) UpdateMenuItem(ctx context.Context, restaurantID, itemID string, req models.UpdateMenuItemRequest, actorID primitive.ObjectID) (*models.MenuItem, error) {
 	restaurantObjectID, err := primitive.ObjectIDFromHex(restaurantID)
 	if err != nil {
 		return nil, errors.New("invalid restaurant ID")
@@ -362,25 +672,80 @@ func (s *restaurantService) UpdateMenuItem(ctx context.Context, restaurantID, it
 		return nil, errors.New("invalid menu item ID")
 	}
 
+	current, err := s.menuRepo.FindByID(ctx, restaurantObjectID, itemObjectID)
+	if err != nil {
+		return nil, err
+	}
+
 	update := bson.M{}
 	if req.Name != "" {
 		update["name"] = req.Name
 	}
+	if req.SKU != "" {
+		update["sku"] = strings.TrimSpace(req.SKU)
+	}
 	if req.Description != "" {
 		update["description"] = req.Description
 	}
 	if req.Price > 0 {
 		update["price"] = req.Price
 	}
-	if req.Category != "" {
-		update["category"] = req.Category
+	if req.Category != "" || req.CategoryID != "" {
+		categories, err := s.categoryRepo.FindByRestaurant(ctx, restaurantObjectID)
+		if err != nil {
+			return nil, err
+		}
+		categoryName, categoryID, err := categoryForItem(categories, req.CategoryID, req.Category)
+		if err != nil {
+			return nil, err
+		}
+		update["category"] = categoryName
+		update["category_id"] = categoryID
 	}
 	if req.Ingredients != nil {
 		update["ingredients"] = req.Ingredients
 	}
+	if req.DietaryTags != nil || req.Allergens != nil {
+		// Checked together with whichever half isn't being changed
+		tags, allergens := current.DietaryTags, current.Allergens
+		if req.DietaryTags != nil {
+			tags = req.DietaryTags
+		}
+		if req.Allergens != nil {
+			allergens = req.Allergens
+		}
+		tags, allergens, err := normalizeDietary(tags, allergens)
+		if err != nil {
+			return nil, err
+		}
+		update["dietary_tags"] = tags
+		update["allergens"] = allergens
+	}
 	if req.Addons != nil {
 		update["addons"] = req.Addons
 	}
+	if req.Variants != nil || req.ModifierGroups != nil {
+		if err := prepareMenuOptions(req.Variants, req.ModifierGroups); err != nil {
+			return nil, err
+		}
+	}
+	if req.Variants != nil {
+		update["variants"] = req.Variants
+	}
+	if req.ModifierGroups != nil {
+		update["modifier_groups"] = req.ModifierGroups
+	}
+	if req.DailyStock != nil || req.LowStockThreshold != nil {
+		stock, err := applyStockRequest(current.Stock, req.DailyStock, req.LowStockThreshold)
+		if err != nil {
+			return nil, err
+		}
+		update["stock"] = stock
+		// Restocking an item the stock had switched off puts it back on
+		if current.Stock != nil && current.Stock.AutoDisabled && (stock == nil || stock.Remaining > 0) {
+			update["is_available"] = true
+		}
+	}
 	if req.IsAvailable != nil {
 		update["is_available"] = *req.IsAvailable
 	}
@@ -391,22 +756,14 @@ func (s *restaurantService) UpdateMenuItem(ctx context.Context, restaurantID, it
 		update["image"] = req.Image
 	}
 
-	if err := s.repo.UpdateMenuItem(ctx, restaurantObjectID, itemObjectID, update); err != nil {
+	if err := s.menuRepo.Update(ctx, restaurantObjectID, itemObjectID, update); err != nil {
 		return nil, err
 	}
 
-	// Fetch and return the updated menu item
-	// We need to get the restaurant and find the menu item
-	restaurant, err := s.repo.FindByID(ctx, restaurantObjectID)
+	updated, err := s.menuRepo.FindByID(ctx, restaurantObjectID, itemObjectID)
 	if err != nil {
 		return nil, err
 	}
-
-	for _, item := range restaurant.Menu {
-		if item.ID == itemObjectID {
-			return &item, nil
-		}
-	}
-
-	return nil, errors.New("menu item not found")
+	s.recordMenuChange(ctx, menuActionUpdated, current, updated, actorID)
+	return updated, nil
 }
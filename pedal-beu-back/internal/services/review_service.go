@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxReviewPhotos       = 5
+	maxReviewCommentRunes = 2000
+)
+
+// ErrReviewNotAllowed is returned when the caller isn't the one who may do
+// this to a review (reviewing someone else's order, replying for a
+// restaurant they don't own).
+var ErrReviewNotAllowed = errors.New("not allowed to do this to the review")
+
+// ReviewService handles restaurant reviews: customers review their own
+// delivered orders, anyone reads the published ones, the restaurant's
+// owner (or an admin) replies, users flag, and admins moderate.
+type ReviewService interface {
+	CreateReview(ctx context.Context, orderID, customerID primitive.ObjectID, req models.CreateReviewRequest) (*models.Review, error)
+	// GetRestaurantReviews returns one page of the restaurant's published
+	// reviews plus the rating summary over all of them.
+	GetRestaurantReviews(ctx context.Context, restaurantID string, query models.ReviewQuery) ([]models.Review, int64, *repositories.ReviewSummary, error)
+	// ReplyToReview sets the restaurant's reply; actorRole "admin" may
+	// reply for any restaurant, anyone else only for one they own.
+	ReplyToReview(ctx context.Context, restaurantID, reviewID string, actorID primitive.ObjectID, actorRole string, req models.ReviewReplyRequest) (*models.Review, error)
+	FlagReview(ctx context.Context, restaurantID, reviewID string, userID primitive.ObjectID, req models.FlagReviewRequest) error
+	// GetReviewsForModeration lists reviews for the admin queue, flagged
+	// ones oldest first by default.
+	GetReviewsForModeration(ctx context.Context, query models.AdminReviewQuery) ([]models.Review, int64, error)
+	ModerateReview(ctx context.Context, reviewID string, adminID primitive.ObjectID, req models.ModerateReviewRequest) (*models.Review, error)
+}
+
+type reviewService struct {
+	reviewRepo     repositories.ReviewRepository
+	orderRepo      repositories.OrderRepository
+	restaurantRepo repositories.RestaurantRepository
+	userRepo       repositories.UserRepository
+}
+
+func NewReviewService(
+	reviewRepo repositories.ReviewRepository,
+	orderRepo repositories.OrderRepository,
+	restaurantRepo repositories.RestaurantRepository,
+	userRepo repositories.UserRepository,
+) ReviewService {
+	return &reviewService{
+		reviewRepo:     reviewRepo,
+		orderRepo:      orderRepo,
+		restaurantRepo: restaurantRepo,
+		userRepo:       userRepo,
+	}
+}
+
+func (s *reviewService) CreateReview(ctx context.Context, orderID, customerID primitive.ObjectID, req models.CreateReviewRequest) (*models.Review, error) {
+	order, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.CustomerID != customerID {
+		return nil, ErrReviewNotAllowed
+	}
+	if order.Status != models.OrderDelivered {
+		return nil, errors.New("order must be delivered before it can be reviewed")
+	}
+
+	// A review of an order that was already rated starts from that rating,
+	// so the comment left there finally becomes readable.
+	if order.Rating != nil {
+		if req.Rating == 0 {
+			req.Rating = order.Rating.RestaurantRating
+		}
+		if strings.TrimSpace(req.Comment) == "" {
+			req.Comment = order.Rating.Comment
+		}
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		return nil, errors.New("rating must be between 1 and 5")
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if utf8.RuneCountInString(comment) > maxReviewCommentRunes {
+		return nil, errors.New("comment is too long")
+	}
+	photos, err := reviewPhotos(req.Photos)
+	if err != nil {
+		return nil, err
+	}
+
+	review := &models.Review{
+		RestaurantID: order.RestaurantID,
+		OrderID:      order.ID,
+		CustomerID:   customerID,
+		CustomerName: s.reviewerName(ctx, customerID),
+		Rating:       req.Rating,
+		Comment:      comment,
+		Photos:       photos,
+		Status:       models.ReviewPublished,
+	}
+	if err := s.reviewRepo.Create(ctx, review); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// reviewPhotos checks the photo URLs a review is posted with: at most
+// maxReviewPhotos, each an http(s) URL (what POST /upload hands back).
+func reviewPhotos(urls []string) ([]string, error) {
+	var photos []string
+	for _, url := range urls {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+			return nil, errors.New("photos must be uploaded first (POST /upload with type=reviews)")
+		}
+		photos = append(photos, url)
+	}
+	if len(photos) > maxReviewPhotos {
+		return nil, errors.New("a review can have at most 5 photos")
+	}
+	return photos, nil
+}
+
+// reviewerName is how a customer is shown on their reviews: first name and
+// last initial, e.g. "Abebe K.".
+func (s *reviewService) reviewerName(ctx context.Context, customerID primitive.ObjectID) string {
+	user, err := s.userRepo.FindByID(ctx, customerID)
+	if err != nil || strings.TrimSpace(user.Profile.FirstName) == "" {
+		return "Customer"
+	}
+	name := strings.TrimSpace(user.Profile.FirstName)
+	if last := strings.TrimSpace(user.Profile.LastName); last != "" {
+		initial, _ := utf8.DecodeRuneInString(last)
+		name += " " + string(initial) + "."
+	}
+	return name
+}
+
+func (s *reviewService) GetRestaurantReviews(ctx context.Context, restaurantID string, query models.ReviewQuery) ([]models.Review, int64, *repositories.ReviewSummary, error) {
+	objectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return nil, 0, nil, errors.New("invalid restaurant ID")
+	}
+	if _, err := s.restaurantRepo.FindByID(ctx, objectID); err != nil {
+		return nil, 0, nil, err
+	}
+	if query.Rating < 0 || query.Rating > 5 {
+		return nil, 0, nil, errors.New("rating must be between 1 and 5")
+	}
+
+	reviews, total, err := s.reviewRepo.Find(ctx, repositories.ReviewFilter{
+		RestaurantID: &objectID,
+		Status:       models.ReviewPublished,
+		Rating:       query.Rating,
+		WithPhotos:   query.WithPhotos,
+		Sort:         query.Sort,
+	}, listPagination(query.Page, query.Limit))
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	for i := range reviews {
+		reviews[i].Flags = nil
+	}
+
+	summary, err := s.reviewRepo.Summary(ctx, objectID)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return reviews, total, summary, nil
+}
+
+func listPagination(page, limit int64) repositories.Pagination {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return repositories.Pagination{Page: page, Limit: limit}
+}
+
+// restaurantReview loads a review and checks it belongs to restaurantID,
+// so the nested routes can't reach another restaurant's reviews.
+func (s *reviewService) restaurantReview(ctx context.Context, restaurantID, reviewID string) (*models.Review, error) {
+	restaurantObjectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return nil, errors.New("invalid restaurant ID")
+	}
+	reviewObjectID, err := primitive.ObjectIDFromHex(reviewID)
+	if err != nil {
+		return nil, errors.New("invalid review ID")
+	}
+
+	review, err := s.reviewRepo.FindByID(ctx, reviewObjectID)
+	if err != nil {
+		return nil, err
+	}
+	if review.RestaurantID != restaurantObjectID {
+		return nil, errors.New("review not found")
+	}
+	return review, nil
+}
+
+func (s *reviewService) ReplyToReview(ctx context.Context, restaurantID, reviewID string, actorID primitive.ObjectID, actorRole string, req models.ReviewReplyRequest) (*models.Review, error) {
+	review, err := s.restaurantReview(ctx, restaurantID, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	role := "admin"
+	if actorRole != "admin" {
+		restaurant, err := s.restaurantRepo.FindByID(ctx, review.RestaurantID)
+		if err != nil {
+			return nil, err
+		}
+		if restaurant.OwnerID != actorID {
+			return nil, ErrReviewNotAllowed
+		}
+		role = "owner"
+	}
+
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, errors.New("reply text is required")
+	}
+	if utf8.RuneCountInString(text) > maxReviewCommentRunes {
+		return nil, errors.New("reply is too long")
+	}
+
+	if err := s.reviewRepo.SetReply(ctx, review.ID, models.ReviewReply{
+		Text:      text,
+		RepliedBy: actorID,
+		Role:      role,
+	}); err != nil {
+		return nil, err
+	}
+	return s.reviewRepo.FindByID(ctx, review.ID)
+}
+
+func (s *reviewService) FlagReview(ctx context.Context, restaurantID, reviewID string, userID primitive.ObjectID, req models.FlagReviewRequest) error {
+	review, err := s.restaurantReview(ctx, restaurantID, reviewID)
+	if err != nil {
+		return err
+	}
+	if review.Status != models.ReviewPublished {
+		return errors.New("review not found")
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return errors.New("reason is required")
+	}
+	added, err := s.reviewRepo.AddFlag(ctx, review.ID, models.ReviewFlag{UserID: userID, Reason: reason})
+	if err != nil {
+		return err
+	}
+	if !added {
+		return errors.New("you've already flagged this review")
+	}
+	return nil
+}
+
+func (s *reviewService) GetReviewsForModeration(ctx context.Context, query models.AdminReviewQuery) ([]models.Review, int64, error) {
+	filter := repositories.ReviewFilter{Sort: "oldest"}
+	switch query.Status {
+	case "", "flagged":
+		filter.FlaggedOnly = true
+		filter.Status = models.ReviewPublished
+	case models.ReviewHidden, models.ReviewPublished:
+		filter.Status = query.Status
+		filter.Sort = "newest"
+	case "all":
+		filter.Sort = "newest"
+	default:
+		return nil, 0, errors.New("status must be flagged, hidden, published or all")
+	}
+	if query.RestaurantID != "" {
+		restaurantID, err := primitive.ObjectIDFromHex(query.RestaurantID)
+		if err != nil {
+			return nil, 0, errors.New("invalid restaurant ID")
+		}
+		filter.RestaurantID = &restaurantID
+	}
+
+	return s.reviewRepo.Find(ctx, filter, listPagination(query.Page, query.Limit))
+}
+
+func (s *reviewService) ModerateReview(ctx context.Context, reviewID string, adminID primitive.ObjectID, req models.ModerateReviewRequest) (*models.Review, error) {
+	objectID, err := primitive.ObjectIDFromHex(reviewID)
+	if err != nil {
+		return nil, errors.New("invalid review ID")
+	}
+
+	status := ""
+	switch req.Action {
+	case "hide":
+		status = models.ReviewHidden
+	case "unhide":
+		status = models.ReviewPublished
+	case "dismiss_flags":
+	default:
+		return nil, errors.New("action must be hide, unhide or dismiss_flags")
+	}
+
+	if err := s.reviewRepo.Moderate(ctx, objectID, status, models.ReviewModeration{
+		Action:      req.Action,
+		Reason:      strings.TrimSpace(req.Reason),
+		ModeratedBy: adminID,
+	}); err != nil {
+		return nil, err
+	}
+	return s.reviewRepo.FindByID(ctx, objectID)
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SearchIndex is the text-matching half of search: given the catalogue it
+// builds whatever structure it likes, then scores restaurants against a
+// query. Ranking by distance/rating/open status and faceting happen in
+// SearchService on top of it, so an index only has to answer "how well
+// does this restaurant match the words". The in-process memoryIndex is the
+// default; a MongoDB $text or external-engine index can be swapped in via
+// NewSearchService without touching callers.
+type SearchIndex interface {
+	Build(restaurants []models.Restaurant)
+	Query(text string) []TextMatch
+}
+
+// TextMatch is one restaurant that matched a query, with the dishes that
+// contributed to the match.
+type TextMatch struct {
+	Restaurant    *models.Restaurant
+	Score         float64
+	MatchedDishes []DishMatch
+}
+
+type DishMatch struct {
+	ID          primitive.ObjectID `json:"id"`
+	Name        string             `json:"name"`
+	Price       float64            `json:"price"`
+	Field       string             `json:"matched_field"` // "name", "description", "ingredients", "category" or "dietary_tags"
+	DietaryTags []string           `json:"dietary_tags,omitempty"`
+	Allergens   []string           `json:"allergens,omitempty"`
+}
+
+// Field weights: a hit on the restaurant's own name beats a hit on a dish
+// name, which beats a passing mention in a description.
+const (
+	weightRestaurantName = 3.0
+	weightCuisine        = 2.0
+	weightDishName       = 2.0
+	weightDishCategory   = 1.0
+	weightDishDetail     = 1.0
+
+	// Non-exact matches are worth less than exact ones, so "kitfo" ranks a
+	// restaurant that actually says kitfo above one that says "kitfa".
+	prefixMatchFactor = 0.8
+	fuzzyMatchFactor  = 0.7
+)
+
+type posting struct {
+	restaurant int
+	dish       int // -1 for restaurant-level fields
+	field      string
+	weight     float64
+}
+
+type memoryIndex struct {
+	mu          sync.RWMutex
+	restaurants []models.Restaurant
+	postings    map[string][]posting
+}
+
+func NewMemorySearchIndex() SearchIndex {
+	return &memoryIndex{postings: make(map[string][]posting)}
+}
+
+func (idx *memoryIndex) Build(restaurants []models.Restaurant) {
+	type postingKey struct {
+		term string
+		posting
+	}
+	postings := make(map[string][]posting)
+	seen := make(map[postingKey]bool)
+	add := func(text string, p posting) {
+		for _, term := range analyze(text) {
+			key := postingKey{term: term, posting: p}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			postings[term] = append(postings[term], p)
+		}
+	}
+
+	for i, restaurant := range restaurants {
+		add(restaurant.Name, posting{restaurant: i, dish: -1, field: "restaurant_name", weight: weightRestaurantName})
+		for _, cuisine := range restaurant.CuisineType {
+			add(cuisine, posting{restaurant: i, dish: -1, field: "cuisine", weight: weightCuisine})
+		}
+		for j, item := range restaurant.Menu {
+			if !item.IsAvailable {
+				continue
+			}
+			add(item.Name, posting{restaurant: i, dish: j, field: "name", weight: weightDishName})
+			add(item.Category, posting{restaurant: i, dish: j, field: "category", weight: weightDishCategory})
+			add(item.Description, posting{restaurant: i, dish: j, field: "description", weight: weightDishDetail})
+			for _, ingredient := range item.Ingredients {
+				add(ingredient, posting{restaurant: i, dish: j, field: "ingredients", weight: weightDishDetail})
+			}
+			// So "tsom" or "vegan" finds tagged dishes whatever they're called
+			for _, tag := range item.DietaryTags {
+				add(tag, posting{restaurant: i, dish: j, field: "dietary_tags", weight: weightDishCategory})
+			}
+		}
+	}
+
+	idx.mu.Lock()
+	idx.restaurants = restaurants
+	idx.postings = postings
+	idx.mu.Unlock()
+}
+
+// Query scores every restaurant that matches all of the query's terms
+// (after synonym folding, and allowing prefix and typo matches). If nothing
+// matches every term, it falls back to restaurants matching any of them,
+// so a long query with one unknown word still returns something useful.
+func (idx *memoryIndex) Query(text string) []TextMatch {
+	terms := analyze(text)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	type hit struct {
+		termScores []float64
+		dishes     map[int]string // dish index -> best field
+		dishScores map[int]float64
+	}
+	hits := make(map[int]*hit)
+
+	for t, term := range terms {
+		for candidate, factor := range idx.expand(term) {
+			for _, p := range idx.postings[candidate] {
+				h := hits[p.restaurant]
+				if h == nil {
+					h = &hit{
+						termScores: make([]float64, len(terms)),
+						dishes:     make(map[int]string),
+						dishScores: make(map[int]float64),
+					}
+					hits[p.restaurant] = h
+				}
+				// A term counts once per restaurant (its best hit), so a
+				// word that appears in twenty dish descriptions doesn't
+				// outrank a restaurant actually named after it.
+				score := p.weight * factor
+				if score > h.termScores[t] {
+					h.termScores[t] = score
+				}
+				if p.dish >= 0 && score > h.dishScores[p.dish] {
+					h.dishScores[p.dish] = score
+					h.dishes[p.dish] = p.field
+				}
+			}
+		}
+	}
+
+	collect := func(requireAll bool) []TextMatch {
+		var matches []TextMatch
+		for i, h := range hits {
+			total, matched := 0.0, 0
+			for _, score := range h.termScores {
+				if score > 0 {
+					matched++
+					total += score
+				}
+			}
+			if requireAll && matched < len(terms) {
+				continue
+			}
+
+			restaurant := &idx.restaurants[i]
+			match := TextMatch{Restaurant: restaurant, Score: total}
+			for j, field := range h.dishes {
+				item := restaurant.Menu[j]
+				match.MatchedDishes = append(match.MatchedDishes, DishMatch{
+					ID:          item.ID,
+					Name:        item.Name,
+					Price:       item.Price,
+					Field:       field,
+					DietaryTags: item.DietaryTags,
+					Allergens:   item.Allergens,
+				})
+			}
+			matches = append(matches, match)
+		}
+		return matches
+	}
+
+	if matches := collect(true); len(matches) > 0 {
+		return matches
+	}
+	return collect(false)
+}
+
+// expand returns the indexed terms a query term should match, with the
+// factor each match is worth: the term itself, terms it's a prefix of (for
+// search-as-you-type), and terms within a small edit distance (typos).
+// Caller holds idx.mu.
+func (idx *memoryIndex) expand(term string) map[string]float64 {
+	candidates := make(map[string]float64)
+	if _, ok := idx.postings[term]; ok {
+		candidates[term] = 1
+	}
+
+	termLen := len([]rune(term))
+	maxEdits := 0
+	switch {
+	case termLen >= 8:
+		maxEdits = 2
+	case termLen >= 4:
+		maxEdits = 1
+	}
+
+	for indexed := range idx.postings {
+		if indexed == term {
+			continue
+		}
+		if termLen >= 3 && strings.HasPrefix(indexed, term) {
+			candidates[indexed] = prefixMatchFactor
+			continue
+		}
+		if maxEdits > 0 {
+			indexedLen := len([]rune(indexed))
+			if indexedLen-termLen > maxEdits || termLen-indexedLen > maxEdits {
+				continue
+			}
+			if levenshtein(term, indexed) <= maxEdits {
+				candidates[indexed] = fuzzyMatchFactor
+			}
+		}
+	}
+
+	// The index only holds canonical terms, so a misspelled synonym
+	// ("chiken") has to be matched against the aliases to reach "doro".
+	if maxEdits > 0 {
+		for alias, canonical := range searchSynonyms {
+			if _, ok := candidates[canonical]; ok {
+				continue
+			}
+			if _, indexed := idx.postings[canonical]; !indexed {
+				continue
+			}
+			aliasLen := len([]rune(alias))
+			if aliasLen-termLen > maxEdits || termLen-aliasLen > maxEdits {
+				continue
+			}
+			if levenshtein(term, alias) <= maxEdits {
+				candidates[canonical] = fuzzyMatchFactor
+			}
+		}
+	}
+	return candidates
+}
+
+// analyze lowercases and splits text into words (any script — Ge'ez
+// letters count as letters), then folds each word through searchSynonyms
+// so Amharic, its common Latin transliterations and the English word all
+// index and query as the same term.
+func analyze(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.Is(unicode.Mn, r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if canonical, ok := searchSynonyms[word]; ok {
+			word = canonical
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// searchSynonyms maps every known spelling of a dish or ingredient to one
+// canonical term. Menus here are written in Amharic, English, or whichever
+// transliteration the owner prefers ("tibs"/"tibbs", "wot"/"wat"/"wet"),
+// and customers search the same way — without this, "ዶሮ ወጥ" and
+// "doro wat" and "chicken stew" would be three different searches.
+var searchSynonyms = func() map[string]string {
+	groups := [][]string{
+		{"doro", "ዶሮ", "chicken"},
+		{"wot", "wat", "wet", "wott", "ወጥ", "stew"},
+		{"tibs", "tibbs", "tibes", "ጥብስ"},
+		{"kitfo", "kitpho", "ክትፎ"},
+		{"shiro", "shero", "ሽሮ"},
+		{"injera", "enjera", "እንጀራ"},
+		{"firfir", "fitfit", "ፍርፍር", "ፍትፍት"},
+		{"beyaynetu", "beyainatu", "beyaynet", "በያይነቱ"},
+		{"gomen", "ጎመን", "collard", "collards"},
+		{"misir", "mesir", "ምስር", "lentil", "lentils"},
+		{"kik", "kek", "ክክ"},
+		{"siga", "sega", "ስጋ", "ሥጋ", "meat"},
+		{"bere", "በሬ", "beef"},
+		{"beg", "በግ", "lamb", "mutton"},
+		{"asa", "ዓሳ", "አሳ", "fish"},
+		{"enkulal", "እንቁላል", "egg", "eggs"},
+		{"atkilt", "አትክልት", "vegetable", "vegetables", "veggie"},
+		// Vegan food is fasting food but not the other way round (fasting
+		// dishes can have fish), so they aren't synonyms.
+		{"tsom", "ፆም", "ጾም", "fasting"},
+		{"dabo", "ዳቦ", "bread"},
+		{"buna", "ቡና", "coffee"},
+		{"shai", "shay", "ሻይ", "tea"},
+		{"chechebsa", "chichibsa", "ጨጨብሳ"},
+		{"genfo", "ገንፎ", "porridge"},
+		{"kurt", "ቁርጥ"},
+		{"ayib", "ayibe", "አይብ", "cheese"},
+		{"berbere", "በርበሬ"},
+		{"mitmita", "ሚጥሚጣ"},
+		{"juice", "ጭማቂ", "jus"},
+		{"pizza", "ፒዛ", "piza"},
+		{"burger", "በርገር", "hamburger"},
+		{"pasta", "ፓስታ"},
+		{"sandwich", "ሳንድዊች", "sandwitch"},
+		{"chips", "ችፕስ", "fries"},
+	}
+
+	synonyms := make(map[string]string)
+	for _, group := range groups {
+		for _, word := range group {
+			synonyms[word] = group[0]
+		}
+	}
+	return synonyms
+}()
+
+// levenshtein is the edit distance between a and b, by rune.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"kitfo", "kitfo", 0},
+		{"kitfo", "kitfa", 1},
+		{"tibs", "tibbs", 1},
+		{"wot", "wat", 1},
+		{"kitten", "sitting", 3},
+		{"", "shiro", 5},
+		// By rune, so a Ge'ez letter is one edit, not three bytes.
+		{"ወጥ", "ወጥ", 0},
+		{"ሽሮ", "ሸሮ", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// searchTestIndex holds three restaurants: one for kitfo, one for doro
+// wot and one serving both.
+func searchTestIndex() *memoryIndex {
+	dish := func(name string) models.MenuItem {
+		return models.MenuItem{Name: name, IsAvailable: true}
+	}
+	idx := NewMemorySearchIndex().(*memoryIndex)
+	idx.Build([]models.Restaurant{
+		{Name: "Kitfo House", Menu: []models.MenuItem{dish("Special Kitfo")}},
+		{Name: "Doro Palace", Menu: []models.MenuItem{dish("Doro Wot")}},
+		{Name: "Habesha Kitchen", Menu: []models.MenuItem{dish("Kitfo"), dish("Doro Wat")}},
+	})
+	return idx
+}
+
+func TestMemoryIndexExpand(t *testing.T) {
+	idx := searchTestIndex()
+	tests := []struct {
+		name string
+		term string
+		want map[string]float64
+	}{
+		{"exact", "kitfo", map[string]float64{"kitfo": 1}},
+		{"prefix", "kit", map[string]float64{"kitfo": prefixMatchFactor, "kitchen": prefixMatchFactor}},
+		{"too short for a prefix", "ki", map[string]float64{}},
+		{"typo", "kitfa", map[string]float64{"kitfo": fuzzyMatchFactor}},
+		{"misspelled synonym", "chiken", map[string]float64{"doro": fuzzyMatchFactor}},
+		{"unknown", "pizza", map[string]float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idx.expand(tt.term); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expand(%q) = %v, want %v", tt.term, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexQuery(t *testing.T) {
+	idx := searchTestIndex()
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"every term matches", "kitfo doro", []string{"Habesha Kitchen"}},
+		{"synonyms fold together", "chicken stew", []string{"Doro Palace", "Habesha Kitchen"}},
+		{"falls back to any term", "kitfo pizza", []string{"Habesha Kitchen", "Kitfo House"}},
+		{"nothing matches", "pizza", nil},
+		{"no words", "  !! ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, match := range idx.Query(tt.query) {
+				got = append(got, match.Restaurant.Name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Query(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
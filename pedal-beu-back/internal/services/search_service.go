@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// searchRadius is how far from the customer search looks by default, same
+// 10km as the older /restaurants/search. Service zones can narrow it.
+const searchRadius = 10000.0
+
+// Price bands by a restaurant's average available-dish price (ETB).
+const (
+	priceBandBudgetMax = 150.0
+	priceBandMidMax    = 400.0
+)
+
+type SearchResult struct {
+	// Restaurant is returned without its menu; MatchedDishes carries the
+	// part of the menu that's relevant to the query.
+	Restaurant    models.Restaurant `json:"restaurant"`
+	Score         float64           `json:"score"`
+	DistanceKm    *float64          `json:"distance_km,omitempty"`
+	IsOpen        bool              `json:"is_open"`
+	PriceBand     string            `json:"price_band,omitempty"`
+	MatchedDishes []DishMatch       `json:"matched_dishes"`
+}
+
+// SearchFacets count the results by cuisine and price band before the
+// cuisine/price_band filters are applied, so the UI can show how many
+// results each filter chip would leave.
+type SearchFacets struct {
+	Cuisines   map[string]int `json:"cuisines"`
+	PriceBands map[string]int `json:"price_bands"`
+}
+
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+	Facets  SearchFacets   `json:"facets"`
+	Total   int            `json:"total"`
+}
+
+// SearchService answers free-text restaurant and dish searches. Matching
+// is delegated to a SearchIndex, rebuilt lazily from the restaurants and
+// menu_items collections once it's older than SEARCH_INDEX_TTL_SECONDS; ranking by
+// distance, rating and open status plus faceting happen here.
+type SearchService interface {
+	Search(ctx context.Context, query models.SearchQuery) (*SearchResponse, error)
+}
+
+type searchService struct {
+	restaurantRepo repositories.RestaurantRepository
+	menuRepo       repositories.MenuItemRepository
+	zones          ZoneService
+	index          SearchIndex
+
+	mu      sync.Mutex
+	builtAt time.Time
+}
+
+func NewSearchService(restaurantRepo repositories.RestaurantRepository, menuRepo repositories.MenuItemRepository, zones ZoneService, index SearchIndex) SearchService {
+	return &searchService{
+		restaurantRepo: restaurantRepo,
+		menuRepo:       menuRepo,
+		zones:          zones,
+		index:          index,
+	}
+}
+
+// ensureIndex rebuilds the index if it's stale. Requests arriving during a
+// rebuild wait for it rather than each starting their own.
+func (s *searchService) ensureIndex(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.builtAt.IsZero() && time.Since(s.builtAt) < searchIndexTTL() {
+		return nil
+	}
+
+	restaurants, err := s.loadCatalogue(ctx)
+	if err != nil {
+		// Serve from the old index if there is one; a failed refresh
+		// shouldn't take search down.
+		if !s.builtAt.IsZero() {
+			log.Printf("⚠️  Search index refresh failed, serving stale index: %v", err)
+			return nil
+		}
+		return err
+	}
+
+	s.index.Build(restaurants)
+	s.builtAt = time.Now()
+	log.Printf("🔍 Search index rebuilt with %d restaurants", len(restaurants))
+	return nil
+}
+
+// loadCatalogue returns the visible restaurants with their menus attached,
+// which is what the index is built from.
+func (s *searchService) loadCatalogue(ctx context.Context) ([]models.Restaurant, error) {
+	restaurants, err := s.restaurantRepo.FindAllVisible(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(restaurants))
+	for i, restaurant := range restaurants {
+		ids[i] = restaurant.ID
+	}
+	menus, err := s.menuRepo.FindByRestaurants(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range restaurants {
+		restaurants[i].Menu = menus[restaurants[i].ID]
+	}
+	return restaurants, nil
+}
+
+func (s *searchService) Search(ctx context.Context, query models.SearchQuery) (*SearchResponse, error) {
+	if err := s.ensureIndex(ctx); err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+	dietaryTags, allergens, err := parseDietaryFilter(query.Diet, query.ExcludeAllergens)
+	if err != nil {
+		return nil, err
+	}
+	dietFiltered := len(dietaryTags) > 0 || len(allergens) > 0
+
+	var location *models.GeoLocation
+	var zone *models.ServiceZone
+	radiusKm := 0.0
+	if query.Latitude != 0 && query.Longitude != 0 {
+		location = &models.GeoLocation{
+			Type:        "Point",
+			Coordinates: []float64{query.Longitude, query.Latitude},
+		}
+		radius, covering, err := deliverableRadius(ctx, s.zones, *location, searchRadius)
+		if err != nil {
+			return nil, err
+		}
+		if radius == 0 {
+			return &SearchResponse{Results: []SearchResult{}, Facets: emptySearchFacets()}, nil
+		}
+		radiusKm = radius / 1000
+		zone = covering
+	}
+
+	now := time.Now()
+	facets := emptySearchFacets()
+	results := []SearchResult{}
+
+	for _, match := range s.index.Query(query.Q) {
+		restaurant := *match.Restaurant
+		if dietFiltered && !menuFitsDietaryFilter(restaurant.Menu, dietaryTags, allergens) {
+			continue
+		}
+
+		var distanceKm *float64
+		if location != nil {
+			// Without coordinates there's no telling whether it delivers
+			// to the caller.
+			if len(restaurant.Location.Coordinates) != 2 {
+				continue
+			}
+			km := calculateDistance(query.Latitude, query.Longitude, restaurant.Location.Coordinates[1], restaurant.Location.Coordinates[0])
+			if km > radiusKm || (zone != nil && !zoneContains(zone, restaurant.Location)) {
+				continue
+			}
+			rounded := math.Round(km*100) / 100
+			distanceKm = &rounded
+		}
+
+		isOpen := openingHoursAllowIn(restaurant.OpeningHours, now, restaurantTimezone(&restaurant))
+		band := priceBand(restaurant.Menu)
+
+		for _, cuisine := range restaurant.CuisineType {
+			facets.Cuisines[cuisine]++
+		}
+		if band != "" {
+			facets.PriceBands[band]++
+		}
+
+		if query.Cuisine != "" && !containsFold(restaurant.CuisineType, query.Cuisine) {
+			continue
+		}
+		if query.PriceBand != "" && band != query.PriceBand {
+			continue
+		}
+
+		dishes := match.MatchedDishes
+		if dietFiltered {
+			dishes = []DishMatch{}
+			for _, dish := range match.MatchedDishes {
+				item := models.MenuItem{DietaryTags: dish.DietaryTags, Allergens: dish.Allergens}
+				if fitsDietaryFilter(&item, dietaryTags, allergens) {
+					dishes = append(dishes, dish)
+				}
+			}
+		}
+		sort.Slice(dishes, func(i, j int) bool {
+			return dishes[i].Name < dishes[j].Name
+		})
+		restaurant.DietaryFlags = menuDietaryFlags(restaurant.Menu)
+		restaurant.Menu = nil
+
+		results = append(results, SearchResult{
+			Restaurant:    restaurant,
+			Score:         rankScore(match.Score, restaurant.Rating, isOpen, distanceKm),
+			DistanceKm:    distanceKm,
+			IsOpen:        isOpen,
+			PriceBand:     band,
+			MatchedDishes: dishes,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	total := len(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return &SearchResponse{Results: results, Facets: facets, Total: total}, nil
+}
+
+// rankScore combines the text relevance with how useful the restaurant is
+// right now: better rated is better, open beats closed, and closer beats
+// further (halving by ~3km). Text relevance still dominates — a nearby
+// restaurant that barely matches shouldn't bury the one named after the
+// dish.
+func rankScore(textScore, rating float64, isOpen bool, distanceKm *float64) float64 {
+	score := textScore * (1 + 0.1*math.Min(math.Max(rating, 0), 5))
+	if !isOpen {
+		score *= 0.5
+	}
+	if distanceKm != nil {
+		score /= 1 + *distanceKm/3
+	}
+	return math.Round(score*1000) / 1000
+}
+
+// priceBand buckets a restaurant by the average price of its available
+// dishes: "$" (under 150 ETB), "$$" (under 400) or "$$$". Empty when there
+// are no available dishes to judge by.
+func priceBand(menu []models.MenuItem) string {
+	var sum float64
+	var count int
+	for _, item := range menu {
+		if item.IsAvailable && item.Price > 0 {
+			sum += item.Price
+			count++
+		}
+	}
+	if count == 0 {
+		return ""
+	}
+
+	average := sum / float64(count)
+	switch {
+	case average < priceBandBudgetMax:
+		return "$"
+	case average < priceBandMidMax:
+		return "$$"
+	default:
+		return "$$$"
+	}
+}
+
+func emptySearchFacets() SearchFacets {
+	return SearchFacets{Cuisines: map[string]int{}, PriceBands: map[string]int{}}
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func searchIndexTTL() time.Duration {
+	return time.Duration(envFloat("SEARCH_INDEX_TTL_SECONDS", 300)) * time.Second
+}
@@ -0,0 +1,642 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+	"github.com/haile-paa/pedal-delivery/pkg/pdf"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StatementService works out what the platform owes each restaurant:
+// per-restaurant commission rates, and periodic statements of delivered
+// orders with their food sales, commission, refunds and adjustments.
+// Refunds are deducted on the first statement generated after they're
+// processed: on the order's own line if it's billed on that statement, or
+// as an adjustment if an earlier statement already billed it.
+type StatementService interface {
+	// GetCommission returns the rate the restaurant is charged and whether
+	// it's the platform default rather than one set for it.
+	GetCommission(ctx context.Context, restaurantID string) (*models.Commission, bool, error)
+	SetCommission(ctx context.Context, restaurantID string, commission models.Commission) (*models.Commission, error)
+	// GenerateStatements bills every unbilled order delivered in the
+	// period, and deducts refunds processed by its end, one statement per
+	// restaurant. Restaurants with neither get no statement.
+	GenerateStatements(ctx context.Context, adminID primitive.ObjectID, req models.GenerateStatementsRequest) ([]models.RestaurantStatement, error)
+	// GetStatements lists statements newest first, without their order
+	// lines; restaurantID and status are optional filters.
+	GetStatements(ctx context.Context, restaurantID, status string, page, limit int64) ([]models.RestaurantStatement, int64, error)
+	GetStatement(ctx context.Context, statementID string) (*models.RestaurantStatement, error)
+	AddAdjustment(ctx context.Context, statementID string, adminID primitive.ObjectID, req models.StatementAdjustmentRequest) (*models.RestaurantStatement, error)
+	MarkPaid(ctx context.Context, statementID string, adminID primitive.ObjectID, req models.MarkStatementPaidRequest) (*models.RestaurantStatement, error)
+}
+
+type statementService struct {
+	statementRepo  repositories.StatementRepository
+	orderRepo      repositories.OrderRepository
+	restaurantRepo repositories.RestaurantRepository
+	refundRepo     repositories.RefundRepository
+}
+
+func NewStatementService(
+	statementRepo repositories.StatementRepository,
+	orderRepo repositories.OrderRepository,
+	restaurantRepo repositories.RestaurantRepository,
+	refundRepo repositories.RefundRepository,
+) StatementService {
+	return &statementService{
+		statementRepo:  statementRepo,
+		orderRepo:      orderRepo,
+		restaurantRepo: restaurantRepo,
+		refundRepo:     refundRepo,
+	}
+}
+
+// defaultCommission is charged to restaurants without a rate of their own:
+// RESTAURANT_COMMISSION_PERCENT (default 15) of the food subtotal plus
+// RESTAURANT_COMMISSION_FIXED (default 0) per order. A restaurant charged
+// no percentage needs a commission of its own; 0 here means the default.
+func defaultCommission() models.Commission {
+	return models.Commission{
+		Percent:       envFloat("RESTAURANT_COMMISSION_PERCENT", 15),
+		FixedPerOrder: envFloat("RESTAURANT_COMMISSION_FIXED", 0),
+	}
+}
+
+// restaurantCommission is the rate the restaurant is charged; a nil
+// restaurant (one since deleted) gets the default.
+func restaurantCommission(restaurant *models.Restaurant) models.Commission {
+	if restaurant != nil && restaurant.Commission != nil {
+		return *restaurant.Commission
+	}
+	return defaultCommission()
+}
+
+// orderRefunded is how much of an order's food sales has gone back to the
+// customer in processed refunds. Orders refunded before refunds were
+// tracked count as refunded in full.
+func orderRefunded(order *models.Order) float64 {
+	if order.Refunds != nil {
+		return order.Refunds.Food
+	}
+	if order.PaymentStatus == "refunded" {
+		return order.TotalAmount.Subtotal
+	}
+	return 0
+}
+
+// orderCommission is the platform's cut of a delivered order. It's charged
+// on the food sales that weren't refunded; delivery fees and tips are the
+// driver's and don't count.
+func orderCommission(commission models.Commission, order *models.Order) float64 {
+	return commissionOn(commission, order.TotalAmount.Subtotal-orderRefunded(order))
+}
+
+// commissionOn is the commission on one order's food sales; it never
+// exceeds them.
+func commissionOn(commission models.Commission, sales float64) float64 {
+	if sales <= 0 {
+		return 0
+	}
+	return roundMoney(math.Min(sales, sales*commission.Percent/100+commission.FixedPerOrder))
+}
+
+// refundedCommission is the commission given back on a refund of an
+// order billed earlier: the commission on the food sales left before the
+// refund less that on the sales left after it. Worked out per order, so a
+// fixed fee and the cap are only applied once.
+func (s *statementService) refundedCommission(ctx context.Context, commission models.Commission, refund *models.Refund) (float64, error) {
+	order, err := s.orderRepo.FindByID(ctx, refund.OrderID)
+	if err != nil {
+		return 0, err
+	}
+	others, err := s.refundRepo.FindByOrder(ctx, refund.OrderID)
+	if err != nil {
+		return 0, err
+	}
+	sales := order.TotalAmount.Subtotal
+	for _, other := range others {
+		if other.ID != refund.ID && other.Status == models.RefundProcessed &&
+			other.ProcessedAt != nil && refund.ProcessedAt != nil && other.ProcessedAt.Before(*refund.ProcessedAt) {
+			sales -= other.Food
+		}
+	}
+	return commissionOn(commission, sales) - commissionOn(commission, sales-refund.Food), nil
+}
+
+// parsePeriod reads a pair of inclusive "2006-01-02" days as [from, to).
+func parsePeriod(start, end string) (time.Time, time.Time, error) {
+	from, err := parseAggregateDay(strings.TrimSpace(start))
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("period_start must be a date like 2006-01-02")
+	}
+	to, err := parseAggregateDay(strings.TrimSpace(end))
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("period_end must be a date like 2006-01-02")
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, errors.New("period_end is before period_start")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+func (s *statementService) restaurant(ctx context.Context, restaurantID string) (*models.Restaurant, error) {
+	objectID, err := primitive.ObjectIDFromHex(restaurantID)
+	if err != nil {
+		return nil, errors.New("invalid restaurant ID")
+	}
+	return s.restaurantRepo.FindByID(ctx, objectID)
+}
+
+func (s *statementService) GetCommission(ctx context.Context, restaurantID string) (*models.Commission, bool, error) {
+	restaurant, err := s.restaurant(ctx, restaurantID)
+	if err != nil {
+		return nil, false, err
+	}
+	commission := restaurantCommission(restaurant)
+	return &commission, restaurant.Commission == nil, nil
+}
+
+func (s *statementService) SetCommission(ctx context.Context, restaurantID string, commission models.Commission) (*models.Commission, error) {
+	if commission.Percent < 0 || commission.Percent > 100 {
+		return nil, errors.New("percent must be between 0 and 100")
+	}
+	if commission.FixedPerOrder < 0 {
+		return nil, errors.New("fixed_per_order can't be negative")
+	}
+	commission.FixedPerOrder = roundMoney(commission.FixedPerOrder)
+
+	restaurant, err := s.restaurant(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.restaurantRepo.Update(ctx, restaurant.ID, bson.M{"commission": commission}); err != nil {
+		return nil, err
+	}
+	return &commission, nil
+}
+
+func (s *statementService) GenerateStatements(ctx context.Context, adminID primitive.ObjectID, req models.GenerateStatementsRequest) ([]models.RestaurantStatement, error) {
+	from, to, err := parsePeriod(req.PeriodStart, req.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	var restaurantIDs []primitive.ObjectID
+	if strings.TrimSpace(req.RestaurantID) != "" {
+		restaurant, err := s.restaurant(ctx, strings.TrimSpace(req.RestaurantID))
+		if err != nil {
+			return nil, err
+		}
+		restaurantIDs = []primitive.ObjectID{restaurant.ID}
+	} else {
+		restaurantIDs, err = s.orderRepo.RestaurantsWithUnbilledDeliveries(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+		withRefunds, err := s.refundRepo.RestaurantsWithUnbilledRefunds(ctx, to)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[primitive.ObjectID]bool, len(restaurantIDs))
+		for _, id := range restaurantIDs {
+			seen[id] = true
+		}
+		for _, id := range withRefunds {
+			if !seen[id] {
+				restaurantIDs = append(restaurantIDs, id)
+			}
+		}
+	}
+
+	statements := []models.RestaurantStatement{}
+	for _, restaurantID := range restaurantIDs {
+		statement, err := s.generateStatement(ctx, adminID, restaurantID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if statement != nil {
+			statements = append(statements, *statement)
+		}
+	}
+	return statements, nil
+}
+
+// generateStatement claims the restaurant's unbilled deliveries and the
+// refunds it can deduct under a fresh statement ID first, so two
+// generations running at once can never bill the same order or deduct the
+// same refund, then totals them up. If the statement can't be saved its
+// orders and refunds are handed back.
+func (s *statementService) generateStatement(ctx context.Context, adminID, restaurantID primitive.ObjectID, from, to time.Time) (*models.RestaurantStatement, error) {
+	statementID := primitive.NewObjectID()
+	claimed, err := s.orderRepo.ClaimForStatement(ctx, restaurantID, statementID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	claimedRefunds, err := s.claimRefunds(ctx, restaurantID, statementID, to)
+	if err != nil {
+		s.releaseStatement(statementID)
+		return nil, err
+	}
+	if claimed == 0 && claimedRefunds == 0 {
+		return nil, nil
+	}
+
+	statement, err := s.buildStatement(ctx, statementID, restaurantID, from, to)
+	if err == nil {
+		statement.CreatedBy = adminID
+		for i := range statement.Adjustments {
+			statement.Adjustments[i].CreatedBy = adminID
+		}
+		err = s.statementRepo.Create(ctx, statement)
+	}
+	if err != nil {
+		s.releaseStatement(statementID)
+		return nil, err
+	}
+	return statement, nil
+}
+
+// claimRefunds claims the restaurant's processed refunds that this
+// statement can deduct: those on orders it bills, and those on orders an
+// earlier statement billed. Refunds on orders not billed yet wait for
+// their order.
+func (s *statementService) claimRefunds(ctx context.Context, restaurantID, statementID primitive.ObjectID, to time.Time) (int64, error) {
+	refunds, err := s.refundRepo.FindUnbilled(ctx, restaurantID, to)
+	if err != nil || len(refunds) == 0 {
+		return 0, err
+	}
+
+	billed := make(map[primitive.ObjectID]bool)
+	var ids []primitive.ObjectID
+	for _, refund := range refunds {
+		if _, checked := billed[refund.OrderID]; !checked {
+			order, err := s.orderRepo.FindByID(ctx, refund.OrderID)
+			if err != nil {
+				return 0, err
+			}
+			billed[refund.OrderID] = order.StatementID != nil
+		}
+		if billed[refund.OrderID] {
+			ids = append(ids, refund.ID)
+		}
+	}
+	return s.refundRepo.ClaimForStatement(ctx, ids, statementID)
+}
+
+// releaseStatement hands an unsaved statement's orders and refunds back.
+func (s *statementService) releaseStatement(statementID primitive.ObjectID) {
+	ctx := context.Background()
+	if err := s.orderRepo.ReleaseStatement(ctx, statementID); err != nil {
+		fmt.Printf("⚠️  Failed to release orders from statement %s: %v\n", statementID.Hex(), err)
+	}
+	if err := s.refundRepo.ReleaseStatement(ctx, statementID); err != nil {
+		fmt.Printf("⚠️  Failed to release refunds from statement %s: %v\n", statementID.Hex(), err)
+	}
+}
+
+func (s *statementService) buildStatement(ctx context.Context, statementID, restaurantID primitive.ObjectID, from, to time.Time) (*models.RestaurantStatement, error) {
+	orders, err := s.orderRepo.FindByStatement(ctx, statementID)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := s.refundRepo.FindByStatement(ctx, statementID)
+	if err != nil {
+		return nil, err
+	}
+	refundedFood := make(map[primitive.ObjectID]float64)
+	for _, refund := range refunds {
+		refundedFood[refund.OrderID] += refund.Food
+	}
+
+	statement := &models.RestaurantStatement{
+		ID:           statementID,
+		RestaurantID: restaurantID,
+		PeriodStart:  from,
+		PeriodEnd:    to,
+		Status:       models.StatementUnpaid,
+		Orders:       make([]models.StatementOrder, 0, len(orders)),
+	}
+	restaurant, err := s.restaurantRepo.FindByID(ctx, restaurantID)
+	if err != nil {
+		restaurant = nil
+	} else {
+		statement.RestaurantName = restaurant.Name
+	}
+	statement.Commission = restaurantCommission(restaurant)
+
+	onStatement := make(map[primitive.ObjectID]bool, len(orders))
+	for i := range orders {
+		order := &orders[i]
+		onStatement[order.ID] = true
+
+		// Orders refunded before refunds were tracked have no refunds to
+		// claim; they count as refunded in full.
+		refunded, ok := refundedFood[order.ID]
+		if !ok && order.Refunds == nil {
+			refunded = orderRefunded(order)
+		}
+		line := models.StatementOrder{
+			OrderID:     order.ID,
+			OrderNumber: order.OrderNumber,
+			DeliveredAt: deliveredAt(order),
+			FoodSales:   roundMoney(order.TotalAmount.Subtotal),
+			Commission:  commissionOn(statement.Commission, order.TotalAmount.Subtotal-refunded),
+			Refunded:    roundMoney(refunded),
+		}
+		line.Net = roundMoney(line.FoodSales - line.Commission - line.Refunded)
+		statement.Orders = append(statement.Orders, line)
+
+		statement.GrossSales += line.FoodSales
+		statement.CommissionTotal += line.Commission
+		statement.Refunds += line.Refunded
+	}
+	sort.SliceStable(statement.Orders, func(i, j int) bool {
+		return statement.Orders[i].DeliveredAt.Before(statement.Orders[j].DeliveredAt)
+	})
+
+	// Refunds on orders an earlier statement billed come off as
+	// adjustments, less the commission the platform no longer keeps on
+	// them.
+	for i := range refunds {
+		refund := &refunds[i]
+		if onStatement[refund.OrderID] {
+			continue
+		}
+		returned, err := s.refundedCommission(ctx, statement.Commission, refund)
+		if err != nil {
+			return nil, err
+		}
+		amount := -roundMoney(refund.Food - returned)
+		statement.Adjustments = append(statement.Adjustments, models.StatementAdjustment{
+			Amount:    amount,
+			Reason:    fmt.Sprintf("Refund on order %s, billed on an earlier statement", refund.OrderNumber),
+			CreatedAt: time.Now(),
+		})
+		statement.AdjustmentTotal += amount
+	}
+
+	statement.OrderCount = len(statement.Orders)
+	statement.GrossSales = roundMoney(statement.GrossSales)
+	statement.CommissionTotal = roundMoney(statement.CommissionTotal)
+	statement.Refunds = roundMoney(statement.Refunds)
+	statement.AdjustmentTotal = roundMoney(statement.AdjustmentTotal)
+	statement.NetPayable = roundMoney(statement.GrossSales - statement.CommissionTotal - statement.Refunds + statement.AdjustmentTotal)
+	return statement, nil
+}
+
+func (s *statementService) GetStatements(ctx context.Context, restaurantID, status string, page, limit int64) ([]models.RestaurantStatement, int64, error) {
+	var filter repositories.StatementFilter
+	if restaurantID != "" {
+		objectID, err := primitive.ObjectIDFromHex(restaurantID)
+		if err != nil {
+			return nil, 0, errors.New("invalid restaurant ID")
+		}
+		filter.RestaurantID = &objectID
+	}
+	switch status {
+	case "", models.StatementUnpaid, models.StatementPaid:
+		filter.Status = status
+	default:
+		return nil, 0, errors.New("status must be unpaid or paid")
+	}
+	return s.statementRepo.Find(ctx, filter, listPagination(page, limit))
+}
+
+func (s *statementService) GetStatement(ctx context.Context, statementID string) (*models.RestaurantStatement, error) {
+	objectID, err := primitive.ObjectIDFromHex(statementID)
+	if err != nil {
+		return nil, errors.New("invalid statement ID")
+	}
+	return s.statementRepo.FindByID(ctx, objectID)
+}
+
+func (s *statementService) AddAdjustment(ctx context.Context, statementID string, adminID primitive.ObjectID, req models.StatementAdjustmentRequest) (*models.RestaurantStatement, error) {
+	objectID, err := primitive.ObjectIDFromHex(statementID)
+	if err != nil {
+		return nil, errors.New("invalid statement ID")
+	}
+	amount := roundMoney(req.Amount)
+	if amount == 0 {
+		return nil, errors.New("amount must be at least 0.01 either way")
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	return s.statementRepo.AddAdjustment(ctx, objectID, models.StatementAdjustment{
+		Amount:    amount,
+		Reason:    reason,
+		CreatedBy: adminID,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (s *statementService) MarkPaid(ctx context.Context, statementID string, adminID primitive.ObjectID, req models.MarkStatementPaidRequest) (*models.RestaurantStatement, error) {
+	objectID, err := primitive.ObjectIDFromHex(statementID)
+	if err != nil {
+		return nil, errors.New("invalid statement ID")
+	}
+	return s.statementRepo.MarkPaid(ctx, objectID, adminID, strings.TrimSpace(req.Reference), time.Now())
+}
+
+// statementPeriod labels a statement's period with its inclusive days.
+func statementPeriod(statement *models.RestaurantStatement) string {
+	from := statement.PeriodStart.In(serviceLocation)
+	to := statement.PeriodEnd.In(serviceLocation).AddDate(0, 0, -1)
+	return from.Format("Jan 2, 2006") + " – " + to.Format("Jan 2, 2006")
+}
+
+func formatCommission(commission models.Commission) string {
+	rate := strconv.FormatFloat(commission.Percent, 'f', -1, 64) + "%"
+	if commission.FixedPerOrder > 0 {
+		rate += fmt.Sprintf(" + %.2f per order", commission.FixedPerOrder)
+	}
+	return rate
+}
+
+// WriteStatementCSV writes a statement as one table: a row per delivered
+// order, a row per adjustment, and a closing total row whose amount is
+// the net payable.
+func WriteStatementCSV(w io.Writer, statement *models.RestaurantStatement) error {
+	writer := csv.NewWriter(w)
+	money := func(amount float64) string {
+		return fmt.Sprintf("%.2f", amount)
+	}
+
+	if err := writer.Write([]string{"type", "reference", "date", "food_sales", "commission", "refunded", "amount"}); err != nil {
+		return err
+	}
+	for _, line := range statement.Orders {
+		if err := writer.Write([]string{
+			"order",
+			line.OrderNumber,
+			line.DeliveredAt.In(serviceLocation).Format(time.RFC3339),
+			money(line.FoodSales),
+			money(line.Commission),
+			money(line.Refunded),
+			money(line.Net),
+		}); err != nil {
+			return err
+		}
+	}
+	for _, adjustment := range statement.Adjustments {
+		if err := writer.Write([]string{
+			"adjustment",
+			adjustment.Reason,
+			adjustment.CreatedAt.In(serviceLocation).Format(time.RFC3339),
+			"", "", "",
+			money(adjustment.Amount),
+		}); err != nil {
+			return err
+		}
+	}
+	if err := writer.Write([]string{
+		"total",
+		statement.ID.Hex(),
+		"",
+		money(statement.GrossSales),
+		money(statement.CommissionTotal),
+		money(statement.Refunds),
+		money(statement.NetPayable),
+	}); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteStatementPDF writes a statement as a printable document: a summary
+// of the totals, then the order lines and adjustments.
+func WriteStatementPDF(w io.Writer, statement *models.RestaurantStatement) error {
+	const (
+		left   = 50.0
+		right  = pdf.PageWidth - 50
+		bottom = pdf.PageHeight - 60
+	)
+	doc := pdf.New()
+	doc.AddPage()
+	y := 60.0
+	money := func(amount float64) string {
+		return fmt.Sprintf("%.2f", amount)
+	}
+	// newLine moves down one line, starting a new page when this one is
+	// full; it reports whether it did.
+	newLine := func(height float64) bool {
+		y += height
+		if y <= bottom {
+			return false
+		}
+		doc.AddPage()
+		y = 60
+		return true
+	}
+
+	doc.Text(left, y, pdf.Bold, 18, "Restaurant settlement statement")
+	newLine(26)
+	name := statement.RestaurantName
+	if name == "" {
+		name = statement.RestaurantID.Hex()
+	}
+	for _, row := range [][2]string{
+		{"Restaurant", name},
+		{"Period", statementPeriod(statement)},
+		{"Statement", statement.ID.Hex()},
+		{"Commission", formatCommission(statement.Commission)},
+		{"Status", statement.Status},
+	} {
+		doc.Text(left, y, pdf.Bold, 10, row[0])
+		doc.Text(left+90, y, pdf.Regular, 10, row[1])
+		newLine(15)
+	}
+	if statement.PaidAt != nil {
+		paid := statement.PaidAt.In(serviceLocation).Format("Jan 2, 2006 15:04")
+		if statement.PaymentReference != "" {
+			paid += " (ref " + statement.PaymentReference + ")"
+		}
+		doc.Text(left, y, pdf.Bold, 10, "Paid")
+		doc.Text(left+90, y, pdf.Regular, 10, paid)
+		newLine(15)
+	}
+
+	newLine(10)
+	for _, row := range []struct {
+		label  string
+		amount float64
+	}{
+		{fmt.Sprintf("Gross food sales (%d orders)", statement.OrderCount), statement.GrossSales},
+		{"Commission", -statement.CommissionTotal},
+		{"Refunds", -statement.Refunds},
+		{"Adjustments", statement.AdjustmentTotal},
+	} {
+		doc.Text(left, y, pdf.Regular, 11, row.label)
+		doc.TextRight(right, y, pdf.Regular, 11, money(row.amount))
+		newLine(16)
+	}
+	doc.Line(left, y-11, right, y-11)
+	newLine(4)
+	doc.Text(left, y, pdf.Bold, 12, "Net payable (ETB)")
+	doc.TextRight(right, y, pdf.Bold, 12, money(statement.NetPayable))
+	newLine(34)
+
+	columns := []struct {
+		title string
+		x     float64 // left edge, or right edge for amounts
+	}{
+		{"Order", left}, {"Delivered", left + 120}, {"Food sales", right - 240},
+		{"Commission", right - 160}, {"Refunded", right - 80}, {"Net", right},
+	}
+	header := func() {
+		for i, column := range columns {
+			if i < 2 {
+				doc.Text(column.x, y, pdf.Bold, 9, column.title)
+			} else {
+				doc.TextRight(column.x, y, pdf.Bold, 9, column.title)
+			}
+		}
+		doc.Line(left, y+4, right, y+4)
+		newLine(16)
+	}
+
+	doc.Text(left, y, pdf.Bold, 12, "Orders")
+	newLine(18)
+	header()
+	for _, line := range statement.Orders {
+		doc.Text(columns[0].x, y, pdf.Regular, 9, line.OrderNumber)
+		doc.Text(columns[1].x, y, pdf.Regular, 9, line.DeliveredAt.In(serviceLocation).Format("Jan 2, 2006 15:04"))
+		for i, amount := range []float64{line.FoodSales, line.Commission, line.Refunded, line.Net} {
+			doc.TextRight(columns[2+i].x, y, pdf.Regular, 9, money(amount))
+		}
+		if newLine(13) {
+			header()
+		}
+	}
+
+	if len(statement.Adjustments) > 0 {
+		newLine(20)
+		doc.Text(left, y, pdf.Bold, 12, "Adjustments")
+		newLine(18)
+		for _, adjustment := range statement.Adjustments {
+			doc.Text(left, y, pdf.Regular, 9, adjustment.CreatedAt.In(serviceLocation).Format("Jan 2, 2006"))
+			doc.Text(left+120, y, pdf.Regular, 9, adjustment.Reason)
+			doc.TextRight(right, y, pdf.Regular, 9, money(adjustment.Amount))
+			newLine(13)
+		}
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
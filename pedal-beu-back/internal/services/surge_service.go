@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+)
+
+// surgeStaleAfter is how long a live surge reading stays valid. The engine
+// refreshes every minute; if it stops (crash, deploy mid-tick), pricing
+// falls back to the admin multiplier rather than freezing on a peak.
+const surgeStaleAfter = 5 * time.Minute
+
+// surgeDemandWindow matches FindAvailableOrders' 30-minute cutoff, so
+// demand counts exactly the orders drivers can still see and take.
+const surgeDemandWindow = 30 * time.Minute
+
+// SurgeUpdate is one zone whose live multiplier changed on this pass, for
+// the caller (main.go's ticker) to broadcast.
+type SurgeUpdate struct {
+	Zone     models.ServiceZone
+	Surge    models.ZoneSurge
+	Previous float64
+}
+
+// SurgeService computes live surge multipliers from supply and demand.
+type SurgeService interface {
+	// RecomputeSurge measures demand (unassigned orders picking up in the
+	// zone) and supply (online drivers in the zone) for every active zone,
+	// stores the resulting multiplier on the zone, and returns the zones
+	// whose multiplier changed.
+	RecomputeSurge(ctx context.Context) ([]SurgeUpdate, error)
+}
+
+type surgeService struct {
+	zoneRepo   repositories.ZoneRepository
+	driverRepo repositories.DriverRepository
+	orderRepo  repositories.OrderRepository
+}
+
+func NewSurgeService(
+	zoneRepo repositories.ZoneRepository,
+	driverRepo repositories.DriverRepository,
+	orderRepo repositories.OrderRepository,
+) SurgeService {
+	return &surgeService{
+		zoneRepo:   zoneRepo,
+		driverRepo: driverRepo,
+		orderRepo:  orderRepo,
+	}
+}
+
+func (s *surgeService) RecomputeSurge(ctx context.Context) ([]SurgeUpdate, error) {
+	zones, err := s.zoneRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var updates []SurgeUpdate
+
+	for _, zone := range zones {
+		if !zone.IsActive {
+			continue
+		}
+
+		demand, err := s.orderRepo.CountUnassignedWithin(ctx, zone.Area, now.Add(-surgeDemandWindow))
+		if err != nil {
+			log.Printf("⚠️  Surge: failed to count demand in zone %s: %v", zone.Name, err)
+			continue
+		}
+		supply, err := s.driverRepo.CountOnlineWithin(ctx, zone.Area)
+		if err != nil {
+			log.Printf("⚠️  Surge: failed to count supply in zone %s: %v", zone.Name, err)
+			continue
+		}
+
+		surge := models.ZoneSurge{
+			Multiplier: surgeMultiplierFor(demand, supply),
+			Demand:     demand,
+			Supply:     supply,
+			ComputedAt: now,
+		}
+		if err := s.zoneRepo.UpdateLiveSurge(ctx, zone.ID, surge); err != nil {
+			log.Printf("⚠️  Surge: failed to store surge for zone %s: %v", zone.Name, err)
+			continue
+		}
+
+		previous := 1.0
+		if zone.LiveSurge != nil && now.Sub(zone.LiveSurge.ComputedAt) < surgeStaleAfter {
+			previous = zone.LiveSurge.Multiplier
+		}
+		if surge.Multiplier != previous {
+			updates = append(updates, SurgeUpdate{Zone: zone, Surge: surge, Previous: previous})
+		}
+	}
+
+	return updates, nil
+}
+
+// surgeMultiplierFor maps a demand/supply ratio to a delivery-fee
+// multiplier. Nothing happens until there are more than
+// SURGE_RATIO_THRESHOLD waiting orders per online driver; past that the
+// multiplier climbs by SURGE_STEP for each extra order per driver, capped
+// at SURGE_MAX_MULTIPLIER and rounded to 0.1 so prices don't flicker on
+// every tick. Orders waiting with no drivers online at all is the worst
+// case and goes straight to the cap.
+func surgeMultiplierFor(demand, supply int64) float64 {
+	if demand == 0 {
+		return 1
+	}
+	maxMultiplier := surgeMaxMultiplier()
+	if supply == 0 {
+		return maxMultiplier
+	}
+
+	ratio := float64(demand) / float64(supply)
+	excess := ratio - surgeRatioThreshold()
+	if excess <= 0 {
+		return 1
+	}
+
+	multiplier := math.Round((1+excess*surgeStep())*10) / 10
+	return math.Min(multiplier, maxMultiplier)
+}
+
+func surgeRatioThreshold() float64 {
+	return envFloat("SURGE_RATIO_THRESHOLD", 1.5)
+}
+
+func surgeStep() float64 {
+	return envFloat("SURGE_STEP", 0.25)
+}
+
+func surgeMaxMultiplier() float64 {
+	return math.Max(1, envFloat("SURGE_MAX_MULTIPLIER", 2))
+}
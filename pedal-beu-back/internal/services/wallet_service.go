@@ -0,0 +1,400 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WalletService keeps customers' wallets: store credit from refunds and
+// admin grants that they can spend on orders, in full or in part. Every
+// change is an entry in an append-only log numbered per user, and each
+// entry records the balance it leaves. The log's unique (user, seq) index
+// is what makes concurrent changes safe: two changes made from the same
+// state race for the same seq, and the loser retries from the winner's.
+// The wallets collection is a projection of the log for reads and the
+// expiry sweep.
+type WalletService interface {
+	GetWallet(ctx context.Context, userID primitive.ObjectID) (*models.Wallet, error)
+	// GetTransactions lists a user's wallet transactions, newest first.
+	GetTransactions(ctx context.Context, userID primitive.ObjectID, page, limit int64) ([]models.WalletTransaction, int64, error)
+	// GrantCredit adds store credit to a customer's wallet on an admin's
+	// say-so, optionally expiring.
+	GrantCredit(ctx context.Context, userID string, adminID primitive.ObjectID, req models.GrantWalletCreditRequest) (*models.WalletTransaction, error)
+	// CreditRefund pays a wallet refund in; crediting the same refund
+	// twice is a no-op.
+	CreditRefund(ctx context.Context, refund *models.Refund) error
+	// PayForOrder spends some of a customer's credit on an order; paying
+	// for the same order twice is a no-op. It fails with
+	// ErrInsufficientWalletBalance if they don't have enough.
+	PayForOrder(ctx context.Context, userID, orderID primitive.ObjectID, amount float64) error
+	// ReturnOrderPayment gives a cancelled order's wallet payment back;
+	// giving it back twice is a no-op.
+	ReturnOrderPayment(ctx context.Context, userID, orderID primitive.ObjectID, amount float64) error
+	// PayTip spends some of a customer's credit on a tip after delivery
+	// and returns the amount taken; paying the same order's tip twice
+	// takes nothing more and returns the first amount. It fails with
+	// ErrInsufficientWalletBalance if they don't have enough.
+	PayTip(ctx context.Context, userID, orderID primitive.ObjectID, amount float64) (float64, error)
+	// ExpireCredits takes expired credit off every wallet holding some and
+	// returns how many wallets it changed.
+	ExpireCredits(ctx context.Context) (int, error)
+}
+
+var (
+	// ErrInsufficientWalletBalance is returned when spending more credit
+	// than the wallet holds.
+	ErrInsufficientWalletBalance = errors.New("not enough wallet balance")
+	// ErrWalletBusy is returned when a wallet kept changing under a change
+	// through every retry.
+	ErrWalletBusy = errors.New("wallet is busy, try again")
+)
+
+// walletRetries is how many times a change is retried after losing a race
+// with another change to the same wallet.
+const walletRetries = 5
+
+type walletService struct {
+	walletRepo repositories.WalletRepository
+	userRepo   repositories.UserRepository
+}
+
+func NewWalletService(
+	walletRepo repositories.WalletRepository,
+	userRepo repositories.UserRepository,
+) WalletService {
+	return &walletService{
+		walletRepo: walletRepo,
+		userRepo:   userRepo,
+	}
+}
+
+// walletChange is a change to make to a wallet. Amount is positive for
+// credit and negative for spending; expiry works out its own.
+type walletChange struct {
+	Type      string
+	Amount    float64
+	ExpiresAt *time.Time
+	Reason    string
+	OrderID   *primitive.ObjectID
+	RefundID  *primitive.ObjectID
+	CreatedBy *primitive.ObjectID
+	Key       string // idempotency key; empty if the change may repeat
+}
+
+func lotExpired(lot models.WalletLot, now time.Time) bool {
+	return lot.ExpiresAt != nil && !lot.ExpiresAt.After(now)
+}
+
+// hasExpiredCredit reports whether the wallet state left by latest holds
+// credit that has expired.
+func hasExpiredCredit(latest *models.WalletTransaction, now time.Time) bool {
+	if latest == nil {
+		return false
+	}
+	for _, lot := range latest.Lots {
+		if lotExpired(lot, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// spendLots takes amount off lots, from the soonest to expire to the ones
+// that never do, and returns what's left.
+func spendLots(lots []models.WalletLot, amount float64) ([]models.WalletLot, error) {
+	order := make([]int, len(lots))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		x, y := lots[order[a]].ExpiresAt, lots[order[b]].ExpiresAt
+		if x == nil || y == nil {
+			return y == nil && x != nil
+		}
+		return x.Before(*y)
+	})
+
+	left := make([]float64, len(lots))
+	for i, lot := range lots {
+		left[i] = lot.Amount
+	}
+	for _, i := range order {
+		if amount <= 0 {
+			break
+		}
+		spent := left[i]
+		if spent > amount {
+			spent = amount
+		}
+		left[i] = roundMoney(left[i] - spent)
+		amount = roundMoney(amount - spent)
+	}
+	if amount > 0 {
+		return nil, ErrInsufficientWalletBalance
+	}
+
+	var kept []models.WalletLot
+	for i, lot := range lots {
+		if left[i] > 0 {
+			lot.Amount = left[i]
+			kept = append(kept, lot)
+		}
+	}
+	return kept, nil
+}
+
+// nextTransaction works out the log entry that makes change to the wallet
+// state latest left. It returns nil for an expiry with nothing to expire.
+func nextTransaction(userID primitive.ObjectID, latest *models.WalletTransaction, change walletChange, now time.Time) (*models.WalletTransaction, error) {
+	txn := &models.WalletTransaction{
+		ID:             primitive.NewObjectID(),
+		UserID:         userID,
+		Seq:            1,
+		Type:           change.Type,
+		Amount:         roundMoney(change.Amount),
+		ExpiresAt:      change.ExpiresAt,
+		Reason:         change.Reason,
+		OrderID:        change.OrderID,
+		RefundID:       change.RefundID,
+		IdempotencyKey: change.Key,
+		CreatedBy:      change.CreatedBy,
+		CreatedAt:      now,
+	}
+	var lots []models.WalletLot
+	if latest != nil {
+		txn.Seq = latest.Seq + 1
+		lots = append(lots, latest.Lots...)
+	}
+
+	switch {
+	case change.Type == models.WalletExpiry:
+		var kept []models.WalletLot
+		expired := 0.0
+		for _, lot := range lots {
+			if lotExpired(lot, now) {
+				expired += lot.Amount
+			} else {
+				kept = append(kept, lot)
+			}
+		}
+		if expired <= 0 {
+			return nil, nil
+		}
+		txn.Amount = -roundMoney(expired)
+		lots = kept
+	case txn.Amount > 0:
+		lots = append(lots, models.WalletLot{
+			TransactionID: txn.ID,
+			Amount:        txn.Amount,
+			ExpiresAt:     change.ExpiresAt,
+		})
+	case txn.Amount < 0:
+		var err error
+		if lots, err = spendLots(lots, -txn.Amount); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("wallet amount must be at least 0.01")
+	}
+
+	balance := 0.0
+	for _, lot := range lots {
+		balance += lot.Amount
+	}
+	txn.BalanceAfter = roundMoney(balance)
+	txn.Lots = lots
+	return txn, nil
+}
+
+// apply records change on the user's wallet and returns its log entry —
+// the one already recorded, if change has a key that was. Any expired
+// credit is taken off in its own entry first, so it can't be spent.
+func (s *walletService) apply(ctx context.Context, userID primitive.ObjectID, change walletChange) (*models.WalletTransaction, error) {
+	for attempt := 0; attempt < walletRetries; attempt++ {
+		if change.Key != "" {
+			recorded, err := s.walletRepo.FindTransactionByKey(ctx, change.Key)
+			if err != nil || recorded != nil {
+				return recorded, err
+			}
+		}
+
+		latest, err := s.walletRepo.LatestTransaction(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		if change.Type != models.WalletExpiry && hasExpiredCredit(latest, now) {
+			if _, err := s.apply(ctx, userID, walletChange{Type: models.WalletExpiry}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		txn, err := nextTransaction(userID, latest, change, now)
+		if err != nil {
+			return nil, err
+		}
+		if txn == nil {
+			// Nothing had expired after all: the projection the sweep
+			// went by was behind the log.
+			if latest != nil {
+				if err := s.walletRepo.Project(ctx, latest); err != nil {
+					fmt.Printf("⚠️  Failed to update wallet balance for user %s: %v\n", userID.Hex(), err)
+				}
+			}
+			return nil, nil
+		}
+		err = s.walletRepo.AppendTransaction(ctx, txn)
+		switch {
+		case errors.Is(err, repositories.ErrWalletSeqTaken):
+			continue
+		case errors.Is(err, repositories.ErrDuplicateWalletTransaction):
+			return s.walletRepo.FindTransactionByKey(ctx, change.Key)
+		case err != nil:
+			return nil, err
+		}
+
+		// The log is already right; a projection left behind here is
+		// caught up by the wallet's next change.
+		if err := s.walletRepo.Project(ctx, txn); err != nil {
+			fmt.Printf("⚠️  Failed to update wallet balance for user %s: %v\n", userID.Hex(), err)
+		}
+		return txn, nil
+	}
+	return nil, ErrWalletBusy
+}
+
+func (s *walletService) GetWallet(ctx context.Context, userID primitive.ObjectID) (*models.Wallet, error) {
+	latest, err := s.walletRepo.LatestTransaction(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if hasExpiredCredit(latest, time.Now()) {
+		expired, err := s.apply(ctx, userID, walletChange{Type: models.WalletExpiry})
+		if err != nil {
+			return nil, err
+		}
+		if expired != nil {
+			latest = expired
+		}
+	}
+
+	wallet := &models.Wallet{UserID: userID, Lots: []models.WalletLot{}}
+	if latest != nil {
+		wallet.Balance = latest.BalanceAfter
+		wallet.Version = latest.Seq
+		wallet.UpdatedAt = latest.CreatedAt
+		if latest.Lots != nil {
+			wallet.Lots = latest.Lots
+		}
+	}
+	return wallet, nil
+}
+
+func (s *walletService) GetTransactions(ctx context.Context, userID primitive.ObjectID, page, limit int64) ([]models.WalletTransaction, int64, error) {
+	return s.walletRepo.FindTransactions(ctx, userID, listPagination(page, limit))
+}
+
+func (s *walletService) GrantCredit(ctx context.Context, userID string, adminID primitive.ObjectID, req models.GrantWalletCreditRequest) (*models.WalletTransaction, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	user, err := s.userRepo.FindByID(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role.Type != "customer" {
+		return nil, errors.New("only customers have wallets")
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("expiry must be in the future")
+	}
+
+	return s.apply(ctx, objectID, walletChange{
+		Type:      models.WalletGrant,
+		Amount:    req.Amount,
+		ExpiresAt: req.ExpiresAt,
+		Reason:    reason,
+		CreatedBy: &adminID,
+	})
+}
+
+func (s *walletService) CreditRefund(ctx context.Context, refund *models.Refund) error {
+	_, err := s.apply(ctx, refund.CustomerID, walletChange{
+		Type:     models.WalletRefund,
+		Amount:   refund.Amount,
+		Reason:   fmt.Sprintf("Refund on order %s", refund.OrderNumber),
+		OrderID:  &refund.OrderID,
+		RefundID: &refund.ID,
+		Key:      "refund:" + refund.ID.Hex(),
+	})
+	return err
+}
+
+func (s *walletService) PayForOrder(ctx context.Context, userID, orderID primitive.ObjectID, amount float64) error {
+	_, err := s.apply(ctx, userID, walletChange{
+		Type:    models.WalletOrderPayment,
+		Amount:  -amount,
+		OrderID: &orderID,
+		Key:     "order:" + orderID.Hex(),
+	})
+	return err
+}
+
+func (s *walletService) ReturnOrderPayment(ctx context.Context, userID, orderID primitive.ObjectID, amount float64) error {
+	_, err := s.apply(ctx, userID, walletChange{
+		Type:    models.WalletOrderReturn,
+		Amount:  amount,
+		OrderID: &orderID,
+		Key:     "order-return:" + orderID.Hex(),
+	})
+	return err
+}
+
+func (s *walletService) PayTip(ctx context.Context, userID, orderID primitive.ObjectID, amount float64) (float64, error) {
+	txn, err := s.apply(ctx, userID, walletChange{
+		Type:    models.WalletTip,
+		Amount:  -amount,
+		OrderID: &orderID,
+		Key:     "tip:" + orderID.Hex(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return -txn.Amount, nil
+}
+
+func (s *walletService) ExpireCredits(ctx context.Context) (int, error) {
+	wallets, err := s.walletRepo.FindWithExpiredLots(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, wallet := range wallets {
+		txn, err := s.apply(ctx, wallet.UserID, walletChange{Type: models.WalletExpiry})
+		if err != nil {
+			fmt.Printf("⚠️  Failed to expire credit in user %s's wallet: %v\n", wallet.UserID.Hex(), err)
+			continue
+		}
+		if txn != nil {
+			expired++
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memWalletRepo is one user's wallet log. conflicts makes that many
+// appends lose the race for their seq to a grant of 5 from elsewhere.
+type memWalletRepo struct {
+	repositories.WalletRepository
+	log       []models.WalletTransaction
+	conflicts int
+}
+
+func (r *memWalletRepo) LatestTransaction(ctx context.Context, userID primitive.ObjectID) (*models.WalletTransaction, error) {
+	if len(r.log) == 0 {
+		return nil, nil
+	}
+	latest := r.log[len(r.log)-1]
+	return &latest, nil
+}
+
+func (r *memWalletRepo) FindTransactionByKey(ctx context.Context, key string) (*models.WalletTransaction, error) {
+	for _, txn := range r.log {
+		if txn.IdempotencyKey == key {
+			return &txn, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memWalletRepo) AppendTransaction(ctx context.Context, txn *models.WalletTransaction) error {
+	if r.conflicts > 0 {
+		r.conflicts--
+		latest, _ := r.LatestTransaction(ctx, txn.UserID)
+		winner, err := nextTransaction(txn.UserID, latest, walletChange{Type: models.WalletGrant, Amount: 5}, time.Now())
+		if err != nil {
+			return err
+		}
+		r.log = append(r.log, *winner)
+	}
+	if txn.Seq != int64(len(r.log))+1 {
+		return repositories.ErrWalletSeqTaken
+	}
+	if txn.IdempotencyKey != "" {
+		if recorded, _ := r.FindTransactionByKey(ctx, txn.IdempotencyKey); recorded != nil {
+			return repositories.ErrDuplicateWalletTransaction
+		}
+	}
+	r.log = append(r.log, *txn)
+	return nil
+}
+
+func (r *memWalletRepo) Project(ctx context.Context, txn *models.WalletTransaction) error {
+	return nil
+}
+
+func walletLot(amount float64, expiresAt *time.Time) models.WalletLot {
+	return models.WalletLot{TransactionID: primitive.NewObjectID(), Amount: amount, ExpiresAt: expiresAt}
+}
+
+func TestSpendLots(t *testing.T) {
+	now := time.Now()
+	soon, later := now.Add(time.Hour), now.Add(48*time.Hour)
+
+	tests := []struct {
+		name   string
+		lots   []float64 // amounts, in the order of expiries below
+		expiry []*time.Time
+		spend  float64
+		want   []float64 // what's left of each lot, 0 for spent
+		err    error
+	}{
+		{
+			name:   "soonest to expire first",
+			lots:   []float64{30, 20, 10},
+			expiry: []*time.Time{nil, &later, &soon},
+			spend:  15,
+			want:   []float64{30, 15, 0},
+		},
+		{
+			name:   "never-expiring credit last",
+			lots:   []float64{30, 20},
+			expiry: []*time.Time{nil, &later},
+			spend:  35,
+			want:   []float64{15, 0},
+		},
+		{
+			name:   "all of it",
+			lots:   []float64{10.5, 4.5},
+			expiry: []*time.Time{&soon, nil},
+			spend:  15,
+			want:   []float64{0, 0},
+		},
+		{
+			name:   "more than the balance",
+			lots:   []float64{10, 4.99},
+			expiry: []*time.Time{&soon, nil},
+			spend:  15,
+			err:    ErrInsufficientWalletBalance,
+		},
+		{
+			name:  "empty wallet",
+			spend: 0.01,
+			err:   ErrInsufficientWalletBalance,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lots []models.WalletLot
+			for i, amount := range tt.lots {
+				lots = append(lots, walletLot(amount, tt.expiry[i]))
+			}
+			kept, err := spendLots(lots, tt.spend)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			left := make([]float64, len(lots))
+			for _, lot := range kept {
+				for i := range lots {
+					if lots[i].TransactionID == lot.TransactionID {
+						left[i] = lot.Amount
+					}
+				}
+			}
+			if !reflect.DeepEqual(left, tt.want) {
+				t.Errorf("left = %v, want %v", left, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalletSpending(t *testing.T) {
+	userID := primitive.NewObjectID()
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	// A wallet of 50 that expired an hour ago and 30 that hasn't.
+	withExpiredCredit := func() []models.WalletTransaction {
+		var log []models.WalletTransaction
+		var latest *models.WalletTransaction
+		for _, change := range []walletChange{
+			{Type: models.WalletGrant, Amount: 50, ExpiresAt: &past},
+			{Type: models.WalletGrant, Amount: 30, ExpiresAt: &future},
+		} {
+			txn, err := nextTransaction(userID, latest, change, past.Add(-time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			log = append(log, *txn)
+			latest = txn
+		}
+		return log
+	}
+
+	tests := []struct {
+		name      string
+		spend     float64
+		conflicts int
+
+		err     error
+		types   []string // of the entries appended
+		balance float64
+	}{
+		{
+			name:    "expired credit is taken off before spending",
+			spend:   20,
+			types:   []string{models.WalletExpiry, models.WalletOrderPayment},
+			balance: 10,
+		},
+		{
+			name:    "expired credit can't be spent",
+			spend:   40,
+			err:     ErrInsufficientWalletBalance,
+			types:   []string{models.WalletExpiry},
+			balance: 30,
+		},
+		{
+			name:      "retries from the change that took its seq",
+			spend:     32,
+			conflicts: 2,
+			types:     []string{models.WalletGrant, models.WalletGrant, models.WalletExpiry, models.WalletOrderPayment},
+			balance:   8,
+		},
+		{
+			name:      "gives up on a wallet that keeps changing",
+			spend:     20,
+			conflicts: 1 + walletRetries,
+			err:       ErrWalletBusy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memWalletRepo{log: withExpiredCredit(), conflicts: tt.conflicts}
+			service := NewWalletService(repo, nil)
+
+			err := service.PayForOrder(context.Background(), userID, primitive.NewObjectID(), tt.spend)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err == ErrWalletBusy {
+				return
+			}
+
+			var types []string
+			for i, txn := range repo.log[2:] {
+				types = append(types, txn.Type)
+				if txn.Seq != int64(i)+3 {
+					t.Errorf("entry %d has seq %d", i+3, txn.Seq)
+				}
+			}
+			if !reflect.DeepEqual(types, tt.types) {
+				t.Errorf("appended %v, want %v", types, tt.types)
+			}
+			if balance := repo.log[len(repo.log)-1].BalanceAfter; balance != tt.balance {
+				t.Errorf("balance = %v, want %v", balance, tt.balance)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haile-paa/pedal-delivery/internal/models"
+	"github.com/haile-paa/pedal-delivery/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrOutsideServiceArea is returned by ZoneFor when zones are configured
+// but none of the active ones covers the location.
+var ErrOutsideServiceArea = errors.New("delivery address is outside our service area")
+
+// ZoneService manages the admin-drawn service zones and answers "which
+// zone is this address in?" for ordering and restaurant discovery.
+type ZoneService interface {
+	ListZones(ctx context.Context) ([]models.ServiceZone, error)
+	GetZone(ctx context.Context, id string) (*models.ServiceZone, error)
+	CreateZone(ctx context.Context, req models.ServiceZoneRequest) (*models.ServiceZone, error)
+	UpdateZone(ctx context.Context, id string, req models.ServiceZoneRequest) (*models.ServiceZone, error)
+	DeleteZone(ctx context.Context, id string) error
+	// ZoneFor returns the active zone containing location. It returns
+	// (nil, nil) when no active zones exist at all — zones are opt-in, so
+	// an empty collection means "deliver anywhere", same as before zones
+	// existed — and ErrOutsideServiceArea when zones exist but none match.
+	ZoneFor(ctx context.Context, location models.GeoLocation) (*models.ServiceZone, error)
+}
+
+type zoneService struct {
+	repo repositories.ZoneRepository
+}
+
+func NewZoneService(repo repositories.ZoneRepository) ZoneService {
+	return &zoneService{repo: repo}
+}
+
+func (s *zoneService) ListZones(ctx context.Context) ([]models.ServiceZone, error) {
+	return s.repo.FindAll(ctx)
+}
+
+func (s *zoneService) GetZone(ctx context.Context, id string) (*models.ServiceZone, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid zone ID")
+	}
+	return s.repo.FindByID(ctx, objectID)
+}
+
+func (s *zoneService) CreateZone(ctx context.Context, req models.ServiceZoneRequest) (*models.ServiceZone, error) {
+	zone, err := zoneFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, zone); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+// UpdateZone replaces the zone's settings wholesale — the admin editor
+// always submits the full zone, and a partial polygon update makes no sense.
+func (s *zoneService) UpdateZone(ctx context.Context, id string, req models.ServiceZoneRequest) (*models.ServiceZone, error) {
+	existing, err := s.GetZone(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	zone, err := zoneFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	zone.ID = existing.ID
+	zone.CreatedAt = existing.CreatedAt
+	zone.LiveSurge = existing.LiveSurge
+	if req.IsActive == nil {
+		zone.IsActive = existing.IsActive
+	}
+
+	if err := s.repo.Replace(ctx, zone); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+func (s *zoneService) DeleteZone(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid zone ID")
+	}
+	return s.repo.Delete(ctx, objectID)
+}
+
+func (s *zoneService) ZoneFor(ctx context.Context, location models.GeoLocation) (*models.ServiceZone, error) {
+	hasCoordinates := len(location.Coordinates) == 2
+	if hasCoordinates {
+		zones, err := s.repo.FindContaining(ctx, location)
+		if err != nil {
+			return nil, err
+		}
+		if len(zones) > 0 {
+			return &zones[0], nil
+		}
+	}
+
+	active, err := s.repo.CountActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if active == 0 {
+		return nil, nil
+	}
+	if !hasCoordinates {
+		return nil, errors.New("address has no map location")
+	}
+	return nil, ErrOutsideServiceArea
+}
+
+// zoneFromRequest validates an admin's zone payload and builds the model.
+// MongoDB would reject a malformed polygon at index time anyway, but with
+// an opaque error — checking here gives the admin editor a usable message.
+func zoneFromRequest(req models.ServiceZoneRequest) (*models.ServiceZone, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("zone name is required")
+	}
+
+	if len(req.Area.Coordinates) == 0 {
+		return nil, errors.New("zone area must have at least one ring")
+	}
+	for i, ring := range req.Area.Coordinates {
+		if len(ring) < 4 {
+			return nil, fmt.Errorf("ring %d needs at least 4 positions (a closed triangle)", i)
+		}
+		for _, position := range ring {
+			if len(position) != 2 || math.Abs(position[0]) > 180 || math.Abs(position[1]) > 90 {
+				return nil, fmt.Errorf("ring %d has an invalid [longitude, latitude] position", i)
+			}
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return nil, fmt.Errorf("ring %d is not closed (first and last positions must match)", i)
+		}
+	}
+
+	if req.SurgeMultiplier < 0 {
+		return nil, errors.New("surge multiplier cannot be negative")
+	}
+	if max := surgeMaxMultiplier(); req.SurgeMultiplier > max {
+		return nil, fmt.Errorf("surge multiplier cannot be above %.2g (SURGE_MAX_MULTIPLIER)", max)
+	}
+	if req.MaxDeliveryDistanceKm < 0 {
+		return nil, errors.New("max delivery distance cannot be negative")
+	}
+
+	bands := append([]models.DeliveryFeeBand(nil), req.FeeBands...)
+	for _, band := range bands {
+		if band.UpToKm <= 0 || band.Fee < 0 {
+			return nil, errors.New("fee bands need a positive up_to_km and a non-negative fee")
+		}
+	}
+	sort.Slice(bands, func(i, j int) bool { return bands[i].UpToKm < bands[j].UpToKm })
+
+	if err := validateOpeningHours(req.ActiveHours); err != nil {
+		return nil, err
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	return &models.ServiceZone{
+		Name:                  name,
+		Area:                  models.GeoPolygon{Type: "Polygon", Coordinates: req.Area.Coordinates},
+		IsActive:              isActive,
+		ActiveHours:           req.ActiveHours,
+		SurgeMultiplier:       req.SurgeMultiplier,
+		FeeBands:              bands,
+		MaxDeliveryDistanceKm: req.MaxDeliveryDistanceKm,
+	}, nil
+}
+
+// zoneContains reports whether location is inside the zone's area: inside
+// its outer ring and outside any holes. It treats the area as flat, which
+// is close enough for city-sized zones.
+func zoneContains(zone *models.ServiceZone, location models.GeoLocation) bool {
+	if len(location.Coordinates) != 2 || len(zone.Area.Coordinates) == 0 {
+		return false
+	}
+	lng, lat := location.Coordinates[0], location.Coordinates[1]
+	if !ringContains(zone.Area.Coordinates[0], lng, lat) {
+		return false
+	}
+	for _, hole := range zone.Area.Coordinates[1:] {
+		if ringContains(hole, lng, lat) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains is the even-odd ray casting test for a closed ring of
+// [longitude, latitude] positions.
+func ringContains(ring [][]float64, lng, lat float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if len(a) < 2 || len(b) < 2 {
+			continue
+		}
+		if (a[1] > lat) != (b[1] > lat) && lng < (b[0]-a[0])*(lat-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// zoneOpenAt reports whether orders are accepted in the zone at t.
+func zoneOpenAt(zone *models.ServiceZone, t time.Time) bool {
+	return openingHoursAllow(zone.ActiveHours, t)
+}
+
+// zoneDeliveryFee prices a delivery of distanceKm using the zone's fee
+// bands. ok is false when there's no zone, it has no bands configured, or
+// the distance is unknown (negative).
+func zoneDeliveryFee(zone *models.ServiceZone, distanceKm float64) (fee float64, ok bool) {
+	if zone == nil || len(zone.FeeBands) == 0 || distanceKm < 0 {
+		return 0, false
+	}
+	for _, band := range zone.FeeBands {
+		if distanceKm <= band.UpToKm {
+			return band.Fee, true
+		}
+	}
+	return zone.FeeBands[len(zone.FeeBands)-1].Fee, true
+}
+
+// zoneSurgeMultiplier is the multiplier in effect for the zone: the higher
+// of the admin-set SurgeMultiplier and the surge engine's live value (if
+// it's fresh), treating unset (0) and anything below 1 as "no surge" so a
+// typo can't discount deliveries, and capped at SURGE_MAX_MULTIPLIER so
+// one can't inflate them either.
+func zoneSurgeMultiplier(zone *models.ServiceZone) float64 {
+	multiplier := 1.0
+	if zone == nil {
+		return multiplier
+	}
+	if zone.SurgeMultiplier > multiplier {
+		multiplier = zone.SurgeMultiplier
+	}
+	if live := zone.LiveSurge; live != nil && time.Since(live.ComputedAt) < surgeStaleAfter && live.Multiplier > multiplier {
+		multiplier = live.Multiplier
+	}
+	return math.Min(multiplier, surgeMaxMultiplier())
+}
+
+// serviceLocation is the timezone schedules are evaluated in. Falls back
+// to a fixed UTC+3 when the host has no tzdata (e.g. a scratch container);
+// Ethiopia doesn't observe DST, so the two are equivalent.
+var serviceLocation = func() *time.Location {
+	if loc, err := time.LoadLocation("Africa/Addis_Ababa"); err == nil {
+		return loc
+	}
+	return time.FixedZone("EAT", 3*60*60)
+}()
+
+// openingHoursAllow reports whether t falls inside one of the day's slots,
+// read in the service timezone.
+func openingHoursAllow(hours models.OpeningHours, t time.Time) bool {
+	return openingHoursAllowIn(hours, t, serviceLocation)
+}
+
+// openingHoursAllowIn reports whether t falls inside one of the day's
+// slots, read in loc. A schedule with no slots on any day means "always
+// open". A slot whose close is before its open (e.g. 18:00–02:00) runs past
+// midnight, so the previous day's slots are checked for the early-morning
+// tail.
+func openingHoursAllowIn(hours models.OpeningHours, t time.Time, loc *time.Location) bool {
+	days := openingHoursByWeekday(hours)
+	empty := true
+	for _, slots := range days {
+		if len(slots) > 0 {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		return true
+	}
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	today := local.Weekday()
+	yesterday := (today + 6) % 7
+
+	for _, slot := range days[today] {
+		opens, closes, ok := parseTimeSlot(slot)
+		if !ok {
+			continue
+		}
+		if opens <= closes && minute >= opens && minute < closes {
+			return true
+		}
+		if opens > closes && minute >= opens {
+			return true
+		}
+	}
+	for _, slot := range days[yesterday] {
+		opens, closes, ok := parseTimeSlot(slot)
+		if ok && opens > closes && minute < closes {
+			return true
+		}
+	}
+	return false
+}
+
+func openingHoursByWeekday(hours models.OpeningHours) [7][]models.TimeSlot {
+	return [7][]models.TimeSlot{
+		time.Sunday:    hours.Sunday,
+		time.Monday:    hours.Monday,
+		time.Tuesday:   hours.Tuesday,
+		time.Wednesday: hours.Wednesday,
+		time.Thursday:  hours.Thursday,
+		time.Friday:    hours.Friday,
+		time.Saturday:  hours.Saturday,
+	}
+}
+
+func validateOpeningHours(hours models.OpeningHours) error {
+	for day, slots := range openingHoursByWeekday(hours) {
+		for _, slot := range slots {
+			if _, _, ok := parseTimeSlot(slot); !ok {
+				return fmt.Errorf("invalid time slot %q–%q on %s (use HH:MM)", slot.Open, slot.Close, time.Weekday(day))
+			}
+		}
+	}
+	return nil
+}
+
+// parseTimeSlot converts a slot's "HH:MM" bounds to minutes since midnight.
+func parseTimeSlot(slot models.TimeSlot) (opens, closes int, ok bool) {
+	parse := func(value string) (int, bool) {
+		t, err := time.Parse("15:04", strings.TrimSpace(value))
+		if err != nil {
+			return 0, false
+		}
+		return t.Hour()*60 + t.Minute(), true
+	}
+	opens, okOpen := parse(slot.Open)
+	closes, okClose := parse(slot.Close)
+	return opens, closes, okOpen && okClose && opens != closes
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/haile-paa/pedal-delivery/internal/repositories"
+	"github.com/haile-paa/pedal-delivery/internal/services"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -29,6 +30,27 @@ func SetDriverRepository(r repositories.DriverRepository) {
 	driverRepo = r
 }
 
+// orderService is set once at startup by SetOrderService so driver GPS
+// pings can be run through the geofence engine (arrival detection).
+var orderService services.OrderService
+
+// SetOrderService injects the order service into the websocket package.
+// Call this from main.go after creating the service.
+func SetOrderService(s services.OrderService) {
+	orderService = s
+}
+
+// anomalyDetector is set once at startup by SetLocationAnomalyDetector.
+// When set, every driver GPS ping is plausibility-checked before it's
+// persisted or used for geofencing.
+var anomalyDetector services.LocationAnomalyDetector
+
+// SetLocationAnomalyDetector injects the GPS anomaly detector into the
+// websocket package. Call this from main.go after creating it.
+func SetLocationAnomalyDetector(d services.LocationAnomalyDetector) {
+	anomalyDetector = d
+}
+
 type Client struct {
 	hub    *Hub
 	conn   *websocket.Conn
@@ -135,6 +157,13 @@ func (c *Client) handleMessage(msg map[string]interface{}) {
 			c.hub.JoinRoom(c, "driver:"+driverID)
 		}
 
+	// Restaurant dashboards (and the admin site's restaurant page) join
+	// this for the restaurant's own events, e.g. menu:low_stock.
+	case "join:restaurant_room":
+		if restaurantID, ok := data["restaurantId"].(string); ok && restaurantID != "" {
+			c.hub.JoinRoom(c, "restaurant:"+restaurantID)
+		}
+
 	case "leave:room":
 		if room, ok := data["room"].(string); ok && room != "" {
 			c.hub.LeaveRoom(c, room)
@@ -219,6 +248,12 @@ func (c *Client) handleDriverStatus(data map[string]interface{}) {
 
 // handleDriverLocationUpdate persists GPS to MongoDB then pushes a
 // driver_location_update event to every admin in the "admin" room.
+//
+// Each ping is run through the anomaly detector first. A flagged ping is
+// still shown to admins (marked suspicious, plus a driver:gps_anomaly
+// alert), but it is NOT saved as the driver's location and NOT fed to the
+// geofence engine — otherwise a mock-location app could fake an arrival
+// or skew anything that reads the stored position.
 func (c *Client) handleDriverLocationUpdate(data map[string]interface{}) {
 	lat, latOK := data["lat"].(float64)
 	lng, lngOK := data["lng"].(float64)
@@ -228,8 +263,14 @@ func (c *Client) handleDriverLocationUpdate(data map[string]interface{}) {
 
 	ctx := context.Background()
 
+	var check *services.LocationCheck
+	if anomalyDetector != nil {
+		check = anomalyDetector.Check(ctx, c.userID, lat, lng, time.Now())
+	}
+	suspicious := check.Flagged()
+
 	// Persist to DB
-	if driverRepo != nil {
+	if driverRepo != nil && !suspicious {
 		if err := driverRepo.UpdateLocation(ctx, c.userID, lng, lat); err != nil {
 			log.Printf("Failed to update driver location: %v", err)
 		}
@@ -239,16 +280,76 @@ func (c *Client) handleDriverLocationUpdate(data map[string]interface{}) {
 	event := WebSocketEvent{
 		Type: "driver_location_update",
 		Data: map[string]interface{}{
-			"driver_id": c.userID.Hex(),
-			"lat":       lat,
-			"lng":       lng,
+			"driver_id":  c.userID.Hex(),
+			"lat":        lat,
+			"lng":        lng,
+			"suspicious": suspicious,
 		},
 	}
 	hub.BroadcastToRoom("admin", event)
+
+	if suspicious {
+		hub.BroadcastToRoom("admin", WebSocketEvent{
+			Type: "driver:gps_anomaly",
+			Data: map[string]interface{}{
+				"driver_id":  c.userID.Hex(),
+				"anomalies":  check.Anomalies,
+				"risk_score": check.RiskScore,
+			},
+		})
+		return
+	}
+
+	c.processGeofence(ctx, lat, lng)
+}
+
+// processGeofence runs the ping through the geofence engine and broadcasts
+// any arrivals to the order room, the customer and the admin room. If the
+// engine auto-advanced the order, an order:status_update goes out too so
+// the customer's tracking screen moves on without a refresh.
+func (c *Client) processGeofence(ctx context.Context, lat, lng float64) {
+	if orderService == nil {
+		return
+	}
+
+	events, err := orderService.ProcessDriverLocation(ctx, c.userID, lat, lng)
+	if err != nil {
+		log.Printf("Geofence check failed for driver %s: %v", c.userID.Hex(), err)
+		return
+	}
+
+	for _, e := range events {
+		orderRoom := "order:" + e.OrderID.Hex()
+		customerRoom := "user:" + e.CustomerID.Hex()
+
+		arrived := WebSocketEvent{Type: "order:arrived", Data: e}
+		hub.BroadcastToRoom(orderRoom, arrived)
+		hub.BroadcastToRoom(customerRoom, arrived)
+		hub.BroadcastToRoom("admin", arrived)
+
+		if e.AdvancedTo != "" {
+			update := WebSocketEvent{
+				Type: "order:status_update",
+				Data: map[string]interface{}{
+					"orderId": e.OrderID.Hex(),
+					"status":  e.AdvancedTo,
+				},
+			}
+			hub.BroadcastToRoom(orderRoom, update)
+			hub.BroadcastToRoom(customerRoom, update)
+			hub.BroadcastToRoom("driver:"+c.userID.Hex(), update)
+		}
+	}
 }
 
 // handleLocationUpdate is the existing per-order location relay (customer tracking).
+// A driver whose latest driver_location ping was flagged as a GPS anomaly
+// isn't relayed, so the customer's map/ETA doesn't jump to a spoofed spot.
 func (c *Client) handleLocationUpdate(data map[string]interface{}) {
+	if c.role == "driver" && anomalyDetector != nil && anomalyDetector.LastPingFlagged(c.userID) {
+		return
+	}
+
 	orderID, _ := data["orderId"].(string)
 	location, _ := data["location"].(map[string]interface{})
 	if orderID != "" && location != nil {
@@ -19,6 +19,7 @@ import (
 	"github.com/haile-paa/pedal-delivery/internal/config"
 	"github.com/haile-paa/pedal-delivery/internal/handlers"
 	"github.com/haile-paa/pedal-delivery/internal/middleware"
+	"github.com/haile-paa/pedal-delivery/internal/models"
 	"github.com/haile-paa/pedal-delivery/internal/repositories"
 	"github.com/haile-paa/pedal-delivery/internal/services"
 	"github.com/haile-paa/pedal-delivery/internal/websocket"
@@ -38,8 +39,11 @@ var cld *cloudinary.Cloudinary
 // any order that's sat unassigned for more than 30 minutes and
 // broadcasting order:cancelled for each one — see the call site in main()
 // for the full reasoning. It's a plain ticker rather than a proper cron
-// library since this is the only scheduled job the service needs right
-// now; reach for something heavier if a second one ever gets added.
+// library; the scheduled jobs here (this, startSurgeEngine,
+// startStockReset, startWalletExpiry, startCheckoutPolling and
+// startVerificationRetries) are simple
+// fixed-interval loops, so reach for something heavier only if one ever
+// needs real scheduling.
 func startStaleOrderSweep(orderService services.OrderService) {
 	const sweepInterval = 1 * time.Minute
 	const staleAfter = 30 * time.Minute
@@ -73,6 +77,252 @@ func startStaleOrderSweep(orderService services.OrderService) {
 	}
 }
 
+// startSurgeEngine recomputes every zone's live surge multiplier once a
+// minute from demand (unassigned orders) vs. supply (online drivers), and
+// tells admins and drivers whenever a zone's multiplier changes — drivers
+// use it to head toward busy areas.
+func startSurgeEngine(surgeService services.SurgeService) {
+	const surgeInterval = 1 * time.Minute
+
+	ticker := time.NewTicker(surgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		updates, err := surgeService.RecomputeSurge(ctx)
+		cancel()
+
+		if err != nil {
+			log.Printf("⚠️  Surge recompute failed: %v", err)
+			continue
+		}
+
+		for _, update := range updates {
+			log.Printf("📈 Zone %s surge %.1fx → %.1fx (demand %d, supply %d)",
+				update.Zone.Name, update.Previous, update.Surge.Multiplier, update.Surge.Demand, update.Surge.Supply)
+			if websocket.GlobalHub == nil {
+				continue
+			}
+			event := websocket.WebSocketEvent{
+				Type: "zone:surge_update",
+				Data: gin.H{
+					"zoneId":     update.Zone.ID.Hex(),
+					"name":       update.Zone.Name,
+					"multiplier": update.Surge.Multiplier,
+					"demand":     update.Surge.Demand,
+					"supply":     update.Surge.Supply,
+				},
+			}
+			websocket.GlobalHub.BroadcastToRoom("admin", event)
+			websocket.GlobalHub.BroadcastToRoom("drivers", event)
+		}
+	}
+}
+
+// startStockReset refills daily menu item stock once the STOCK_RESET_TIME
+// boundary passes (see services/menu_stock.go). It checks once at startup,
+// so a server that was down over the boundary catches up, and then every
+// minute; items already reset since the boundary aren't touched.
+func startStockReset(restaurantService services.RestaurantService) {
+	const resetInterval = 1 * time.Minute
+
+	ticker := time.NewTicker(resetInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		reset, err := restaurantService.ResetDailyStock(ctx, time.Now())
+		cancel()
+
+		if err != nil {
+			log.Printf("⚠️  Daily stock reset failed: %v", err)
+		} else if reset > 0 {
+			log.Printf("📦 Reset daily stock for %d menu items", reset)
+		}
+
+		<-ticker.C
+	}
+}
+
+// startWalletExpiry takes expired store credit off customers' wallets
+// every ten minutes. Credit is also expired the moment its wallet is next
+// read or spent from, so this only keeps balances and the log current for
+// wallets nobody is using.
+func startWalletExpiry(walletService services.WalletService) {
+	const expiryInterval = 10 * time.Minute
+
+	ticker := time.NewTicker(expiryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		expired, err := walletService.ExpireCredits(ctx)
+		cancel()
+
+		if err != nil {
+			log.Printf("⚠️  Wallet credit expiry failed: %v", err)
+		} else if expired > 0 {
+			log.Printf("👛 Expired store credit in %d wallets", expired)
+		}
+	}
+}
+
+// startCheckoutPolling asks the payment providers once a minute about
+// hosted checkouts they haven't sent a webhook for, so a lost webhook only
+// delays an order, and cancels checkouts left unpaid past
+// PAYMENT_CHECKOUT_TTL_MIN.
+func startCheckoutPolling(orderService services.OrderService) {
+	const pollInterval = 1 * time.Minute
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
+		settled, err := orderService.PollCheckouts(ctx)
+		cancel()
+
+		if err != nil {
+			log.Printf("⚠️  Checkout polling failed: %v", err)
+		} else if settled > 0 {
+			log.Printf("💳 Settled %d checkouts by polling", settled)
+		}
+	}
+}
+
+// startVerificationRetries asks the providers again, every 15 seconds,
+// about transfers they couldn't be asked about when the customer
+// submitted them, with the backoff and window the service reads from
+// PAYMENT_VERIFICATION_RETRY_*.
+func startVerificationRetries(orderService services.OrderService) {
+	const retryInterval = 15 * time.Second
+
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		tried, err := orderService.RetryPaymentVerifications(ctx)
+		cancel()
+
+		if err != nil {
+			log.Printf("⚠️  Payment verification retries failed: %v", err)
+		} else if tried > 0 {
+			log.Printf("🔁 Retried %d payment verifications", tried)
+		}
+	}
+}
+
+// broadcastPaymentVerification tells the customer's order room where the
+// check of their transfer stands: retrying, verified, failed or sent to
+// an admin for review.
+func broadcastPaymentVerification(order models.Order) {
+	if websocket.GlobalHub == nil {
+		return
+	}
+	websocket.GlobalHub.BroadcastToRoom("order:"+order.ID.Hex(), websocket.WebSocketEvent{
+		Type: "payment:verification",
+		Data: gin.H{
+			"orderId":              order.ID.Hex(),
+			"payment_status":       order.PaymentStatus,
+			"payment_verification": order.PaymentVerification,
+		},
+	})
+}
+
+// broadcastPaymentReview tells the admin room an order's payment review
+// was claimed, released or decided, so every admin's queue stays current.
+func broadcastPaymentReview(order models.Order) {
+	if websocket.GlobalHub == nil {
+		return
+	}
+	websocket.GlobalHub.BroadcastToRoom("admin", websocket.WebSocketEvent{
+		Type: "payment_review:updated",
+		Data: gin.H{
+			"orderId":              order.ID.Hex(),
+			"payment_status":       order.PaymentStatus,
+			"payment_verification": order.PaymentVerification,
+		},
+	})
+}
+
+// broadcastOrderReleased announces an order to drivers once its hosted
+// checkout is paid, as CreateOrder does for orders that don't wait on
+// payment, and tells the customer's order room it's paid.
+func broadcastOrderReleased(order models.Order) {
+	log.Printf("💳 Order %s paid, released to drivers", order.ID.Hex())
+	if websocket.GlobalHub == nil {
+		return
+	}
+	websocket.GlobalHub.BroadcastToRoom("drivers", websocket.WebSocketEvent{
+		Type: "order:new",
+		Data: order,
+	})
+	websocket.GlobalHub.BroadcastToRoom("order:"+order.ID.Hex(), websocket.WebSocketEvent{
+		Type: "order:paid",
+		Data: gin.H{"orderId": order.ID.Hex(), "status": order.Status},
+	})
+}
+
+// broadcastStockAlert tells the admin site and the restaurant's own room
+// that an item is running low or has sold out.
+func broadcastStockAlert(alert services.StockAlert) {
+	if alert.SoldOut {
+		log.Printf("🚫 %s sold out (restaurant %s)", alert.Name, alert.RestaurantID.Hex())
+	} else {
+		log.Printf("📉 %s is low on stock: %d of %d left", alert.Name, alert.Remaining, alert.DailyQuantity)
+	}
+	if websocket.GlobalHub == nil {
+		return
+	}
+	event := websocket.WebSocketEvent{
+		Type: "menu:low_stock",
+		Data: gin.H{
+			"restaurantId":  alert.RestaurantID.Hex(),
+			"menuItemId":    alert.MenuItemID.Hex(),
+			"name":          alert.Name,
+			"remaining":     alert.Remaining,
+			"dailyQuantity": alert.DailyQuantity,
+			"soldOut":       alert.SoldOut,
+		},
+	}
+	websocket.GlobalHub.BroadcastToRoom("admin", event)
+	websocket.GlobalHub.BroadcastToRoom("restaurant:"+alert.RestaurantID.Hex(), event)
+}
+
+// sendTipReceived lets a driver know they've been tipped.
+func sendTipReceived(tip services.TipReceived) {
+	if websocket.GlobalHub == nil {
+		return
+	}
+	websocket.GlobalHub.BroadcastToRoom("driver:"+tip.DriverID.Hex(), websocket.WebSocketEvent{
+		Type: "tip_received",
+		Data: gin.H{
+			"orderId":       tip.OrderID.Hex(),
+			"orderNumber":   tip.OrderNumber,
+			"amount":        tip.Amount,
+			"afterDelivery": tip.AfterDelivery,
+		},
+	})
+}
+
+// rebuildAggregates runs services.OrderService.RebuildAggregates for the
+// rebuild-aggregates command.
+func rebuildAggregates(orderService services.OrderService) error {
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	report, err := orderService.RebuildAggregates(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("✅ Rebuilt aggregates from %d orders in %s: %d deliveries, %d ratings, %d drivers, %d restaurants, %d earning days",
+		report.Orders, time.Since(started).Round(time.Millisecond), report.Deliveries, report.Ratings,
+		report.Drivers, report.Restaurants, report.EarningDays)
+	return nil
+}
+
 func initCloudinary() error {
 	cloudName := os.Getenv("CLOUDINARY_CLOUD_NAME")
 	apiKey := os.Getenv("CLOUDINARY_API_KEY")
@@ -183,7 +433,7 @@ func main() {
 	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
 		log.Printf("Warning: Failed to create uploads directory: %v", err)
 	}
-	subdirs := []string{"restaurants", "menu", "users", "documents"}
+	subdirs := []string{"restaurants", "menu", "users", "documents", "reviews"}
 	for _, subdir := range subdirs {
 		dirPath := filepath.Join(uploadsDir, subdir)
 		if err := os.MkdirAll(dirPath, 0755); err != nil {
@@ -197,6 +447,29 @@ func main() {
 	orderRepo := repositories.NewOrderRepository()
 	restaurantRepo := repositories.NewRestaurantRepository()
 	driverRepo := repositories.NewDriverRepository()
+	zoneRepo := repositories.NewZoneRepository()
+	menuItemRepo := repositories.NewMenuItemRepository()
+	menuCategoryRepo := repositories.NewMenuCategoryRepository()
+	menuHistoryRepo := repositories.NewMenuHistoryRepository()
+	reviewRepo := repositories.NewReviewRepository()
+	driverEarningsRepo := repositories.NewDriverEarningsRepository()
+	ledgerRepo := repositories.NewLedgerRepository()
+	settlementRepo := repositories.NewSettlementRepository()
+	statementRepo := repositories.NewStatementRepository()
+	refundRepo := repositories.NewRefundRepository()
+	walletRepo := repositories.NewWalletRepository()
+	paymentEventRepo := repositories.NewPaymentEventRepository()
+	paymentVerificationJobRepo := repositories.NewPaymentVerificationJobRepository()
+
+	// One-off move of menus out of restaurant documents into menu_items;
+	// a no-op once every restaurant has been migrated.
+	migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), 2*time.Minute)
+	if migrated, err := menuItemRepo.MigrateEmbeddedMenus(migrateCtx); err != nil {
+		log.Printf("❌ Menu migration failed: %v", err)
+	} else if migrated > 0 {
+		log.Printf("✅ Moved %d restaurant menus into menu_items", migrated)
+	}
+	cancelMigrate()
 
 	// PHONE VERIFICATION (commented out — switched to email verification, see below)
 	// var smsClient *sms.Client
@@ -242,8 +515,32 @@ func main() {
 
 	// Initialize services
 	authService := services.NewAuthService(userRepo, adminRepo)
-	orderService := services.NewOrderService(orderRepo, restaurantRepo, userRepo, driverRepo)
-	restaurantService := services.NewRestaurantService(restaurantRepo)
+	zoneService := services.NewZoneService(zoneRepo)
+	surgeService := services.NewSurgeService(zoneRepo, driverRepo, orderRepo)
+	searchService := services.NewSearchService(restaurantRepo, menuItemRepo, zoneService, services.NewMemorySearchIndex())
+	ledgerService := services.NewLedgerService(ledgerRepo, settlementRepo, driverRepo, userRepo)
+	walletService := services.NewWalletService(walletRepo, userRepo)
+	paymentProviders := services.NewPaymentProviders(services.NewChapaProvider(services.ChapaConfigFromEnv()))
+	orderService := services.NewOrderService(orderRepo, restaurantRepo, menuItemRepo, menuCategoryRepo, menuHistoryRepo, userRepo, driverRepo, driverEarningsRepo, refundRepo, zoneService, ledgerService, walletService, paymentProviders, paymentVerificationJobRepo)
+	restaurantService := services.NewRestaurantService(restaurantRepo, menuItemRepo, menuCategoryRepo, menuHistoryRepo, zoneService)
+	reviewService := services.NewReviewService(reviewRepo, orderRepo, restaurantRepo, userRepo)
+	statementService := services.NewStatementService(statementRepo, orderRepo, restaurantRepo, refundRepo)
+	refundService := services.NewRefundService(refundRepo, orderRepo, ledgerService, walletService)
+	paymentEventService := services.NewPaymentEventService(paymentEventRepo, paymentProviders, orderService)
+	orderService.OnStockAlert(broadcastStockAlert)
+	orderService.OnTipReceived(sendTipReceived)
+	orderService.OnOrderReleased(broadcastOrderReleased)
+	orderService.OnPaymentVerificationUpdated(broadcastPaymentVerification)
+	orderService.OnPaymentReviewUpdated(broadcastPaymentReview)
+
+	// `pedal-delivery rebuild-aggregates` recomputes the rating and
+	// earnings aggregates from order history and exits instead of serving.
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-aggregates" {
+		if err := rebuildAggregates(orderService); err != nil {
+			log.Fatalf("❌ Aggregate rebuild failed: %v", err)
+		}
+		return
+	}
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService, emailClient)
@@ -251,6 +548,15 @@ func main() {
 	restaurantHandler := handlers.NewRestaurantHandler(restaurantService)
 	adminHandler := handlers.NewAdminHandler(orderRepo, restaurantRepo, driverRepo, adminRepo)
 	driverHandler := handlers.NewDriverHandler(driverRepo, userRepo) // NEW
+	zoneHandler := handlers.NewZoneHandler(zoneService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	reviewHandler := handlers.NewReviewHandler(reviewService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+	statementHandler := handlers.NewStatementHandler(statementService)
+	refundHandler := handlers.NewRefundHandler(refundService)
+	walletHandler := handlers.NewWalletHandler(walletService)
+	paymentEventHandler := handlers.NewPaymentEventHandler(paymentEventService)
+	paymentReviewHandler := handlers.NewPaymentReviewHandler(orderService)
 
 	handlers.SetUserRepository(userRepo)
 	handlers.SetAdminRepository(adminRepo)
@@ -472,6 +778,8 @@ func main() {
 			})
 		}
 
+		api.GET("/search", searchHandler.Search)
+
 		restaurants := api.Group("/restaurants")
 		{
 			restaurants.GET("", restaurantHandler.GetRestaurants)
@@ -479,8 +787,15 @@ func main() {
 			restaurants.GET("/search", restaurantHandler.SearchRestaurants)
 			restaurants.GET("/:id", restaurantHandler.GetRestaurantByID)
 			restaurants.GET("/:id/menu", restaurantHandler.GetRestaurantMenu)
+			restaurants.GET("/:id/categories", restaurantHandler.GetMenuCategories)
+			restaurants.GET("/:id/reviews", reviewHandler.GetRestaurantReviews)
 		}
 
+		// Payment providers call back here; each webhook is checked against
+		// its provider's signature rather than a user token, and stored in
+		// payment_events before it's applied.
+		api.POST("/webhooks/payments/:provider", paymentEventHandler.PaymentWebhook)
+
 		protected := api.Group("")
 		protected.Use(middleware.AuthMiddleware())
 		{
@@ -492,6 +807,7 @@ func main() {
 				admin.PUT("/profile", adminHandler.UpdateProfile)
 				admin.GET("/orders", orderHandler.GetAllOrders)
 				admin.POST("/orders/:id/payment-review", orderHandler.ReviewPaymentProof)
+				admin.POST("/aggregates/rebuild", orderHandler.RebuildAggregates)
 
 				// ── Driver management routes (admin only) ──────────────────
 				admin.GET("/drivers", driverHandler.GetAllDrivers)
@@ -499,6 +815,58 @@ func main() {
 				admin.POST("/drivers", driverHandler.CreateDriver)
 				admin.PUT("/drivers/:id", driverHandler.UpdateDriver)
 				admin.PUT("/drivers/:id/status", driverHandler.UpdateDriverStatus)
+				admin.GET("/drivers/:id/ledger", ledgerHandler.GetDriverLedger)
+				admin.POST("/drivers/:id/ledger", ledgerHandler.PostLedgerAdjustment)
+				admin.GET("/drivers/:id/cash", ledgerHandler.GetDriverCashAccount)
+				admin.POST("/drivers/:id/cash-deposits", ledgerHandler.PostCashDeposit)
+				admin.PUT("/drivers/:id/cash-limit", ledgerHandler.SetCashLimit)
+
+				// ── Driver settlements (payout runs) ────────────────────────
+				admin.POST("/settlements", ledgerHandler.RunSettlement)
+				admin.GET("/settlements", ledgerHandler.GetSettlements)
+				admin.GET("/settlements/:id", ledgerHandler.GetSettlement)
+				admin.GET("/settlements/:id/export", ledgerHandler.ExportSettlement)
+
+				// ── Restaurant statements (what we owe restaurants) ─────────
+				admin.POST("/statements", statementHandler.GenerateStatements)
+				admin.GET("/statements", statementHandler.GetStatements)
+				admin.GET("/statements/:id", statementHandler.GetStatement)
+				admin.GET("/statements/:id/export", statementHandler.ExportStatement)
+				admin.POST("/statements/:id/adjustments", statementHandler.AddStatementAdjustment)
+				admin.POST("/statements/:id/mark-paid", statementHandler.MarkStatementPaid)
+
+				// ── Service zones (delivery areas) ──────────────────────────
+				admin.GET("/zones", zoneHandler.GetZones)
+				admin.POST("/zones", zoneHandler.CreateZone)
+				admin.GET("/zones/:id", zoneHandler.GetZone)
+				admin.PUT("/zones/:id", zoneHandler.UpdateZone)
+				admin.DELETE("/zones/:id", zoneHandler.DeleteZone)
+
+				// ── Review moderation ───────────────────────────────────────
+				admin.GET("/reviews", reviewHandler.GetReviewsForModeration)
+				admin.PATCH("/reviews/:id/moderation", reviewHandler.ModerateReview)
+
+				// ── Customer wallets (store credit) ─────────────────────────
+				admin.GET("/users/:id/wallet", walletHandler.GetUserWallet)
+				admin.GET("/users/:id/wallet/transactions", walletHandler.GetUserWalletTransactions)
+				admin.POST("/users/:id/wallet/credits", walletHandler.GrantWalletCredit)
+				admin.GET("/payment-events", paymentEventHandler.GetPaymentEvents)
+				admin.GET("/payment-events/:id", paymentEventHandler.GetPaymentEvent)
+				admin.POST("/payment-events/:id/replay", paymentEventHandler.ReplayPaymentEvent)
+				admin.GET("/payment-reviews", paymentReviewHandler.GetPaymentReviewQueue)
+				admin.POST("/payment-reviews/bulk", paymentReviewHandler.BulkReviewPaymentProof)
+				admin.POST("/payment-reviews/:id/claim", paymentReviewHandler.ClaimPaymentReview)
+				admin.DELETE("/payment-reviews/:id/claim", paymentReviewHandler.ReleasePaymentReview)
+			}
+
+			// Refunds are issued by admins.
+			refunds := protected.Group("/refunds")
+			refunds.Use(middleware.AdminOnly())
+			{
+				refunds.POST("", refundHandler.CreateRefund)
+				refunds.GET("", refundHandler.GetRefunds)
+				refunds.GET("/:id", refundHandler.GetRefund)
+				refunds.POST("/:id/process", refundHandler.ProcessRefund)
 			}
 
 			user := protected.Group("/users")
@@ -515,6 +883,9 @@ func main() {
 				user.DELETE("/favorites/:restaurantId", userHandler.RemoveFavoriteRestaurant)
 				user.GET("/favorites", userHandler.GetFavoriteRestaurants)
 
+				user.GET("/wallet", walletHandler.GetMyWallet)
+				user.GET("/wallet/transactions", walletHandler.GetMyWalletTransactions)
+
 				user.POST("/upload", func(c *gin.Context) {
 					file, err := c.FormFile("image")
 					if err != nil {
@@ -580,6 +951,8 @@ func main() {
 			orders := protected.Group("/orders")
 			{
 				orders.POST("", orderHandler.CreateOrder)
+				orders.POST("/quote", orderHandler.QuoteOrder)
+				orders.GET("/tip-presets", orderHandler.GetTipPresets)
 				orders.GET("", orderHandler.GetCustomerOrders)
 				orders.GET("/health/payment-verification", orderHandler.GetPaymentVerificationHealth)
 				orders.GET("/driver", orderHandler.GetDriverOrders) // must be before /:id
@@ -588,6 +961,8 @@ func main() {
 				orders.POST("/:id/payment-proof", orderHandler.SubmitPaymentProof)
 				orders.POST("/:id/cancel", orderHandler.CancelOrder)
 				orders.POST("/:id/rate", orderHandler.RateOrder)
+				orders.POST("/:id/tip", orderHandler.TipOrder)
+				orders.POST("/:id/review", reviewHandler.CreateReview)
 				orders.PUT("/:id/status", orderHandler.UpdateOrderStatus)
 			}
 
@@ -597,9 +972,21 @@ func main() {
 				driver.GET("/orders/available", orderHandler.GetAvailableOrders)
 				driver.POST("/orders/:id/accept", orderHandler.AcceptOrder)
 				driver.POST("/orders/:id/reject", orderHandler.RejectOrder)
+				driver.POST("/orders/:id/cash-collected", orderHandler.ConfirmCashCollected)
 				driver.GET("/stats", orderHandler.GetDriverStats)
 				driver.GET("/earnings/chart", orderHandler.GetDriverEarningsChart)
 				driver.GET("/earnings/transactions", orderHandler.GetDriverEarningsTransactions)
+				driver.GET("/payout-account", ledgerHandler.GetPayoutAccount)
+				driver.PUT("/payout-account", ledgerHandler.UpdatePayoutAccount)
+				driver.GET("/cash", ledgerHandler.GetMyCashAccount)
+			}
+
+			// Replies are checked against the restaurant's owner in the
+			// service, so these aren't admin-only.
+			reviews := protected.Group("/restaurants/:id/reviews")
+			{
+				reviews.POST("/:reviewId/reply", reviewHandler.ReplyToReview)
+				reviews.POST("/:reviewId/flag", reviewHandler.FlagReview)
 			}
 
 			restaurantAdmin := protected.Group("/restaurants")
@@ -609,9 +996,20 @@ func main() {
 				restaurantAdmin.GET("/all", restaurantHandler.GetAllRestaurantsAdmin)
 				restaurantAdmin.PUT("/:id", restaurantHandler.UpdateRestaurant)
 				restaurantAdmin.PATCH("/:id/verify", restaurantHandler.VerifyRestaurant)
+				restaurantAdmin.GET("/:id/commission", statementHandler.GetCommission)
+				restaurantAdmin.PUT("/:id/commission", statementHandler.SetCommission)
 				restaurantAdmin.DELETE("/:id", restaurantHandler.DeleteRestaurant)
 				restaurantAdmin.POST("/:id/menu", restaurantHandler.AddMenuItem)
 				restaurantAdmin.PUT("/:id/menu/:itemId", restaurantHandler.UpdateMenuItem)
+				restaurantAdmin.DELETE("/:id/menu/:itemId", restaurantHandler.DeleteMenuItem)
+				restaurantAdmin.POST("/:id/menu/:itemId/unarchive", restaurantHandler.UnarchiveMenuItem)
+				restaurantAdmin.GET("/:id/menu/history", restaurantHandler.GetMenuHistory)
+				restaurantAdmin.POST("/:id/menu/history/:changeId/restore", restaurantHandler.RestoreMenuItemVersion)
+				restaurantAdmin.GET("/:id/menu/export", restaurantHandler.ExportMenu)
+				restaurantAdmin.POST("/:id/menu/import", restaurantHandler.ImportMenu)
+				restaurantAdmin.POST("/:id/categories", restaurantHandler.CreateMenuCategory)
+				restaurantAdmin.PUT("/:id/categories/:categoryId", restaurantHandler.UpdateMenuCategory)
+				restaurantAdmin.DELETE("/:id/categories/:categoryId", restaurantHandler.DeleteMenuCategory)
 			}
 		}
 	}
@@ -619,6 +1017,12 @@ func main() {
 	// Inject driver repository so WebSocket handlers can persist online
 	// status and GPS location when the driver toggles or moves.
 	websocket.SetDriverRepository(driverRepo)
+	// Inject the order service so driver GPS pings also feed the geofence
+	// engine (arrival detection / optional auto-advance).
+	websocket.SetOrderService(orderService)
+	// Plausibility-check driver GPS (impossible speed, teleports, repeated
+	// fixes, out-of-area points) before it's trusted for anything.
+	websocket.SetLocationAnomalyDetector(services.NewLocationAnomalyDetector(driverRepo))
 	websocket.SetupWebSocketRoutes(router.Group(""), middleware.AuthMiddleware())
 
 	// Auto-cancel sweep: nothing previously expired an order that no
@@ -630,6 +1034,25 @@ func main() {
 	// instead of only after their next pull-to-refresh.
 	go startStaleOrderSweep(orderService)
 
+	// Surge engine: per-zone demand/supply → live delivery-fee multiplier.
+	// Only does anything once service zones have been drawn.
+	go startSurgeEngine(surgeService)
+
+	// Daily stock: refill stock-tracked menu items (and switch sold-out
+	// ones back on) at STOCK_RESET_TIME each day.
+	go startStockReset(restaurantService)
+
+	// Wallets: take store credit off wallets once it expires.
+	go startWalletExpiry(walletService)
+
+	// Hosted checkout: catch payments whose webhook never arrived and
+	// cancel checkouts that expired unpaid.
+	go startCheckoutPolling(orderService)
+
+	// Transfer verification: keep asking the providers about transfers
+	// they didn't answer for, and hand unresolved ones to admin review.
+	go startVerificationRetries(orderService)
+
 	if cfg.Server.Environment != "production" {
 		router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/haile-paa/pedal-delivery/internal/config"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -17,15 +18,29 @@ var (
 	client      *mongo.Client
 	database    *mongo.Database
 	collections = struct {
-		Users         *mongo.Collection
-		Admins        *mongo.Collection
-		Drivers       *mongo.Collection
-		Restaurants   *mongo.Collection
-		Orders        *mongo.Collection
-		MenuItems     *mongo.Collection
-		Documents     *mongo.Collection
-		Notifications *mongo.Collection
-		ChatMessages  *mongo.Collection
+		Users          *mongo.Collection
+		Admins         *mongo.Collection
+		Drivers        *mongo.Collection
+		Restaurants    *mongo.Collection
+		Orders         *mongo.Collection
+		MenuItems      *mongo.Collection
+		Documents      *mongo.Collection
+		Notifications  *mongo.Collection
+		ChatMessages   *mongo.Collection
+		ServiceZones   *mongo.Collection
+		MenuCategories *mongo.Collection
+		MenuHistory    *mongo.Collection
+		Reviews        *mongo.Collection
+		DriverEarnings *mongo.Collection
+		LedgerEntries  *mongo.Collection
+		SettlementRuns *mongo.Collection
+		PayoutBatches  *mongo.Collection
+		Statements     *mongo.Collection
+		Refunds        *mongo.Collection
+		Wallets        *mongo.Collection
+		WalletEntries  *mongo.Collection
+		PaymentEvents  *mongo.Collection
+		PaymentJobs    *mongo.Collection
 	}{}
 )
 
@@ -74,6 +89,20 @@ func initializeCollections() {
 	collections.Documents = database.Collection("documents")
 	collections.Notifications = database.Collection("notifications")
 	collections.ChatMessages = database.Collection("chat_messages")
+	collections.ServiceZones = database.Collection("service_zones")
+	collections.MenuCategories = database.Collection("menu_categories")
+	collections.MenuHistory = database.Collection("menu_item_history")
+	collections.Reviews = database.Collection("reviews")
+	collections.DriverEarnings = database.Collection("driver_earnings_daily")
+	collections.LedgerEntries = database.Collection("ledger_entries")
+	collections.SettlementRuns = database.Collection("settlement_runs")
+	collections.PayoutBatches = database.Collection("payout_batches")
+	collections.Statements = database.Collection("restaurant_statements")
+	collections.Refunds = database.Collection("refunds")
+	collections.Wallets = database.Collection("wallets")
+	collections.WalletEntries = database.Collection("wallet_transactions")
+	collections.PaymentEvents = database.Collection("payment_events")
+	collections.PaymentJobs = database.Collection("payment_verification_jobs")
 }
 
 func createIndexes(ctx context.Context) {
@@ -172,6 +201,10 @@ func createIndexes(ctx context.Context) {
 		},
 	})
 
+	collections.Orders.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"zone_id": 1},
+	})
+
 	collections.Orders.Indexes().CreateOne(ctx, mongo.IndexModel{
 		Keys: map[string]interface{}{
 			"payment_verification.transaction_reference": 1,
@@ -185,6 +218,192 @@ func createIndexes(ctx context.Context) {
 				},
 			}),
 	})
+
+	// The admin payment review queue, oldest first.
+	collections.Orders.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "payment_verification.status", Value: 1}, {Key: "payment_verification.review_requested_at", Value: 1}},
+	})
+
+	// Menu items: every menu read is scoped to one restaurant and usually
+	// filtered or sorted by category. bson.D keeps the key order, which
+	// matters for a compound index.
+	collections.MenuItems.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "category", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+
+	collections.MenuItems.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"category": 1},
+	})
+
+	collections.MenuItems.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"category_id": 1},
+	})
+
+	// SKUs are the restaurant's own codes, so unique per restaurant;
+	// partial so items without one don't collide.
+	collections.MenuItems.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "sku", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(map[string]interface{}{
+				"sku": map[string]interface{}{"$type": "string"},
+			}),
+	})
+
+	// The daily stock reset looks for tracked items by when they were last
+	// refilled; sparse so untracked items stay out of the index.
+	collections.MenuItems.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]interface{}{"stock.reset_at": 1},
+		Options: options.Index().SetSparse(true),
+	})
+
+	// Menu categories are always read per restaurant in display order.
+	collections.MenuCategories.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "sort_order", Value: 1}},
+	})
+
+	// Menu history is read newest first, per restaurant or per item.
+	collections.MenuHistory.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+
+	collections.MenuHistory.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "menu_item_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+
+	// One review per order; the public list is per restaurant, newest
+	// first, and moderation works through flagged reviews.
+	collections.Reviews.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]interface{}{"order_id": 1},
+		Options: options.Index().SetUnique(true),
+	})
+
+	collections.Reviews.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+
+	collections.Reviews.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "flag_count", Value: -1}, {Key: "created_at", Value: 1}},
+	})
+
+	// One earnings bucket per driver per day; the stats read a driver's
+	// buckets by day range.
+	collections.DriverEarnings.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "driver_id", Value: 1}, {Key: "day", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	// Ledger: an event (a delivery, a payout) posts at most once; drivers
+	// read their own entries newest first; settlement runs claim the
+	// unsettled entries in their period.
+	collections.LedgerEntries.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]interface{}{"idempotency_key": 1},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+
+	collections.LedgerEntries.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "driver_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+
+	collections.LedgerEntries.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "settlement_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+
+	collections.SettlementRuns.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"created_at": -1},
+	})
+
+	collections.PayoutBatches.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "settlement_id", Value: 1}, {Key: "driver_name", Value: 1}},
+	})
+
+	// Restaurant statements: listed newest first, overall and per
+	// restaurant; orders are looked up by the statement that billed them.
+	collections.Statements.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+
+	collections.Statements.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"created_at": -1},
+	})
+
+	collections.Orders.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]interface{}{"statement_id": 1},
+		Options: options.Index().SetSparse(true),
+	})
+
+	// Refunds: listed per order and, for the admin queue, by status newest
+	// first; statement generation looks up a restaurant's unbilled ones.
+	collections.Refunds.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "order_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+
+	collections.Refunds.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+
+	collections.Refunds.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "statement_id", Value: 1}, {Key: "status", Value: 1}},
+	})
+
+	// Wallets: one projection per user; the expiry sweep looks for lots
+	// past their expiry.
+	collections.Wallets.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]interface{}{"user_id": 1},
+		Options: options.Index().SetUnique(true),
+	})
+
+	collections.Wallets.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"lots.expires_at": 1},
+	})
+
+	// Wallet log: one entry per sequence number per user, which is what
+	// serialises concurrent balance changes; an event (a refund credit, an
+	// order payment) is recorded at most once.
+	collections.WalletEntries.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "seq", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	collections.WalletEntries.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]interface{}{"idempotency_key": 1},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+
+	// Payment events: a provider's event is stored once, however often it's
+	// delivered; admins list them by status newest first.
+	collections.PaymentEvents.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "provider", Value: 1}, {Key: "event_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	collections.PaymentEvents.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "received_at", Value: -1}},
+	})
+
+	collections.PaymentEvents.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"order_id": 1},
+	})
+
+	// Payment verification retries: one job per order; the retry worker
+	// picks up queued jobs as they fall due.
+	collections.PaymentJobs.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]interface{}{"order_id": 1},
+		Options: options.Index().SetUnique(true),
+	})
+
+	collections.PaymentJobs.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+	})
+
+	// Service zones: ZoneRepository.FindContaining runs $geoIntersects
+	// against "area", which needs the 2dsphere index.
+	collections.ServiceZones.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"area": "2dsphere"},
+	})
+
+	collections.ServiceZones.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"is_active": 1},
+	})
 }
 
 func GetClient() *mongo.Client {
@@ -196,15 +415,29 @@ func GetDB() *mongo.Database {
 }
 
 func GetCollections() struct {
-	Users         *mongo.Collection
-	Admins        *mongo.Collection
-	Drivers       *mongo.Collection
-	Restaurants   *mongo.Collection
-	Orders        *mongo.Collection
-	MenuItems     *mongo.Collection
-	Documents     *mongo.Collection
-	Notifications *mongo.Collection
-	ChatMessages  *mongo.Collection
+	Users          *mongo.Collection
+	Admins         *mongo.Collection
+	Drivers        *mongo.Collection
+	Restaurants    *mongo.Collection
+	Orders         *mongo.Collection
+	MenuItems      *mongo.Collection
+	Documents      *mongo.Collection
+	Notifications  *mongo.Collection
+	ChatMessages   *mongo.Collection
+	ServiceZones   *mongo.Collection
+	MenuCategories *mongo.Collection
+	MenuHistory    *mongo.Collection
+	Reviews        *mongo.Collection
+	DriverEarnings *mongo.Collection
+	LedgerEntries  *mongo.Collection
+	SettlementRuns *mongo.Collection
+	PayoutBatches  *mongo.Collection
+	Statements     *mongo.Collection
+	Refunds        *mongo.Collection
+	Wallets        *mongo.Collection
+	WalletEntries  *mongo.Collection
+	PaymentEvents  *mongo.Collection
+	PaymentJobs    *mongo.Collection
 } {
 	return collections
 }
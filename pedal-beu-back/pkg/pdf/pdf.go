@@ -0,0 +1,190 @@
+// Package pdf writes simple text-and-rules PDF documents — enough for
+// statements and reports — using the standard Helvetica fonts every PDF
+// reader ships, so nothing has to be embedded.
+//
+// Text is encoded as WinAnsi (Latin-1); characters outside it, such as
+// Ethiopic, are written as "?".
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 portrait, in points.
+const (
+	PageWidth  = 595.0
+	PageHeight = 842.0
+)
+
+// Font is one of the two fonts a Document can use.
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+// Document is a PDF being built page by page. Coordinates are in points
+// from the top-left corner of the page.
+type Document struct {
+	pages []*bytes.Buffer
+}
+
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage starts a new page; everything drawn afterwards goes on it.
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *Document) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// Text draws s with its baseline starting at (x, y).
+func (d *Document) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(d.page(), "BT /F%d %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font+1, size, x, PageHeight-y, escape(s))
+}
+
+// TextRight draws s so it ends at x — for columns of amounts.
+func (d *Document) TextRight(x, y float64, font Font, size float64, s string) {
+	d.Text(x-TextWidth(font, size, s), y, font, size, s)
+}
+
+// Line draws a thin rule from (x1, y1) to (x2, y2).
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// TextWidth is how wide s is when drawn in font at size. Widths are exact
+// for digits, punctuation and spaces and approximate for letters, which is
+// all right-aligned amounts need.
+func TextWidth(font Font, size float64, s string) float64 {
+	units := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			units += 556
+		case r == ' ' || r == '.' || r == ',':
+			units += 278
+		case r == '-':
+			units += 333
+		case r == '–':
+			units += 556
+		case r == '%':
+			units += 889
+		case font == Bold:
+			units += 611
+		default:
+			units += 556
+		}
+	}
+	return float64(units) * size / 1000
+}
+
+// winAnsiPunctuation maps the typographic characters WinAnsi keeps in
+// 0x80–0x9f to their codes there.
+var winAnsiPunctuation = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97,
+}
+
+// escape encodes s as WinAnsi and escapes it for a PDF string literal.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if code, ok := winAnsiPunctuation[r]; ok {
+			b.WriteByte(code)
+			continue
+		}
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r == '\t':
+			b.WriteByte(' ')
+		case r < 0x20 || (r >= 0x7f && r < 0xa0) || r > 0xff:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
+
+// countingWriter tracks the byte offsets the cross-reference table needs.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) printf(format string, args ...interface{}) {
+	if c.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(c.w, format, args...)
+	c.n += int64(n)
+	c.err = err
+}
+
+// WriteTo writes the finished document. A document with nothing drawn on
+// it gets a single blank page.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	d.page()
+
+	// Objects: 1 catalog, 2 page tree, 3 and 4 the fonts, then a page and
+	// its content stream for each page.
+	objects := 4 + 2*len(d.pages)
+	offsets := make([]int64, objects+1)
+	out := &countingWriter{w: w}
+
+	begin := func(id int) {
+		offsets[id] = out.n
+		out.printf("%d 0 obj\n", id)
+	}
+
+	out.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	begin(1)
+	out.printf("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	begin(2)
+	out.printf("<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(d.pages))
+
+	for i, name := range []string{"Helvetica", "Helvetica-Bold"} {
+		begin(3 + i)
+		out.printf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\nendobj\n", name)
+	}
+
+	for i, content := range d.pages {
+		pageID := 5 + 2*i
+		begin(pageID)
+		out.printf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			PageWidth, PageHeight, pageID+1)
+
+		begin(pageID + 1)
+		out.printf("<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", content.Len(), content.Bytes())
+	}
+
+	xref := out.n
+	out.printf("xref\n0 %d\n0000000000 65535 f \n", objects+1)
+	for id := 1; id <= objects; id++ {
+		out.printf("%010d 00000 n \n", offsets[id])
+	}
+	out.printf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", objects+1, xref)
+
+	return out.n, out.err
+}
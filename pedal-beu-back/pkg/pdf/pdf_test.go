@@ -0,0 +1,86 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"(draft)", `\(draft\)`},
+		{`C:\menu`, `C:\\menu`},
+		{`a (b\c)`, `a \(b\\c\)`},
+		{"tab\there", "tab here"},
+		{"café", "caf\xe9"},
+		{"10–20", "10\x9620"},
+		{"ሽሮ", "??"},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestWriteToXref checks the cross-reference table a reader uses to find
+// each object: every offset has to land on that object's header.
+func TestWriteToXref(t *testing.T) {
+	doc := New()
+	doc.Text(40, 40, Bold, 14, `Statement (draft) \ March`)
+	doc.Line(40, 50, 555, 50)
+	doc.AddPage()
+	doc.TextRight(555, 40, Regular, 10, "1,250.00")
+
+	var buf bytes.Buffer
+	n, err := doc.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.Bytes()
+	if n != int64(len(out)) {
+		t.Errorf("WriteTo returned %d, wrote %d bytes", n, len(out))
+	}
+
+	trailer := regexp.MustCompile(`trailer\n<< /Size (\d+) /Root 1 0 R >>\nstartxref\n(\d+)\n%%EOF\n$`).FindSubmatch(out)
+	if trailer == nil {
+		t.Fatalf("no trailer at the end of:\n%s", out)
+	}
+	size, _ := strconv.Atoi(string(trailer[1]))
+	xref, _ := strconv.Atoi(string(trailer[2]))
+	// Catalog, page tree, two fonts, and a page and contents per page.
+	if size != 4+2*2+1 {
+		t.Errorf("trailer /Size = %d, want %d", size, 4+2*2+1)
+	}
+
+	lines := strings.Split(string(out[xref:]), "\n")
+	if lines[0] != "xref" || lines[1] != fmt.Sprintf("0 %d", size) {
+		t.Fatalf("startxref %d doesn't point at the xref table: %q", xref, lines[:2])
+	}
+	if lines[2] != "0000000000 65535 f " {
+		t.Errorf("free entry = %q", lines[2])
+	}
+	for id := 1; id < size; id++ {
+		entry := lines[2+id]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("xref entry for object %d = %q", id, entry)
+		}
+		offset, err := strconv.Atoi(entry[:10])
+		if err != nil {
+			t.Fatalf("xref entry for object %d = %q", id, entry)
+		}
+		if header := fmt.Sprintf("%d 0 obj\n", id); !bytes.HasPrefix(out[offset:], []byte(header)) {
+			t.Errorf("object %d's offset %d points at %q", id, offset, out[offset:min(offset+20, len(out))])
+		}
+	}
+
+	if !bytes.Contains(out, []byte(`(Statement \(draft\) \\ March) Tj`)) {
+		t.Errorf("text isn't escaped in the content stream:\n%s", out)
+	}
+}
@@ -295,7 +295,7 @@ export const restaurantAPI = {
   ): Promise<{ success: boolean; data: MenuItem[]; error?: string }> => {
     try {
       const response = await retryRequest(
-        () => api.get(`/restaurants/${restaurantId}/menu`),
+        () => api.get(`/restaurants/${restaurantId}/menu`, { params: { flat: true } }),
         2,
         500,
       );